@@ -0,0 +1,91 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/spf13/cobra"
+)
+
+var auditCheckpointsCmd = &cobra.Command{
+	Use:   "audit-checkpoints",
+	Short: "Report which versions have recoverable state",
+	Long: `Try to fetch the checkpoint of every version in the stack's history and
+report which versions can be rolled back to. Nothing is modified.
+
+Some backends prune old checkpoints, so run this before an incident to know
+how far back a rollback is possible.
+
+Examples:
+  pulumi-rollback audit-checkpoints --stack mystack
+
+  # Fetch up to 8 checkpoints at a time
+  pulumi-rollback audit-checkpoints --stack mystack --max-concurrent-fetches 8`,
+	RunE: runAuditCheckpoints,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCheckpointsCmd)
+}
+
+func runAuditCheckpoints(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	stack, err := getStackName()
+	if err != nil {
+		return err
+	}
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+
+	opts := rollback.RollbackOptions{
+		ProjectPath:   getProjectPath(),
+		StackName:     stack,
+		Verbose:       isVerbose(),
+		SkipHashCheck: skipHashCheck,
+		Output:        os.Stdout,
+		Operator:      newStackOperator(pulumiCommand),
+		Logger:        newLogger(os.Stdout),
+	}
+
+	statuses, err := rollback.AuditCheckpoints(ctx, opts, maxConcurrentFetches)
+	if err != nil {
+		return fmt.Errorf("audit failed: %w", err)
+	}
+	if len(statuses) == 0 {
+		fmt.Println("No deployment history found for this stack.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tCHECKPOINT\tDETAIL")
+	fmt.Fprintln(w, "-------\t----------\t------")
+
+	available, oldest := 0, 0
+	for _, s := range statuses {
+		if s.Available {
+			available++
+			oldest = s.Version
+			fmt.Fprintf(w, "%d\t✓ available\t%d bytes\n", s.Version, s.Size)
+		} else {
+			fmt.Fprintf(w, "%d\t✗ missing\t%s\n", s.Version, truncateString(s.Err.Error(), 60))
+		}
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d of %d version(s) have recoverable state\n", available, len(statuses))
+	if available > 0 {
+		fmt.Printf("Oldest recoverable version: %d\n", oldest)
+	}
+	setGitHubOutput("recoverable-versions", strconv.Itoa(available))
+	return nil
+}
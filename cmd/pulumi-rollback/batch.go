@@ -0,0 +1,331 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/config"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/manifest"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchManifest string
+	batchPreview  bool
+	batchOutput   string
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Roll back several stacks listed in a manifest, all or nothing",
+	Long: `Roll back several stacks together, as listed in a YAML or JSON manifest:
+
+  stacks:
+    - stack: network
+      version: 4
+      cwd: network
+    - stack: app
+      version: 12
+      cwd: app
+
+"cwd" is the stack's project directory, relative to the manifest. Files
+ending in .yaml or .yml are read as YAML, anything else as JSON.
+
+Every stack's rollback is previewed first, up to --max-concurrent-fetches at
+a time, and a combined report is printed. With --preview nothing else
+happens. Otherwise, if every stack can be rolled back, the stacks are rolled
+back one at a time in manifest order after confirmation. A stack with a
+confirmation phrase in its project's .pulumi-rollback.json (or --config)
+needs its phrase typed. If one fails, the
+batch is aborted: the failed stack and every stack already rolled back are
+restored from their backups, newest first, and the remaining stacks are not
+changed.
+
+Examples:
+  # Review a coordinated rollback
+  pulumi-rollback batch --file rollbacks.yaml --preview
+
+  # Execute it
+  pulumi-rollback batch --file rollbacks.yaml`,
+	RunE: runBatch,
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.Flags().StringVarP(&batchManifest, "file", "f", "", "Path to the YAML or JSON manifest listing the stacks and versions (required)")
+	batchCmd.Flags().StringVar(&batchManifest, "manifest", "", "Alias of --file")
+	batchCmd.Flags().BoolVar(&batchPreview, "preview", false, "Preview every stack's rollback and print the combined report without executing")
+	batchCmd.Flags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip confirmation prompt")
+	batchCmd.Flags().StringVarP(&batchOutput, "output", "o", "text", "Output format of the report: text or json")
+	batchCmd.MarkFlagsOneRequired("file", "manifest")
+	batchCmd.MarkFlagsMutuallyExclusive("file", "manifest")
+}
+
+// batchStackRecord is one stack of the JSON batch report
+type batchStackRecord struct {
+	Stack         string         `json:"stack"`
+	ProjectPath   string         `json:"cwd"`
+	TargetVersion int            `json:"targetVersion"`
+	Success       bool           `json:"success"`
+	Error         string         `json:"error,omitempty"`
+	Changes       map[string]int `json:"changes,omitempty"`
+}
+
+// batchReport is the document written by batch --output json
+type batchReport struct {
+	Stacks  []batchStackRecord `json:"stacks"`
+	Changes map[string]int     `json:"changes"`
+	Deletes int                `json:"deletes"`
+	Failed  []string           `json:"failed,omitempty"`
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if err := requireProjectDir("batch"); err != nil {
+		return err
+	}
+	jsonOutput, err := isJSONOutput(batchOutput)
+	if err != nil {
+		return err
+	}
+	if jsonOutput && !batchPreview {
+		return fmt.Errorf("--output json requires --preview")
+	}
+	yes, err := assumeYes()
+	if err != nil {
+		return err
+	}
+	if !yes && !batchPreview {
+		if err := requirePrompt(stdinIsTerminal, confirmHint); err != nil {
+			return err
+		}
+	}
+
+	m, err := manifest.Load(batchManifest)
+	if err != nil {
+		return err
+	}
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+
+	targets := make([]rollback.RollbackSpec, len(m.Stacks))
+	for i, e := range m.Stacks {
+		targets[i] = rollback.RollbackSpec{StackName: e.Stack, ProjectPath: e.Cwd, TargetVersion: e.Version}
+	}
+	phrases, err := batchPhrases(targets)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if jsonOutput {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "Previewing rollback of %d stack(s)...\n", len(targets))
+
+	opts := rollback.RollbackOptions{
+		Verbose:       isVerbose(),
+		SkipHashCheck: skipHashCheck,
+		Operator:      newStackOperator(pulumiCommand),
+
+		ToolVersion: Version,
+		Initiator:   getInitiator(),
+		BackupDir:   getBackupDir(),
+	}
+	report := func(previews []rollback.BatchPreview, summary rollback.BatchSummary) error {
+		if isVerbose() {
+			for _, p := range previews {
+				fmt.Fprintf(out, "\n--- %s ---\n%s", p.Target.StackName, p.Log)
+			}
+		}
+		if jsonOutput {
+			return writeBatchReportJSON(os.Stdout, previews, summary)
+		}
+		printBatchReport(out, previews, summary)
+		return nil
+	}
+
+	if batchPreview {
+		previews, err := rollback.PreviewBatch(ctx, targets, opts, maxConcurrentFetches)
+		if err != nil {
+			return err
+		}
+		summary := rollback.SummarizeBatch(previews)
+		if err := report(previews, summary); err != nil {
+			return err
+		}
+		if len(summary.Failed) > 0 {
+			return fmt.Errorf("%d of %d stack(s) cannot be rolled back", len(summary.Failed), len(targets))
+		}
+		return nil
+	}
+
+	opts.Output = out
+	opts.Logger = newLogger(out)
+	result, err := rollback.BatchRollback(ctx, targets, rollback.BatchOptions{
+		Rollback: opts,
+		Limit:    maxConcurrentFetches,
+		Confirm: func(previews []rollback.BatchPreview, summary rollback.BatchSummary) (bool, error) {
+			if err := report(previews, summary); err != nil {
+				return false, err
+			}
+			if yes {
+				return true, nil
+			}
+			fmt.Fprintln(out, "⚠️  WARNING: This will modify the infrastructure of every stack listed!")
+			return confirmBatch(ctx, out, os.Stdin, targets, phrases)
+		},
+	})
+	if errors.Is(err, rollback.ErrBatchPreviewFailed) {
+		if reportErr := report(result.Previews, result.Summary); reportErr != nil {
+			return reportErr
+		}
+		return err
+	}
+	if result != nil && result.Cancelled {
+		fmt.Fprintln(out, "Rollback cancelled.")
+		return nil
+	}
+	if err != nil {
+		if result != nil && len(result.Executions) > 0 {
+			printBatchRestores(out, result.Executions)
+		}
+		return err
+	}
+
+	fmt.Fprintf(out, "\n✓ Rolled back %d stack(s)\n", len(targets))
+	return nil
+}
+
+// batchPhrases returns the confirmation phrase of each stack of a batch, or
+// "" for a stack that has none. A stack's configuration file is read from
+// its project directory, unless --config names one for every stack.
+func batchPhrases(specs []rollback.RollbackSpec) ([]string, error) {
+	configs := make(map[string]*config.Config)
+	phrases := make([]string, len(specs))
+	for i, spec := range specs {
+		file := configFile
+		if file == "" {
+			file = filepath.Join(spec.ProjectPath, config.DefaultName)
+		}
+		cfg, ok := configs[file]
+		if !ok {
+			var err error
+			if cfg, err = config.Load(file); err != nil {
+				return nil, err
+			}
+			configs[file] = cfg
+		}
+		phrases[i] = cfg.ConfirmationPhrase(spec.StackName)
+	}
+	return phrases, nil
+}
+
+// confirmBatch asks whether to roll back a batch. Every stack that has a
+// confirmation phrase needs its phrase typed; a batch without any asks y/N.
+func confirmBatch(ctx context.Context, out io.Writer, in io.Reader, specs []rollback.RollbackSpec, phrases []string) (bool, error) {
+	// One reader for every answer, so none is lost to another's buffer
+	reader := bufio.NewReader(in)
+	protected := false
+	for i, phrase := range phrases {
+		if phrase == "" {
+			continue
+		}
+		protected = true
+		fmt.Fprintf(out, "Stack %s is protected. ", specs[i].StackName)
+		confirmed, err := confirmRollback(ctx, out, reader, phrase)
+		if err != nil || !confirmed {
+			return false, err
+		}
+	}
+	if protected {
+		return true, nil
+	}
+	return confirmRollback(ctx, out, reader, "")
+}
+
+// printBatchRestores reports how each stack of an aborted batch was left
+func printBatchRestores(w io.Writer, executions []rollback.BatchExecution) {
+	fmt.Fprintln(w, "\nThe batch was aborted:")
+	for _, e := range executions {
+		switch {
+		case e.Restored:
+			fmt.Fprintf(w, "  %s: restored to version %d\n", e.Target.StackName, e.PreviousVersion)
+		case e.RestoreErr != nil:
+			fmt.Fprintf(w, "  %s: ✗ restore failed, restore it by hand: %v\n", e.Target.StackName, e.RestoreErr)
+		default:
+			fmt.Fprintf(w, "  %s: not changed\n", e.Target.StackName)
+		}
+	}
+	fmt.Fprintln(w, "Remaining stacks were not changed.")
+}
+
+// printBatchReport prints the combined preview of a batch rollback
+func printBatchReport(w io.Writer, previews []rollback.BatchPreview, summary rollback.BatchSummary) {
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STACK\tVERSION\tSTATUS\tCHANGES")
+	fmt.Fprintln(tw, "-----\t-------\t------\t-------")
+	for _, p := range previews {
+		if p.Err != nil {
+			fmt.Fprintf(tw, "%s\t%d\t✗ failed\t%s\n", p.Target.StackName, p.Target.TargetVersion, truncateString(p.Err.Error(), 60))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t✓ ok\t%s\n", p.Target.StackName, p.Target.TargetVersion, formatChanges(p.Result.ResourceChanges))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nTotal changes: %s\n", formatChanges(summary.Changes))
+	fmt.Fprintf(w, "Total deletes: %d\n", summary.Deletes)
+	if len(summary.Failed) > 0 {
+		fmt.Fprintf(w, "\n%d stack(s) cannot be rolled back:\n", len(summary.Failed))
+		for _, p := range previews {
+			if p.Err != nil {
+				fmt.Fprintf(w, "  %s: %v\n", p.Target.StackName, p.Err)
+			}
+		}
+	}
+}
+
+// writeBatchReportJSON writes the combined preview of a batch rollback to w
+func writeBatchReportJSON(w io.Writer, previews []rollback.BatchPreview, summary rollback.BatchSummary) error {
+	report := batchReport{
+		Stacks:  make([]batchStackRecord, len(previews)),
+		Changes: summary.Changes,
+		Deletes: summary.Deletes,
+		Failed:  summary.Failed,
+	}
+	for i, p := range previews {
+		record := batchStackRecord{
+			Stack:         p.Target.StackName,
+			ProjectPath:   p.Target.ProjectPath,
+			TargetVersion: p.Target.TargetVersion,
+			Success:       p.Err == nil,
+		}
+		if p.Err != nil {
+			record.Error = p.Err.Error()
+		} else {
+			record.Changes = p.Result.ResourceChanges
+		}
+		report.Stacks[i] = record
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+)
+
+func TestBatchPhrases(t *testing.T) {
+	old := configFile
+	configFile = ""
+	t.Cleanup(func() { configFile = old })
+
+	dir := t.TempDir()
+	network := filepath.Join(dir, "network")
+	app := filepath.Join(dir, "app")
+	for _, d := range []string{network, app} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := `{"confirmations": [{"stack": "*prod*", "phrase": "roll back network"}]}`
+	if err := os.WriteFile(filepath.Join(network, ".pulumi-rollback.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	specs := []rollback.RollbackSpec{
+		{StackName: "prod", ProjectPath: network, TargetVersion: 4},
+		{StackName: "dev", ProjectPath: network, TargetVersion: 2},
+		{StackName: "prod", ProjectPath: app, TargetVersion: 12},
+	}
+	phrases, err := batchPhrases(specs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"roll back network", "", ""}
+	if !reflect.DeepEqual(phrases, expected) {
+		t.Errorf("Expected phrases %q, got %q", expected, phrases)
+	}
+}
+
+func TestConfirmBatch(t *testing.T) {
+	specs := []rollback.RollbackSpec{{StackName: "network"}, {StackName: "app"}}
+
+	tests := []struct {
+		name     string
+		phrases  []string
+		input    string
+		expected bool
+	}{
+		{name: "unprotected yes", phrases: []string{"", ""}, input: "y\n", expected: true},
+		{name: "unprotected no", phrases: []string{"", ""}, input: "n\n", expected: false},
+		{name: "y does not pass a phrase", phrases: []string{"", "roll back app"}, input: "y\n", expected: false},
+		{name: "phrase typed", phrases: []string{"", "roll back app"}, input: "roll back app\n", expected: true},
+		{name: "every phrase typed", phrases: []string{"roll back network", "roll back app"}, input: "roll back network\nroll back app\n", expected: true},
+		{name: "second phrase wrong", phrases: []string{"roll back network", "roll back app"}, input: "roll back network\ny\n", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			confirmed, err := confirmBatch(context.Background(), &out, strings.NewReader(tt.input), specs, tt.phrases)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if confirmed != tt.expected {
+				t.Errorf("Expected confirmed=%v, got %v (output %q)", tt.expected, confirmed, out.String())
+			}
+		})
+	}
+}
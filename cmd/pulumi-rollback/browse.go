@@ -0,0 +1,141 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/spf13/cobra"
+)
+
+const defaultBrowsePageSize = 20
+
+const browseHelp = `Commands:
+  n, <enter>   next page
+  p            previous page
+  g <version>  jump to the page containing a version
+  s <version>  show full details of a version
+  r <version>  preview a rollback to a version
+  q            quit`
+
+// runInteractiveList browses the stack history one page at a time, fetching
+// pages from the backend only as they are viewed
+func runInteractiveList(cmd *cobra.Command, stack, projectPath string, selector history.StackSelector) error {
+	ctx := cmd.Context()
+	pageSize := defaultBrowsePageSize
+	if listLimit > 0 {
+		pageSize = listLimit
+	}
+
+	pager, err := history.NewPager(ctx, projectPath, stack, pageSize, selector)
+	if err != nil {
+		return err
+	}
+
+	first, err := pager.Page(ctx, 1)
+	if err != nil {
+		return err
+	}
+	if len(first) == 0 {
+		fmt.Println("No deployment history found for this stack.")
+		return nil
+	}
+	latest := first[0].Version
+
+	page := 1
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		updates, err := pager.Page(ctx, page)
+		if err != nil {
+			return err
+		}
+		annotateProvenance(stack, updates)
+
+		fmt.Printf("\nStack %s — page %d (versions %d-%d of %d)\n\n",
+			stack, page, updates[len(updates)-1].Version, updates[0].Version, latest)
+		printHistoryTable(os.Stdout, updates, format.ChangeStyleSymbolic, false, nil, useColor(listNoColor))
+		fmt.Printf("\n[n]ext [p]rev [g]oto [s]how [r]ollback preview [q]uit, ? for help: ")
+
+		line, err := readLine(ctx, reader)
+		if err != nil {
+			fmt.Println()
+			return nil
+		}
+
+		command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch command {
+		case "", "n":
+			next, err := pager.Page(ctx, page+1)
+			if err != nil {
+				return err
+			}
+			if len(next) == 0 {
+				fmt.Println("Already at the oldest page.")
+				continue
+			}
+			page++
+		case "p":
+			if page == 1 {
+				fmt.Println("Already at the newest page.")
+				continue
+			}
+			page--
+		case "g", "s", "r":
+			version, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil {
+				fmt.Printf("Invalid version %q\n", arg)
+				continue
+			}
+			update, found, err := pager.FindVersion(ctx, latest, version)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			switch command {
+			case "g":
+				page = found
+			case "s":
+				details := []history.UpdateInfo{*update}
+				annotateProvenance(stack, details)
+				printUpdateDetails(&details[0])
+			case "r":
+				return previewRollbackTo(cmd, version)
+			}
+		case "?", "h":
+			fmt.Println(browseHelp)
+		case "q":
+			return nil
+		default:
+			fmt.Printf("Unknown command %q\n%s\n", command, browseHelp)
+		}
+	}
+}
+
+func printUpdateDetails(update *history.UpdateInfo) {
+	fmt.Printf("\nVersion %d\n", update.Version)
+	fmt.Printf("  Kind:    %s\n", update.Kind)
+	fmt.Printf("  Result:  %s\n", formatResult(update.Result))
+	fmt.Printf("  Start:   %s\n", formatUpdateTime(update.StartTime, update.RawStartTime))
+	fmt.Printf("  End:     %s\n", formatUpdateTime(update.EndTime, update.RawEndTime))
+	fmt.Printf("  Changes: %s\n", formatChanges(update.ResourceChanges))
+	if update.Message != "" {
+		fmt.Printf("  Message: %s\n", update.Message)
+	}
+	if p, ok := update.Provenance(); ok {
+		fmt.Printf("  Rollback of version %d", p.SourceVersion)
+		if p.ToolVersion != "" {
+			fmt.Printf(" (pulumi-rollback %s)", p.ToolVersion)
+		}
+		if p.Operator != "" {
+			fmt.Printf(" by %s", p.Operator)
+		}
+		fmt.Println()
+	}
+}
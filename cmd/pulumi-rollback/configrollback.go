@@ -0,0 +1,158 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configVersion int
+	configUp      bool
+	configPreview bool
+)
+
+var configRollbackCmd = &cobra.Command{
+	Use:   "config-rollback",
+	Short: "Restore the stack configuration of a previous version",
+	Long: `Restore the stack's configuration to what it was at a previous version,
+without touching the resource state. This targets incidents caused by a bad
+config change rather than a bad deployment.
+
+The configuration of each version is taken from the stack's update history.
+Keys added since the target version are removed. Secret values are set as
+secrets again and never printed.
+
+With --up, 'up' runs afterwards so the resources are reconciled to the
+restored configuration. Otherwise the next deployment picks it up. A stack
+with a confirmation phrase in the configuration file needs the phrase typed,
+as with 'to'.
+
+Examples:
+  # Show which config keys would change
+  pulumi-rollback config-rollback --stack app --version 5 --preview
+
+  # Restore the config of version 5 and apply it
+  pulumi-rollback config-rollback --stack app --version 5 --up`,
+	RunE: runConfigRollback,
+}
+
+func init() {
+	rootCmd.AddCommand(configRollbackCmd)
+	configRollbackCmd.Flags().IntVarP(&configVersion, "version", "V", 0, "Version whose configuration to restore (required)")
+	configRollbackCmd.Flags().BoolVar(&configUp, "up", false, "Run up after restoring the configuration")
+	configRollbackCmd.Flags().BoolVar(&configPreview, "preview", false, "Only show the config changes")
+	configRollbackCmd.Flags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip confirmation prompt")
+	configRollbackCmd.MarkFlagRequired("version")
+	configRollbackCmd.MarkFlagsMutuallyExclusive("preview", "up")
+}
+
+func runConfigRollback(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if err := requireProjectDir("config-rollback"); err != nil {
+		return err
+	}
+	yes, err := assumeYes()
+	if err != nil {
+		return err
+	}
+	if !yes && !configPreview {
+		if err := requirePrompt(stdinIsTerminal, confirmHint); err != nil {
+			return err
+		}
+	}
+
+	stack, err := getStackName()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	opts := rollback.RollbackOptions{
+		ProjectPath:   getProjectPath(),
+		StackName:     stack,
+		TargetVersion: configVersion,
+		DryRun:        true,
+		Verbose:       isVerbose(),
+		SkipHashCheck: skipHashCheck,
+		Output:        out,
+		Operator:      newStackOperator(pulumiCommand),
+		Logger:        newLogger(out),
+	}
+
+	preview, err := rollback.RollbackConfig(ctx, opts, false)
+	if err != nil {
+		return withVersionHint(ctx, err, stack, nil)
+	}
+	if len(preview.Changes) == 0 {
+		fmt.Fprintf(out, "The configuration of stack '%s' already matches version %d.\n", stack, configVersion)
+		return nil
+	}
+	fmt.Fprintf(out, "\nRestoring the configuration of stack '%s' to version %d:\n", stack, configVersion)
+	printConfigChanges(out, preview.Changes)
+	if configPreview {
+		return nil
+	}
+
+	if !yes {
+		if configUp {
+			fmt.Fprintln(out, "⚠️  WARNING: up will apply the restored configuration to your infrastructure!")
+		}
+		confirmed, err := confirmRollback(ctx, out, os.Stdin, cfg.ConfirmationPhrase(stack))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Fprintln(out, "Rollback cancelled.")
+			return nil
+		}
+	}
+
+	opts.DryRun = false
+	result, err := rollback.RollbackConfig(ctx, opts, configUp)
+	if err != nil {
+		return err
+	}
+	if isVerbose() && result.Stdout != "" {
+		fmt.Fprintln(out, result.Stdout)
+	}
+
+	fmt.Fprintf(out, "\n✓ %s\n", result.Message)
+	if result.ResourceChanges != nil {
+		fmt.Fprintf(out, "Resource changes: %s\n", formatChanges(result.ResourceChanges))
+	} else {
+		fmt.Fprintln(out, "Run 'pulumi up' (or pass --up) to apply it to your resources.")
+	}
+	return nil
+}
+
+// printConfigChanges prints the config changes of a config rollback
+func printConfigChanges(w io.Writer, changes []rollback.ConfigChange) {
+	for _, change := range changes {
+		switch {
+		case change.Action == rollback.ConfigRemove:
+			fmt.Fprintf(w, "  - %s (was %s)\n", change.Key, change.Old)
+		case change.Old == "":
+			fmt.Fprintf(w, "  + %s = %s\n", change.Key, change.New)
+		default:
+			fmt.Fprintf(w, "  ~ %s: %s → %s\n", change.Key, change.Old, change.New)
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFrom          int
+	diffTo            int
+	diffShowUnchanged bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show which resources differ between two versions",
+	Long: `Compare the recorded state of two versions and list the resources that
+were added, removed or changed between them, with the names of the changed
+properties. Property values are not printed. Nothing is modified.
+
+Resources are matched by URN. A resource whose URN changed is shown as
+renamed when an alias links the old and new URN, and otherwise as one
+removal and one addition.
+
+Examples:
+  # What changed from version 3 to version 5
+  pulumi-rollback diff --stack mystack --from 3 --to 5
+
+  # Also list the resources that did not change
+  pulumi-rollback diff --stack mystack --from 3 --to 5 --show-unchanged`,
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().IntVar(&diffFrom, "from", 0, "Version to compare from (required)")
+	diffCmd.Flags().IntVar(&diffTo, "to", 0, "Version to compare to (required)")
+	diffCmd.Flags().BoolVar(&diffShowUnchanged, "show-unchanged", false, "Also list resources that did not change")
+	diffCmd.MarkFlagRequired("from")
+	diffCmd.MarkFlagRequired("to")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	stack, err := getStackName()
+	if err != nil {
+		return err
+	}
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+
+	opts := rollback.RollbackOptions{
+		ProjectPath:   getProjectPath(),
+		StackName:     stack,
+		Verbose:       isVerbose(),
+		SkipHashCheck: skipHashCheck,
+		Output:        os.Stdout,
+		Operator:      newStackOperator(pulumiCommand),
+		Logger:        newLogger(os.Stdout),
+	}
+
+	diffs, err := rollback.DiffVersions(ctx, opts, diffFrom, diffTo)
+	if err != nil {
+		return fmt.Errorf("diff failed: %w", withVersionHint(ctx, err, stack, nil))
+	}
+
+	fmt.Printf("Resources of stack %s from version %d to version %d:\n\n", stack, diffFrom, diffTo)
+	printResourceDiffs(os.Stdout, diffs, diffShowUnchanged)
+	return nil
+}
+
+// printResourceDiffs prints the resources that differ between two versions,
+// and the unchanged ones with showUnchanged, followed by the counts
+func printResourceDiffs(w io.Writer, diffs []rollback.ResourceDiff, showUnchanged bool) {
+	counts := make(map[rollback.DiffKind]int)
+	for _, d := range diffs {
+		counts[d.Kind]++
+		switch d.Kind {
+		case rollback.DiffAdded:
+			fmt.Fprintf(w, "  + %s\n", d.URN)
+		case rollback.DiffRemoved:
+			fmt.Fprintf(w, "  - %s\n", d.URN)
+		case rollback.DiffChanged:
+			fmt.Fprintf(w, "  ~ %s (%s)\n", d.URN, strings.Join(d.Properties, ", "))
+		case rollback.DiffRenamed:
+			if len(d.Properties) > 0 {
+				fmt.Fprintf(w, "  > %s => %s (%s)\n", d.From, d.URN, strings.Join(d.Properties, ", "))
+			} else {
+				fmt.Fprintf(w, "  > %s => %s\n", d.From, d.URN)
+			}
+		case rollback.DiffUnchanged:
+			if showUnchanged {
+				fmt.Fprintf(w, "    %s\n", d.URN)
+			}
+		}
+	}
+	if len(diffs) == counts[rollback.DiffUnchanged] && (!showUnchanged || len(diffs) == 0) {
+		fmt.Fprintln(w, "  No differences.")
+	}
+
+	fmt.Fprintf(w, "\n%d added, %d removed, %d changed, %d renamed, %d unchanged\n",
+		counts[rollback.DiffAdded], counts[rollback.DiffRemoved], counts[rollback.DiffChanged], counts[rollback.DiffRenamed], counts[rollback.DiffUnchanged])
+}
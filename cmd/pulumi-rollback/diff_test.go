@@ -0,0 +1,38 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+)
+
+func TestPrintResourceDiffs(t *testing.T) {
+	diffs := []rollback.ResourceDiff{
+		{URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::added", Kind: rollback.DiffAdded},
+		{URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::new", Kind: rollback.DiffRenamed,
+			From: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::old", Properties: []string{"acl"}},
+		{URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::kept", Kind: rollback.DiffUnchanged},
+	}
+
+	var out bytes.Buffer
+	printResourceDiffs(&out, diffs, false)
+	got := out.String()
+
+	for _, want := range []string{
+		"  + urn:pulumi:dev::proj::aws:s3/bucket:Bucket::added\n",
+		"  > urn:pulumi:dev::proj::aws:s3/bucket:Bucket::old => urn:pulumi:dev::proj::aws:s3/bucket:Bucket::new (acl)\n",
+		"1 added, 0 removed, 0 changed, 1 renamed, 1 unchanged\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "::kept") {
+		t.Errorf("Expected unchanged resources to be hidden, got:\n%s", got)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// isGitHubActions reports whether GitHub Actions workflow commands should be
+// emitted. An explicit --github-actions flag wins over auto-detection.
+func isGitHubActions() bool {
+	if rootCmd.PersistentFlags().Changed("github-actions") {
+		return githubActions
+	}
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// escapeWorkflowData escapes a message for use in a workflow command
+func escapeWorkflowData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	s = strings.ReplaceAll(s, "\n", "%0A")
+	return s
+}
+
+func ghNotice(format string, args ...interface{}) {
+	ghCommand("notice", format, args...)
+}
+
+func ghWarning(format string, args ...interface{}) {
+	ghCommand("warning", format, args...)
+}
+
+func ghError(format string, args ...interface{}) {
+	ghCommand("error", format, args...)
+}
+
+func ghCommand(command, format string, args ...interface{}) {
+	if !isGitHubActions() {
+		return
+	}
+	fmt.Printf("::%s::%s\n", command, escapeWorkflowData(fmt.Sprintf(format, args...)))
+}
+
+// setGitHubOutput writes a step output to the file named by $GITHUB_OUTPUT
+func setGitHubOutput(name, value string) {
+	if !isGitHubActions() {
+		return
+	}
+
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write GitHub output %s: %v\n", name, err)
+		return
+	}
+	defer f.Close()
+
+	if strings.Contains(value, "\n") {
+		fmt.Fprintf(f, "%s<<PULUMI_ROLLBACK_EOF\n%s\nPULUMI_ROLLBACK_EOF\n", name, value)
+	} else {
+		fmt.Fprintf(f, "%s=%s\n", name, value)
+	}
+}
+
+// setGitHubChangesOutput writes resource changes as a JSON step output
+func setGitHubChangesOutput(name string, changes map[string]int) {
+	if changes == nil {
+		changes = map[string]int{}
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return
+	}
+	setGitHubOutput(name, string(data))
+}
@@ -6,16 +6,36 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
 	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
 	"github.com/spf13/cobra"
 )
 
 var (
-	listLimit int
+	listLimit       int
+	listInteractive bool
+	listStats       bool
+	listFormat      string
+	listResult      string
+	listSince       string
+	listUntil       string
+	listFrom        int
+	listTo          int
+	listChanges     string
+	listLegend      bool
+	listAllStacks   bool
+	listResources   bool
+	listWatch       bool
+	listInterval    time.Duration
+	listNoColor     bool
 )
 
 var listCmd = &cobra.Command{
@@ -29,17 +49,106 @@ Examples:
   pulumi-rollback list --stack mystack
 
   # List last 10 deployments
-  pulumi-rollback list --stack mystack --limit 10`,
+  pulumi-rollback list --stack mystack --limit 10
+
+  # Show deployment frequency and reliability statistics
+  pulumi-rollback list --stack mystack --stats
+
+  # Browse history interactively, 20 entries per page
+  pulumi-rollback list --stack mystack --interactive
+
+  # Show versions 10 through 20
+  pulumi-rollback list --stack mystack --from 10 --to 20
+
+  # Show the deployments of January 2024
+  pulumi-rollback list --stack mystack --since 2024-01-01 --until 2024-02-01
+
+  # Print the number of failed deployments in the last hour, for alerting
+  pulumi-rollback list --stack mystack --result failed --since 1h --format count
+
+  # Spell out the changes of each deployment
+  pulumi-rollback list --stack mystack --changes verbose
+
+  # Export the history of 2024 for a spreadsheet
+  pulumi-rollback list --stack mystack --since 2024-01-01 --until 2025-01-01 --format csv > history.csv
+
+  # Print counts by result, e.g. "succeeded=40 failed=2"
+  pulumi-rollback list --stack mystack --format count-by-result
+
+  # Show how many resources each of the last 10 versions had
+  pulumi-rollback list --stack mystack --limit 10 --resource-counts
+
+  # List the last 5 deployments of every stack in the project
+  pulumi-rollback list --all-stacks --limit 5
+
+  # Redraw the last 10 deployments every 30 seconds, marking new ones
+  pulumi-rollback list --stack mystack --limit 10 --watch --interval 30s`,
 	RunE: runList,
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 0, "Limit the number of entries to show (0 = all)")
+	listCmd.Flags().BoolVarP(&listInteractive, "interactive", "i", false, "Browse history page by page (--limit sets the page size)")
+	listCmd.Flags().BoolVar(&listStats, "stats", false, "Also print deployment frequency, success rate and duration statistics")
+	listCmd.Flags().StringVar(&listFormat, "format", "table", "Output format: table, csv, count (number of deployments) or count-by-result")
+	listCmd.Flags().StringVar(&listResult, "result", "", "Only include deployments with this result: succeeded, failed or in-progress")
+	listCmd.Flags().StringVar(&listSince, "since", "", "Only include deployments started at or after this time: a duration ago (1h), a date (2024-01-15) or an RFC 3339 time")
+	listCmd.Flags().StringVar(&listUntil, "until", "", "Only include deployments started at or before this time, in the same formats as --since")
+	listCmd.Flags().IntVar(&listFrom, "from", 0, "Only include versions from this one on")
+	listCmd.Flags().IntVar(&listTo, "to", 0, "Only include versions up to and including this one")
+	listCmd.Flags().StringVar(&listChanges, "changes", string(format.ChangeStyleSymbolic), "How to show resource changes: symbolic (+3 ~2 -1) or verbose (3 created, 2 updated, 1 deleted)")
+	listCmd.Flags().BoolVar(&listLegend, "legend", false, "Explain the change symbols below the table")
+	listCmd.Flags().BoolVar(&listAllStacks, "all-stacks", false, "List the history of every stack in the project, fetched concurrently (see --max-concurrent-fetches)")
+	listCmd.Flags().BoolVar(&listResources, "resource-counts", false, "Add a column with the number of resources at each version (fetches the checkpoint of every listed version)")
+	listCmd.Flags().BoolVarP(&listWatch, "watch", "w", false, "Redraw the table on an interval until interrupted, marking versions that appeared since the last poll")
+	listCmd.Flags().DurationVar(&listInterval, "interval", 30*time.Second, "Time between polls with --watch")
+	listCmd.Flags().BoolVar(&listNoColor, "no-color", false, "Do not color results and changes (color is only used when stdout is a terminal)")
+	listCmd.MarkFlagsMutuallyExclusive("format", "interactive")
+	listCmd.MarkFlagsMutuallyExclusive("format", "stats")
+	listCmd.MarkFlagsMutuallyExclusive("interactive", "result")
+	listCmd.MarkFlagsMutuallyExclusive("interactive", "since")
+	listCmd.MarkFlagsMutuallyExclusive("interactive", "until")
+	listCmd.MarkFlagsMutuallyExclusive("interactive", "from")
+	listCmd.MarkFlagsMutuallyExclusive("interactive", "to")
+	listCmd.MarkFlagsMutuallyExclusive("all-stacks", "interactive")
+	listCmd.MarkFlagsMutuallyExclusive("all-stacks", "format")
+	listCmd.MarkFlagsMutuallyExclusive("all-stacks", "stats")
+	listCmd.MarkFlagsMutuallyExclusive("resource-counts", "format")
+	listCmd.MarkFlagsMutuallyExclusive("resource-counts", "interactive")
+	listCmd.MarkFlagsMutuallyExclusive("resource-counts", "all-stacks")
+	listCmd.MarkFlagsMutuallyExclusive("watch", "interactive")
+	listCmd.MarkFlagsMutuallyExclusive("watch", "format")
+	listCmd.MarkFlagsMutuallyExclusive("watch", "all-stacks")
+	listCmd.MarkFlagsMutuallyExclusive("watch", "resource-counts")
+	listCmd.MarkFlagsMutuallyExclusive("watch", "stats")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx := cmd.Context()
+
+	switch listFormat {
+	case "table", "csv", "count", "count-by-result":
+	default:
+		return fmt.Errorf("unknown format %q (expected table, csv, count or count-by-result)", listFormat)
+	}
+	switch listResult {
+	case "", "succeeded", "failed", "in-progress":
+	default:
+		return fmt.Errorf("unknown result %q (expected succeeded, failed or in-progress)", listResult)
+	}
+	filter, err := listFilter(time.Now())
+	if err != nil {
+		return err
+	}
+	changeStyle, err := format.ParseChangeStyle(listChanges)
+	if err != nil {
+		return err
+	}
+
+	if listAllStacks {
+		return runListAllStacks(ctx, filter, changeStyle)
+	}
 
 	stack, err := getStackName()
 	if err != nil {
@@ -48,13 +157,40 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	projectPath := getProjectPath()
 
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+	selector := newStackSelector(pulumiCommand)
+
+	if listInteractive {
+		return runInteractiveList(cmd, stack, projectPath, selector)
+	}
+	if listWatch {
+		return runWatchList(ctx, stack, projectPath, filter, changeStyle, selector)
+	}
+
 	if isVerbose() {
 		fmt.Printf("Fetching history for stack %s in %s...\n", stack, projectPath)
 	}
 
-	updates, err := history.GetStackHistory(ctx, projectPath, stack)
+	updates, err := fetchListHistory(ctx, stack, projectPath, filter, selector)
 	if err != nil {
-		return fmt.Errorf("failed to get stack history: %w", err)
+		return err
+	}
+
+	// Counts and CSV skip the table entirely so tools can parse the output
+	switch listFormat {
+	case "csv":
+		return history.WriteCSV(os.Stdout, updates)
+	case "count", "count-by-result":
+		counts := history.SummarizeHistory(updates)
+		if listFormat == "count" {
+			fmt.Println(counts.Total())
+		} else {
+			fmt.Println(counts)
+		}
+		return nil
 	}
 
 	if len(updates) == 0 {
@@ -62,39 +198,339 @@ func runList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Counting fetches a checkpoint per version, so only listed versions are counted
+	if listResources {
+		opts := rollback.RollbackOptions{
+			ProjectPath:   projectPath,
+			StackName:     stack,
+			Verbose:       isVerbose(),
+			SkipHashCheck: skipHashCheck,
+			Output:        os.Stdout,
+			Operator:      newStackOperator(pulumiCommand),
+			Logger:        newLogger(os.Stdout),
+		}
+		failed, err := rollback.CountResourcesByVersion(ctx, opts, updates, maxConcurrentFetches)
+		if err != nil {
+			return fmt.Errorf("failed to count resources: %w", err)
+		}
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: could not read the checkpoint of %d version(s); their resource count is shown as -\n", failed)
+		}
+	}
+
+	printHistoryTable(os.Stdout, updates, changeStyle, listResources, nil, useColor(listNoColor))
+	if listLegend {
+		fmt.Printf("\n%s\n", format.ChangesLegend())
+	}
+
+	fmt.Printf("\nTotal: %d deployment(s)\n", len(updates))
+
+	if listStats {
+		printHistoryStats(os.Stdout, history.ComputeHistoryStats(updates))
+	}
+	fmt.Println("\nUse 'pulumi-rollback preview --stack <stack> --version <n>' to preview a rollback")
+
+	return nil
+}
+
+// fetchListHistory fetches the updates list shows, newest first
+func fetchListHistory(ctx context.Context, stack, projectPath string, filter history.FilterCriteria, selector history.StackSelector) ([]history.UpdateInfo, error) {
+	// Without a filter the limit is applied by the backend, so only the
+	// first page of history is fetched
+	var updates []history.UpdateInfo
+	var err error
+	if filter == (history.FilterCriteria{}) {
+		updates, err = history.GetRecentHistoryWithSelector(ctx, projectPath, stack, listLimit, selector)
+	} else {
+		updates, err = history.GetFilteredHistoryWithSelector(ctx, projectPath, stack, filter, selector)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack history: %w", err)
+	}
+
 	// Apply limit if specified
 	if listLimit > 0 && listLimit < len(updates) {
 		updates = updates[:listLimit]
 	}
+	annotateProvenance(stack, updates)
+	return updates, nil
+}
 
-	// Create a tabwriter for aligned output
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "VERSION\tKIND\tRESULT\tTIME\tCHANGES\tMESSAGE")
-	fmt.Fprintln(w, "-------\t----\t------\t----\t-------\t-------")
+// runWatchList redraws the history table every --interval until ctx is
+// cancelled, marking the versions that appeared since the previous poll.
+// A failed poll is reported and retried on the next one.
+func runWatchList(ctx context.Context, stack, projectPath string, filter history.FilterCriteria, style format.ChangeStyle, selector history.StackSelector) error {
+	if listInterval <= 0 {
+		return fmt.Errorf("--interval must be positive, got %s", listInterval)
+	}
+	ticker := time.NewTicker(listInterval)
+	defer ticker.Stop()
 
-	for _, update := range updates {
-		timeStr := formatTime(update.StartTime)
-		changesStr := formatChanges(update.ResourceChanges)
-		message := truncateString(update.Message, 40)
+	var previous []history.UpdateInfo
+	polled := false
+	for {
+		updates, err := fetchListHistory(ctx, stack, projectPath, filter, selector)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		case err == nil:
+			var added map[int]bool
+			if polled {
+				added = history.NewVersions(previous, updates)
+			}
+			drawWatchList(os.Stdout, stack, updates, added, style)
+			previous, polled = updates, true
+		}
 
-		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
-			update.Version,
-			update.Kind,
-			formatResult(update.Result),
-			timeStr,
-			changesStr,
-			message,
-		)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
 	}
+}
 
-	w.Flush()
+// drawWatchList prints one poll of list --watch, clearing the screen first
+// when stdout is a terminal
+func drawWatchList(out io.Writer, stack string, updates []history.UpdateInfo, added map[int]bool, style format.ChangeStyle) {
+	if stdoutIsTerminal() {
+		fmt.Fprint(out, "\033[H\033[2J")
+	} else {
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintf(out, "Stack %s at %s, every %s. Press Ctrl+C to stop.\n\n", stack, time.Now().Format("15:04:05"), listInterval)
 
-	fmt.Printf("\nTotal: %d deployment(s)\n", len(updates))
-	fmt.Println("\nUse 'pulumi-rollback preview --stack <stack> --version <n>' to preview a rollback")
+	if len(updates) == 0 {
+		fmt.Fprintln(out, "No deployment history found for this stack.")
+		return
+	}
+	printHistoryTable(out, updates, style, false, added, useColor(listNoColor))
+	if listLegend {
+		fmt.Fprintf(out, "\n%s\n", format.ChangesLegend())
+	}
+	if len(added) > 0 {
+		versions := make([]int, 0, len(added))
+		for v := range added {
+			versions = append(versions, v)
+		}
+		fmt.Fprintf(out, "\n* new since the last poll: %s\n", history.FormatVersions(versions))
+	}
+}
 
+// runListAllStacks prints the history of every stack in the project,
+// grouped by stack. Stacks that could not be fetched are reported at the end.
+func runListAllStacks(ctx context.Context, filter history.FilterCriteria, style format.ChangeStyle) error {
+	if err := requireProjectDir("list --all-stacks"); err != nil {
+		return err
+	}
+	if repoURL != "" {
+		return fmt.Errorf("list --all-stacks lists the stacks of the project directory and cannot be used with --repo")
+	}
+	projectPath := getProjectPath()
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+	selector := newStackSelector(pulumiCommand)
+
+	stacks, err := selector.ListStacks(ctx, projectPath)
+	if err != nil {
+		return err
+	}
+	if len(stacks) == 0 {
+		fmt.Println("No stacks found in this project.")
+		return nil
+	}
+	if isVerbose() {
+		fmt.Printf("Fetching history for %d stack(s) in %s...\n", len(stacks), projectPath)
+	}
+
+	// Each stack is fetched as list fetches a single stack, so --limit
+	// without a filter only reads the first page of each history
+	result, err := history.FetchStackHistoriesConcurrent(ctx, stacks, maxConcurrentFetches, func(ctx context.Context, stack string) ([]history.UpdateInfo, error) {
+		return fetchListHistory(ctx, stack, projectPath, filter, selector)
+	})
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, stack := range stacks {
+		updates, ok := result.Histories[stack]
+		if !ok {
+			continue
+		}
+		total += len(updates)
+
+		fmt.Printf("Stack: %s\n\n", stack)
+		if len(updates) == 0 {
+			fmt.Println("No deployment history found for this stack.")
+		} else {
+			printHistoryTable(os.Stdout, updates, style, false, nil, useColor(listNoColor))
+		}
+		fmt.Println()
+	}
+	if listLegend {
+		fmt.Printf("%s\n\n", format.ChangesLegend())
+	}
+	fmt.Printf("Total: %d deployment(s) in %d stack(s)\n", total, len(result.Histories))
+
+	if result.AnyFailed() {
+		return fmt.Errorf("failed to get the history of %d stack(s):\n%w", len(result.Failures), result.Errors())
+	}
 	return nil
 }
 
+// listFilter builds the history filter from the list flags
+func listFilter(now time.Time) (history.FilterCriteria, error) {
+	filter := history.FilterCriteria{Result: listResult, MinVersion: listFrom, MaxVersion: listTo}
+	if listFrom < 0 || listTo < 0 {
+		return filter, fmt.Errorf("--from and --to must not be negative")
+	}
+	if listSince != "" {
+		since, err := history.ParseTimeBound(listSince, now)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --since: %w", err)
+		}
+		filter.Since = since
+	}
+	if listUntil != "" {
+		until, err := history.ParseTimeBound(listUntil, now)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --until: %w", err)
+		}
+		filter.Until = until
+	}
+	return filter, filter.Validate()
+}
+
+// printHistoryTable prints updates as a table. With resources set it adds
+// the ResourceCount of each update. Versions in marked are flagged with *.
+// With color set, results and changes are colored.
+func printHistoryTable(out io.Writer, updates []history.UpdateInfo, style format.ChangeStyle, resources bool, marked map[int]bool, color bool) {
+	var rows [][]string
+	if resources {
+		rows = append(rows,
+			[]string{"VERSION", "KIND", "RESULT", "TIME", "RESOURCES", "CHANGES", "MESSAGE"},
+			[]string{"-------", "----", "------", "----", "---------", "-------", "-------"})
+	} else {
+		rows = append(rows,
+			[]string{"VERSION", "KIND", "RESULT", "TIME", "CHANGES", "MESSAGE"},
+			[]string{"-------", "----", "------", "----", "-------", "-------"})
+	}
+
+	for _, update := range updates {
+		version := strconv.Itoa(update.Version)
+		if marked[update.Version] {
+			version += " *"
+		}
+		result := formatResult(update.Result)
+		changes := format.Changes(update.ResourceChanges, style)
+		if color {
+			result = format.Colorize(result, resultColor(update.Result))
+			changes = format.ColoredChanges(update.ResourceChanges, style)
+		}
+
+		row := []string{version, update.Kind, result, formatUpdateTime(update.StartTime, update.RawStartTime)}
+		if resources {
+			row = append(row, formatResourceCount(update.ResourceCount))
+		}
+		rows = append(rows, append(row, changes, truncateString(formatMessage(update), 40)))
+	}
+
+	writeTable(out, rows)
+}
+
+// writeTable writes rows as columns separated by two spaces, like a
+// tabwriter. Widths are measured by format.VisibleWidth, so color codes in a
+// cell do not throw off the alignment. The last column is not padded.
+func writeTable(out io.Writer, rows [][]string) {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], format.VisibleWidth(cell))
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-format.VisibleWidth(cell)+2))
+			}
+		}
+		b.WriteString("\n")
+	}
+	io.WriteString(out, b.String())
+}
+
+// formatResourceCount formats a resource count, which is nil when unknown
+func formatResourceCount(count *int) string {
+	if count == nil {
+		return "-"
+	}
+	return fmt.Sprint(*count)
+}
+
+// formatMessage labels rollback updates with the version they restored
+func formatMessage(update history.UpdateInfo) string {
+	p, ok := update.Provenance()
+	if !ok {
+		return update.Message
+	}
+	label := fmt.Sprintf("↩ rollback to v%d", p.SourceVersion)
+	if p.Operator != "" {
+		label += " by " + p.Operator
+	}
+	return label
+}
+
+// formatUpdateTime formats a parsed timestamp, falling back to the raw
+// backend value, marked as unparseable, when it could not be parsed
+func formatUpdateTime(t time.Time, raw string) string {
+	if t.IsZero() && raw != "" {
+		return raw + " (unparseable)"
+	}
+	return formatTime(t)
+}
+
+func printHistoryStats(out io.Writer, stats history.HistoryStats) {
+	fmt.Fprintln(out, "\nStatistics:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  Period:\t%s - %s\n", formatTime(stats.First), formatTime(stats.Last))
+	fmt.Fprintf(w, "  Deployments:\t%.1f per day, %.1f per week\n", stats.PerDay, stats.PerWeek)
+	fmt.Fprintf(w, "  Success rate:\t%.0f%% (%d succeeded, %d failed)\n", stats.SuccessRate*100, stats.Succeeded, stats.Failed)
+	if stats.AverageDuration > 0 {
+		fmt.Fprintf(w, "  Average duration:\t%s\n", stats.AverageDuration.Round(time.Second))
+	}
+	if stats.LongestGap > 0 {
+		fmt.Fprintf(w, "  Longest gap:\t%s (from %s)\n", formatDuration(stats.LongestGap), formatTime(stats.LongestGapStart))
+	}
+	for i, k := range stats.FailingKinds {
+		label := ""
+		if i == 0 {
+			label = "  Failing kinds:"
+		}
+		fmt.Fprintf(w, "%s\t%s (%d)\n", label, k.Kind, k.Count)
+	}
+	w.Flush()
+}
+
+// formatDuration formats long durations in days and hours
+func formatDuration(d time.Duration) string {
+	if d < 24*time.Hour {
+		return d.Round(time.Minute).String()
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int((d % (24 * time.Hour)) / time.Hour)
+	return fmt.Sprintf("%dd%dh", days, hours)
+}
+
 func formatTime(t time.Time) string {
 	if t.IsZero() {
 		return "N/A"
@@ -102,6 +538,20 @@ func formatTime(t time.Time) string {
 	return t.Format("2006-01-02 15:04")
 }
 
+// resultColor is the color of a deployment result
+func resultColor(result string) format.Color {
+	switch result {
+	case "succeeded":
+		return format.Green
+	case "failed":
+		return format.Red
+	case "in-progress":
+		return format.Yellow
+	default:
+		return format.NoColor
+	}
+}
+
 func formatResult(result string) string {
 	switch result {
 	case "succeeded":
@@ -115,47 +565,17 @@ func formatResult(result string) string {
 	}
 }
 
+// formatChanges formats change counts in the compact symbolic style
 func formatChanges(changes map[string]int) string {
-	if len(changes) == 0 {
-		return "-"
-	}
-
-	create := changes["create"]
-	update := changes["update"]
-	delete := changes["delete"]
-	same := changes["same"]
-
-	parts := []string{}
-	if create > 0 {
-		parts = append(parts, fmt.Sprintf("+%d", create))
-	}
-	if update > 0 {
-		parts = append(parts, fmt.Sprintf("~%d", update))
-	}
-	if delete > 0 {
-		parts = append(parts, fmt.Sprintf("-%d", delete))
-	}
-	if same > 0 && len(parts) == 0 {
-		return fmt.Sprintf("=%d", same)
-	}
-
-	if len(parts) == 0 {
-		return "-"
-	}
-
-	result := ""
-	for i, p := range parts {
-		if i > 0 {
-			result += " "
-		}
-		result += p
-	}
-	return result
+	return format.Changes(changes, format.ChangeStyleSymbolic)
 }
 
+// truncateString shortens s to maxLen runes, ending it with "..." when cut,
+// so multi-byte text is never split inside a character
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
 		return s
 	}
-	return s[:maxLen-3] + "..."
+	return string(runes[:maxLen-3]) + "..."
 }
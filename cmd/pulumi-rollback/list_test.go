@@ -0,0 +1,92 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+)
+
+func TestFormatResourceCount(t *testing.T) {
+	zero, three := 0, 3
+	tests := []struct {
+		name     string
+		count    *int
+		expected string
+	}{
+		{"unknown", nil, "-"},
+		{"zero", &zero, "0"},
+		{"some", &three, "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatResourceCount(tt.count); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected string
+	}{
+		{"short", "deploy", "deploy"},
+		{"ascii", "deploy the new api", "deploy..."},
+		{"multi-byte fits", "↩ 部署新的接口", "↩ 部署新的接口"},
+		{"multi-byte", "↩ 部署新的接口部署", "↩ 部署新的..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateString(tt.s, 9)
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Expected valid UTF-8, got %q", got)
+			}
+		})
+	}
+}
+
+func TestPrintHistoryTable_ColorKeepsAlignment(t *testing.T) {
+	updates := []history.UpdateInfo{
+		{Version: 3, Kind: "update", Result: "failed", Message: "third",
+			ResourceChanges: map[string]int{"create": 12, "delete": 1}},
+		{Version: 2, Kind: "update", Result: "succeeded", Message: "second",
+			ResourceChanges: map[string]int{"update": 1}},
+		{Version: 1, Kind: "update", Result: "in-progress", Message: "first"},
+	}
+
+	var plain, colored bytes.Buffer
+	printHistoryTable(&plain, updates, format.ChangeStyleSymbolic, false, nil, false)
+	printHistoryTable(&colored, updates, format.ChangeStyleSymbolic, false, nil, true)
+
+	if !strings.Contains(colored.String(), "\x1b[") {
+		t.Fatal("Expected colored output to contain escape codes")
+	}
+	stripped := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(colored.String(), "")
+	if stripped != plain.String() {
+		t.Errorf("Expected colored output to align like plain output.\nPlain:\n%s\nColored, stripped:\n%s", plain.String(), stripped)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(plain.String(), "\n"), "\n")
+	col := strings.Index(lines[0], "MESSAGE")
+	for _, line := range lines[2:] {
+		runes := []rune(line)
+		if string(runes[col-2:col]) != "  " || runes[col] == ' ' {
+			t.Errorf("Expected the message to start at column %d in %q", col, line)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/lockfile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lockfilePath string
+	pinVersion   int
+	pinNote      string
+	pinFailed    bool
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin",
+	Short: "Record a known good version for a stack in rollback.lock",
+	Long: `Record a known good version for a stack in the lockfile (rollback.lock in
+the project directory by default). Commit the lockfile so that
+'pulumi-rollback to --to-pinned' can roll back to it later.
+
+Examples:
+  # Pin the latest version after verifying a deploy
+  pulumi-rollback pin --stack prod
+
+  # Pin a specific version with a note
+  pulumi-rollback pin --stack prod --version 38 --note "before schema migration"`,
+	RunE: runPin,
+}
+
+func init() {
+	rootCmd.AddCommand(pinCmd)
+	pinCmd.Flags().IntVarP(&pinVersion, "version", "V", 0, "Version to pin (default: latest)")
+	pinCmd.Flags().StringVar(&pinNote, "note", "", "Note recorded with the pin")
+	pinCmd.Flags().BoolVar(&pinFailed, "allow-failed", false, "Allow pinning a version whose update did not succeed")
+	pinCmd.Flags().StringVar(&lockfilePath, "lockfile", "", "Path to the lockfile (default: rollback.lock in the project directory)")
+}
+
+func runPin(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	stack, err := getStackName()
+	if err != nil {
+		return err
+	}
+
+	projectPath := getProjectPath()
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+	selector := history.NewCachingSelector(newStackSelector(pulumiCommand))
+
+	version := pinVersion
+	if version == 0 {
+		version, err = history.GetLatestVersionWithSelector(ctx, projectPath, stack, selector)
+		if err != nil {
+			return fmt.Errorf("failed to get latest version: %w", err)
+		}
+	}
+
+	update, err := history.GetUpdateByVersionWithSelector(ctx, projectPath, stack, version, selector)
+	if err != nil {
+		err = withVersionHint(ctx, err, stack, selector)
+		return fmt.Errorf("failed to find version %d: %w", version, err)
+	}
+	if update.Result != "succeeded" && !pinFailed {
+		return fmt.Errorf("version %d did not succeed (result: %s); use --allow-failed to pin it anyway", version, update.Result)
+	}
+
+	path := getLockfilePath()
+	lf, err := lockfile.Load(path)
+	if err != nil {
+		return err
+	}
+	previous, hadPin := lf.Get(stack)
+	lf.Set(stack, lockfile.Pin{
+		Version:  version,
+		PinnedAt: time.Now().UTC(),
+		PinnedBy: getInitiator(),
+		Note:     pinNote,
+	})
+	if err := lf.Save(path); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	if hadPin && previous.Version != version {
+		fmt.Printf("Pinned stack '%s' to version %d in %s (was %d)\n", stack, version, path, previous.Version)
+	} else {
+		fmt.Printf("Pinned stack '%s' to version %d in %s\n", stack, version, path)
+	}
+	return nil
+}
+
+// getLockfilePath returns the lockfile path from the flag or the project directory
+func getLockfilePath() string {
+	if lockfilePath != "" {
+		return lockfilePath
+	}
+	return filepath.Join(getProjectPath(), lockfile.DefaultName)
+}
+
+// getPinnedVersion returns the version pinned for a stack in the lockfile
+func getPinnedVersion(stack string) (int, error) {
+	path := getLockfilePath()
+	lf, err := lockfile.Load(path)
+	if err != nil {
+		return 0, err
+	}
+	pin, ok := lf.Get(stack)
+	if !ok {
+		return 0, fmt.Errorf("no version pinned for stack %s in %s (use 'pulumi-rollback pin')", stack, path)
+	}
+	return pin.Version, nil
+}
@@ -4,9 +4,14 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
 	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
@@ -14,7 +19,22 @@ import (
 )
 
 var (
-	previewVersion int
+	previewVersion      int
+	previewCheckPlugins bool
+	previewTypes        []string
+	previewTargets      []string
+	previewCheck        bool
+	previewAllowNoop    bool
+	previewMode         string
+	previewExpect       string
+	previewReencrypt    bool
+	previewReport       string
+	previewVerify       bool
+	previewDetailedExit bool
+	previewForce        bool
+	previewIgnoreBusy   bool
+	previewRenamedFrom  string
+	previewDiff         bool
 )
 
 var previewCmd = &cobra.Command{
@@ -25,20 +45,73 @@ to a specific version without actually making any changes.
 
 This is equivalent to 'pulumi preview' but targeting a historical state.
 
+By default the preview compares against the recorded state of the target
+version only (--mode state-only). Use --mode live to refresh the target state
+against live infrastructure within the preview, which is slower but reflects
+drift. The refreshed state is never saved.
+
 Examples:
   # Preview rolling back to version 5
-  pulumi-rollback preview --stack mystack --version 5`,
+  pulumi-rollback preview --stack mystack --version 5
+
+  # Exit nonzero if rolling back to version 5 would change anything
+  pulumi-rollback preview --stack mystack --version 5 --check
+
+  # The same, with the full preview output
+  pulumi-rollback preview --stack mystack --version 5 --detailed-exitcode
+
+  # Fail unless the rollback deletes nothing and creates at most 5 resources
+  pulumi-rollback preview --stack mystack --version 5 --expect 'delete<=0,create<=5'
+
+  # Write a markdown report to attach to a change ticket
+  pulumi-rollback preview --stack mystack --version 5 --report report.md
+
+  # Show which properties of each resource would change
+  pulumi-rollback preview --stack mystack --version 5 --diff
+
+  # Fail if the change counts disagree with the resources Pulumi enumerated
+  pulumi-rollback preview --stack mystack --version 5 --verify
+
+  # Preview rolling back only Lambda functions
+  pulumi-rollback preview --stack mystack --version 5 --type aws:lambda/function:Function`,
 	RunE: runPreview,
 }
 
 func init() {
 	rootCmd.AddCommand(previewCmd)
 	previewCmd.Flags().IntVarP(&previewVersion, "version", "V", 0, "Target version to roll back to (required)")
+	previewCmd.Flags().StringArrayVar(&previewTypes, "type", nil, "Only preview resources of this type token (repeatable)")
+	previewCmd.Flags().StringArrayVar(&previewTargets, "target", nil, "Only preview the resource with this URN (repeatable)")
+	previewCmd.Flags().BoolVar(&previewReencrypt, "reencrypt-secrets", false, "Re-encrypt the target checkpoint's secrets when its secrets provider differs from the stack's (source passphrase from PULUMI_ROLLBACK_SOURCE_PASSPHRASE)")
+	previewCmd.Flags().BoolVar(&previewCheck, "check", false, "Print a one-line summary and exit 0 if the rollback makes no changes, 2 if it does")
+	previewCmd.Flags().BoolVar(&previewDetailedExit, "detailed-exitcode", false, "Print the full preview and exit 0 if the rollback makes no changes, 2 if it does, 1 on error")
+	previewCmd.MarkFlagsMutuallyExclusive("check", "detailed-exitcode")
+	previewCmd.Flags().StringVar(&previewMode, "mode", string(rollback.PreviewModeStateOnly), "Preview against recorded state only (state-only) or refresh against live infrastructure within the preview (live)")
+	previewCmd.Flags().BoolVar(&previewAllowNoop, "allow-noop", false, "Preview even when the target is the current version")
+	previewCmd.Flags().StringVar(&resultFile, "result-file", "", "Write the preview result as JSON to this file")
+	previewCmd.Flags().BoolVar(&previewVerify, "verify", false, "Fail if the change counts do not match the steps of Pulumi's preview")
+	previewCmd.Flags().BoolVar(&previewCheckPlugins, "check-plugins", false, "Fail if the target checkpoint needs provider plugins that are not installed")
+	previewCmd.Flags().StringVar(&previewExpect, "expect", "", "Fail unless the changes satisfy these constraints, e.g. 'delete<=0,create<=5'")
+	previewCmd.Flags().BoolVar(&previewDiff, "diff", false, "Show the property changes of each resource the rollback would change")
+	previewCmd.Flags().BoolVar(&previewForce, "force", false, "Preview even when safety checks fail")
+	previewCmd.Flags().BoolVar(&previewIgnoreBusy, "ignore-busy", false, "Preview while the latest update of the stack is still in progress, e.g. after its process died")
+	previewCmd.Flags().StringVar(&previewRenamedFrom, "renamed-from", "", "Accept checkpoints taken before the stack was renamed from this name, rewriting their URNs to the current name")
+	previewCmd.Flags().StringVar(&previewReport, "report", "", "Write the proposed rollback as a markdown report to this file")
 	previewCmd.MarkFlagRequired("version")
 }
 
 func runPreview(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	return previewRollbackTo(cmd, previewVersion)
+}
+
+// previewRollbackTo previews rolling the stack back to version with the
+// preview flags, for preview and for browse's r command
+func previewRollbackTo(cmd *cobra.Command, version int) error {
+	ctx := cmd.Context()
+
+	if err := requireProjectDir("preview"); err != nil {
+		return err
+	}
 
 	stack, err := getStackName()
 	if err != nil {
@@ -47,47 +120,138 @@ func runPreview(cmd *cobra.Command, args []string) error {
 
 	projectPath := getProjectPath()
 
+	mode, err := rollback.ParsePreviewMode(previewMode)
+	if err != nil {
+		return err
+	}
+
+	expectations, err := rollback.ParseExpectations(previewExpect)
+	if err != nil {
+		return err
+	}
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+	selector := history.NewCachingSelector(newStackSelector(pulumiCommand))
+
 	// Validate the version exists
-	update, err := history.GetUpdateByVersion(ctx, projectPath, stack, previewVersion)
+	if err := validateTargetVersion(ctx, stack, version, selector); err != nil {
+		return err
+	}
+	update, err := history.GetUpdateByVersionWithSelector(ctx, projectPath, stack, version, selector)
 	if err != nil {
-		return fmt.Errorf("failed to find version %d: %w", previewVersion, err)
+		err = withVersionHint(ctx, err, stack, selector)
+		return fmt.Errorf("failed to find version %d: %w", version, err)
 	}
 
 	// Check if this is the latest version
-	latest, err := history.GetLatestVersion(ctx, projectPath, stack)
+	latest, err := history.GetLatestVersionWithSelector(ctx, projectPath, stack, selector)
 	if err != nil {
 		return fmt.Errorf("failed to get latest version: %w", err)
 	}
 
-	if previewVersion == latest {
-		fmt.Println("Warning: Version", previewVersion, "is the current version. No rollback needed.")
-		return nil
+	if version == latest && !previewAllowNoop {
+		if previewCheck {
+			fmt.Printf("No changes: stack %s is already at version %d\n", stack, version)
+			return nil
+		}
+		fmt.Println("Warning: Version", version, "is the current version. No rollback needed.")
+		fmt.Println("Use --allow-noop to preview re-applying the current state anyway.")
+		ghWarning("Version %d is the current version of stack %s", version, stack)
+		if previewDetailedExit {
+			return nil
+		}
+		return exitWithCode(cmd, exitCodeNoop)
 	}
 
-	fmt.Printf("Previewing rollback to version %d...\n", previewVersion)
-	fmt.Printf("  Kind: %s\n", update.Kind)
-	fmt.Printf("  Result: %s\n", update.Result)
-	fmt.Printf("  Time: %s\n", formatTime(update.StartTime))
-	if update.Message != "" {
-		fmt.Printf("  Message: %s\n", update.Message)
+	// In check mode only the summary line goes to stdout
+	var output io.Writer = os.Stdout
+	if previewCheck {
+		output = os.Stderr
+	} else {
+		updates, err := history.GetStackHistoryWithSelector(ctx, projectPath, stack, selector)
+		if err != nil {
+			return fmt.Errorf("failed to get history: %w", err)
+		}
+		annotateProvenance(stack, updates)
+		switch history.DirectionTo(updates, version) {
+		case history.DirectionForward:
+			fmt.Printf("Previewing roll-forward to version %d...\n", version)
+		case history.DirectionReapply:
+			fmt.Printf("Previewing re-applying version %d...\n", version)
+		default:
+			fmt.Printf("Previewing rollback to version %d...\n", version)
+		}
+		fmt.Printf("  Kind: %s\n", update.Kind)
+		fmt.Printf("  Result: %s\n", update.Result)
+		fmt.Printf("  Time: %s\n", formatUpdateTime(update.StartTime, update.RawStartTime))
+		if update.Message != "" {
+			fmt.Printf("  Message: %s\n", update.Message)
+		}
+		fmt.Println()
 	}
-	fmt.Println()
+	printProgramNotice(ctx, output, projectPath, stack, latest, update, selector)
 
 	opts := rollback.RollbackOptions{
 		ProjectPath:   projectPath,
 		StackName:     stack,
-		TargetVersion: previewVersion,
+		TargetVersion: version,
 		DryRun:        true,
 		Verbose:       isVerbose(),
-		Output:        os.Stdout,
+		SkipHashCheck: skipHashCheck,
+		Output:        output,
+		Operator:      newStackOperator(pulumiCommand),
+		Logger:        newLogger(output),
+		PreviewMode:   mode,
+		Types:         previewTypes,
+		Targets:       previewTargets,
+		CheckPlugins:  previewCheckPlugins,
+		VerifyPreview: previewVerify,
+		Diff:          previewDiff,
+		Force:         previewForce,
+		IgnoreBusy:    previewIgnoreBusy,
+		RenamedFrom:   previewRenamedFrom,
+
+		ReencryptSecrets: previewReencrypt,
+		SourcePassphrase: os.Getenv("PULUMI_ROLLBACK_SOURCE_PASSPHRASE"),
 	}
 
 	result, err := rollback.PreviewRollback(ctx, opts)
+	writeResultFile("preview", stack, version, result, err)
+	if errors.Is(err, rollback.ErrStackBusy) {
+		fmt.Fprintln(output, "\nAnother update of the stack is running. Wait for it to finish, or re-run with --ignore-busy if it is known to be dead.")
+	}
 	if err != nil {
 		return fmt.Errorf("preview failed: %w", err)
 	}
 
+	if previewReport != "" {
+		if err := writePreviewReport(ctx, selector, stack, latest, update, result); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		fmt.Fprintf(output, "Report written to %s\n", previewReport)
+	}
+
+	if err := rollback.CheckExpectations(expectations, result.ResourceChanges); err != nil {
+		return err
+	}
+
+	if previewCheck {
+		if !result.HasChanges() {
+			fmt.Printf("No changes: stack %s already matches version %d\n", stack, version)
+			return nil
+		}
+		fmt.Printf("Changes: rolling back stack %s to version %d would apply %s\n",
+			stack, version, formatChanges(result.ResourceChanges))
+		return exitWithCode(cmd, exitCodeChanges)
+	}
+
 	fmt.Println("\n" + result.Message)
+	ghNotice("Previewed rollback of stack %s to version %d", stack, version)
+	setGitHubOutput("preview-version", strconv.Itoa(version))
+	setGitHubChangesOutput("resource-changes", result.ResourceChanges)
 
 	if len(result.ResourceChanges) > 0 {
 		fmt.Println("\nResource changes:")
@@ -96,8 +260,41 @@ func runPreview(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if previewDiff && len(result.Steps) > 0 {
+		fmt.Println("\nProperty changes:")
+		if err := rollback.WriteStepDiffs(os.Stdout, result.Steps); err != nil {
+			return fmt.Errorf("failed to write property changes: %w", err)
+		}
+	}
+
 	fmt.Println("\nTo execute this rollback, run:")
-	fmt.Printf("  pulumi-rollback to --stack %s --version %d\n", stack, previewVersion)
+	fmt.Printf("  pulumi-rollback to --stack %s --version %d\n", stack, version)
 
+	if previewDetailedExit && result.HasChanges() {
+		return exitWithCode(cmd, exitCodeChanges)
+	}
 	return nil
 }
+
+// writePreviewReport writes the markdown report for --report. The duration
+// is estimated from the average duration of the stack's past updates.
+func writePreviewReport(ctx context.Context, selector history.StackSelector, stack string, current int, target *history.UpdateInfo, result *rollback.RollbackResult) error {
+	updates, err := history.GetStackHistoryWithSelector(ctx, getProjectPath(), stack, selector)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = rollback.WriteMarkdownReport(&buf, rollback.PreviewReport{
+		StackName:         stack,
+		CurrentVersion:    current,
+		Target:            *target,
+		Preview:           result,
+		EstimatedDuration: history.ComputeHistoryStats(updates).AverageDuration,
+		Generated:         time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(previewReport, buf.Bytes())
+}
@@ -0,0 +1,43 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+)
+
+// printProgramNotice reminds the user that a rollback restores state, not
+// code, and warns when the local program differs from the programs that
+// deployed the current and target versions. A program cloned with --repo is
+// not compared.
+func printProgramNotice(ctx context.Context, w io.Writer, projectPath, stack string, latest int, target *history.UpdateInfo, selector history.StackSelector) {
+	fmt.Fprintf(w, "Note: the rollback restores the state of version %d, not its program.\n", target.Version)
+	if repoURL != "" {
+		fmt.Fprintf(w, "      Previews and updates run the program cloned from %s.\n\n", describeRepo())
+		return
+	}
+	fmt.Fprintf(w, "      Previews and updates run the program in %s as it is now.\n", projectPath)
+
+	local, err := history.LocalProgramRevision(ctx, projectPath)
+	if err != nil {
+		newLogger(w).Debugf("cannot compare program revisions: %v", err)
+		fmt.Fprintln(w)
+		return
+	}
+	current, err := history.GetUpdateByVersionWithSelector(ctx, projectPath, stack, latest, selector)
+	if err != nil {
+		newLogger(w).Debugf("cannot compare program revisions: %v", err)
+		fmt.Fprintln(w)
+		return
+	}
+
+	for _, warning := range history.ProgramWarnings(local, *current, *target) {
+		fmt.Fprintf(w, "⚠️  %s\n", warning)
+	}
+	fmt.Fprintln(w)
+}
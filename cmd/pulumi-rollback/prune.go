@@ -0,0 +1,116 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupDir      string
+	pruneOlderThan string
+	pruneKeep      int
+	pruneDryRun    bool
+)
+
+var pruneBackupsCmd = &cobra.Command{
+	Use:   "prune-backups",
+	Short: "Remove old state backups created before rollbacks",
+	Long: `Remove the state backups written by 'to'.
+
+For each stack the most recent --keep backups are kept, as is any backup
+newer than --older-than. Only files created by pulumi-rollback are removed.
+
+Examples:
+  # Show what would be removed
+  pulumi-rollback prune-backups --backup-dir ./backups --dry-run
+
+  # Keep the last 10 backups per stack and anything from the last 30 days
+  pulumi-rollback prune-backups --backup-dir ./backups --older-than 30d --keep 10`,
+	RunE: runPruneBackups,
+}
+
+func init() {
+	rootCmd.AddCommand(pruneBackupsCmd)
+	pruneBackupsCmd.Flags().StringVar(&backupDir, "backup-dir", "", "Directory containing the backups (or set PULUMI_ROLLBACK_BACKUP_DIR; default: "+rollback.DefaultBackupDir+" in the project directory)")
+	pruneBackupsCmd.Flags().StringVar(&pruneOlderThan, "older-than", "30d", "Only remove backups older than this (e.g. 30d, 12h)")
+	pruneBackupsCmd.Flags().IntVar(&pruneKeep, "keep", 10, "Always keep this many of the most recent backups per stack")
+	pruneBackupsCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "List the backups that would be removed without removing them")
+}
+
+func runPruneBackups(cmd *cobra.Command, args []string) error {
+	dir := rollback.ProjectBackupDir(getProjectPath(), getBackupDir())
+	if pruneKeep < 0 {
+		return fmt.Errorf("--keep must not be negative")
+	}
+	olderThan, err := parseAge(pruneOlderThan)
+	if err != nil {
+		return err
+	}
+
+	backups, err := rollback.ListBackups(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	prunable := rollback.SelectPrunable(backups, pruneKeep, olderThan, time.Now())
+	if len(prunable) == 0 {
+		fmt.Printf("No backups to remove (%d found)\n", len(backups))
+		return nil
+	}
+
+	removed := 0
+	for _, b := range prunable {
+		if pruneDryRun {
+			fmt.Printf("Would remove %s (stack %s, %s)\n", b.CheckpointPath, b.Stack, formatTime(b.Created.Local()))
+			continue
+		}
+		if err := rollback.RemoveBackup(b); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", b.CheckpointPath, err)
+		}
+		if isVerbose() {
+			fmt.Printf("Removed %s\n", b.CheckpointPath)
+		}
+		removed++
+	}
+
+	if pruneDryRun {
+		fmt.Printf("\n%d of %d backup(s) would be removed\n", len(prunable), len(backups))
+	} else {
+		fmt.Printf("Removed %d of %d backup(s)\n", removed, len(backups))
+	}
+	return nil
+}
+
+// getBackupDir returns the backup directory from the flag or environment,
+// or an empty string for the default of rollback.ProjectBackupDir
+func getBackupDir() string {
+	if backupDir != "" {
+		return backupDir
+	}
+	return os.Getenv("PULUMI_ROLLBACK_BACKUP_DIR")
+}
+
+// parseAge parses a duration, additionally accepting a number of days ("30d")
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
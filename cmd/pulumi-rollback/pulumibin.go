@@ -0,0 +1,98 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// binaryCommand runs a Pulumi CLI at any path. auto.NewPulumiCommand only
+// finds binaries named pulumi in a bin directory under a root.
+type binaryCommand struct {
+	path    string
+	version semver.Version
+}
+
+var _ auto.PulumiCommand = binaryCommand{}
+
+// newBinaryCommand checks that path is a Pulumi CLI it can run and reads
+// its version
+func newBinaryCommand(path string) (binaryCommand, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return binaryCommand{}, fmt.Errorf("pulumi binary %s: %w", path, err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return binaryCommand{}, fmt.Errorf("pulumi binary %s not found: %w", path, err)
+	}
+	if info.IsDir() {
+		return binaryCommand{}, fmt.Errorf("pulumi binary %s is a directory", path)
+	}
+
+	cmd := exec.Command(abs, "version")
+	cmd.Env = append(os.Environ(), "PULUMI_SKIP_UPDATE_CHECK=true")
+	out, err := cmd.Output()
+	if err != nil {
+		return binaryCommand{}, fmt.Errorf("failed to run %s version: %w", path, err)
+	}
+	version, err := semver.ParseTolerant(strings.TrimSpace(string(out)))
+	if err != nil {
+		return binaryCommand{}, fmt.Errorf("%s is not a pulumi binary: unexpected version %q", path, strings.TrimSpace(string(out)))
+	}
+	return binaryCommand{path: abs, version: version}, nil
+}
+
+// Run runs the binary non-interactively, as the Automation API's own
+// command does, with the binary's directory first on PATH so plugins it
+// ships with are found
+func (c binaryCommand) Run(ctx context.Context, workdir string, stdin io.Reader,
+	additionalOutput []io.Writer, additionalErrorOutput []io.Writer, additionalEnv []string, args ...string,
+) (string, string, int, error) {
+	if !slices.Contains(args, "--non-interactive") {
+		args = append(slices.Clip(args), "--non-interactive")
+	}
+	cmd := exec.CommandContext(ctx, c.path, args...)
+	cmd.Dir = workdir
+	cmd.Env = append(append(os.Environ(), additionalEnv...), "PULUMI_AUTOMATION_API=true",
+		"PATH="+filepath.Dir(c.path)+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cmd.Stdin = stdin
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = 10 * time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = io.MultiWriter(append(slices.Clip(additionalOutput), &stdout)...)
+	cmd.Stderr = io.MultiWriter(append(slices.Clip(additionalErrorOutput), &stderr)...)
+
+	code := -2
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		code = exitErr.ExitCode()
+	} else if err == nil {
+		code = 0
+	}
+	return stdout.String(), stderr.String(), code, err
+}
+
+// Version returns the version the binary reported
+func (c binaryCommand) Version() semver.Version {
+	return c.version
+}
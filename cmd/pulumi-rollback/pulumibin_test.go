@@ -0,0 +1,101 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakePulumi writes an executable script standing in for the pulumi CLI
+func fakePulumi(t *testing.T, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake binaries are shell scripts")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestGetPulumiCommand(t *testing.T) {
+	pinned := fakePulumi(t, "pulumi-3.100.0", `if [ "$1" = version ]; then echo v3.100.0; else echo "$@"; fi`+"\n")
+
+	tests := []struct {
+		name        string
+		flag        string
+		env         string
+		expected    string
+		expectedErr string
+	}{
+		{name: "not set"},
+		{name: "flag", flag: pinned, expected: "3.100.0"},
+		{name: "environment", env: pinned, expected: "3.100.0"},
+		{name: "flag wins over environment", flag: pinned, env: "/nonexistent/pulumi", expected: "3.100.0"},
+		{name: "missing", flag: filepath.Join(t.TempDir(), "pulumi"), expectedErr: "not found"},
+		{name: "directory", flag: t.TempDir(), expectedErr: "is a directory"},
+		{name: "failing", flag: fakePulumi(t, "pulumi", "exit 1\n"), expectedErr: "failed to run"},
+		{name: "not pulumi", flag: fakePulumi(t, "pulumi", "echo hello\n"), expectedErr: "is not a pulumi binary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pulumiBin = tt.flag
+			t.Cleanup(func() { pulumiBin = "" })
+			t.Setenv("PULUMI_BINARY", tt.env)
+
+			command, err := getPulumiCommand()
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.expected == "" {
+				if command != nil {
+					t.Errorf("Expected the pulumi on PATH, got %v", command)
+				}
+				return
+			}
+			if command == nil || command.Version().String() != tt.expected {
+				t.Errorf("Expected pulumi %s, got %v", tt.expected, command)
+			}
+		})
+	}
+}
+
+func TestBinaryCommand_Run(t *testing.T) {
+	bin := fakePulumi(t, "pulumi-pinned", `if [ "$1" = version ]; then echo v3.150.0; exit 0; fi
+echo "$@ $PULUMI_AUTOMATION_API"
+echo oops >&2
+exit 3
+`)
+	command, err := newBinaryCommand(bin)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stdout, stderr, code, err := command.Run(context.Background(), t.TempDir(), nil, nil, nil, nil, "stack", "ls")
+	if err == nil {
+		t.Error("Expected the exit status as an error")
+	}
+	if code != 3 {
+		t.Errorf("Expected exit code 3, got %d", code)
+	}
+	if strings.TrimSpace(stdout) != "stack ls --non-interactive true" {
+		t.Errorf("Unexpected stdout %q", stdout)
+	}
+	if strings.TrimSpace(stderr) != "oops" {
+		t.Errorf("Unexpected stderr %q", stderr)
+	}
+}
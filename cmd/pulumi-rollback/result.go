@@ -0,0 +1,133 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+)
+
+// resultRecord is the document written by --result-file and --output json
+type resultRecord struct {
+	Operation     string                   `json:"operation"`
+	Stack         string                   `json:"stack"`
+	TargetVersion int                      `json:"targetVersion"`
+	Timestamp     time.Time                `json:"timestamp"`
+	Success       bool                     `json:"success"`
+	Error         string                   `json:"error,omitempty"`
+	Result        *rollback.RollbackResult `json:"result,omitempty"`
+
+	// PreviousVersion is the stack's latest version before a rollback
+	PreviousVersion int `json:"previousVersion,omitempty"`
+	// BackupPath is the backup of the state taken before a rollback
+	BackupPath string `json:"backupPath,omitempty"`
+
+	// Set when a rollback fails, describing how far it got. Phase is empty
+	// when it failed before the rollback started.
+	Phase          string                 `json:"phase,omitempty"`
+	PartialChanges map[string]int         `json:"partialChanges,omitempty"`
+	Phases         []rollback.PhaseTiming `json:"phases,omitempty"`
+	// Recovered is set when an interrupted or unverified rollback restored
+	// the state from before it
+	Recovered bool `json:"recovered,omitempty"`
+
+	// RunID is the --run-id of the rollback. Replay is set when the record
+	// is the earlier result of a run that had already succeeded.
+	RunID  string `json:"runId,omitempty"`
+	Replay bool   `json:"replay,omitempty"`
+}
+
+// newResultRecord describes the outcome of an operation
+func newResultRecord(operation, stack string, targetVersion int, result *rollback.RollbackResult, opErr error) resultRecord {
+	record := resultRecord{
+		Operation:     operation,
+		Stack:         stack,
+		TargetVersion: targetVersion,
+		Timestamp:     time.Now().UTC(),
+		Success:       opErr == nil && result != nil && result.Success,
+		Result:        result,
+		RunID:         runID,
+	}
+	if opErr != nil {
+		record.Error = opErr.Error()
+	}
+	if result != nil {
+		record.BackupPath = result.BackupFile
+	}
+
+	var rbErr *rollback.RollbackError
+	if errors.As(opErr, &rbErr) {
+		record.Phase = rbErr.Phase.String()
+		record.BackupPath = rbErr.BackupFile
+		record.PartialChanges = rbErr.ResourceChanges
+		record.Phases = rbErr.Phases
+		record.Recovered = rbErr.Recovered
+	}
+	return record
+}
+
+// writeResultFile writes the outcome of an operation to --result-file, if set.
+// Failures to write are reported but do not fail the operation.
+func writeResultFile(operation, stack string, targetVersion int, result *rollback.RollbackResult, opErr error) {
+	writeRecordFile(newResultRecord(operation, stack, targetVersion, result, opErr))
+}
+
+// writeRecordFile writes a result record to --result-file, if set
+func writeRecordFile(record resultRecord) {
+	if resultFile == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(resultFile, append(data, '\n'))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write result file %s: %v\n", resultFile, err)
+	}
+}
+
+// writeResultJSON writes the outcome of an operation to w for --output json
+func writeResultJSON(w io.Writer, operation, stack string, targetVersion int, result *rollback.RollbackResult, opErr error) error {
+	return writeRecordJSON(w, newResultRecord(operation, stack, targetVersion, result, opErr))
+}
+
+// writeRecordJSON writes a result record to w
+func writeRecordJSON(w io.Writer, record resultRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(record)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it into place so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -4,9 +4,23 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/concurrent"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/config"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/logging"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/spf13/cobra"
 )
 
@@ -14,6 +28,33 @@ var (
 	stackName   string
 	projectPath string
 	verbose     bool
+	pulumiBin   string
+
+	githubActions bool
+
+	maxConcurrentFetches int
+
+	resultFile string
+
+	logLevel string
+
+	configFile string
+
+	orgName     string
+	projectName string
+
+	repoURL    string
+	repoBranch string
+	repoCommit string
+	repoDir    string
+	// repoClone is the command's clone of --repo, created by gitRepo
+	repoClone *history.GitClone
+
+	skipHashCheck bool
+
+	timeout time.Duration
+	// cancelTimeout releases the --timeout context once the command returns
+	cancelTimeout context.CancelFunc = func() {}
 )
 
 var rootCmd = &cobra.Command{
@@ -34,35 +75,289 @@ Examples:
 
   # Roll back to a specific version
   pulumi-rollback to --stack mystack --version 5`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := concurrent.ValidateLimit(maxConcurrentFetches); err != nil {
+			return fmt.Errorf("invalid --max-concurrent-fetches: %w", err)
+		}
+		if _, err := logging.ParseLevel(logLevel); err != nil {
+			return fmt.Errorf("invalid --log-level: %w", err)
+		}
+		// Diagnostics go to stderr so they never mix into JSON or CSV output
+		history.Logger = newLogger(os.Stderr)
+
+		if repoURL == "" && (repoBranch != "" || repoCommit != "" || repoDir != "") {
+			return fmt.Errorf("--repo-branch, --repo-commit and --repo-dir need --repo")
+		}
+
+		if timeout < 0 {
+			return fmt.Errorf("--timeout must not be negative, got %s", timeout)
+		}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+			cancelTimeout = cancel
+		}
+		return nil
+	},
 }
 
+// Execute runs the root command. Interrupting the process (Ctrl-C or
+// SIGTERM) cancels the command's context, aborting in-flight Pulumi
+// operations.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// After the first signal, a second one terminates the process at once
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	if repoClone != nil {
+		if closeErr := repoClone.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove the clone of %s: %v\n", repoURL, closeErr)
+		}
+	}
+	if err != nil && timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%w (--timeout %s exceeded)", err, timeout)
+	}
+	var exitErr *exitCodeError
+	if err != nil && !errors.As(err, &exitErr) {
+		ghError("%v", err)
+	}
+	return err
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&stackName, "stack", "s", "", "Name of the Pulumi stack")
 	rootCmd.PersistentFlags().StringVarP(&projectPath, "cwd", "C", ".", "Path to the Pulumi project directory")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output (alias for --log-level debug)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level: error, warn, info or debug")
+	rootCmd.PersistentFlags().BoolVar(&githubActions, "github-actions", false, "Emit GitHub Actions annotations and step outputs (default: auto-detect via GITHUB_ACTIONS)")
+	rootCmd.PersistentFlags().IntVar(&maxConcurrentFetches, "max-concurrent-fetches", concurrent.DefaultLimit, "Maximum number of concurrent backend requests for bulk operations")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to the configuration file (default: .pulumi-rollback.json in the project directory)")
+	rootCmd.PersistentFlags().StringVar(&orgName, "org", "", "Organization of the stack; with --project, selects the stack without a project directory unless --cwd is given")
+	rootCmd.PersistentFlags().StringVar(&projectName, "project", "", "Project of the stack; with --org, selects the stack without a project directory")
+	rootCmd.MarkFlagsRequiredTogether("org", "project")
+	rootCmd.PersistentFlags().StringVar(&repoURL, "repo", "", "Clone the stack's program from this git repository instead of using a project directory (token from PULUMI_ROLLBACK_GIT_TOKEN)")
+	rootCmd.PersistentFlags().StringVar(&repoBranch, "repo-branch", "", "Branch of --repo to check out (default: the repository's default branch)")
+	rootCmd.PersistentFlags().StringVar(&repoCommit, "repo-commit", "", "Commit of --repo to check out")
+	rootCmd.PersistentFlags().StringVar(&repoDir, "repo-dir", "", "Directory of the Pulumi project within --repo (default: the repository root)")
+	rootCmd.MarkFlagsMutuallyExclusive("repo-branch", "repo-commit")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Abort the command if it takes longer than this, e.g. 30m (0 = no limit)")
+	rootCmd.PersistentFlags().BoolVar(&skipHashCheck, "skip-hash-check", false, "Do not compare checkpoints read from a self-managed backend with the MD5 hash its bucket reports (sizes are still checked)")
+	rootCmd.PersistentFlags().StringVar(&pulumiBin, "pulumi-bin", "", "Path to the pulumi CLI binary (default: pulumi on PATH, or PULUMI_BINARY)")
 }
 
 func getStackName() (string, error) {
-	if stackName != "" {
-		return stackName, nil
+	name := stackName
+	if name == "" {
+		// Try to detect from environment or Pulumi.yaml
+		name = os.Getenv("PULUMI_STACK")
+	}
+	if name == "" {
+		return "", fmt.Errorf("stack name is required: use --stack flag or set PULUMI_STACK environment variable")
 	}
 
-	// Try to detect from environment or Pulumi.yaml
-	if envStack := os.Getenv("PULUMI_STACK"); envStack != "" {
-		return envStack, nil
+	if isRemoteStack() {
+		if strings.Contains(name, "/") {
+			return "", fmt.Errorf("--stack must be a bare stack name when --org and --project are set, got %q", name)
+		}
+		return orgName + "/" + projectName + "/" + name, nil
 	}
+	return name, nil
+}
+
+// isRemoteStack reports whether the stack is selected by its fully qualified
+// name instead of from a project directory
+func isRemoteStack() bool {
+	return projectName != ""
+}
 
-	return "", fmt.Errorf("stack name is required: use --stack flag or set PULUMI_STACK environment variable")
+// cwdGiven reports whether --cwd was passed, naming the project directory
+// explicitly
+func cwdGiven() bool {
+	flag := rootCmd.PersistentFlags().Lookup("cwd")
+	return flag != nil && flag.Changed
+}
+
+// remoteProject returns the project a stack is selected from without a
+// project directory: --project, unless --cwd names the directory holding
+// the program
+func remoteProject() string {
+	if cwdGiven() {
+		return ""
+	}
+	return projectName
+}
+
+// requireProjectDir fails for commands that run the stack's program, which
+// is only available in a project directory or a --repo clone. A fully
+// qualified stack name built from --org and --project is selected from the
+// directory --cwd names, when given.
+func requireProjectDir(command string) error {
+	if remoteProject() != "" && repoURL == "" {
+		return fmt.Errorf("%s needs the stack's program and cannot be used with --org and --project alone; pass --cwd with the project directory or --repo", command)
+	}
+	return nil
+}
+
+// gitRepo returns the clone of the stack's program from --repo, or nil
+// without it. The command shares one clone, which Execute removes once the
+// command returns.
+func gitRepo() *history.GitClone {
+	if repoURL == "" {
+		return nil
+	}
+	if repoClone != nil {
+		return repoClone
+	}
+	repo := auto.GitRepo{
+		URL:         repoURL,
+		Branch:      repoBranch,
+		CommitHash:  repoCommit,
+		ProjectPath: repoDir,
+	}
+	if token := os.Getenv("PULUMI_ROLLBACK_GIT_TOKEN"); token != "" {
+		repo.Auth = &auto.GitAuth{PersonalAccessToken: token}
+	}
+	repoClone = history.NewGitClone(repo)
+	return repoClone
+}
+
+// describeRepo names the --repo revision the program is cloned from
+func describeRepo() string {
+	switch {
+	case repoCommit != "":
+		return repoURL + "@" + repoCommit
+	case repoBranch != "":
+		return repoURL + " (" + repoBranch + ")"
+	default:
+		return repoURL
+	}
+}
+
+// newStackSelector returns the stack selector for the current flags
+func newStackSelector(pulumiCommand auto.PulumiCommand) *history.DefaultStackSelector {
+	return &history.DefaultStackSelector{PulumiCommand: pulumiCommand, Project: remoteProject(), Repo: gitRepo()}
+}
+
+// newStackOperator returns the stack operator for the current flags
+func newStackOperator(pulumiCommand auto.PulumiCommand) *rollback.DefaultStackOperator {
+	return &rollback.DefaultStackOperator{PulumiCommand: pulumiCommand, Project: remoteProject(), Repo: gitRepo()}
+}
+
+// withVersionHint adds the versions of the stack's history to an error for
+// a version that is not in it. The history is fetched with selector when
+// the error does not list the versions; a nil selector skips that.
+func withVersionHint(ctx context.Context, err error, stack string, selector history.StackSelector) error {
+	var notFound *history.VersionNotFoundError
+	if !errors.As(err, &notFound) {
+		return err
+	}
+
+	available := notFound.Available
+	if available == nil && selector != nil {
+		updates, histErr := history.GetStackHistoryWithSelector(ctx, getProjectPath(), stack, selector)
+		if histErr != nil {
+			return err
+		}
+		for _, u := range updates {
+			available = append(available, u.Version)
+		}
+	}
+	if len(available) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (available versions: %s)", err, history.FormatVersions(available))
+}
+
+// validateTargetVersion checks a version given by the user against the
+// stack's history before anything is changed
+func validateTargetVersion(ctx context.Context, stack string, version int, selector history.StackSelector) error {
+	updates, err := history.GetStackHistoryWithSelector(ctx, getProjectPath(), stack, selector)
+	if err != nil {
+		return err
+	}
+	if err := history.ValidateTargetVersion(updates, version); err != nil {
+		return fmt.Errorf("invalid target version: %w", withVersionHint(ctx, err, stack, nil))
+	}
+	return nil
 }
 
 func getProjectPath() string {
 	return projectPath
 }
 
+// loadConfig loads the configuration file from --config or the project directory
+func loadConfig() (*config.Config, error) {
+	file := configFile
+	if file == "" {
+		file = filepath.Join(getProjectPath(), config.DefaultName)
+	}
+	return config.Load(file)
+}
+
+// provenanceLogPath returns the path of the project's provenance log
+func provenanceLogPath() string {
+	return filepath.Join(getProjectPath(), history.ProvenanceLogName)
+}
+
+// annotateProvenance attaches the provenance recorded in the project's
+// provenance log to the updates of a stack
+func annotateProvenance(stack string, updates []history.UpdateInfo) {
+	log, err := history.LoadProvenanceLog(provenanceLogPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	log.Annotate(stack, updates)
+}
+
 func isVerbose() bool {
-	return verbose
+	return getLogLevel() >= logging.LevelDebug
+}
+
+func getLogLevel() logging.Level {
+	if verbose {
+		return logging.LevelDebug
+	}
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		return logging.LevelInfo
+	}
+	return level
+}
+
+func newLogger(w io.Writer) logging.Logger {
+	return logging.New(w, getLogLevel())
+}
+
+func getPulumiBinary() string {
+	if pulumiBin != "" {
+		return pulumiBin
+	}
+	return os.Getenv("PULUMI_BINARY")
+}
+
+// getPulumiCommand returns the Pulumi CLI to use for workspace operations,
+// or nil to fall back to the pulumi binary found on PATH.
+func getPulumiCommand() (auto.PulumiCommand, error) {
+	bin := getPulumiBinary()
+	if bin == "" {
+		return nil, nil
+	}
+
+	command, err := newBinaryCommand(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	if isVerbose() {
+		fmt.Fprintf(os.Stderr, "Using pulumi %s from %s\n", command.Version(), bin)
+	}
+
+	return command, nil
 }
@@ -0,0 +1,47 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"testing"
+)
+
+func TestRequireProjectDir(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     string
+		cwd         bool
+		repo        string
+		expectError bool
+	}{
+		{name: "project directory"},
+		{name: "org and project alone", project: "website", expectError: true},
+		{name: "org and project with cwd", project: "website", cwd: true},
+		{name: "org and project with repo", project: "website", repo: "https://example.com/infra.git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldProject, oldRepo := projectName, repoURL
+			projectName, repoURL = tt.project, tt.repo
+			flag := rootCmd.PersistentFlags().Lookup("cwd")
+			flag.Changed = tt.cwd
+			t.Cleanup(func() {
+				projectName, repoURL = oldProject, oldRepo
+				flag.Changed = false
+			})
+
+			err := requireProjectDir("preview")
+			if tt.expectError && err == nil {
+				t.Error("Expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.cwd && remoteProject() != "" {
+				t.Errorf("Expected the stack to be selected from --cwd, got project %q", remoteProject())
+			}
+		})
+	}
+}
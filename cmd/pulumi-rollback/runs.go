@@ -0,0 +1,83 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/runs"
+)
+
+var (
+	runID    string
+	runsFile string
+)
+
+// getRunsFile returns the path of the run log
+func getRunsFile() string {
+	if runsFile != "" {
+		return runsFile
+	}
+	return filepath.Join(getProjectPath(), runs.DefaultName)
+}
+
+// replayRun prints the recorded result when --run-id already rolled back
+// the stack to the version successfully. It returns true if it did.
+func replayRun(out io.Writer, jsonOutput bool, stack string, version int) (bool, error) {
+	log, err := runs.Load(getRunsFile())
+	if err != nil {
+		return false, err
+	}
+	run, ok, err := log.Replay(runID, stack, version)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	var record resultRecord
+	if err := json.Unmarshal(run.Result, &record); err != nil {
+		return false, fmt.Errorf("failed to read the recorded result of run %s: %w", runID, err)
+	}
+	record.Replay = true
+
+	fmt.Fprintf(out, "Replay: run %s already rolled back stack '%s' to version %d at %s.\n",
+		runID, stack, version, run.CompletedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintln(out, "Nothing was applied. Use a new --run-id to roll back again.")
+	ghNotice("Run %s already rolled back stack %s to version %d; replaying its result", runID, stack, version)
+
+	writeRecordFile(record)
+	if jsonOutput {
+		if err := writeRecordJSON(os.Stdout, record); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// recordRun saves the outcome of a rollback under --run-id. Failures to
+// save are reported but do not fail the rollback.
+func recordRun(record resultRecord) {
+	result, err := json.Marshal(record)
+	if err == nil {
+		var log *runs.Log
+		log, err = runs.Load(getRunsFile())
+		if err == nil {
+			log.Record(runID, runs.Run{
+				Stack:         record.Stack,
+				TargetVersion: record.TargetVersion,
+				Success:       record.Success,
+				CompletedAt:   time.Now().UTC(),
+				Result:        result,
+			})
+			err = log.Save(getRunsFile())
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record run %s in %s: %v\n", runID, getRunsFile(), err)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+)
+
+const selectHelp = `  y  roll back this resource
+  n  skip this resource
+  a  roll back this and all remaining resources
+  d  skip this and all remaining resources
+  q  cancel the rollback`
+
+// errSelectionCancelled is returned when the user quits the selection
+var errSelectionCancelled = errors.New("rollback cancelled")
+
+// readLine reads a line from in, giving up when ctx is cancelled, e.g. by
+// Ctrl-C at a prompt
+func readLine(ctx context.Context, in *bufio.Reader) (string, error) {
+	type line struct {
+		text string
+		err  error
+	}
+	lines := make(chan line, 1)
+	go func() {
+		text, err := in.ReadString('\n')
+		lines <- line{text, err}
+	}()
+
+	select {
+	case l := <-lines:
+		return l.text, l.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// selectSteps shows each planned change and asks whether to roll back the
+// resource. It returns the URNs of the accepted resources, in step order.
+func selectSteps(ctx context.Context, in *bufio.Reader, out io.Writer, steps []rollback.ResourceStep) ([]string, error) {
+	fmt.Fprintf(out, "Select the resources to roll back (%d planned change(s)):\n", len(steps))
+
+	var selected []string
+	for i := 0; i < len(steps); i++ {
+		step := steps[i]
+		fmt.Fprintf(out, "\n[%d/%d] %s %s\n", i+1, len(steps), step.Op, step.URN)
+		fmt.Fprint(out, "Roll back this resource? [y,n,a,d,q,?]: ")
+
+		response, err := readLine(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "y", "yes":
+			selected = append(selected, step.URN)
+		case "n", "no":
+		case "a":
+			for _, s := range steps[i:] {
+				selected = append(selected, s.URN)
+			}
+			return selected, nil
+		case "d":
+			return selected, nil
+		case "q":
+			return nil, errSelectionCancelled
+		default:
+			fmt.Fprintln(out, selectHelp)
+			i--
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current version and whether the stack can be rolled back",
+	Long: `Show the stack's current version and the result of its update, how many
+earlier versions there are to roll back to, and whether an update is still
+running. Nothing is modified.
+
+Examples:
+  pulumi-rollback status --stack mystack`,
+	RunE: runStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	stack, err := getStackName()
+	if err != nil {
+		return err
+	}
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+
+	updates, err := history.GetStackHistoryWithSelector(cmd.Context(), getProjectPath(), stack, newStackSelector(pulumiCommand))
+	if err != nil {
+		return fmt.Errorf("failed to get stack history: %w", err)
+	}
+
+	status, err := history.GetStackStatus(updates, stack)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Status of stack %s:\n\n", stack)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  Current version:\t%d\n", status.Latest.Version)
+	fmt.Fprintf(w, "  Result:\t%s\n", formatResult(status.Latest.Result))
+	fmt.Fprintf(w, "  Started:\t%s\n", formatUpdateTime(status.Latest.StartTime, status.Latest.RawStartTime))
+	fmt.Fprintf(w, "  Earlier versions:\t%d\n", status.AvailableVersions)
+	if status.LastSucceededVersion > 0 {
+		fmt.Fprintf(w, "  Last succeeded before it:\t%d\n", status.LastSucceededVersion)
+	}
+	w.Flush()
+
+	setGitHubOutput("current-version", strconv.Itoa(status.Latest.Version))
+	switch {
+	case status.InProgress:
+		fmt.Printf("\nWarning: version %d is still being deployed. Rolling back now is unsafe.\n", status.Latest.Version)
+		ghWarning("Version %d of stack %s is still being deployed", status.Latest.Version, stack)
+	case status.AvailableVersions == 0:
+		fmt.Println("\nThere is no earlier version to roll back to.")
+	default:
+		fmt.Println("\nReady to roll back.")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+// assumeYesEnv skips confirmation prompts like --yes
+const assumeYesEnv = "PULUMI_ROLLBACK_ASSUME_YES"
+
+// stdinIsTerminal reports whether prompts can be answered on stdin
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// stdoutIsTerminal reports whether output can be redrawn in place
+var stdoutIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// useColor reports whether output is colored: only when stdout is a
+// terminal, so escape codes never end up in pipes or files, and neither
+// noColor nor NO_COLOR turn it off
+func useColor(noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && stdoutIsTerminal()
+}
+
+// assumeYes reports whether confirmation prompts are skipped, by --yes or
+// by PULUMI_ROLLBACK_ASSUME_YES
+func assumeYes() (bool, error) {
+	if skipConfirm {
+		return true, nil
+	}
+	value := os.Getenv(assumeYesEnv)
+	if value == "" {
+		return false, nil
+	}
+	yes, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: expected true or false", assumeYesEnv, value)
+	}
+	return yes, nil
+}
+
+// requirePrompt fails fast when a prompt cannot be answered because stdin
+// is not a terminal according to isTerminal, e.g. in CI, instead of waiting
+// for input forever. Commands pass stdinIsTerminal. hint says how to avoid
+// the prompt.
+func requirePrompt(isTerminal func() bool, hint string) error {
+	if isTerminal() {
+		return nil
+	}
+	return fmt.Errorf("stdin is not a terminal, so the prompt cannot be answered: %s", hint)
+}
+
+// confirmHint is the hint of requirePrompt for a confirmation that --yes skips
+var confirmHint = fmt.Sprintf("pass --yes or set %s=true to proceed without confirmation", assumeYesEnv)
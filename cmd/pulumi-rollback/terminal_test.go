@@ -0,0 +1,62 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequirePrompt(t *testing.T) {
+	if err := requirePrompt(func() bool { return true }, confirmHint); err != nil {
+		t.Errorf("Expected a terminal to allow the prompt, got %v", err)
+	}
+
+	err := requirePrompt(func() bool { return false }, confirmHint)
+	if err == nil {
+		t.Fatal("Expected an error without a terminal")
+	}
+	if !strings.Contains(err.Error(), "not a terminal") || !strings.Contains(err.Error(), assumeYesEnv) {
+		t.Errorf("Expected the error to explain how to skip the prompt, got %v", err)
+	}
+}
+
+func TestAssumeYes(t *testing.T) {
+	tests := []struct {
+		name        string
+		flag        bool
+		env         string
+		expected    bool
+		expectedErr bool
+	}{
+		{name: "neither"},
+		{name: "flag", flag: true, expected: true},
+		{name: "environment", env: "true", expected: true},
+		{name: "environment false", env: "false"},
+		{name: "flag wins over environment", flag: true, env: "false", expected: true},
+		{name: "invalid environment", env: "sure", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skipConfirm = tt.flag
+			t.Cleanup(func() { skipConfirm = false })
+			t.Setenv(assumeYesEnv, tt.env)
+
+			yes, err := assumeYes()
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if yes != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, yes)
+			}
+		})
+	}
+}
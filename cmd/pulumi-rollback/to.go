@@ -6,129 +6,658 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
 	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/runs"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/spf13/cobra"
 )
 
 var (
-	rollbackVersion int
-	skipConfirm     bool
+	rollbackVersion     int
+	skipConfirm         bool
+	maxRefreshDrift     int
+	refreshParallel     int
+	upParallel          int
+	forceRollback       bool
+	ignoreBusy          bool
+	renamedFrom         string
+	checkPlugins        bool
+	rollbackTypes       []string
+	rollbackTargets     []string
+	allowNoop           bool
+	toPinned            bool
+	rollbackOutput      string
+	reencrypt           bool
+	orphanNew           bool
+	forceImport         bool
+	noUpRefresh         bool
+	skipRefresh         bool
+	stateOnly           bool
+	showProgress        bool
+	allowEmpty          bool
+	gitTag              string
+	rollbackBefore      string
+	interactive         bool
+	dumpStatesDir       string
+	compressCheckpoints bool
+	noBackup            bool
 )
 
 var toCmd = &cobra.Command{
-	Use:   "to",
-	Short: "Roll back to a specific version",
+	Use:     "to",
+	Aliases: []string{"set"},
+	Short:   "Roll back to a specific version",
 	Long: `Roll back the stack to a specific version from the deployment history.
 
+Any version in the history can be targeted. After an earlier rollback, a
+version newer than the restored one rolls the stack forward again; the
+confirmation shows which direction the stack moves. 'set' is an alias.
+
 This will:
 1. Restore the stack state to the target version
 2. Refresh to reconcile with actual infrastructure
 3. Run 'up' to apply any necessary changes
 
+With --state-only, only step 1 runs: the recorded state is restored and
+infrastructure is left untouched, like 'pulumi stack import' of the target
+version's checkpoint.
+
 Examples:
   # Roll back to version 5
   pulumi-rollback to --stack mystack --version 5
 
+  # Roll forward to version 9 after an earlier rollback to version 5
+  pulumi-rollback set --stack mystack --version 9
+
   # Roll back without confirmation prompt
-  pulumi-rollback to --stack mystack --version 5 --yes`,
+  pulumi-rollback to --stack mystack --version 5 --yes
+
+  # Roll back to the version pinned in rollback.lock
+  pulumi-rollback to --stack mystack --to-pinned
+
+  # Roll back to the last successful deployment of git tag v1.4.0
+  pulumi-rollback to --stack mystack --git-tag v1.4.0
+
+  # Roll back to the newest deployment started by 2pm UTC on January 15
+  pulumi-rollback to --stack mystack --before 2024-01-15T14:00:00Z
+
+  # Make a CI retry safe: a second run with the same id replays the result
+  pulumi-rollback to --stack mystack --version 5 --yes --run-id "$CI_PIPELINE_ID"
+
+  # Re-apply the current version to force reconciliation
+  pulumi-rollback to --stack mystack --version 7 --allow-noop
+
+  # Only roll back Lambda functions
+  pulumi-rollback to --stack mystack --version 5 --type aws:lambda/function:Function
+
+  # Only roll back one resource
+  pulumi-rollback to --stack mystack --version 5 --target 'urn:pulumi:mystack::app::aws:s3/bucket:Bucket::logs'
+
+  # Preview, then choose the resources to roll back one at a time
+  pulumi-rollback to --stack mystack --version 5 --interactive
+
+  # Keep resources added after version 5 instead of recreating them
+  pulumi-rollback to --stack mystack --version 5 --orphan-new-resources
+
+  # Skip the refresh on a large stack and apply the state of version 5 as-is
+  pulumi-rollback to --stack mystack --version 5 --skip-refresh
+
+  # Infrastructure was already reverted by hand: only restore the state of version 5
+  pulumi-rollback to --stack mystack --version 5 --state-only
+
+  # Show each resource as the refresh and up reach it
+  pulumi-rollback to --stack mystack --version 5 --progress
+
+  # Apply the checkpoint of version 5 as ground truth, without refreshing
+  pulumi-rollback to --stack mystack --version 5 --force-import
+
+  # Abort if the refresh finds more than 3 drifted resources
+  pulumi-rollback to --stack mystack --version 5 --max-refresh-drift 3
+
+  # Refresh a large stack with 64 operations at once
+  pulumi-rollback to --stack mystack --version 5 --refresh-parallel 64`,
 	RunE: runRollback,
 }
 
 func init() {
 	rootCmd.AddCommand(toCmd)
-	toCmd.Flags().IntVarP(&rollbackVersion, "version", "V", 0, "Target version to roll back to (required)")
+	toCmd.Flags().IntVarP(&rollbackVersion, "version", "V", 0, "Target version to roll back to (required unless --to-pinned, --git-tag or --before)")
 	toCmd.Flags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip confirmation prompt")
-	toCmd.MarkFlagRequired("version")
+	toCmd.Flags().IntVar(&maxRefreshDrift, "max-refresh-drift", 0, "Abort if the refresh changes more than this many resources (0 = no limit)")
+	toCmd.Flags().IntVar(&refreshParallel, "refresh-parallel", 0, "Resource operations the refresh runs at once (0 = Pulumi default)")
+	toCmd.Flags().IntVar(&upParallel, "parallel", 0, "Resource operations up runs at once (0 = Pulumi default)")
+	toCmd.Flags().StringArrayVar(&rollbackTypes, "type", nil, "Only roll back resources of this type token (repeatable)")
+	toCmd.Flags().StringArrayVar(&rollbackTargets, "target", nil, "Only roll back the resource with this URN (repeatable)")
+	toCmd.Flags().BoolVar(&reencrypt, "reencrypt-secrets", false, "Re-encrypt the target checkpoint's secrets when its secrets provider differs from the stack's (source passphrase from PULUMI_ROLLBACK_SOURCE_PASSPHRASE)")
+	toCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Preview the rollback and choose which changed resources to roll back")
+	toCmd.MarkFlagsMutuallyExclusive("interactive", "yes")
+	toCmd.Flags().BoolVar(&orphanNew, "orphan-new-resources", false, "Leave resources added after the target version in place, no longer managed by the stack")
+	toCmd.Flags().BoolVar(&forceImport, "force-import", false, "Skip the refresh and apply the target checkpoint as ground truth (always asks for typed confirmation)")
+	toCmd.MarkFlagsMutuallyExclusive("force-import", "max-refresh-drift")
+	toCmd.MarkFlagsMutuallyExclusive("force-import", "refresh-parallel")
+	toCmd.Flags().BoolVar(&skipRefresh, "skip-refresh", false, "Go straight from the import to up, applying the target state as-is without reconciling drift (always asks for typed confirmation)")
+	toCmd.MarkFlagsMutuallyExclusive("skip-refresh", "force-import")
+	toCmd.MarkFlagsMutuallyExclusive("skip-refresh", "max-refresh-drift")
+	toCmd.MarkFlagsMutuallyExclusive("skip-refresh", "refresh-parallel")
+	toCmd.Flags().BoolVar(&noUpRefresh, "no-up-refresh", false, "With --force-import or --skip-refresh, also stop up from refreshing when Pulumi.yaml sets options.refresh: always")
+	toCmd.Flags().BoolVar(&stateOnly, "state-only", false, "Only import the target checkpoint; do not refresh or run up, leaving infrastructure untouched")
+	for _, other := range []string{"skip-refresh", "force-import", "no-up-refresh", "max-refresh-drift", "refresh-parallel", "parallel", "type", "target", "interactive", "orphan-new-resources"} {
+		toCmd.MarkFlagsMutuallyExclusive("state-only", other)
+	}
+	toCmd.Flags().BoolVar(&showProgress, "progress", false, "Stream Pulumi's per-resource output of the refresh and up as they run")
+	toCmd.Flags().BoolVar(&allowEmpty, "allow-empty", false, "Allow rolling back to a version with no resources, deleting all current infrastructure")
+	toCmd.Flags().BoolVar(&allowNoop, "allow-noop", false, "Re-apply the target even when it is the current version")
+	toCmd.Flags().StringVar(&resultFile, "result-file", "", "Write the rollback result as JSON to this file")
+	toCmd.Flags().StringVar(&runID, "run-id", "", "Identify this rollback; re-running with the id of a successful rollback replays its result instead of rolling back again")
+	toCmd.Flags().StringVar(&runsFile, "runs-file", "", "Where --run-id outcomes are recorded (default: "+runs.DefaultName+" in the project directory)")
+	toCmd.Flags().BoolVar(&checkPlugins, "check-plugins", false, "Fail if the target checkpoint needs provider plugins that are not installed")
+	toCmd.Flags().BoolVar(&forceRollback, "force", false, "Proceed even when safety checks fail")
+	toCmd.Flags().BoolVar(&ignoreBusy, "ignore-busy", false, "Proceed while the latest update of the stack is still in progress, e.g. after its process died")
+	toCmd.Flags().StringVar(&renamedFrom, "renamed-from", "", "Accept checkpoints taken before the stack was renamed from this name, rewriting their URNs to the current name")
+	toCmd.Flags().StringVar(&backupDir, "backup-dir", "", "Save the current state here before rolling back (or set PULUMI_ROLLBACK_BACKUP_DIR; default: "+rollback.DefaultBackupDir+" in the project directory)")
+	toCmd.Flags().BoolVar(&noBackup, "no-backup", false, "Do not save the current state before rolling back")
+	toCmd.Flags().StringVar(&dumpStatesDir, "dump-states", "", "Write the current state and the target checkpoint to this directory before rolling back")
+	toCmd.Flags().BoolVar(&compressCheckpoints, "compress", false, "Gzip the backup and the checkpoints written by --dump-states (as .checkpoint.json.gz)")
+	toCmd.Flags().BoolVar(&toPinned, "to-pinned", false, "Roll back to the version pinned in the lockfile")
+	toCmd.Flags().StringVar(&gitTag, "git-tag", "", "Roll back to the newest successful update deployed from this git tag, branch or commit")
+	toCmd.Flags().StringVar(&rollbackBefore, "before", "", "Roll back to the newest update that started at or before this time (RFC 3339, e.g. 2024-01-15T14:00:00Z)")
+	toCmd.Flags().StringVar(&lockfilePath, "lockfile", "", "Path to the lockfile (default: rollback.lock in the project directory)")
+	toCmd.Flags().StringVarP(&rollbackOutput, "output", "o", "text", "Output format: text or json (json writes the result to stdout, even on failure)")
+	toCmd.MarkFlagsOneRequired("version", "to-pinned", "git-tag", "before")
+	toCmd.MarkFlagsMutuallyExclusive("version", "to-pinned", "git-tag", "before")
 }
 
 func runRollback(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx := cmd.Context()
 
-	stack, err := getStackName()
+	jsonOutput, err := isJSONOutput(rollbackOutput)
 	if err != nil {
 		return err
 	}
+	// In JSON mode every failure still writes a result document, so
+	// automation gets structured details even when the rollback never started
+	var stack string
+	failed := func(err error) error {
+		if err == nil {
+			return nil
+		}
+		record := newResultRecord("rollback", stack, rollbackVersion, nil, err)
+		writeRecordFile(record)
+		if jsonOutput {
+			if jsonErr := writeRecordJSON(os.Stdout, record); jsonErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to write JSON result: %v\n", jsonErr)
+			}
+		}
+		return err
+	}
+	if refreshParallel < 0 {
+		return failed(fmt.Errorf("--refresh-parallel must not be negative"))
+	}
+	if upParallel < 0 {
+		return failed(fmt.Errorf("--parallel must not be negative"))
+	}
+	if noUpRefresh && !skipsRefresh() {
+		return failed(fmt.Errorf("--no-up-refresh requires --force-import or --skip-refresh"))
+	}
+	// In JSON mode stdout carries only the result document
+	var out io.Writer = os.Stdout
+	if jsonOutput {
+		out = os.Stderr
+	}
 
-	projectPath := getProjectPath()
+	if err := requireProjectDir("rollback"); err != nil {
+		return failed(err)
+	}
 
-	// Validate the version exists
-	update, err := history.GetUpdateByVersion(ctx, projectPath, stack, rollbackVersion)
+	// Fail before any work when a prompt would wait on stdin forever
+	yes, err := assumeYes()
 	if err != nil {
-		return fmt.Errorf("failed to find version %d: %w", rollbackVersion, err)
+		return failed(err)
+	}
+	if err := checkRollbackPrompts(yes, stdinIsTerminal); err != nil {
+		return failed(err)
 	}
 
-	// Check if this is the latest version
-	latest, err := history.GetLatestVersion(ctx, projectPath, stack)
+	stack, err = getStackName()
 	if err != nil {
-		return fmt.Errorf("failed to get latest version: %w", err)
+		return failed(err)
 	}
 
-	if rollbackVersion == latest {
-		fmt.Println("Version", rollbackVersion, "is the current version. No rollback needed.")
-		return nil
-	}
+	projectPath := getProjectPath()
 
-	// Show target version info
-	fmt.Printf("Rolling back stack '%s' to version %d\n", stack, rollbackVersion)
-	fmt.Printf("  Kind: %s\n", update.Kind)
-	fmt.Printf("  Result: %s\n", update.Result)
-	fmt.Printf("  Time: %s\n", formatTime(update.StartTime))
-	if update.Message != "" {
-		fmt.Printf("  Message: %s\n", update.Message)
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return failed(err)
 	}
-	fmt.Println()
+	// The target, the latest update and the program notice all read the
+	// same history, so it is fetched once
+	selector := history.NewCachingSelector(newStackSelector(pulumiCommand))
 
-	// Warn about rollback
-	fmt.Println("⚠️  WARNING: This will modify your infrastructure!")
-	fmt.Printf("   Current version: %d\n", latest)
-	fmt.Printf("   Target version:  %d\n", rollbackVersion)
-	fmt.Println()
-
-	// Confirmation prompt
-	if !skipConfirm {
-		fmt.Print("Do you want to proceed? [y/N]: ")
-		reader := bufio.NewReader(os.Stdin)
-		response, err := reader.ReadString('\n')
+	if toPinned {
+		rollbackVersion, err = getPinnedVersion(stack)
 		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+			return failed(err)
 		}
+		fmt.Fprintf(out, "Using version %d pinned in %s\n", rollbackVersion, getLockfilePath())
+	}
+	if gitTag != "" {
+		rollbackVersion, err = findGitTagVersion(ctx, projectPath, stack, gitTag, selector)
+		if err != nil {
+			return failed(err)
+		}
+		fmt.Fprintf(out, "Using version %d deployed from git ref %s\n", rollbackVersion, gitTag)
+	}
+	if rollbackBefore != "" {
+		rollbackVersion, err = findVersionBefore(ctx, projectPath, stack, rollbackBefore, selector)
+		if err != nil {
+			return failed(err)
+		}
+		fmt.Fprintf(out, "Using version %d, the newest update started at or before %s\n", rollbackVersion, rollbackBefore)
+	}
 
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "y" && response != "yes" {
-			fmt.Println("Rollback cancelled.")
-			return nil
+	if runID != "" {
+		replayed, err := replayRun(out, jsonOutput, stack, rollbackVersion)
+		if err != nil || replayed {
+			return failed(err)
+		}
+	}
+
+	// Validate the version exists
+	if err := validateTargetVersion(ctx, stack, rollbackVersion, selector); err != nil {
+		if toPinned {
+			return failed(fmt.Errorf("pinned version %d is no longer valid: %w", rollbackVersion, err))
+		}
+		return failed(err)
+	}
+	update, err := history.GetUpdateByVersionWithSelector(ctx, projectPath, stack, rollbackVersion, selector)
+	if err != nil {
+		err = withVersionHint(ctx, err, stack, selector)
+		if toPinned {
+			return failed(fmt.Errorf("pinned version %d is no longer valid: %w", rollbackVersion, err))
 		}
+		return failed(fmt.Errorf("failed to find version %d: %w", rollbackVersion, err))
 	}
 
-	fmt.Println("\nStarting rollback...")
+	// Check if this is the latest version
+	latest, err := history.GetLatestVersionWithSelector(ctx, projectPath, stack, selector)
+	if err != nil {
+		return failed(fmt.Errorf("failed to get latest version: %w", err))
+	}
+
+	if rollbackVersion == latest && !allowNoop {
+		fmt.Fprintln(out, "Version", rollbackVersion, "is the current version. No rollback needed.")
+		fmt.Fprintln(out, "Use --allow-noop to re-apply the current state anyway.")
+		ghNotice("Stack %s is already at version %d, no rollback needed", stack, rollbackVersion)
+		return exitWithCode(cmd, exitCodeNoop)
+	}
 
+	// After an earlier rollback the stack holds an older version's state,
+	// so a later version moves it forward. Refreshes do not change which.
+	updates, err := history.GetStackHistoryWithSelector(ctx, projectPath, stack, selector)
+	if err != nil {
+		return failed(fmt.Errorf("failed to get history: %w", err))
+	}
+	annotateProvenance(stack, updates)
+	direction := history.DirectionTo(updates, rollbackVersion)
+
+	// Show target version info
+	switch direction {
+	case history.DirectionForward:
+		fmt.Fprintf(out, "Rolling stack '%s' forward to version %d\n", stack, rollbackVersion)
+	case history.DirectionReapply:
+		fmt.Fprintf(out, "Re-applying version %d to stack '%s'\n", rollbackVersion, stack)
+	default:
+		fmt.Fprintf(out, "Rolling back stack '%s' to version %d\n", stack, rollbackVersion)
+	}
+	fmt.Fprintf(out, "  Kind: %s\n", update.Kind)
+	fmt.Fprintf(out, "  Result: %s\n", update.Result)
+	fmt.Fprintf(out, "  Time: %s\n", formatUpdateTime(update.StartTime, update.RawStartTime))
+	if update.Message != "" {
+		fmt.Fprintf(out, "  Message: %s\n", update.Message)
+	}
+	fmt.Fprintln(out)
+	printProgramNotice(ctx, out, projectPath, stack, latest, update, selector)
+
+	operator := newStackOperator(pulumiCommand)
+	operator.NoUpRefresh = noUpRefresh
 	opts := rollback.RollbackOptions{
 		ProjectPath:   projectPath,
 		StackName:     stack,
 		TargetVersion: rollbackVersion,
 		DryRun:        false,
 		Verbose:       isVerbose(),
-		Output:        os.Stdout,
+		SkipHashCheck: skipHashCheck,
+		Output:        out,
+		Operator:      operator,
+		Logger:        newLogger(out),
+
+		MaxRefreshDrift: maxRefreshDrift,
+		RefreshParallel: refreshParallel,
+		Types:           rollbackTypes,
+		Targets:         rollbackTargets,
+		CheckPlugins:    checkPlugins,
+		Force:           forceRollback,
+		IgnoreBusy:      ignoreBusy,
+		RenamedFrom:     renamedFrom,
+		ToolVersion:     Version,
+		ProvenanceLog:   provenanceLogPath(),
+		Initiator:       getInitiator(),
+		BackupDir:       getBackupDir(),
+		NoBackup:        noBackup,
+		DumpStatesDir:   dumpStatesDir,
+		Compress:        compressCheckpoints,
+
+		ReencryptSecrets: reencrypt,
+		SourcePassphrase: os.Getenv("PULUMI_ROLLBACK_SOURCE_PASSPHRASE"),
+
+		OrphanNewResources: orphanNew,
+		ForceImport:        forceImport,
+		SkipRefresh:        skipRefresh,
+		StateOnly:          stateOnly,
+		AllowEmpty:         allowEmpty,
+	}
+	if showProgress {
+		opts.ProgressWriter = out
+	}
+	if upParallel > 0 {
+		opts.UpOptions = append(opts.UpOptions, optup.Parallel(upParallel))
 	}
 
+	// Confirmation and selection prompts share stdin, so they share a reader
+	stdin := bufio.NewReader(os.Stdin)
+
+	if interactive {
+		targets, err := selectRollbackTargets(ctx, out, stdin, opts)
+		if err != nil {
+			if errors.Is(err, errSelectionCancelled) {
+				fmt.Fprintln(out, "Rollback cancelled.")
+				return nil
+			}
+			return failed(err)
+		}
+		if len(targets) == 0 {
+			fmt.Fprintln(out, "No resources selected. Rollback cancelled.")
+			return nil
+		}
+		opts.Types = nil
+		opts.Targets = targets
+		fmt.Fprintf(out, "\nRolling back %d selected resource(s)\n\n", len(targets))
+	}
+
+	// Warn about rollback
+	if stateOnly {
+		fmt.Fprintln(out, "⚠️  WARNING: This will replace the stack's recorded state!")
+		fmt.Fprintln(out, "   Infrastructure is not changed: it must already match the target version.")
+	} else {
+		fmt.Fprintln(out, "⚠️  WARNING: This will modify your infrastructure!")
+	}
+	fmt.Fprintf(out, "   Current version: %d\n", latest)
+	if stateUpdate, ok := history.LatestStateUpdate(updates); ok && stateUpdate.StateVersion() != latest {
+		fmt.Fprintf(out, "   Current state:   version %d (set by version %d)\n", stateUpdate.StateVersion(), stateUpdate.Version)
+	}
+	fmt.Fprintf(out, "   Target version:  %d\n", rollbackVersion)
+	fmt.Fprintf(out, "   Direction:       %s\n", direction)
+	fmt.Fprintln(out)
+
+	// Confirmation prompt. Skipping the refresh always needs a typed confirmation.
+	if !yes || skipsRefresh() {
+		cfg, err := loadConfig()
+		if err != nil {
+			return failed(err)
+		}
+		phrase := rollbackPhrase(cfg.ConfirmationPhrase(stack), stack)
+		if skipsRefresh() {
+			fmt.Fprintln(out, "⚠️  The refresh is skipped: the checkpoint is applied as ground truth")
+			fmt.Fprintln(out, "   without being reconciled with live infrastructure.")
+		}
+		confirmed, err := confirmRollback(ctx, out, stdin, phrase)
+		if err != nil {
+			return failed(err)
+		}
+		if !confirmed {
+			fmt.Fprintln(out, "Rollback cancelled.")
+			return nil
+		}
+	}
+
+	fmt.Fprintln(out, "\nStarting rollback...")
+
 	result, err := rollback.ExecuteRollback(ctx, opts)
+	record := newResultRecord("rollback", stack, rollbackVersion, result, err)
+	record.PreviousVersion = latest
+	writeRecordFile(record)
+	if runID != "" {
+		recordRun(record)
+	}
+	if jsonOutput {
+		if jsonErr := writeRecordJSON(os.Stdout, record); jsonErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write JSON result: %v\n", jsonErr)
+		}
+	}
 	if err != nil {
+		if errors.Is(err, rollback.ErrDriftExceeded) {
+			ghWarning("Rollback of stack %s aborted: live infrastructure has drifted", stack)
+			fmt.Fprintln(out, "\nThe stack no longer matches its recorded state. The previous state has been restored.")
+			fmt.Fprintln(out, "Re-run with --force to roll back anyway.")
+		}
+		if errors.Is(err, rollback.ErrStackBusy) {
+			fmt.Fprintln(out, "\nAnother update of the stack is running or holds its lock. Importing now would corrupt the state.")
+			fmt.Fprintln(out, "Wait for it to finish, or re-run with --ignore-busy if it is known to be dead.")
+		}
+		if errors.Is(err, rollback.ErrPendingOperations) {
+			fmt.Fprintln(out, "\nRun 'pulumi cancel' or 'pulumi refresh --clear-pending-creates' to resolve them,")
+			fmt.Fprintln(out, "or re-run with --force to roll back anyway.")
+		}
+		var rbErr *rollback.RollbackError
+		if errors.As(err, &rbErr) {
+			printPhaseSummary(out, rbErr.Phases)
+			switch {
+			case rbErr.Recovered && rbErr.Phase == rollback.PhaseVerify:
+				fmt.Fprintln(out, "\nThe imported state did not match the target version; the state from before the rollback has been restored.")
+			case rbErr.Recovered:
+				fmt.Fprintln(out, "\nThe rollback was interrupted; the state from before it has been restored.")
+				fmt.Fprintln(out, "Resources changed before the interruption may differ from that state: run 'pulumi refresh' to reconcile them.")
+			}
+			if rbErr.BackupFile != "" {
+				fmt.Fprintf(out, "\nThe state from before the rollback is saved in %s\n", rbErr.BackupFile)
+			}
+		}
+		if errors.Is(err, rollback.ErrEmptyCheckpoint) {
+			fmt.Fprintln(out, "\n⚠️  The target version has no resources: rolling back would DELETE ALL infrastructure in the stack.")
+			fmt.Fprintln(out, "Re-run with --allow-empty if that is really what you want.")
+		}
 		return fmt.Errorf("rollback failed: %w", err)
 	}
 
-	fmt.Println("\n✓", result.Message)
+	fmt.Fprintln(out, "\n✓", result.Message)
+	verb := "Rolled back"
+	switch result.Direction {
+	case history.DirectionForward:
+		verb = "Rolled forward"
+	case history.DirectionReapply:
+		verb = "Re-applied"
+	}
+	ghNotice("%s stack %s from version %d to version %d", verb, stack, latest, rollbackVersion)
+	setGitHubOutput("rolled-back-to", strconv.Itoa(rollbackVersion))
+	setGitHubOutput("previous-version", strconv.Itoa(latest))
+	setGitHubChangesOutput("resource-changes", result.ResourceChanges)
 
+	printPhaseSummary(out, result.Phases)
 	if len(result.ResourceChanges) > 0 {
-		fmt.Println("\nResource changes applied:")
-		for change, count := range result.ResourceChanges {
-			fmt.Printf("  %s: %d\n", change, count)
+		fmt.Fprintf(out, "Resource changes applied: %s\n", formatChanges(result.ResourceChanges))
+	}
+	if result.BackupFile != "" {
+		fmt.Fprintf(out, "State before the rollback: %s\n", result.BackupFile)
+	}
+	if result.CheckpointHash != "" {
+		fmt.Fprintf(out, "Imported checkpoint SHA-256: %s\n", result.CheckpointHash)
+	}
+
+	if len(result.Orphaned) > 0 {
+		fmt.Fprintln(out, "\nResources orphaned (still exist, no longer managed by the stack):")
+		for _, o := range result.Orphaned {
+			fmt.Fprintf(out, "  %s\n", o)
 		}
+		ghWarning("Rollback of stack %s orphaned %d resource(s)", stack, len(result.Orphaned))
 	}
 
 	return nil
 }
+
+// printPhaseSummary prints the status and duration of each phase of a rollback
+func printPhaseSummary(w io.Writer, phases []rollback.PhaseTiming) {
+	if len(phases) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PHASE\tSTATUS\tDURATION")
+	fmt.Fprintln(tw, "-----\t------\t--------")
+	for _, p := range phases {
+		duration := "-"
+		if p.Status != rollback.PhaseSkipped {
+			duration = p.Duration.Round(100 * time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Phase, p.Status, duration)
+	}
+	tw.Flush()
+}
+
+// skipsRefresh reports whether the rollback applies the target state
+// without reconciling it with live infrastructure first
+func skipsRefresh() bool {
+	return forceImport || skipRefresh
+}
+
+// checkRollbackPrompts fails before any work when a prompt of to would
+// wait on stdin forever. isTerminal reports whether stdin is a terminal. A
+// rollback that skips the refresh always asks for the confirmation phrase,
+// even with --yes.
+func checkRollbackPrompts(yes bool, isTerminal func() bool) error {
+	switch {
+	case interactive:
+		return requirePrompt(isTerminal, "--interactive chooses the resources at a prompt")
+	case skipsRefresh():
+		return requirePrompt(isTerminal, "--force-import and --skip-refresh always ask for the confirmation phrase to be typed")
+	case !yes:
+		return requirePrompt(isTerminal, confirmHint)
+	}
+	return nil
+}
+
+// rollbackPhrase returns the phrase to type to confirm a rollback: the
+// configured one, or the stack name when the refresh is skipped. An empty
+// phrase asks y/N.
+func rollbackPhrase(configured, stack string) string {
+	if configured == "" && skipsRefresh() {
+		return stack
+	}
+	return configured
+}
+
+// confirmRollback prompts for confirmation. When phrase is set it must be
+// typed exactly; otherwise "y" or "yes" confirms.
+func confirmRollback(ctx context.Context, out io.Writer, in io.Reader, phrase string) (bool, error) {
+	if phrase != "" {
+		fmt.Fprintf(out, "Type '%s' to proceed: ", phrase)
+	} else {
+		fmt.Fprint(out, "Do you want to proceed? [y/N]: ")
+	}
+
+	response, err := readLine(ctx, bufio.NewReader(in))
+	if err != nil {
+		return false, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	response = strings.TrimSpace(response)
+	if phrase != "" {
+		return response == phrase, nil
+	}
+	response = strings.ToLower(response)
+	return response == "y" || response == "yes", nil
+}
+
+// getInitiator returns who is performing the rollback, for the update's provenance
+func getInitiator() string {
+	if actor := os.Getenv("GITHUB_ACTOR"); actor != "" && isGitHubActions() {
+		return actor
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// isJSONOutput validates an --output value and reports whether it selects JSON
+func isJSONOutput(format string) (bool, error) {
+	switch format {
+	case "", "text":
+		return false, nil
+	case "json":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown output format %q (expected text or json)", format)
+	}
+}
+
+// findGitTagVersion returns the version deployed from a git ref, matching
+// update messages with the configured pattern
+func findGitTagVersion(ctx context.Context, projectPath, stack, ref string, selector history.StackSelector) (int, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return 0, err
+	}
+	pattern, err := cfg.GitRefRegexp()
+	if err != nil {
+		return 0, err
+	}
+
+	updates, err := history.GetStackHistoryWithSelector(ctx, projectPath, stack, selector)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stack history: %w", err)
+	}
+	return history.FindVersionByGitRefPattern(updates, ref, pattern)
+}
+
+// findVersionBefore resolves --before to the version of the newest update
+// that started at or before the given time
+func findVersionBefore(ctx context.Context, projectPath, stack, before string, selector history.StackSelector) (int, error) {
+	t, ok := history.ParseTimestamp(before)
+	if !ok {
+		return 0, fmt.Errorf("invalid --before time %q (expected RFC 3339, e.g. 2024-01-15T14:00:00Z)", before)
+	}
+
+	updates, err := history.GetStackHistoryWithSelector(ctx, projectPath, stack, selector)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stack history: %w", err)
+	}
+	return history.FindVersionBeforeTime(updates, t)
+}
+
+// selectRollbackTargets previews the rollback and lets the user choose the
+// changed resources to roll back
+func selectRollbackTargets(ctx context.Context, out io.Writer, in *bufio.Reader, opts rollback.RollbackOptions) ([]string, error) {
+	fmt.Fprintln(out, "Previewing rollback to find the changed resources...")
+	opts.DryRun = true
+	result, err := rollback.PreviewRollback(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("preview failed: %w", err)
+	}
+	if len(result.Steps) == 0 {
+		fmt.Fprintln(out, "The preview found no resources to change.")
+		return nil, nil
+	}
+	fmt.Fprintln(out)
+	return selectSteps(ctx, in, out, result.Steps)
+}
@@ -0,0 +1,149 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// setRefreshFlags sets --force-import and --skip-refresh for a test
+func setRefreshFlags(t *testing.T, force, skip bool) {
+	t.Helper()
+	oldForce, oldSkip := forceImport, skipRefresh
+	forceImport, skipRefresh = force, skip
+	t.Cleanup(func() { forceImport, skipRefresh = oldForce, oldSkip })
+}
+
+func TestRollbackPhrase(t *testing.T) {
+	tests := []struct {
+		name       string
+		force      bool
+		skip       bool
+		configured string
+		expected   string
+	}{
+		{name: "plain", expected: ""},
+		{name: "configured", configured: "roll back prod", expected: "roll back prod"},
+		{name: "force import", force: true, expected: "prod"},
+		{name: "skip refresh", skip: true, expected: "prod"},
+		{name: "skip refresh with configured phrase", skip: true, configured: "roll back prod", expected: "roll back prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRefreshFlags(t, tt.force, tt.skip)
+			if got := rollbackPhrase(tt.configured, "prod"); got != tt.expected {
+				t.Errorf("Expected phrase %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckRollbackPrompts(t *testing.T) {
+	tests := []struct {
+		name        string
+		yes         bool
+		skip        bool
+		terminal    bool
+		expectedErr string
+	}{
+		{name: "yes without a terminal", yes: true},
+		{name: "yes with a terminal", yes: true, terminal: true},
+		{name: "prompt with a terminal", terminal: true},
+		{name: "prompt without a terminal", expectedErr: "--yes"},
+		// --skip-refresh --yes still asks for the phrase, like --force-import
+		{name: "skip-refresh with yes without a terminal", yes: true, skip: true, expectedErr: "--skip-refresh"},
+		{name: "skip-refresh with yes with a terminal", yes: true, skip: true, terminal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRefreshFlags(t, false, tt.skip)
+			err := checkRollbackPrompts(tt.yes, func() bool { return tt.terminal })
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected an error mentioning %s, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestConfirmRollback_Phrase(t *testing.T) {
+	setRefreshFlags(t, false, true)
+	phrase := rollbackPhrase("", "prod")
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "y\n", expected: false},
+		{input: "prod\n", expected: true},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		confirmed, err := confirmRollback(context.Background(), &out, strings.NewReader(tt.input), phrase)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if confirmed != tt.expected {
+			t.Errorf("Expected %q to confirm: %v, got %v", strings.TrimSpace(tt.input), tt.expected, confirmed)
+		}
+		if !strings.Contains(out.String(), "Type 'prod'") {
+			t.Errorf("Expected the phrase to be asked for, got %q", out.String())
+		}
+	}
+}
+
+func TestRunRollback_JSONOnEarlyFailure(t *testing.T) {
+	oldOutput, oldParallel := rollbackOutput, refreshParallel
+	rollbackOutput, refreshParallel = "json", -1
+	t.Cleanup(func() { rollbackOutput, refreshParallel = oldOutput, oldParallel })
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	oldStdout := os.Stdout
+	os.Stdout = w
+	runErr := runRollback(toCmd, nil)
+	os.Stdout = oldStdout
+	w.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if runErr == nil {
+		t.Fatal("Expected an error for a negative --refresh-parallel")
+	}
+	var record resultRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("Expected a JSON result document, got %q: %v", data, err)
+	}
+	if record.Success || record.Error != runErr.Error() || record.Phase != "" {
+		t.Errorf("Expected a failed record with error %q and no phase, got %+v", runErr, record)
+	}
+}
+
+func TestRunRollback_NoUpRefreshNeedsSkippedRefresh(t *testing.T) {
+	setRefreshFlags(t, false, false)
+	noUpRefresh = true
+	t.Cleanup(func() { noUpRefresh = false })
+
+	err := runRollback(toCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--no-up-refresh requires") {
+		t.Errorf("Expected --no-up-refresh to require --force-import or --skip-refresh, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Roll back the latest deployment",
+	Long: `Roll the stack back to the version just before the latest one.
+
+This is 'to --version <n>' with n the second newest version in the history,
+and asks for the same confirmation.
+
+Examples:
+  pulumi-rollback undo --stack mystack
+
+  # Without confirmation prompt
+  pulumi-rollback undo --stack mystack --yes`,
+	RunE: runUndo,
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+	undoCmd.Flags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip confirmation prompt")
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	stack, err := getStackName()
+	if err != nil {
+		return err
+	}
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+
+	// History is newest first, so only the first two entries are needed
+	updates, err := history.GetRecentHistoryWithSelector(cmd.Context(), getProjectPath(), stack, 2, newStackSelector(pulumiCommand))
+	if err != nil {
+		return fmt.Errorf("failed to get stack history: %w", err)
+	}
+	if len(updates) < 2 {
+		return fmt.Errorf("nothing to undo: stack %s has no version before the latest", stack)
+	}
+
+	rollbackVersion = updates[1].Version
+	return runRollback(cmd, args)
+}
@@ -4,8 +4,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
 	"github.com/spf13/cobra"
 )
 
@@ -16,16 +19,49 @@ var (
 	BuildDate = "unknown"
 )
 
+var versionOutput string
+
+// versionInfo is the document written by version --output json
+type versionInfo struct {
+	Version      string                 `json:"version"`
+	GitCommit    string                 `json:"gitCommit"`
+	BuildDate    string                 `json:"buildDate"`
+	Capabilities rollback.CapabilitySet `json:"capabilities"`
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version information",
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Print the version information.
+
+With --output json the tool's capabilities are included, so scripts can check
+for a feature instead of parsing --help:
+
+  pulumi-rollback version -o json | jq '.capabilities.features'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOutput, err := isJSONOutput(versionOutput)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(versionInfo{
+				Version:      Version,
+				GitCommit:    GitCommit,
+				BuildDate:    BuildDate,
+				Capabilities: rollback.Capabilities(),
+			})
+		}
+
 		fmt.Printf("pulumi-rollback %s\n", Version)
 		fmt.Printf("  Git commit: %s\n", GitCommit)
 		fmt.Printf("  Build date: %s\n", BuildDate)
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().StringVarP(&versionOutput, "output", "o", "text", "Output format: text or json (json includes capabilities)")
 }
@@ -0,0 +1,151 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/watch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval     time.Duration
+	watchCooldown     time.Duration
+	watchAutoRollback bool
+	watchMaxRollbacks int
+	watchWebhook      string
+	watchOnlyNew      bool
+	watchStateFile    string
+)
+
+var watchStackCmd = &cobra.Command{
+	Use:   "watch-stack",
+	Short: "Watch a stack and roll back failed deployments",
+	Long: `Poll the stack's history and react when the latest deployment fails.
+
+The rollback to the last succeeded version is previewed and reported. With
+--auto-rollback it is also executed. Each failed version is handled once,
+automatic rollbacks are spaced by --cooldown and capped by --max-rollbacks,
+and a failed rollback is never rolled back again.
+
+With --only-new-failures the last handled failed version is saved to a
+state file (--state-file, by default .pulumi-rollback-watch-<stack>.json in
+the project directory), so a restarted watcher stays quiet until a newer
+version fails.
+
+Events are logged and, with --webhook, POSTed as JSON. The watcher stops
+cleanly on SIGINT or SIGTERM.
+
+Examples:
+  # Report failures and the rollback that would fix them
+  pulumi-rollback watch-stack --stack app --interval 60s --webhook https://hooks.example.com/rollback
+
+  # Roll back failed deployments automatically
+  pulumi-rollback watch-stack --stack app --auto-rollback --cooldown 30m
+
+  # Act once per failed version, even across restarts
+  pulumi-rollback watch-stack --stack app --auto-rollback --only-new-failures`,
+	RunE: runWatchStack,
+}
+
+func init() {
+	rootCmd.AddCommand(watchStackCmd)
+	watchStackCmd.Flags().DurationVar(&watchInterval, "interval", time.Minute, "Time between history polls")
+	watchStackCmd.Flags().DurationVar(&watchCooldown, "cooldown", 15*time.Minute, "Minimum time between automatic rollbacks")
+	watchStackCmd.Flags().BoolVar(&watchAutoRollback, "auto-rollback", false, "Roll back failed deployments instead of only reporting them")
+	watchStackCmd.Flags().IntVar(&watchMaxRollbacks, "max-rollbacks", 3, "Stop rolling back after this many automatic rollbacks (0 = no limit)")
+	watchStackCmd.Flags().StringVar(&watchWebhook, "webhook", "", "POST events as JSON to this URL")
+	watchStackCmd.Flags().BoolVar(&watchOnlyNew, "only-new-failures", false, "Remember handled failures in a state file and only act on newer ones after a restart")
+	watchStackCmd.Flags().StringVar(&watchStateFile, "state-file", "", "State file for --only-new-failures (default: .pulumi-rollback-watch-<stack>.json in the project directory)")
+}
+
+func runWatchStack(cmd *cobra.Command, args []string) error {
+	if err := requireProjectDir("watch-stack"); err != nil {
+		return err
+	}
+	if watchMaxRollbacks < 0 {
+		return fmt.Errorf("--max-rollbacks must not be negative, got %d", watchMaxRollbacks)
+	}
+
+	stack, err := getStackName()
+	if err != nil {
+		return err
+	}
+
+	pulumiCommand, err := getPulumiCommand()
+	if err != nil {
+		return err
+	}
+
+	rollbackOptions := func(target int) rollback.RollbackOptions {
+		return rollback.RollbackOptions{
+			ProjectPath:   getProjectPath(),
+			StackName:     stack,
+			TargetVersion: target,
+			Verbose:       isVerbose(),
+			SkipHashCheck: skipHashCheck,
+			Output:        os.Stdout,
+			Operator:      newStackOperator(pulumiCommand),
+			Logger:        newLogger(os.Stdout),
+
+			ToolVersion:   Version,
+			Initiator:     getInitiator(),
+			ProvenanceLog: provenanceLogPath(),
+			BackupDir:     getBackupDir(),
+		}
+	}
+
+	opts := watch.Options{
+		ProjectPath:  getProjectPath(),
+		StackName:    stack,
+		Selector:     newStackSelector(pulumiCommand),
+		Interval:     watchInterval,
+		Cooldown:     watchCooldown,
+		AutoRollback: watchAutoRollback,
+		MaxRollbacks: watchMaxRollbacks,
+		Preview: func(ctx context.Context, target int) (*rollback.RollbackResult, error) {
+			return rollback.PreviewRollback(ctx, rollbackOptions(target))
+		},
+		Rollback: func(ctx context.Context, target int) (*rollback.RollbackResult, error) {
+			return rollback.ExecuteRollback(ctx, rollbackOptions(target))
+		},
+		Logger: newLogger(os.Stdout),
+	}
+	if watchOnlyNew {
+		opts.StateFile = watchStateFile
+		if opts.StateFile == "" {
+			opts.StateFile = filepath.Join(getProjectPath(), watch.StateFileName(stack))
+		}
+	} else if watchStateFile != "" {
+		return fmt.Errorf("--state-file requires --only-new-failures")
+	}
+	if watchWebhook != "" {
+		opts.Notify = watch.WebhookNotifier(watchWebhook, nil)
+	}
+
+	watcher, err := watch.New(opts)
+	if err != nil {
+		return err
+	}
+
+	// The root context is cancelled on SIGINT and SIGTERM
+	ctx := cmd.Context()
+
+	mode := "report only"
+	if watchAutoRollback {
+		mode = "auto-rollback"
+	}
+	fmt.Printf("Watching stack '%s' every %s (%s). Press Ctrl+C to stop.\n", stack, watchInterval, mode)
+	if err := watcher.Run(ctx); err != nil {
+		return err
+	}
+	fmt.Println("Stopped watching.")
+	return nil
+}
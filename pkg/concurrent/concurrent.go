@@ -0,0 +1,69 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+// Package concurrent provides the bounded worker pool used by every
+// operation that fans out calls to a Pulumi backend.
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultLimit is the default number of concurrent backend calls
+const DefaultLimit = 4
+
+// MaxLimit caps the concurrency to avoid overwhelming rate-limited backends
+const MaxLimit = 64
+
+// ValidateLimit checks that a concurrency limit is within the supported range
+func ValidateLimit(limit int) error {
+	if limit < 1 || limit > MaxLimit {
+		return fmt.Errorf("concurrency limit must be between 1 and %d, got %d", MaxLimit, limit)
+	}
+	return nil
+}
+
+// ForEach calls fn for each index in [0, n) with at most limit calls in
+// flight. It returns the error from each call, indexed like the input.
+// Calls that have not started when ctx is cancelled are skipped and
+// report the context error.
+func ForEach(ctx context.Context, limit, n int, fn func(ctx context.Context, i int) error) []error {
+	if limit < 1 {
+		limit = 1
+	}
+
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	skipRest := func(i int) []error {
+		for j := i; j < n; j++ {
+			errs[j] = ctx.Err()
+		}
+		wg.Wait()
+		return errs
+	}
+	for i := 0; i < n; i++ {
+		// A free slot must not win over a cancellation that already happened
+		if ctx.Err() != nil {
+			return skipRest(i)
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return skipRest(i)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(ctx, i)
+		}(i)
+	}
+
+	wg.Wait()
+	return errs
+}
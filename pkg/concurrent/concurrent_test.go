@@ -0,0 +1,99 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateLimit(t *testing.T) {
+	tests := []struct {
+		limit       int
+		expectError bool
+	}{
+		{0, true},
+		{-1, true},
+		{1, false},
+		{DefaultLimit, false},
+		{MaxLimit, false},
+		{MaxLimit + 1, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateLimit(tt.limit)
+		if tt.expectError && err == nil {
+			t.Errorf("ValidateLimit(%d): expected error, got nil", tt.limit)
+		}
+		if !tt.expectError && err != nil {
+			t.Errorf("ValidateLimit(%d): unexpected error: %v", tt.limit, err)
+		}
+	}
+}
+
+func TestForEach_RespectsLimit(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	errs := ForEach(context.Background(), 3, 20, func(ctx context.Context, i int) error {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil
+	})
+
+	if len(errs) != 20 {
+		t.Fatalf("Expected 20 results, got %d", len(errs))
+	}
+	if maxInFlight > 3 {
+		t.Errorf("Expected at most 3 calls in flight, got %d", maxInFlight)
+	}
+}
+
+func TestForEach_CollectsErrors(t *testing.T) {
+	errs := ForEach(context.Background(), 2, 4, func(ctx context.Context, i int) error {
+		if i%2 == 1 {
+			return errors.New("odd")
+		}
+		return nil
+	})
+
+	for i, err := range errs {
+		if (i%2 == 1) != (err != nil) {
+			t.Errorf("Index %d: unexpected error state %v", i, err)
+		}
+	}
+}
+
+func TestForEach_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls int32
+	errs := ForEach(ctx, 1, 5, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+
+	cancelled := 0
+	for _, err := range errs {
+		if errors.Is(err, context.Canceled) {
+			cancelled++
+		}
+	}
+	if int(calls)+cancelled != 5 {
+		t.Errorf("Expected every index to run or be cancelled, got %d calls and %d cancelled", calls, cancelled)
+	}
+	if cancelled == 0 {
+		t.Error("Expected at least one index to be cancelled")
+	}
+}
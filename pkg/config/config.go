@@ -0,0 +1,99 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+// Package config loads the optional pulumi-rollback configuration file.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+)
+
+// DefaultName is the file name of the configuration file within a project
+const DefaultName = ".pulumi-rollback.json"
+
+// Config is the contents of the configuration file
+type Config struct {
+	// Confirmations require typing a phrase before rolling back matching
+	// stacks. The first matching rule applies.
+	Confirmations []ConfirmationRule `json:"confirmations,omitempty"`
+
+	// GitRefPattern extracts the git ref from update messages for
+	// "to --git-tag". It must have exactly one capture group. Defaults to
+	// history.DefaultGitRefPattern.
+	GitRefPattern string `json:"gitRefPattern,omitempty"`
+}
+
+// ConfirmationRule requires Phrase to be typed before rolling back a stack
+// whose name matches the Stack glob pattern (e.g. "*prod*")
+type ConfirmationRule struct {
+	Stack  string `json:"stack"`
+	Phrase string `json:"phrase"`
+}
+
+// Load reads a configuration file. A missing file yields an empty config.
+func Load(file string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	for i, rule := range cfg.Confirmations {
+		if _, err := path.Match(rule.Stack, ""); err != nil || rule.Stack == "" {
+			return nil, fmt.Errorf("invalid stack pattern %q in confirmation %d of %s", rule.Stack, i+1, file)
+		}
+		if strings.TrimSpace(rule.Phrase) == "" {
+			return nil, fmt.Errorf("empty phrase in confirmation %d of %s", i+1, file)
+		}
+	}
+	if cfg.GitRefPattern != "" {
+		if _, err := history.CompileGitRefPattern(cfg.GitRefPattern); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return cfg, nil
+}
+
+// GitRefRegexp returns the compiled pattern that extracts git refs from
+// update messages
+func (c *Config) GitRefRegexp() (*regexp.Regexp, error) {
+	if c.GitRefPattern == "" {
+		return history.CompileGitRefPattern(history.DefaultGitRefPattern)
+	}
+	return history.CompileGitRefPattern(c.GitRefPattern)
+}
+
+// ConfirmationPhrase returns the phrase required to roll back a stack, or ""
+// if a plain confirmation is enough. Patterns are matched against the full
+// stack name and, for fully qualified names like "org/project/stack", against
+// the stack name alone.
+func (c *Config) ConfirmationPhrase(stack string) string {
+	short := stack
+	if i := strings.LastIndex(stack, "/"); i >= 0 {
+		short = stack[i+1:]
+	}
+
+	for _, rule := range c.Confirmations {
+		if matched, _ := path.Match(rule.Stack, stack); matched {
+			return rule.Phrase
+		}
+		if matched, _ := path.Match(rule.Stack, short); matched {
+			return rule.Phrase
+		}
+	}
+	return ""
+}
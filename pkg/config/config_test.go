@@ -0,0 +1,84 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMissing(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), DefaultName))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if phrase := cfg.ConfirmationPhrase("prod"); phrase != "" {
+		t.Errorf("Expected no phrase, got %q", phrase)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"malformed", `{"confirmations":`},
+		{"bad pattern", `{"confirmations":[{"stack":"[prod","phrase":"x"}]}`},
+		{"empty pattern", `{"confirmations":[{"stack":"","phrase":"x"}]}`},
+		{"empty phrase", `{"confirmations":[{"stack":"*prod*","phrase":"  "}]}`},
+		{"git ref pattern without group", `{"gitRefPattern":"deploy \\S+"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), DefaultName)
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(file); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestConfirmationPhrase(t *testing.T) {
+	file := filepath.Join(t.TempDir(), DefaultName)
+	content := `{
+		"confirmations": [
+			{"stack": "*prod*", "phrase": "rollback production"},
+			{"stack": "staging", "phrase": "rollback staging"},
+			{"stack": "acme/*/qa", "phrase": "rollback acme qa"}
+		]
+	}`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		stack    string
+		expected string
+	}{
+		{"prod", "rollback production"},
+		{"eu-prod-1", "rollback production"},
+		{"acme/app/prod", "rollback production"},
+		{"staging", "rollback staging"},
+		{"acme/app/qa", "rollback acme qa"},
+		{"dev", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stack, func(t *testing.T) {
+			if got := cfg.ConfirmationPhrase(tt.stack); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
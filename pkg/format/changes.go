@@ -0,0 +1,105 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeStyle selects how resource change counts are written
+type ChangeStyle string
+
+const (
+	// ChangeStyleSymbolic writes compact counts, e.g. "+3 ~2 -1"
+	ChangeStyleSymbolic ChangeStyle = "symbolic"
+	// ChangeStyleVerbose writes counts in words, e.g. "3 created, 2 updated"
+	ChangeStyleVerbose ChangeStyle = "verbose"
+)
+
+// ParseChangeStyle validates a change style, defaulting to symbolic
+func ParseChangeStyle(s string) (ChangeStyle, error) {
+	switch ChangeStyle(s) {
+	case "", ChangeStyleSymbolic:
+		return ChangeStyleSymbolic, nil
+	case ChangeStyleVerbose:
+		return ChangeStyleVerbose, nil
+	default:
+		return "", fmt.Errorf("unknown change style %q (expected symbolic or verbose)", s)
+	}
+}
+
+// changeOp is an operation shown in change counts
+type changeOp struct {
+	op     string
+	symbol string
+	verb   string
+	color  Color
+}
+
+// changeOps lists the operations in display order. Unchanged resources are
+// only shown when nothing else changed. The extra steps of a replacement,
+// such as create-replacement, are counted by replace.
+var changeOps = []changeOp{
+	{"create", "+", "created", Green},
+	{"update", "~", "updated", Yellow},
+	{"delete", "-", "deleted", Red},
+	{"replace", "+-", "replaced", Red},
+	{"read", ">", "read", NoColor},
+	{"import", "<=", "imported", NoColor},
+}
+
+var sameOp = changeOp{"same", "=", "unchanged", NoColor}
+
+// Changes formats resource change counts, or "-" when there are none
+func Changes(changes map[string]int, style ChangeStyle) string {
+	return formatChanges(changes, style, false)
+}
+
+// ColoredChanges is Changes with each count colored by its operation:
+// creates green, updates yellow, deletes and replacements red
+func ColoredChanges(changes map[string]int, style ChangeStyle) string {
+	return formatChanges(changes, style, true)
+}
+
+func formatChanges(changes map[string]int, style ChangeStyle, color bool) string {
+	var parts []string
+	for _, op := range changeOps {
+		if n := changes[op.op]; n > 0 {
+			part := op.format(n, style)
+			if color {
+				part = Colorize(part, op.color)
+			}
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		if n := changes[sameOp.op]; n > 0 {
+			return sameOp.format(n, style)
+		}
+		return "-"
+	}
+
+	if style == ChangeStyleVerbose {
+		return strings.Join(parts, ", ")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (o changeOp) format(n int, style ChangeStyle) string {
+	if style == ChangeStyleVerbose {
+		return fmt.Sprintf("%d %s", n, o.verb)
+	}
+	return fmt.Sprintf("%s%d", o.symbol, n)
+}
+
+// ChangesLegend explains the symbols of the symbolic style
+func ChangesLegend() string {
+	parts := make([]string, 0, len(changeOps)+1)
+	for _, op := range changeOps {
+		parts = append(parts, fmt.Sprintf("%s %s", op.symbol, op.verb))
+	}
+	parts = append(parts, fmt.Sprintf("%s %s", sameOp.symbol, sameOp.verb))
+	return "Changes: " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package format
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		changes  map[string]int
+		symbolic string
+		verbose  string
+	}{
+		{"none", nil, "-", "-"},
+		{"only zero counts", map[string]int{"create": 0}, "-", "-"},
+		{"unchanged only", map[string]int{"same": 5}, "=5", "5 unchanged"},
+		{"unchanged hidden", map[string]int{"same": 5, "update": 1}, "~1", "1 updated"},
+		{
+			"every op",
+			map[string]int{"import": 1, "read": 2, "replace": 1, "delete": 1, "update": 2, "create": 3, "create-replacement": 1},
+			"+3 ~2 -1 +-1 >2 <=1",
+			"3 created, 2 updated, 1 deleted, 1 replaced, 2 read, 1 imported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Changes(tt.changes, ChangeStyleSymbolic); got != tt.symbolic {
+				t.Errorf("Expected %q, got %q", tt.symbolic, got)
+			}
+			if got := Changes(tt.changes, ChangeStyleVerbose); got != tt.verbose {
+				t.Errorf("Expected %q, got %q", tt.verbose, got)
+			}
+		})
+	}
+}
+
+func TestParseChangeStyle(t *testing.T) {
+	if style, err := ParseChangeStyle(""); err != nil || style != ChangeStyleSymbolic {
+		t.Errorf("Expected symbolic by default, got %q (%v)", style, err)
+	}
+	if style, err := ParseChangeStyle("verbose"); err != nil || style != ChangeStyleVerbose {
+		t.Errorf("Expected verbose, got %q (%v)", style, err)
+	}
+	if _, err := ParseChangeStyle("emoji"); err == nil {
+		t.Error("Expected error for unknown style")
+	}
+}
+
+func TestChangesLegend(t *testing.T) {
+	legend := ChangesLegend()
+	for _, expected := range []string{"+ created", "~ updated", "- deleted", "+- replaced", "> read", "<= imported", "= unchanged"} {
+		if !strings.Contains(legend, expected) {
+			t.Errorf("Expected the legend to explain %q, got %q", expected, legend)
+		}
+	}
+}
+
+func TestColoredChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		changes  map[string]int
+		style    ChangeStyle
+		expected string
+	}{
+		{"none", nil, ChangeStyleSymbolic, "-"},
+		{"unchanged only", map[string]int{"same": 5}, ChangeStyleSymbolic, "=5"},
+		{
+			"symbolic",
+			map[string]int{"create": 3, "update": 2, "delete": 1, "read": 1},
+			ChangeStyleSymbolic,
+			"\x1b[32m+3\x1b[0m \x1b[33m~2\x1b[0m \x1b[31m-1\x1b[0m >1",
+		},
+		{"verbose", map[string]int{"replace": 1}, ChangeStyleVerbose, "\x1b[31m1 replaced\x1b[0m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ColoredChanges(tt.changes, tt.style); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestColorize(t *testing.T) {
+	if got := Colorize("ok", Green); got != "\x1b[32mok\x1b[0m" {
+		t.Errorf("Expected green text, got %q", got)
+	}
+	if got := Colorize("ok", NoColor); got != "ok" {
+		t.Errorf("Expected plain text without a color, got %q", got)
+	}
+}
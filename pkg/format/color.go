@@ -0,0 +1,44 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package format
+
+// Color is an ANSI foreground color
+type Color string
+
+const (
+	// NoColor leaves text as it is
+	NoColor Color = ""
+	Red     Color = "31"
+	Green   Color = "32"
+	Yellow  Color = "33"
+)
+
+// Colorize wraps s in the ANSI escape codes of c
+func Colorize(s string, c Color) string {
+	if c == NoColor || s == "" {
+		return s
+	}
+	return "\x1b[" + string(c) + "m" + s + "\x1b[0m"
+}
+
+// VisibleWidth returns the number of runes s shows on a terminal, not
+// counting ANSI escape sequences such as those added by Colorize
+func VisibleWidth(s string) int {
+	width := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case inEscape:
+			// A CSI sequence ends with a letter
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEscape = false
+			}
+		case r == '\x1b':
+			inEscape = true
+		default:
+			width++
+		}
+	}
+	return width
+}
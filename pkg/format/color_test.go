@@ -0,0 +1,28 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package format
+
+import "testing"
+
+func TestVisibleWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{"empty", "", 0},
+		{"plain", "success", 7},
+		{"multibyte", "✓ success", 9},
+		{"colored", Colorize("✓ success", Green), 9},
+		{"several colors", Colorize("+1", Green) + " " + Colorize("-2", Red), 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VisibleWidth(tt.input); got != tt.expected {
+				t.Errorf("VisibleWidth(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+// Package format prepares checkpoint values, change counts and messages for
+// display. Every output path that may show resource properties, engine
+// output or errors derived from a checkpoint goes through a Redactor, so
+// secrets are masked the same way everywhere.
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/logging"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/sig"
+)
+
+// Mask replaces secret values in output
+const Mask = "[secret]"
+
+// minSecretLength is the length below which plaintext secrets are not masked
+// inside free text, where masking e.g. "1" or "on" would garble the output.
+// Secret values in properties are masked regardless of their length.
+const minSecretLength = 4
+
+// Redactor masks the secrets of one or more deployments. A nil Redactor
+// masks secret values in properties but knows no plaintext.
+type Redactor struct {
+	// secrets holds the known plaintext secrets, longest first so a secret
+	// containing another is masked whole
+	secrets []string
+}
+
+// NewRedactor returns a redactor for the secrets of the given deployments.
+// Only secrets stored as plaintext, e.g. after re-encryption, can be masked
+// in free text; encrypted secrets are masked as property values.
+func NewRedactor(deployments ...apitype.UntypedDeployment) (*Redactor, error) {
+	r := &Redactor{}
+	for _, deployment := range deployments {
+		if err := r.Add(deployment); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add records the plaintext secrets of a deployment
+func (r *Redactor) Add(deployment apitype.UntypedDeployment) error {
+	if len(deployment.Deployment) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(deployment.Deployment))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("failed to parse deployment: %w", err)
+	}
+
+	seen := make(map[string]bool, len(r.secrets))
+	for _, s := range r.secrets {
+		seen[s] = true
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if isSecret(v) {
+				for _, s := range plaintextValues(v) {
+					if len(s) >= minSecretLength && !seen[s] {
+						seen[s] = true
+						r.secrets = append(r.secrets, s)
+					}
+				}
+				return
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+
+	r.sort()
+	return nil
+}
+
+// AddPlaintext records plaintext secrets that do not come from a
+// deployment, such as secret config values
+func (r *Redactor) AddPlaintext(values ...string) {
+	for _, s := range values {
+		if len(s) >= minSecretLength && !slices.Contains(r.secrets, s) {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	r.sort()
+}
+
+// sort orders the secrets longest first
+func (r *Redactor) sort() {
+	sort.SliceStable(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// isSecret reports whether a decoded JSON object is a Pulumi secret
+func isSecret(v map[string]interface{}) bool {
+	return v[sig.Key] == sig.Secret
+}
+
+// plaintextValues returns the strings a plaintext secret may be displayed
+// as: the JSON encoded value and, for strings, the bare value
+func plaintextValues(v map[string]interface{}) []string {
+	plaintext, ok := v["plaintext"].(string)
+	if !ok {
+		return nil
+	}
+	values := []string{plaintext}
+
+	var decoded string
+	if err := json.Unmarshal([]byte(plaintext), &decoded); err == nil {
+		values = append(values, decoded)
+	}
+	return values
+}
+
+// String masks every known secret in s
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	return s
+}
+
+// Value returns a copy of a decoded JSON value, such as a resource's inputs
+// or outputs, with every secret replaced by Mask and known secrets masked
+// in strings
+func (r *Redactor) Value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if isSecret(v) {
+			return Mask
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			out[k] = r.Value(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = r.Value(child)
+		}
+		return out
+	case string:
+		return r.String(v)
+	default:
+		return v
+	}
+}
+
+// Properties returns a copy of a resource's property map with its secrets
+// masked
+func (r *Redactor) Properties(props map[string]interface{}) map[string]interface{} {
+	if props == nil {
+		return nil
+	}
+	return r.Value(props).(map[string]interface{})
+}
+
+// Error returns err with every known secret masked in its message. The
+// result still matches err's chain with errors.Is and errors.As.
+func (r *Redactor) Error(err error) error {
+	if err == nil || r == nil || len(r.secrets) == 0 {
+		return err
+	}
+	msg := err.Error()
+	if redacted := r.String(msg); redacted != msg {
+		return &redactedError{msg: redacted, err: err}
+	}
+	return err
+}
+
+// redactedError is an error whose message has been redacted
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// Writer returns a writer that masks known secrets before writing to w.
+// Each write is redacted on its own, so callers should write whole lines.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	if r == nil || len(r.secrets) == 0 {
+		return w
+	}
+	return &redactingWriter{r: r, w: w}
+}
+
+type redactingWriter struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Logger returns a logger that masks known secrets before logging to l
+func (r *Redactor) Logger(l logging.Logger) logging.Logger {
+	if r == nil || len(r.secrets) == 0 {
+		return l
+	}
+	return &redactingLogger{r: r, l: l}
+}
+
+type redactingLogger struct {
+	r *Redactor
+	l logging.Logger
+}
+
+func (l *redactingLogger) Debugf(format string, args ...interface{}) {
+	l.l.Debugf("%s", l.r.String(fmt.Sprintf(format, args...)))
+}
+
+func (l *redactingLogger) Infof(format string, args ...interface{}) {
+	l.l.Infof("%s", l.r.String(fmt.Sprintf(format, args...)))
+}
+
+func (l *redactingLogger) Warnf(format string, args ...interface{}) {
+	l.l.Warnf("%s", l.r.String(fmt.Sprintf(format, args...)))
+}
+
+func (l *redactingLogger) Errorf(format string, args ...interface{}) {
+	l.l.Errorf("%s", l.r.String(fmt.Sprintf(format, args...)))
+}
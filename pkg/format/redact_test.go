@@ -0,0 +1,139 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/logging"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+const secretDeployment = `{
+	"resources": [{
+		"urn": "urn:pulumi:dev::proj::aws:rds/instance:Instance::db",
+		"inputs": {
+			"password": {"4dabf18193072939515e22adb298388d": "1b47061264138c4ac30d75fd1eb44270", "plaintext": "\"hunter2-long\""},
+			"pin": {"4dabf18193072939515e22adb298388d": "1b47061264138c4ac30d75fd1eb44270", "plaintext": "\"42\""},
+			"apiKey": {"4dabf18193072939515e22adb298388d": "1b47061264138c4ac30d75fd1eb44270", "ciphertext": "AAABAJ"},
+			"port": 5432
+		}
+	}]
+}`
+
+func newTestRedactor(t *testing.T) *Redactor {
+	t.Helper()
+	r, err := NewRedactor(apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(secretDeployment)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return r
+}
+
+func TestRedactorString(t *testing.T) {
+	r := newTestRedactor(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"password is hunter2-long", "password is [secret]"},
+		{`{"password":"hunter2-long"}`, `{"password":[secret]}`},
+		{"port 42 is too short to mask", "port 42 is too short to mask"},
+		{"nothing secret", "nothing secret"},
+	}
+
+	for _, tt := range tests {
+		if got := r.String(tt.input); got != tt.expected {
+			t.Errorf("String(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	var nilRedactor *Redactor
+	if got := nilRedactor.String("hunter2-long"); got != "hunter2-long" {
+		t.Errorf("Expected a nil redactor to pass strings through, got %q", got)
+	}
+}
+
+func TestRedactorAddPlaintext(t *testing.T) {
+	r := newTestRedactor(t)
+	r.AddPlaintext("db-token", "hunter2-long", "on")
+
+	if got := r.String("db-token hunter2-long on"); got != "[secret] [secret] on" {
+		t.Errorf("Expected both secrets masked and short values kept, got %q", got)
+	}
+}
+
+func TestRedactorProperties(t *testing.T) {
+	r := newTestRedactor(t)
+
+	var doc struct {
+		Resources []apitype.ResourceV3 `json:"resources"`
+	}
+	if err := json.Unmarshal([]byte(secretDeployment), &doc); err != nil {
+		t.Fatal(err)
+	}
+	inputs := doc.Resources[0].Inputs
+	inputs["note"] = "rotated from hunter2-long"
+
+	props := r.Properties(inputs)
+	for _, key := range []string{"password", "pin", "apiKey"} {
+		if props[key] != Mask {
+			t.Errorf("Expected %s to be masked, got %v", key, props[key])
+		}
+	}
+	if props["port"] != float64(5432) {
+		t.Errorf("Expected port to be kept, got %v", props["port"])
+	}
+	if props["note"] != "rotated from [secret]" {
+		t.Errorf("Expected the secret to be masked in note, got %v", props["note"])
+	}
+	if _, ok := inputs["password"].(map[string]interface{}); !ok {
+		t.Error("Expected the original properties to be unchanged")
+	}
+}
+
+func TestRedactorError(t *testing.T) {
+	r := newTestRedactor(t)
+	sentinel := errors.New("import failed")
+
+	err := r.Error(errors.Join(sentinel, errors.New("bad value hunter2-long")))
+	if strings.Contains(err.Error(), "hunter2-long") {
+		t.Errorf("Expected the secret to be masked, got %q", err)
+	}
+	if !errors.Is(err, sentinel) {
+		t.Error("Expected the redacted error to wrap the original")
+	}
+	if r.Error(nil) != nil {
+		t.Error("Expected nil for a nil error")
+	}
+}
+
+func TestRedactorWriterAndLogger(t *testing.T) {
+	r := newTestRedactor(t)
+
+	var buf bytes.Buffer
+	w := r.Writer(&buf)
+	if _, err := w.Write([]byte("value: hunter2-long\n")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r.Logger(logging.New(&buf, logging.LevelDebug)).Debugf("decrypted %s", "hunter2-long")
+
+	if strings.Contains(buf.String(), "hunter2-long") {
+		t.Errorf("Expected secrets to be masked, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "Debug: decrypted [secret]") {
+		t.Errorf("Expected the log prefix to be kept, got %q", buf.String())
+	}
+}
+
+func TestNewRedactor_InvalidDeployment(t *testing.T) {
+	if _, err := NewRedactor(apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{invalid}`)}); err == nil {
+		t.Error("Expected error for invalid deployment")
+	}
+}
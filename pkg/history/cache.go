@@ -0,0 +1,131 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// CachingSelector wraps a StackSelector so that each stack is selected once
+// and each page of its history is fetched from the backend once. A page
+// that lies within a larger page already fetched, or within the whole
+// history, is served from it. It is meant for a single command, which should
+// see one consistent history, not for polling. Failures are not cached.
+type CachingSelector struct {
+	selector StackSelector
+
+	mu     sync.Mutex
+	stacks map[stackKey]*cachedStack
+}
+
+type stackKey struct {
+	name, projectPath string
+}
+
+// NewCachingSelector returns a CachingSelector that selects stacks with selector
+func NewCachingSelector(selector StackSelector) *CachingSelector {
+	return &CachingSelector{selector: selector, stacks: make(map[stackKey]*cachedStack)}
+}
+
+// SelectStack selects a stack, reusing an earlier selection of the same stack
+func (c *CachingSelector) SelectStack(ctx context.Context, stackName, projectPath string) (Stack, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := stackKey{name: stackName, projectPath: projectPath}
+	if stack, ok := c.stacks[key]; ok {
+		return stack, nil
+	}
+	stack, err := c.selector.SelectStack(ctx, stackName, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	cached := &cachedStack{stack: stack, pages: make(map[pageKey][]auto.UpdateSummary)}
+	c.stacks[key] = cached
+	return cached, nil
+}
+
+type pageKey struct {
+	size, page int
+}
+
+// cachedStack memoizes the history pages of a stack
+type cachedStack struct {
+	stack Stack
+
+	mu    sync.Mutex
+	pages map[pageKey][]auto.UpdateSummary
+}
+
+// History returns a page of the stack's history, fetching it only when no
+// cached page covers it
+func (s *cachedStack) History(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+	key := normalizePage(pageSize, page)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.lookup(key); ok {
+		return cached, nil
+	}
+
+	history, err := s.stack.History(ctx, pageSize, page)
+	if err != nil {
+		return nil, err
+	}
+	s.pages[key] = history
+	return append([]auto.UpdateSummary(nil), history...), nil
+}
+
+// normalizePage maps the page arguments of History to a cache key. A page
+// size of zero or less means the whole history; pages start at 1.
+func normalizePage(pageSize, page int) pageKey {
+	if pageSize <= 0 {
+		return pageKey{}
+	}
+	if page < 1 {
+		page = 1
+	}
+	return pageKey{size: pageSize, page: page}
+}
+
+// lookup returns the updates of a page from a cached page that covers it
+func (s *cachedStack) lookup(want pageKey) ([]auto.UpdateSummary, bool) {
+	if cached, ok := s.pages[want]; ok {
+		return append([]auto.UpdateSummary(nil), cached...), true
+	}
+
+	wantStart, wantEnd := 0, -1
+	if want.size > 0 {
+		wantStart, wantEnd = (want.page-1)*want.size, want.page*want.size
+	}
+	for key, cached := range s.pages {
+		// A backend that ignores the page size returns pages that cannot be
+		// sliced reliably
+		if key.size > 0 && len(cached) > key.size {
+			continue
+		}
+		start := 0
+		if key.size > 0 {
+			start = (key.page - 1) * key.size
+		}
+		// The page is the end of the history when it is short
+		complete := key.size == 0 || len(cached) < key.size
+		if wantStart < start || (!complete && (wantEnd < 0 || wantEnd > start+key.size)) {
+			continue
+		}
+
+		from, to := wantStart-start, len(cached)
+		if wantEnd >= 0 && wantEnd-start < to {
+			to = wantEnd - start
+		}
+		if from > to {
+			from = to
+		}
+		return append([]auto.UpdateSummary(nil), cached[from:to]...), true
+	}
+	return nil, false
+}
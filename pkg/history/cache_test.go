@@ -0,0 +1,154 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// pagedStack serves a history of the given length, newest first, a page at
+// a time, and counts the calls
+type pagedStack struct {
+	length int
+	calls  int
+}
+
+func (p *pagedStack) History(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+	p.calls++
+	start, end := 0, p.length
+	if pageSize > 0 {
+		start, end = (page-1)*pageSize, page*pageSize
+	}
+	var updates []auto.UpdateSummary
+	for i := start; i < end && i < p.length; i++ {
+		updates = append(updates, auto.UpdateSummary{Version: p.length - i})
+	}
+	return updates, nil
+}
+
+func versions(updates []auto.UpdateSummary) []int {
+	out := make([]int, len(updates))
+	for i, u := range updates {
+		out[i] = u.Version
+	}
+	return out
+}
+
+func TestCachingSelector_History(t *testing.T) {
+	tests := []struct {
+		name     string
+		fetched  [][2]int // page size and page, fetched first
+		request  [2]int
+		expected []int
+		calls    int
+	}{
+		{name: "same page", fetched: [][2]int{{10, 1}}, request: [2]int{10, 1}, expected: []int{12, 11, 10, 9, 8, 7, 6, 5, 4, 3}, calls: 1},
+		{name: "latest from a larger page", fetched: [][2]int{{10, 1}}, request: [2]int{1, 1}, expected: []int{12}, calls: 1},
+		{name: "page from the whole history", fetched: [][2]int{{0, 0}}, request: [2]int{5, 2}, expected: []int{7, 6, 5, 4, 3}, calls: 1},
+		{name: "whole history from a short page", fetched: [][2]int{{20, 1}}, request: [2]int{0, 0}, expected: []int{12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, calls: 1},
+		{name: "beyond a short page", fetched: [][2]int{{20, 1}}, request: [2]int{5, 4}, expected: []int{}, calls: 1},
+		{name: "page outside a full page", fetched: [][2]int{{5, 1}}, request: [2]int{5, 2}, expected: []int{7, 6, 5, 4, 3}, calls: 2},
+		{name: "whole history is not in a full page", fetched: [][2]int{{5, 1}}, request: [2]int{0, 0}, expected: []int{12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, calls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stack := &pagedStack{length: 12}
+			selector := NewCachingSelector(&MockStackSelector{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+					return stack, nil
+				},
+			})
+			ctx := context.Background()
+
+			cached, err := selector.SelectStack(ctx, "dev", ".")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, f := range tt.fetched {
+				if _, err := cached.History(ctx, f[0], f[1]); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			got, err := cached.History(ctx, tt.request[0], tt.request[1])
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(versions(got), tt.expected) {
+				t.Errorf("Expected versions %v, got %v", tt.expected, versions(got))
+			}
+			if stack.calls != tt.calls {
+				t.Errorf("Expected %d backend call(s), got %d", tt.calls, stack.calls)
+			}
+		})
+	}
+}
+
+func TestCachingSelector_SelectsOnce(t *testing.T) {
+	selections := 0
+	selector := NewCachingSelector(&MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			selections++
+			return &pagedStack{length: 3}, nil
+		},
+	})
+	ctx := context.Background()
+
+	update, err := GetUpdateByVersionWithSelector(ctx, ".", "dev", 2, selector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	latest, err := GetLatestVersionWithSelector(ctx, ".", "dev", selector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if update.Version != 2 || latest != 3 {
+		t.Errorf("Expected version 2 and latest 3, got %d and %d", update.Version, latest)
+	}
+	if selections != 1 {
+		t.Errorf("Expected the stack to be selected once, got %d", selections)
+	}
+
+	if _, err := selector.SelectStack(ctx, "prod", "."); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if selections != 2 {
+		t.Errorf("Expected another stack to be selected separately, got %d selection(s)", selections)
+	}
+}
+
+func TestCachingSelector_ErrorsNotCached(t *testing.T) {
+	calls := 0
+	stack := &MockStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			calls++
+			if calls == 1 {
+				return nil, errors.New("connection reset")
+			}
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+	}
+	selector := NewCachingSelector(&MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			return stack, nil
+		},
+	})
+
+	ctx := context.Background()
+	if _, err := GetLatestVersionWithSelector(ctx, ".", "dev", selector); err == nil {
+		t.Fatal("Expected the first fetch to fail")
+	}
+	latest, err := GetLatestVersionWithSelector(ctx, ".", "dev", selector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if latest != 1 || calls != 2 {
+		t.Errorf("Expected latest 1 after 2 calls, got %d after %d", latest, calls)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// GitClone is a checkout of a stack's program from a git repository. It is
+// cloned on first use and shared by every stack selected from it, so a
+// command clones the repository once however often it selects the stack.
+// Close removes it.
+type GitClone struct {
+	repo auto.GitRepo
+	// clone checks the repository out into dir and returns the project
+	// directory within it. It is a field so tests can avoid git.
+	clone func(ctx context.Context, dir string, opts ...auto.LocalWorkspaceOption) (string, error)
+
+	mu         sync.Mutex
+	root       string
+	projectDir string
+}
+
+// NewGitClone returns a clone of repo that is checked out on first use
+func NewGitClone(repo auto.GitRepo) *GitClone {
+	return &GitClone{repo: repo, clone: cloneRepo(repo)}
+}
+
+// cloneRepo checks repo out the way auto.SelectStackRemoteSource does,
+// including its Setup, into a given directory
+func cloneRepo(repo auto.GitRepo) func(ctx context.Context, dir string, opts ...auto.LocalWorkspaceOption) (string, error) {
+	return func(ctx context.Context, dir string, opts ...auto.LocalWorkspaceOption) (string, error) {
+		opts = append(opts, auto.Repo(repo), auto.WorkDir(dir))
+		ws, err := auto.NewLocalWorkspace(ctx, opts...)
+		if err != nil {
+			return "", err
+		}
+		return ws.WorkDir(), nil
+	}
+}
+
+// Repo returns the repository the program is cloned from
+func (c *GitClone) Repo() auto.GitRepo {
+	return c.repo
+}
+
+// ProjectDir returns the project directory of the clone, cloning the
+// repository first if needed. opts configure the workspace that clones it,
+// e.g. the Pulumi CLI to use. A failed clone is removed and tried again on
+// the next call.
+func (c *GitClone) ProjectDir(ctx context.Context, opts ...auto.LocalWorkspaceOption) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.projectDir != "" {
+		return c.projectDir, nil
+	}
+
+	root, err := os.MkdirTemp("", "pulumi-rollback-repo-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a directory for the clone: %w", err)
+	}
+	projectDir, err := c.clone(ctx, root, opts...)
+	if err != nil {
+		os.RemoveAll(root)
+		return "", fmt.Errorf("failed to clone %s: %w", c.repo.URL, err)
+	}
+	c.root, c.projectDir = root, projectDir
+	return projectDir, nil
+}
+
+// Close removes the clone. It may be checked out again afterwards.
+func (c *GitClone) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.root == "" {
+		return nil
+	}
+	err := os.RemoveAll(c.root)
+	c.root, c.projectDir = "", ""
+	return err
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func TestGitClone_ClonesOnce(t *testing.T) {
+	clones := 0
+	c := NewGitClone(auto.GitRepo{URL: "https://github.com/acme/website.git", ProjectPath: "infra"})
+	c.clone = func(ctx context.Context, dir string, opts ...auto.LocalWorkspaceOption) (string, error) {
+		clones++
+		projectDir := filepath.Join(dir, "infra")
+		return projectDir, os.MkdirAll(projectDir, 0o755)
+	}
+
+	first, err := c.ProjectDir(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := c.ProjectDir(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if clones != 1 {
+		t.Errorf("Expected 1 clone, got %d", clones)
+	}
+	if first != second {
+		t.Errorf("Expected the clone to be reused, got %s and %s", first, second)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(first)); !os.IsNotExist(err) {
+		t.Errorf("Expected the clone to be removed, got %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Expected a second Close to do nothing, got %v", err)
+	}
+}
+
+func TestGitClone_FailureNotCached(t *testing.T) {
+	var dirs []string
+	c := NewGitClone(auto.GitRepo{URL: "https://github.com/acme/website.git"})
+	c.clone = func(ctx context.Context, dir string, opts ...auto.LocalWorkspaceOption) (string, error) {
+		dirs = append(dirs, dir)
+		if len(dirs) == 1 {
+			return "", errors.New("authentication required")
+		}
+		return dir, nil
+	}
+	t.Cleanup(func() { c.Close() })
+
+	if _, err := c.ProjectDir(context.Background()); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := os.Stat(dirs[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected the failed clone to be removed, got %v", err)
+	}
+	if _, err := c.ProjectDir(context.Background()); err != nil {
+		t.Fatalf("Expected the clone to be retried, got %v", err)
+	}
+	if len(dirs) != 2 {
+		t.Errorf("Expected 2 clones, got %d", len(dirs))
+	}
+}
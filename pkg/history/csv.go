@@ -0,0 +1,54 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVHeader is the header row written by WriteCSV
+var CSVHeader = []string{"version", "kind", "result", "start_time", "end_time", "create", "update", "delete", "message"}
+
+// WriteCSV writes updates as CSV: a header row, then one row per update in
+// order. Times are RFC 3339 in UTC; a timestamp that could not be parsed is
+// written as recorded, and a missing one is left empty.
+func WriteCSV(w io.Writer, updates []UpdateInfo) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	for _, u := range updates {
+		row := []string{
+			strconv.Itoa(u.Version),
+			u.Kind,
+			u.Result,
+			csvTime(u.StartTime, u.RawStartTime),
+			csvTime(u.EndTime, u.RawEndTime),
+			strconv.Itoa(u.ResourceChanges["create"]),
+			strconv.Itoa(u.ResourceChanges["update"]),
+			strconv.Itoa(u.ResourceChanges["delete"]),
+			u.Message,
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// csvTime formats a timestamp for WriteCSV
+func csvTime(t time.Time, raw string) string {
+	if t.IsZero() {
+		return raw
+	}
+	return t.UTC().Format(time.RFC3339)
+}
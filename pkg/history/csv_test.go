@@ -0,0 +1,55 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	updates := []UpdateInfo{
+		{
+			Version:         2,
+			Kind:            "update",
+			Result:          "succeeded",
+			StartTime:       time.Date(2026, 1, 15, 14, 0, 0, 0, time.FixedZone("CET", 3600)),
+			EndTime:         time.Date(2026, 1, 15, 13, 5, 0, 0, time.UTC),
+			Message:         "Fix \"prod\", again,\nwith a newline",
+			ResourceChanges: map[string]int{"create": 3, "update": 2, "delete": 1, "same": 9},
+		},
+		{Version: 1, Kind: "update", Result: "in-progress", RawStartTime: "yesterday"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, updates); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got %v", err)
+	}
+	expected := [][]string{
+		CSVHeader,
+		{"2", "update", "succeeded", "2026-01-15T13:00:00Z", "2026-01-15T13:05:00Z", "3", "2", "1", "Fix \"prod\", again,\nwith a newline"},
+		{"1", "update", "in-progress", "yesterday", "", "0", "0", "0", ""},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %q, got %q", expected, rows)
+	}
+}
+
+func TestWriteCSV_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf.String() != "version,kind,result,start_time,end_time,create,update,delete,message\n" {
+		t.Errorf("Expected only the header row, got %q", buf.String())
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrVersionNotFound matches every VersionNotFoundError with errors.Is
+var ErrVersionNotFound = errors.New("version not found in stack history")
+
+// ErrNoUpdateBefore is returned when no update started at or before a time
+var ErrNoUpdateBefore = errors.New("no update found before the given time")
+
+// VersionNotFoundError is returned when a version is not in a stack's history
+type VersionNotFoundError struct {
+	Version int
+	// Available lists the versions in the history, when they are known
+	Available []int
+}
+
+func (e *VersionNotFoundError) Error() string {
+	return fmt.Sprintf("version %d not found in stack history", e.Version)
+}
+
+// Is reports whether target is ErrVersionNotFound
+func (e *VersionNotFoundError) Is(target error) bool {
+	return target == ErrVersionNotFound
+}
+
+// VersionOutOfRangeError is returned for a version outside the range of a
+// stack's history, such as zero, a negative number or one newer than the
+// latest update
+type VersionOutOfRangeError struct {
+	Version  int
+	Min, Max int
+}
+
+func (e *VersionOutOfRangeError) Error() string {
+	if e.Min == e.Max {
+		return fmt.Sprintf("version %d out of range; available %d", e.Version, e.Min)
+	}
+	return fmt.Sprintf("version %d out of range; available %d-%d", e.Version, e.Min, e.Max)
+}
+
+// Is reports whether target is ErrVersionNotFound
+func (e *VersionOutOfRangeError) Is(target error) bool {
+	return target == ErrVersionNotFound
+}
+
+// FormatVersions writes versions as sorted, comma separated ranges,
+// e.g. "1-3, 5, 7-9"
+func FormatVersions(versions []int) string {
+	sorted := append([]int(nil), versions...)
+	sort.Ints(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[i] == sorted[j] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// filterPageSize is the page size used when filtering history client-side
+const filterPageSize = 50
+
+// FilterCriteria narrows a history query. Bounds are inclusive and zero
+// values leave a bound open. Updates without a start time are not excluded
+// by the time bounds.
+type FilterCriteria struct {
+	MinVersion int
+	MaxVersion int
+	Since      time.Time
+	Until      time.Time
+	// Result, when set, only accepts updates with this result, e.g. "failed"
+	Result string
+}
+
+// Validate rejects a filter whose lower bound is above its upper bound
+func (f FilterCriteria) Validate() error {
+	if f.MinVersion > 0 && f.MaxVersion > 0 && f.MinVersion > f.MaxVersion {
+		return fmt.Errorf("version range %d-%d is empty: the lower bound is above the upper bound", f.MinVersion, f.MaxVersion)
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Since.After(f.Until) {
+		return fmt.Errorf("time range is empty: %s is after %s", f.Since.Format(time.RFC3339), f.Until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ParseTimeBound parses a time filter bound: a duration before now, such
+// as "1h", a date such as "2024-01-15", taken as midnight UTC, or a
+// timestamp such as "2024-01-15T14:00:00Z"
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("duration %q must not be negative", value)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if t, ok := ParseTimestamp(value); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected a duration such as 1h, a date such as 2024-01-15 or an RFC 3339 time)", value)
+}
+
+// Matches reports whether an update falls within the filter
+func (f FilterCriteria) Matches(u UpdateInfo) bool {
+	if f.MinVersion > 0 && u.Version < f.MinVersion {
+		return false
+	}
+	if f.MaxVersion > 0 && u.Version > f.MaxVersion {
+		return false
+	}
+	if !f.Since.IsZero() && !u.StartTime.IsZero() && u.StartTime.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !u.StartTime.IsZero() && u.StartTime.After(f.Until) {
+		return false
+	}
+	if f.Result != "" && u.Result != f.Result {
+		return false
+	}
+	return true
+}
+
+// exhausted reports whether an update is older than anything the filter
+// accepts, so no later (older) page can contain a match
+func (f FilterCriteria) exhausted(u UpdateInfo) bool {
+	if f.MinVersion > 0 && u.Version < f.MinVersion {
+		return true
+	}
+	return !f.Since.IsZero() && !u.StartTime.IsZero() && u.StartTime.Before(f.Since)
+}
+
+// GetFilteredHistoryWithSelector retrieves the updates matching filter,
+// newest first. The history is fetched a page at a time and fetching stops
+// once the pages are older than the filter allows.
+func GetFilteredHistoryWithSelector(ctx context.Context, projectPath, stackName string, filter FilterCriteria, selector StackSelector) ([]UpdateInfo, error) {
+	stack, err := selector.SelectStack(ctx, stackName, projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select stack %s: %w", stackName, err)
+	}
+
+	var matches []UpdateInfo
+	lastVersion := 0
+	for page := 1; ; page++ {
+		Logger.Debugf("fetching history for stack %s (page size %d, page %d)", stackName, filterPageSize, page)
+		history, err := stack.History(ctx, filterPageSize, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stack history: %w", err)
+		}
+		updates := ConvertUpdates(history)
+		if len(updates) == 0 {
+			break
+		}
+		// A backend that ignores paging returns the same page again
+		if lastVersion > 0 && updates[0].Version >= lastVersion {
+			break
+		}
+		lastVersion = updates[len(updates)-1].Version
+
+		matches = append(matches, FilterUpdates(updates, filter)...)
+		if len(updates) < filterPageSize || filter.exhausted(updates[len(updates)-1]) {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// FilterUpdates returns the updates matching filter, in their original order
+func FilterUpdates(updates []UpdateInfo, filter FilterCriteria) []UpdateInfo {
+	var matches []UpdateInfo
+	for _, u := range updates {
+		if filter.Matches(u) {
+			matches = append(matches, u)
+		}
+	}
+	return matches
+}
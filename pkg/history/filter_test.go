@@ -0,0 +1,205 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// pagedHistory returns a History function serving versions latest..1 newest first
+func pagedHistory(latest int, calls *int) func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+	return func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+		*calls++
+		var updates []auto.UpdateSummary
+		for v := latest - (page-1)*pageSize; v > 0 && v > latest-page*pageSize; v-- {
+			updates = append(updates, auto.UpdateSummary{Version: v})
+		}
+		return updates, nil
+	}
+}
+
+func TestHistoryFilterMatches(t *testing.T) {
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	filter := FilterCriteria{MinVersion: 3, MaxVersion: 8, Since: day}
+
+	tests := []struct {
+		name     string
+		update   UpdateInfo
+		expected bool
+	}{
+		{"within bounds", UpdateInfo{Version: 5, StartTime: day.Add(time.Hour)}, true},
+		{"below minimum", UpdateInfo{Version: 2, StartTime: day.Add(time.Hour)}, false},
+		{"above maximum", UpdateInfo{Version: 9, StartTime: day.Add(time.Hour)}, false},
+		{"before since", UpdateInfo{Version: 5, StartTime: day.Add(-time.Hour)}, false},
+		{"unknown time", UpdateInfo{Version: 5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Matches(tt.update); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	failed := FilterCriteria{Result: "failed"}
+	if failed.Matches(UpdateInfo{Version: 5, Result: "succeeded"}) || !failed.Matches(UpdateInfo{Version: 5, Result: "failed"}) {
+		t.Error("Expected the result filter to only accept failed updates")
+	}
+}
+
+func TestFilterUpdates(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var updates []UpdateInfo
+	for v := 30; v >= 1; v-- {
+		updates = append(updates, UpdateInfo{Version: v, StartTime: day.Add(time.Duration(v) * 24 * time.Hour)})
+	}
+	updates = append(updates, UpdateInfo{Version: 0})
+
+	tests := []struct {
+		name     string
+		filter   FilterCriteria
+		expected []int
+	}{
+		{name: "version range", filter: FilterCriteria{MinVersion: 10, MaxVersion: 12}, expected: []int{12, 11, 10}},
+		{name: "only lower version bound", filter: FilterCriteria{MinVersion: 29}, expected: []int{30, 29}},
+		{name: "only upper version bound", filter: FilterCriteria{MaxVersion: 2}, expected: []int{2, 1, 0}},
+		{name: "single version", filter: FilterCriteria{MinVersion: 7, MaxVersion: 7}, expected: []int{7}},
+		{
+			name:     "time range",
+			filter:   FilterCriteria{Since: day.Add(5 * 24 * time.Hour), Until: day.Add(6 * 24 * time.Hour)},
+			expected: []int{6, 5, 0},
+		},
+		{name: "only since", filter: FilterCriteria{Since: day.Add(29 * 24 * time.Hour)}, expected: []int{30, 29, 0}},
+		{name: "only until", filter: FilterCriteria{Until: day.Add(24 * time.Hour)}, expected: []int{1, 0}},
+		{
+			name:     "versions and times",
+			filter:   FilterCriteria{MinVersion: 3, MaxVersion: 10, Since: day.Add(8 * 24 * time.Hour)},
+			expected: []int{10, 9, 8},
+		},
+		{name: "no match", filter: FilterCriteria{MinVersion: 40}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, u := range FilterUpdates(updates, tt.filter) {
+				got = append(got, u.Version)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected versions %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHistoryFilterValidate(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		filter      FilterCriteria
+		expectedErr bool
+	}{
+		{name: "empty", filter: FilterCriteria{}},
+		{name: "one version bound", filter: FilterCriteria{MinVersion: 20}},
+		{name: "valid versions", filter: FilterCriteria{MinVersion: 10, MaxVersion: 20}},
+		{name: "inverted versions", filter: FilterCriteria{MinVersion: 20, MaxVersion: 10}, expectedErr: true},
+		{name: "valid times", filter: FilterCriteria{Since: day, Until: day.Add(time.Hour)}},
+		{name: "inverted times", filter: FilterCriteria{Since: day.Add(time.Hour), Until: day}, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("Expected error: %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value       string
+		expected    time.Time
+		expectedErr bool
+	}{
+		{value: "1h", expected: now.Add(-time.Hour)},
+		{value: "2024-01-15", expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-15T14:00:00Z", expected: time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)},
+		{value: "2024-01-15T14:00:00+02:00", expected: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{value: "-1h", expectedErr: true},
+		{value: "last tuesday", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTimeBound(tt.value, now)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expected error: %v, got %v", tt.expectedErr, err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetFilteredHistoryWithSelector_StopsPaging(t *testing.T) {
+	calls := 0
+	stack := &MockStack{HistoryFunc: pagedHistory(200, &calls)}
+	selector := &MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			return stack, nil
+		},
+	}
+
+	updates, err := GetFilteredHistoryWithSelector(context.Background(), "/path", "stack",
+		FilterCriteria{MinVersion: 140, MaxVersion: 160}, selector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(updates) != 21 {
+		t.Errorf("Expected 21 updates, got %d", len(updates))
+	}
+	if updates[0].Version != 160 || updates[len(updates)-1].Version != 140 {
+		t.Errorf("Expected versions 160..140, got %d..%d", updates[0].Version, updates[len(updates)-1].Version)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 page fetches, got %d", calls)
+	}
+}
+
+func TestGetFilteredHistoryWithSelector_IgnoredPaging(t *testing.T) {
+	calls := 0
+	stack := &MockStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			calls++
+			var updates []auto.UpdateSummary
+			for v := 100; v > 0; v-- {
+				updates = append(updates, auto.UpdateSummary{Version: v})
+			}
+			return updates, nil
+		},
+	}
+	selector := &MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			return stack, nil
+		},
+	}
+
+	updates, err := GetFilteredHistoryWithSelector(context.Background(), "/path", "stack", FilterCriteria{}, selector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(updates) != 100 || calls != 2 {
+		t.Errorf("Expected 100 updates from 2 calls, got %d from %d", len(updates), calls)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultGitRefPattern matches a version-like git tag such as "v1.4.0" in an
+// update message, e.g. "Deploy v1.4.0" or "release v2.0.0-rc.1"
+const DefaultGitRefPattern = `(?:^|[\s\[(])(v\d+(?:\.\d+)*(?:[-+][0-9A-Za-z.-]+)?)(?:$|[\s\]),])`
+
+// ErrGitRefNotFound is returned when no update in the history matches a git ref
+var ErrGitRefNotFound = errors.New("no update found for git ref")
+
+// Environment keys the Pulumi CLI records for updates run in a git repository
+const (
+	envGitHead     = "git.head"
+	envGitHeadName = "git.headName"
+)
+
+// minCommitPrefix is the shortest commit hash prefix accepted as a ref
+const minCommitPrefix = 7
+
+var defaultGitRefPattern = regexp.MustCompile(DefaultGitRefPattern)
+
+// CompileGitRefPattern compiles a pattern that extracts a git ref from an
+// update message. The pattern must have exactly one capture group, which
+// holds the ref.
+func CompileGitRefPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid git ref pattern: %w", err)
+	}
+	if re.NumSubexp() != 1 {
+		return nil, fmt.Errorf("git ref pattern %q must have exactly one capture group, has %d", pattern, re.NumSubexp())
+	}
+	return re, nil
+}
+
+// FindVersionByGitRef returns the version of the newest successful update
+// deployed from a git ref, using DefaultGitRefPattern for update messages
+func FindVersionByGitRef(history []UpdateInfo, ref string) (int, error) {
+	return FindVersionByGitRefPattern(history, ref, defaultGitRefPattern)
+}
+
+// FindVersionByGitRefPattern returns the version of the newest successful
+// update deployed from a git ref. An update matches when pattern extracts
+// the ref from its message, when its recorded branch or tag is the ref, or
+// when ref is a prefix of at least 7 characters of its recorded commit.
+func FindVersionByGitRefPattern(history []UpdateInfo, ref string, pattern *regexp.Regexp) (int, error) {
+	if ref == "" {
+		return 0, fmt.Errorf("git ref must not be empty")
+	}
+
+	best := 0
+	failed := 0
+	for _, update := range history {
+		if update.IsRollback() || !matchesGitRef(update, ref, pattern) {
+			continue
+		}
+		if update.Result != "succeeded" {
+			failed = max(failed, update.Version)
+			continue
+		}
+		best = max(best, update.Version)
+	}
+
+	if best == 0 {
+		if failed > 0 {
+			return 0, fmt.Errorf("%w %s: only failed update %d matches", ErrGitRefNotFound, ref, failed)
+		}
+		return 0, fmt.Errorf("%w %s", ErrGitRefNotFound, ref)
+	}
+	return best, nil
+}
+
+// matchesGitRef reports whether an update was deployed from ref
+func matchesGitRef(update UpdateInfo, ref string, pattern *regexp.Regexp) bool {
+	for _, match := range pattern.FindAllStringSubmatch(update.Message, -1) {
+		if match[1] == ref {
+			return true
+		}
+	}
+
+	if name := update.Environment[envGitHeadName]; name != "" {
+		if name == ref || name == "refs/tags/"+ref || name == "refs/heads/"+ref {
+			return true
+		}
+	}
+	if head := update.Environment[envGitHead]; len(ref) >= minCommitPrefix && strings.HasPrefix(head, strings.ToLower(ref)) {
+		return true
+	}
+	return false
+}
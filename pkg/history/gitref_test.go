@@ -0,0 +1,93 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestFindVersionByGitRef(t *testing.T) {
+	updates := []UpdateInfo{
+		{Version: 6, Result: "failed", Message: "Deploy v1.5.0"},
+		{Version: 5, Result: "succeeded", Message: RollbackMessage(Provenance{SourceVersion: 3})},
+		{Version: 4, Result: "succeeded", Message: "Deploy v1.4.0 (hotfix)"},
+		{Version: 3, Result: "succeeded", Message: "Deploy v1.4.0"},
+		{Version: 2, Result: "succeeded", Message: "release v1.4.0-rc.1",
+			Environment: map[string]string{"git.head": "0123456789abcdef", "git.headName": "refs/heads/main"}},
+		{Version: 1, Result: "succeeded", Message: "Deploy v1.4.00"},
+	}
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected int
+		notFound bool
+	}{
+		{name: "newest matching message", ref: "v1.4.0", expected: 4},
+		{name: "prerelease tag", ref: "v1.4.0-rc.1", expected: 2},
+		{name: "no partial match", ref: "v1.4", notFound: true},
+		{name: "commit prefix", ref: "0123456", expected: 2},
+		{name: "short commit prefix", ref: "0123", notFound: true},
+		{name: "branch", ref: "main", expected: 2},
+		{name: "only failed", ref: "v1.5.0", notFound: true},
+		{name: "unknown", ref: "v9.9.9", notFound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := FindVersionByGitRef(updates, tt.ref)
+			if tt.notFound {
+				if !errors.Is(err, ErrGitRefNotFound) {
+					t.Errorf("Expected ErrGitRefNotFound, got %v (version %d)", err, version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if version != tt.expected {
+				t.Errorf("Expected version %d, got %d", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestFindVersionByGitRefPattern(t *testing.T) {
+	updates := []UpdateInfo{
+		{Version: 2, Result: "succeeded", Message: "deploy release-2026.10 from ci"},
+		{Version: 1, Result: "succeeded", Message: "deploy release-2026.09 from ci"},
+	}
+	pattern, err := CompileGitRefPattern(`deploy (\S+)`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	version, err := FindVersionByGitRefPattern(updates, "release-2026.09", pattern)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Expected version 1, got %d", version)
+	}
+
+	if _, err := FindVersionByGitRefPattern(updates, "", pattern); err == nil {
+		t.Error("Expected error for an empty ref")
+	}
+}
+
+func TestCompileGitRefPattern(t *testing.T) {
+	for _, pattern := range []string{`(`, `deploy \S+`, `(a)(b)`} {
+		if _, err := CompileGitRefPattern(pattern); err == nil {
+			t.Errorf("Expected error for pattern %q", pattern)
+		}
+	}
+	if _, err := CompileGitRefPattern(DefaultGitRefPattern); err != nil {
+		t.Errorf("Unexpected error for the default pattern: %v", err)
+	}
+	if re := regexp.MustCompile(DefaultGitRefPattern); re.NumSubexp() != 1 {
+		t.Errorf("Expected one capture group in the default pattern, got %d", re.NumSubexp())
+	}
+}
@@ -6,11 +6,16 @@ package history
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/logging"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 )
 
+// Logger receives debug output from the history package
+var Logger logging.Logger = logging.Discard
+
 // UpdateInfo represents information about a stack update
 type UpdateInfo struct {
 	Version         int
@@ -20,6 +25,52 @@ type UpdateInfo struct {
 	Result          string
 	Message         string
 	ResourceChanges map[string]int
+	// ResourceCount is the number of resources the stack had at this
+	// version. The update summary does not record it, so it is only set
+	// when the history is enriched from checkpoints, e.g. by
+	// rollback.CountResourcesByVersion; nil means it is unknown.
+	ResourceCount *int
+
+	// Environment holds the metadata the Pulumi CLI recorded for the
+	// update, such as the git commit ("git.head") and branch ("git.headName")
+	Environment map[string]string
+
+	// Rollback is the provenance recorded for the update in a provenance
+	// log, set by ProvenanceLog.Annotate for rollbacks whose message does
+	// not carry it
+	Rollback *Provenance
+
+	// RawStartTime and RawEndTime hold the original timestamps when they
+	// could not be parsed, so they can still be shown
+	RawStartTime string
+	RawEndTime   string
+}
+
+// timestampLayouts are the timestamp formats accepted from Pulumi backends
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// ParseTimestamp parses a backend timestamp in any of the accepted layouts
+// or as Unix seconds. Timestamps without a zone are taken to be UTC.
+func ParseTimestamp(s string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil && secs > 0 {
+		return time.Unix(secs, 0).UTC(), true
+	}
+	return time.Time{}, false
 }
 
 // GetStackHistory retrieves the deployment history for a stack
@@ -29,6 +80,13 @@ func GetStackHistory(ctx context.Context, projectPath, stackName string) ([]Upda
 
 // GetStackHistoryWithSelector retrieves the deployment history using a custom selector
 func GetStackHistoryWithSelector(ctx context.Context, projectPath, stackName string, selector StackSelector) ([]UpdateInfo, error) {
+	// pageSize=0, page=0 means get all
+	return GetStackHistoryPageWithSelector(ctx, projectPath, stackName, 0, 0, selector)
+}
+
+// GetStackHistoryPageWithSelector retrieves a single page of the deployment history.
+// Pages start at 1 and are ordered newest first.
+func GetStackHistoryPageWithSelector(ctx context.Context, projectPath, stackName string, pageSize, page int, selector StackSelector) ([]UpdateInfo, error) {
 	// Create or select the stack using the provided selector
 	stack, err := selector.SelectStack(ctx, stackName, projectPath)
 	if err != nil {
@@ -36,14 +94,34 @@ func GetStackHistoryWithSelector(ctx context.Context, projectPath, stackName str
 	}
 
 	// Get the stack history
-	history, err := stack.History(ctx, 0, 0) // pageSize=0, page=0 means get all
+	Logger.Debugf("fetching history for stack %s (page size %d, page %d)", stackName, pageSize, page)
+	history, err := stack.History(ctx, pageSize, page)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stack history: %w", err)
 	}
+	Logger.Debugf("fetched %d update(s) for stack %s", len(history), stackName)
 
 	return ConvertUpdates(history), nil
 }
 
+// GetRecentHistoryWithSelector retrieves the limit most recent updates,
+// fetching only the first page of history. A limit of zero or less
+// retrieves the whole history.
+func GetRecentHistoryWithSelector(ctx context.Context, projectPath, stackName string, limit int, selector StackSelector) ([]UpdateInfo, error) {
+	if limit <= 0 {
+		return GetStackHistoryWithSelector(ctx, projectPath, stackName, selector)
+	}
+	updates, err := GetStackHistoryPageWithSelector(ctx, projectPath, stackName, limit, 1, selector)
+	if err != nil {
+		return nil, err
+	}
+	// Not every backend honors the page size
+	if len(updates) > limit {
+		updates = updates[:limit]
+	}
+	return updates, nil
+}
+
 // ConvertUpdates converts auto.UpdateSummary slice to UpdateInfo slice
 func ConvertUpdates(history []auto.UpdateSummary) []UpdateInfo {
 	var updates []UpdateInfo
@@ -54,17 +132,24 @@ func ConvertUpdates(history []auto.UpdateSummary) []UpdateInfo {
 			Result:          update.Result,
 			Message:         update.Message,
 			ResourceChanges: make(map[string]int),
+			Environment:     update.Environment,
 		}
 
-		// Parse timestamps
+		// Parse timestamps, keeping the original when the format is unknown
 		if update.StartTime != "" {
-			if t, err := time.Parse(time.RFC3339, update.StartTime); err == nil {
+			if t, ok := ParseTimestamp(update.StartTime); ok {
 				info.StartTime = t
+			} else {
+				Logger.Debugf("unrecognized start time %q for version %d", update.StartTime, update.Version)
+				info.RawStartTime = update.StartTime
 			}
 		}
 		if update.EndTime != nil && *update.EndTime != "" {
-			if t, err := time.Parse(time.RFC3339, *update.EndTime); err == nil {
+			if t, ok := ParseTimestamp(*update.EndTime); ok {
 				info.EndTime = t
+			} else {
+				Logger.Debugf("unrecognized end time %q for version %d", *update.EndTime, update.Version)
+				info.RawEndTime = *update.EndTime
 			}
 		}
 
@@ -86,25 +171,103 @@ func GetUpdateByVersion(ctx context.Context, projectPath, stackName string, vers
 	return GetUpdateByVersionWithSelector(ctx, projectPath, stackName, version, DefaultSelector)
 }
 
-// GetUpdateByVersionWithSelector retrieves a specific update by version number using a custom selector
+// GetUpdateByVersionWithSelector retrieves a specific update by version number using a custom selector.
+// Only the matching update is fetched, so a VersionNotFoundError does not list the available versions.
 func GetUpdateByVersionWithSelector(ctx context.Context, projectPath, stackName string, version int, selector StackSelector) (*UpdateInfo, error) {
-	history, err := GetStackHistoryWithSelector(ctx, projectPath, stackName, selector)
+	filter := FilterCriteria{MinVersion: version, MaxVersion: version}
+	history, err := GetFilteredHistoryWithSelector(ctx, projectPath, stackName, filter, selector)
 	if err != nil {
 		return nil, err
 	}
 
-	return FindUpdateByVersion(history, version)
+	for i := range history {
+		if history[i].Version == version {
+			return &history[i], nil
+		}
+	}
+	return nil, &VersionNotFoundError{Version: version}
 }
 
-// FindUpdateByVersion finds an update by version in a slice of updates
+// FindUpdateByVersion finds an update by version in a slice of updates.
+// The result points into history, so changes made through it are visible
+// in the slice.
 func FindUpdateByVersion(history []UpdateInfo, version int) (*UpdateInfo, error) {
+	for i := range history {
+		if history[i].Version == version {
+			return &history[i], nil
+		}
+	}
+
+	available := make([]int, 0, len(history))
 	for _, update := range history {
-		if update.Version == version {
-			return &update, nil
+		available = append(available, update.Version)
+	}
+	return nil, &VersionNotFoundError{Version: version, Available: available}
+}
+
+// GetVersionRange returns the oldest and newest versions in a history, in
+// any order. Both are 0 for an empty history.
+func GetVersionRange(history []UpdateInfo) (min, max int) {
+	for i, update := range history {
+		if i == 0 || update.Version < min {
+			min = update.Version
+		}
+		if i == 0 || update.Version > max {
+			max = update.Version
+		}
+	}
+	return min, max
+}
+
+// ValidateTargetVersion checks that version can be rolled back to: it must
+// lie within the range of the stack's history and be part of it. A version
+// out of range fails with a VersionOutOfRangeError, a gap in the history
+// with a VersionNotFoundError; both match ErrVersionNotFound.
+func ValidateTargetVersion(history []UpdateInfo, version int) error {
+	if len(history) == 0 {
+		return &VersionNotFoundError{Version: version}
+	}
+	min, max := GetVersionRange(history)
+	if version < min || version > max {
+		return &VersionOutOfRangeError{Version: version, Min: min, Max: max}
+	}
+	_, err := FindUpdateByVersion(history, version)
+	return err
+}
+
+// NewVersions returns the versions in current that are not in previous,
+// such as the deployments that landed between two polls of the history
+func NewVersions(previous, current []UpdateInfo) map[int]bool {
+	seen := make(map[int]bool, len(previous))
+	for _, update := range previous {
+		seen[update.Version] = true
+	}
+	added := make(map[int]bool)
+	for _, update := range current {
+		if !seen[update.Version] {
+			added[update.Version] = true
 		}
 	}
+	return added
+}
 
-	return nil, fmt.Errorf("version %d not found in stack history", version)
+// FindVersionBeforeTime returns the version of the newest update that
+// started at or before t. Updates without a start time are skipped.
+func FindVersionBeforeTime(history []UpdateInfo, t time.Time) (int, error) {
+	var best *UpdateInfo
+	for i, update := range history {
+		if update.StartTime.IsZero() || update.StartTime.After(t) {
+			continue
+		}
+		if best == nil || update.StartTime.After(best.StartTime) ||
+			(update.StartTime.Equal(best.StartTime) && update.Version > best.Version) {
+			best = &history[i]
+		}
+	}
+	if best == nil {
+		return 0, fmt.Errorf("%w: %s", ErrNoUpdateBefore, t.Format(time.RFC3339))
+	}
+	return best.Version, nil
 }
 
 // GetLatestVersion returns the latest version number
@@ -114,7 +277,7 @@ func GetLatestVersion(ctx context.Context, projectPath, stackName string) (int,
 
 // GetLatestVersionWithSelector returns the latest version number using a custom selector
 func GetLatestVersionWithSelector(ctx context.Context, projectPath, stackName string, selector StackSelector) (int, error) {
-	history, err := GetStackHistoryWithSelector(ctx, projectPath, stackName, selector)
+	history, err := GetRecentHistoryWithSelector(ctx, projectPath, stackName, 1, selector)
 	if err != nil {
 		return 0, err
 	}
@@ -6,6 +6,7 @@ package history
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -132,6 +133,8 @@ func TestGetStackHistoryWithSelector_HistoryError(t *testing.T) {
 
 func TestConvertUpdates(t *testing.T) {
 	endTime := "2024-01-15T10:05:00Z"
+	offsetEndTime := "2024-01-15T05:05:00.25-05:00"
+	invalidEndTime := "soon"
 	resourceChanges := map[string]int{"create": 1}
 
 	tests := []struct {
@@ -219,6 +222,61 @@ func TestConvertUpdates(t *testing.T) {
 				{
 					Version:         4,
 					StartTime:       time.Time{},
+					RawStartTime:    "invalid-date",
+					ResourceChanges: map[string]int{},
+				},
+			},
+		},
+		{
+			name: "update with offset and nanosecond timestamps",
+			input: []auto.UpdateSummary{
+				{
+					Version:   5,
+					StartTime: "2024-01-15T12:00:00.123456789+02:00",
+					EndTime:   &endTime,
+				},
+			},
+			expected: []UpdateInfo{
+				{
+					Version:         5,
+					StartTime:       time.Date(2024, 1, 15, 10, 0, 0, 123456789, time.UTC),
+					EndTime:         time.Date(2024, 1, 15, 10, 5, 0, 0, time.UTC),
+					ResourceChanges: map[string]int{},
+				},
+			},
+		},
+		{
+			name: "update with nanosecond UTC start and offset end",
+			input: []auto.UpdateSummary{
+				{
+					Version:   6,
+					StartTime: "2024-01-15T10:00:00.5Z",
+					EndTime:   &offsetEndTime,
+				},
+			},
+			expected: []UpdateInfo{
+				{
+					Version:         6,
+					StartTime:       time.Date(2024, 1, 15, 10, 0, 0, 500000000, time.UTC),
+					EndTime:         time.Date(2024, 1, 15, 10, 5, 0, 250000000, time.UTC),
+					ResourceChanges: map[string]int{},
+				},
+			},
+		},
+		{
+			name: "update with invalid end time format",
+			input: []auto.UpdateSummary{
+				{
+					Version:   7,
+					StartTime: "2024-01-15T10:00:00Z",
+					EndTime:   &invalidEndTime,
+				},
+			},
+			expected: []UpdateInfo{
+				{
+					Version:         7,
+					StartTime:       time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+					RawEndTime:      "soon",
 					ResourceChanges: map[string]int{},
 				},
 			},
@@ -237,6 +295,50 @@ func TestConvertUpdates(t *testing.T) {
 				if result[i].Version != exp.Version {
 					t.Errorf("Update %d: expected Version %d, got %d", i, exp.Version, result[i].Version)
 				}
+				if !result[i].StartTime.Equal(exp.StartTime) {
+					t.Errorf("Update %d: expected StartTime %v, got %v", i, exp.StartTime, result[i].StartTime)
+				}
+				if !result[i].EndTime.Equal(exp.EndTime) {
+					t.Errorf("Update %d: expected EndTime %v, got %v", i, exp.EndTime, result[i].EndTime)
+				}
+				if result[i].RawStartTime != exp.RawStartTime {
+					t.Errorf("Update %d: expected RawStartTime %q, got %q", i, exp.RawStartTime, result[i].RawStartTime)
+				}
+				if result[i].RawEndTime != exp.RawEndTime {
+					t.Errorf("Update %d: expected RawEndTime %q, got %q", i, exp.RawEndTime, result[i].RawEndTime)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{"2024-01-15T10:00:00Z", true},
+		{"2024-01-15T10:00:00.000Z", true},
+		{"2024-01-15T11:00:00+01:00", true},
+		{"2024-01-15T11:00:00+0100", true},
+		{"2024-01-15 10:00:00 +0000 UTC", true},
+		{"2024-01-15T10:00:00", true},
+		{"2024-01-15 10:00:00", true},
+		{"1705312800", true},
+		{"yesterday", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := ParseTimestamp(tt.input)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+			}
+			if ok && !got.Equal(expected) {
+				t.Errorf("Expected %v, got %v", expected, got)
 			}
 		})
 	}
@@ -320,6 +422,41 @@ func TestFindUpdateByVersion(t *testing.T) {
 	}
 }
 
+func TestFindUpdateByVersion_PointsIntoSlice(t *testing.T) {
+	history := []UpdateInfo{{Version: 1, Message: "first"}, {Version: 2, Message: "second"}}
+
+	first, err := FindUpdateByVersion(history, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := FindUpdateByVersion(history, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first == second {
+		t.Fatal("Expected distinct updates to have distinct pointers")
+	}
+	if first != &history[0] || second != &history[1] {
+		t.Error("Expected the results to point into the history slice")
+	}
+
+	second.Message = "changed"
+	if history[1].Message != "changed" {
+		t.Errorf("Expected the change to be visible in the slice, got %q", history[1].Message)
+	}
+	if first.Message != "first" || history[0].Message != "first" {
+		t.Errorf("Expected the other update to be unchanged, got %q", history[0].Message)
+	}
+
+	again, err := FindUpdateByVersion(history, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if again != second || again.Message != "changed" {
+		t.Errorf("Expected repeated lookups to return the same update, got %+v", again)
+	}
+}
+
 func TestFindUpdateByVersion_EmptyHistory(t *testing.T) {
 	_, err := FindUpdateByVersion([]UpdateInfo{}, 1)
 	if err == nil {
@@ -327,6 +464,94 @@ func TestFindUpdateByVersion_EmptyHistory(t *testing.T) {
 	}
 }
 
+func TestGetVersionRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []int
+		min, max int
+	}{
+		{name: "empty history", versions: nil, min: 0, max: 0},
+		{name: "single update", versions: []int{4}, min: 4, max: 4},
+		{name: "newest first", versions: []int{12, 11, 3, 1}, min: 1, max: 12},
+		{name: "unordered", versions: []int{5, 9, 2}, min: 2, max: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var history []UpdateInfo
+			for _, v := range tt.versions {
+				history = append(history, UpdateInfo{Version: v})
+			}
+			min, max := GetVersionRange(history)
+			if min != tt.min || max != tt.max {
+				t.Errorf("Expected range %d-%d, got %d-%d", tt.min, tt.max, min, max)
+			}
+		})
+	}
+}
+
+func TestValidateTargetVersion(t *testing.T) {
+	history := []UpdateInfo{{Version: 12}, {Version: 11}, {Version: 2}, {Version: 1}}
+
+	tests := []struct {
+		name     string
+		history  []UpdateInfo
+		version  int
+		expected string
+	}{
+		{name: "valid version", history: history, version: 11},
+		{name: "newer than the latest", history: history, version: 99, expected: "version 99 out of range; available 1-12"},
+		{name: "zero", history: history, version: 0, expected: "version 0 out of range; available 1-12"},
+		{name: "negative", history: history, version: -3, expected: "version -3 out of range; available 1-12"},
+		{name: "gap in the history", history: history, version: 5, expected: "version 5 not found in stack history"},
+		{name: "single version", history: []UpdateInfo{{Version: 1}}, version: 2, expected: "version 2 out of range; available 1"},
+		{name: "empty history", history: nil, version: 1, expected: "version 1 not found in stack history"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTargetVersion(tt.history, tt.version)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Fatalf("Expected error %q, got %v", tt.expected, err)
+			}
+			if !errors.Is(err, ErrVersionNotFound) {
+				t.Errorf("Expected the error to match ErrVersionNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewVersions(t *testing.T) {
+	previous := []UpdateInfo{{Version: 3}, {Version: 2}, {Version: 1}}
+
+	tests := []struct {
+		name     string
+		previous []UpdateInfo
+		current  []UpdateInfo
+		expected map[int]bool
+	}{
+		{name: "unchanged", previous: previous, current: previous, expected: map[int]bool{}},
+		{name: "new deployments", previous: previous, current: []UpdateInfo{{Version: 5}, {Version: 4}, {Version: 3}}, expected: map[int]bool{4: true, 5: true}},
+		{name: "first poll", previous: nil, current: []UpdateInfo{{Version: 1}}, expected: map[int]bool{1: true}},
+		{name: "version dropped out", previous: previous, current: []UpdateInfo{{Version: 3}}, expected: map[int]bool{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewVersions(tt.previous, tt.current)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestGetLatestVersionFromHistory(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -445,6 +670,49 @@ func TestGetLatestVersionWithSelector(t *testing.T) {
 	}
 }
 
+func TestGetRecentHistoryWithSelector(t *testing.T) {
+	tests := []struct {
+		name         string
+		limit        int
+		wantPageSize int
+		wantPage     int
+		wantVersions int
+	}{
+		{"limit fetches the first page", 2, 2, 1, 2},
+		{"zero fetches everything", 0, 0, 0, 5},
+		{"negative fetches everything", -1, 0, 0, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPageSize, gotPage int
+			mockStack := &MockStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					gotPageSize, gotPage = pageSize, page
+					// Return more than a page, as a backend ignoring the page size would
+					return []auto.UpdateSummary{{Version: 5}, {Version: 4}, {Version: 3}, {Version: 2}, {Version: 1}}, nil
+				},
+			}
+			mockSelector := &MockStackSelector{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+					return mockStack, nil
+				},
+			}
+
+			updates, err := GetRecentHistoryWithSelector(context.Background(), "/path", "stack", tt.limit, mockSelector)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if gotPageSize != tt.wantPageSize || gotPage != tt.wantPage {
+				t.Errorf("Expected page size %d and page %d, got %d and %d", tt.wantPageSize, tt.wantPage, gotPageSize, gotPage)
+			}
+			if len(updates) != tt.wantVersions || updates[0].Version != 5 {
+				t.Errorf("Expected %d updates starting at version 5, got %+v", tt.wantVersions, updates)
+			}
+		})
+	}
+}
+
 func TestGetLatestVersionWithSelector_Error(t *testing.T) {
 	mockSelector := &MockStackSelector{
 		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
@@ -489,3 +757,81 @@ func TestUpdateInfoStruct(t *testing.T) {
 		t.Errorf("Expected Message to be 'test deployment', got %q", info.Message)
 	}
 }
+
+func TestFindUpdateByVersion_NotFound(t *testing.T) {
+	updates := []UpdateInfo{{Version: 5}, {Version: 2}, {Version: 1}}
+
+	_, err := FindUpdateByVersion(updates, 4)
+	if !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("Expected ErrVersionNotFound, got %v", err)
+	}
+	var notFound *VersionNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected a VersionNotFoundError, got %T", err)
+	}
+	if notFound.Version != 4 || !reflect.DeepEqual(notFound.Available, []int{5, 2, 1}) {
+		t.Errorf("Unexpected error fields: %+v", notFound)
+	}
+}
+
+func TestFindVersionBeforeTime(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2024, 1, 15, hour, 0, 0, 0, time.UTC)
+	}
+	updates := []UpdateInfo{
+		{Version: 4, StartTime: at(16)},
+		{Version: 3, StartTime: at(14)},
+		{Version: 2},
+		{Version: 1, StartTime: at(10)},
+	}
+
+	tests := []struct {
+		name        string
+		t           time.Time
+		expected    int
+		expectedErr bool
+	}{
+		{name: "exact start time", t: at(14), expected: 3},
+		{name: "between updates", t: at(15), expected: 3},
+		{name: "after all updates", t: at(20), expected: 4},
+		{name: "first update", t: at(11), expected: 1},
+		{name: "before all updates", t: at(9), expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := FindVersionBeforeTime(updates, tt.t)
+			if tt.expectedErr {
+				if !errors.Is(err, ErrNoUpdateBefore) {
+					t.Errorf("Expected ErrNoUpdateBefore, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if version != tt.expected {
+				t.Errorf("Expected version %d, got %d", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestFormatVersions(t *testing.T) {
+	tests := []struct {
+		versions []int
+		expected string
+	}{
+		{nil, ""},
+		{[]int{3}, "3"},
+		{[]int{5, 4, 3, 2, 1}, "1-5"},
+		{[]int{9, 8, 7, 5, 2, 1}, "1-2, 5, 7-9"},
+		{[]int{2, 2, 1}, "1-2"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatVersions(tt.versions); got != tt.expected {
+			t.Errorf("FormatVersions(%v): expected %q, got %q", tt.versions, tt.expected, got)
+		}
+	}
+}
@@ -5,8 +5,12 @@ package history
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
 // StackSelector is an interface for selecting stacks
@@ -20,17 +24,86 @@ type Stack interface {
 }
 
 // DefaultStackSelector uses the real Pulumi SDK
-type DefaultStackSelector struct{}
+type DefaultStackSelector struct {
+	// PulumiCommand overrides the Pulumi CLI used by the workspace.
+	// When nil, the pulumi binary found on PATH is used.
+	PulumiCommand auto.PulumiCommand
+	// Project, when set, selects the stack by its fully qualified name
+	// without a local project directory. See SelectRemoteStack.
+	Project string
+	// Repo, when set and Project is not, is the clone of the stack's
+	// program to find its project in. Reading history does not need the
+	// program, so setting Project as well avoids the clone.
+	Repo *GitClone
+}
 
 // SelectStack selects a stack using the Pulumi SDK
 func (d *DefaultStackSelector) SelectStack(ctx context.Context, stackName, projectPath string) (Stack, error) {
-	stack, err := auto.SelectStackLocalSource(ctx, stackName, projectPath)
+	var wsOpts []auto.LocalWorkspaceOption
+	if d.PulumiCommand != nil {
+		wsOpts = append(wsOpts, auto.Pulumi(d.PulumiCommand))
+	}
+
+	var stack auto.Stack
+	var err error
+	switch {
+	case d.Project != "":
+		stack, err = SelectRemoteStack(ctx, stackName, d.Project, wsOpts...)
+	case d.Repo != nil:
+		var dir string
+		dir, err = d.Repo.ProjectDir(ctx, wsOpts...)
+		if err == nil {
+			stack, err = auto.SelectStackLocalSource(ctx, stackName, dir, wsOpts...)
+		}
+	default:
+		stack, err = auto.SelectStackLocalSource(ctx, stackName, projectPath, wsOpts...)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &RealStack{stack: stack}, nil
 }
 
+// ListStacks returns the names of the stacks of the project in projectPath
+func (d *DefaultStackSelector) ListStacks(ctx context.Context, projectPath string) ([]string, error) {
+	wsOpts := []auto.LocalWorkspaceOption{auto.WorkDir(projectPath)}
+	if d.PulumiCommand != nil {
+		wsOpts = append(wsOpts, auto.Pulumi(d.PulumiCommand))
+	}
+
+	ws, err := auto.NewLocalWorkspace(ctx, wsOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workspace: %w", err)
+	}
+	stacks, err := ws.ListStacks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stacks: %w", err)
+	}
+
+	names := make([]string, len(stacks))
+	for i, stack := range stacks {
+		names[i] = stack.Name
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ErrNoProgram is returned when a stack selected by SelectRemoteStack tries
+// to run its program
+var ErrNoProgram = errors.New("the stack's program is not available without its project directory")
+
+// SelectRemoteStack selects a stack by its fully qualified name
+// ("org/project/stack") in the backend the Pulumi CLI is logged in to,
+// without a local project directory. History, export, import and refresh
+// work; operations that run the program, such as preview and up, fail with
+// ErrNoProgram.
+func SelectRemoteStack(ctx context.Context, stackName, project string, opts ...auto.LocalWorkspaceOption) (auto.Stack, error) {
+	program := func(*pulumi.Context) error {
+		return ErrNoProgram
+	}
+	return auto.SelectStackInlineSource(ctx, stackName, project, program, opts...)
+}
+
 // RealStack wraps a real Pulumi stack
 type RealStack struct {
 	stack auto.Stack
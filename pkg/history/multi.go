@@ -0,0 +1,93 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/concurrent"
+)
+
+// StackHistories is the result of GetStackHistoriesConcurrent. Every
+// requested stack appears in exactly one of Histories and Failures, so the
+// stacks that worked can be shown even when others failed.
+type StackHistories struct {
+	// Histories maps each stack that was fetched to its history
+	Histories map[string][]UpdateInfo
+	// Failures maps each stack that could not be fetched to its error
+	Failures map[string]error
+}
+
+// AnyFailed reports whether any stack could not be fetched
+func (s *StackHistories) AnyFailed() bool {
+	return len(s.Failures) > 0
+}
+
+// Errors joins the per-stack errors in stack name order, or returns nil if
+// every stack was fetched
+func (s *StackHistories) Errors() error {
+	stacks := make([]string, 0, len(s.Failures))
+	for stack := range s.Failures {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	errs := make([]error, len(stacks))
+	for i, stack := range stacks {
+		errs[i] = fmt.Errorf("stack %s: %w", stack, s.Failures[stack])
+	}
+	return errors.Join(errs...)
+}
+
+// GetStackHistoriesConcurrent fetches the full history of several stacks in
+// the same project with at most limit fetches in flight. A failing stack
+// never discards the results of the others. The error is only set for an
+// invalid limit.
+func GetStackHistoriesConcurrent(ctx context.Context, projectPath string, stackNames []string, limit int, selector StackSelector) (*StackHistories, error) {
+	return FetchStackHistoriesConcurrent(ctx, stackNames, limit, func(ctx context.Context, stackName string) ([]UpdateInfo, error) {
+		return GetStackHistoryWithSelector(ctx, projectPath, stackName, selector)
+	})
+}
+
+// FetchStackHistoriesConcurrent is GetStackHistoriesConcurrent with the
+// history of each stack fetched by fetch, e.g. to fetch only recent updates
+func FetchStackHistoriesConcurrent(ctx context.Context, stackNames []string, limit int, fetch func(ctx context.Context, stackName string) ([]UpdateInfo, error)) (*StackHistories, error) {
+	if err := concurrent.ValidateLimit(limit); err != nil {
+		return nil, err
+	}
+
+	result := &StackHistories{
+		Histories: make(map[string][]UpdateInfo),
+		Failures:  make(map[string]error),
+	}
+	var mu sync.Mutex
+	errs := concurrent.ForEach(ctx, limit, len(stackNames), func(ctx context.Context, i int) error {
+		history, err := fetch(ctx, stackNames[i])
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		result.Histories[stackNames[i]] = history
+		mu.Unlock()
+		return nil
+	})
+
+	// Includes stacks skipped after ctx was cancelled
+	for i, err := range errs {
+		if err != nil {
+			result.Failures[stackNames[i]] = err
+		}
+	}
+	return result, nil
+}
+
+// GetMultiStackHistory fetches the history of several stacks in the same
+// project with at most concurrency fetches in flight
+func GetMultiStackHistory(ctx context.Context, projectPath string, stackNames []string, concurrency int) (*StackHistories, error) {
+	return GetStackHistoriesConcurrent(ctx, projectPath, stackNames, concurrency, DefaultSelector)
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func TestGetStackHistoriesConcurrent_PartialFailure(t *testing.T) {
+	var stacks []string
+	for i := 0; i < 8; i++ {
+		stacks = append(stacks, fmt.Sprintf("stack-%d", i))
+	}
+
+	// Every odd stack fails
+	mockSelector := &MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			var n int
+			fmt.Sscanf(stackName, "stack-%d", &n)
+			if n%2 == 1 {
+				return nil, errors.New("backend unavailable")
+			}
+			return &MockStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					return []auto.UpdateSummary{{Version: n + 1}}, nil
+				},
+			}, nil
+		},
+	}
+
+	result, err := GetStackHistoriesConcurrent(context.Background(), ".", stacks, 3, mockSelector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result.Histories) != 4 || len(result.Failures) != 4 {
+		t.Fatalf("Expected 4 successes and 4 failures, got %d and %d", len(result.Histories), len(result.Failures))
+	}
+	for i, stack := range stacks {
+		history, ok := result.Histories[stack]
+		_, failed := result.Failures[stack]
+		if ok == failed {
+			t.Errorf("Expected %s in exactly one of Histories and Failures", stack)
+		}
+		if i%2 == 0 && (len(history) != 1 || history[0].Version != i+1) {
+			t.Errorf("Unexpected history for %s: %v", stack, history)
+		}
+	}
+
+	if !result.AnyFailed() {
+		t.Error("Expected AnyFailed to be true")
+	}
+	errs := result.Errors()
+	if errs == nil {
+		t.Fatal("Expected joined errors")
+	}
+	msg := errs.Error()
+	if !strings.Contains(msg, "stack stack-1:") || strings.Contains(msg, "stack-0") {
+		t.Errorf("Unexpected errors: %s", msg)
+	}
+	if strings.Index(msg, "stack-1") > strings.Index(msg, "stack-3") {
+		t.Errorf("Expected errors in stack order, got %s", msg)
+	}
+}
+
+func TestGetStackHistoriesConcurrent_AllSucceed(t *testing.T) {
+	mockSelector := &MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			return &MockStack{}, nil
+		},
+	}
+
+	result, err := GetStackHistoriesConcurrent(context.Background(), ".", []string{"a", "b"}, 2, mockSelector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.AnyFailed() || result.Errors() != nil {
+		t.Errorf("Expected no failures, got %v", result.Errors())
+	}
+	if _, ok := result.Histories["a"]; !ok {
+		t.Error("Expected a stack with empty history to be reported as fetched")
+	}
+}
+
+func TestGetStackHistoriesConcurrent_InvalidLimit(t *testing.T) {
+	if _, err := GetStackHistoriesConcurrent(context.Background(), ".", []string{"a"}, 0, &MockStackSelector{}); err == nil {
+		t.Error("Expected error for invalid limit")
+	}
+}
+
+func TestGetStackHistoriesConcurrent_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockSelector := &MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			return &MockStack{}, nil
+		},
+	}
+
+	result, err := GetStackHistoriesConcurrent(ctx, ".", []string{"a", "b", "c"}, 2, mockSelector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Failures) != 3 {
+		t.Fatalf("Expected all 3 stacks to fail, got %v", result.Failures)
+	}
+	for stack, err := range result.Failures {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected stack %s to fail with context.Canceled, got %v", stack, err)
+		}
+	}
+}
+
+func TestFetchStackHistoriesConcurrent_Recent(t *testing.T) {
+	var mu sync.Mutex
+	pageSizes := map[string]int{}
+	mockSelector := &MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			return &MockStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					mu.Lock()
+					pageSizes[stackName] = pageSize
+					mu.Unlock()
+					return []auto.UpdateSummary{{Version: 2}, {Version: 1}}, nil
+				},
+			}, nil
+		},
+	}
+
+	result, err := FetchStackHistoriesConcurrent(context.Background(), []string{"a", "b"}, 2, func(ctx context.Context, stackName string) ([]UpdateInfo, error) {
+		return GetRecentHistoryWithSelector(ctx, ".", stackName, 2, mockSelector)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, stack := range []string{"a", "b"} {
+		if pageSizes[stack] != 2 {
+			t.Errorf("Expected %s to fetch a page of 2, got %d", stack, pageSizes[stack])
+		}
+		if len(result.Histories[stack]) != 2 {
+			t.Errorf("Expected 2 updates for %s, got %v", stack, result.Histories[stack])
+		}
+	}
+}
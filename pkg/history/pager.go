@@ -0,0 +1,86 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"fmt"
+)
+
+// Pager fetches deployment history one page at a time, so very large
+// histories can be browsed without loading every update
+type Pager struct {
+	stack    Stack
+	pageSize int
+	pages    map[int][]UpdateInfo
+}
+
+// NewPager selects the stack and returns a pager over its history
+func NewPager(ctx context.Context, projectPath, stackName string, pageSize int, selector StackSelector) (*Pager, error) {
+	if pageSize < 1 {
+		return nil, fmt.Errorf("page size must be positive, got %d", pageSize)
+	}
+
+	stack, err := selector.SelectStack(ctx, stackName, projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select stack %s: %w", stackName, err)
+	}
+
+	return &Pager{
+		stack:    stack,
+		pageSize: pageSize,
+		pages:    make(map[int][]UpdateInfo),
+	}, nil
+}
+
+// PageSize returns the number of updates per page
+func (p *Pager) PageSize() int {
+	return p.pageSize
+}
+
+// Page returns the given page of history, newest first. Pages start at 1.
+// Pages are cached once fetched.
+func (p *Pager) Page(ctx context.Context, page int) ([]UpdateInfo, error) {
+	if page < 1 {
+		return nil, fmt.Errorf("page must be at least 1, got %d", page)
+	}
+	if updates, ok := p.pages[page]; ok {
+		return updates, nil
+	}
+
+	Logger.Debugf("fetching history page %d (page size %d)", page, p.pageSize)
+	history, err := p.stack.History(ctx, p.pageSize, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack history page %d: %w", page, err)
+	}
+
+	updates := ConvertUpdates(history)
+	p.pages[page] = updates
+	return updates, nil
+}
+
+// PageForVersion returns the page expected to contain version, assuming
+// versions are numbered contiguously up to latest
+func (p *Pager) PageForVersion(latest, version int) int {
+	if version >= latest {
+		return 1
+	}
+	return (latest-version)/p.pageSize + 1
+}
+
+// FindVersion returns the update for version and the page it was found on.
+// The update points into the cached page, like the slices Page returns.
+func (p *Pager) FindVersion(ctx context.Context, latest, version int) (*UpdateInfo, int, error) {
+	page := p.PageForVersion(latest, version)
+	updates, err := p.Page(ctx, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	update, err := FindUpdateByVersion(updates, version)
+	if err != nil {
+		return nil, 0, err
+	}
+	return update, page, nil
+}
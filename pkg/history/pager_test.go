@@ -0,0 +1,125 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// newPagedMockStack returns a mock stack with versions latest..1, newest first
+func newPagedMockStack(latest int, calls *int) *MockStack {
+	return &MockStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			*calls++
+			var updates []auto.UpdateSummary
+			start := latest - (page-1)*pageSize
+			for v := start; v > 0 && v > start-pageSize; v-- {
+				updates = append(updates, auto.UpdateSummary{Version: v})
+			}
+			return updates, nil
+		},
+	}
+}
+
+func newTestPager(t *testing.T, stack Stack, pageSize int) *Pager {
+	t.Helper()
+	selector := &MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			return stack, nil
+		},
+	}
+	pager, err := NewPager(context.Background(), "/path", "stack", pageSize, selector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return pager
+}
+
+func TestNewPager_Errors(t *testing.T) {
+	selector := &MockStackSelector{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+			return nil, errors.New("stack not found")
+		},
+	}
+
+	if _, err := NewPager(context.Background(), "/path", "stack", 0, selector); err == nil {
+		t.Error("Expected error for zero page size")
+	}
+	if _, err := NewPager(context.Background(), "/path", "stack", 10, selector); err == nil {
+		t.Error("Expected error when stack selection fails")
+	}
+}
+
+func TestPager_Page(t *testing.T) {
+	calls := 0
+	pager := newTestPager(t, newPagedMockStack(25, &calls), 10)
+	ctx := context.Background()
+
+	page, err := pager.Page(ctx, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(page) != 10 || page[0].Version != 25 {
+		t.Errorf("Expected page 1 to start at version 25 with 10 entries, got %d entries", len(page))
+	}
+
+	page, err = pager.Page(ctx, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(page) != 5 || page[0].Version != 5 {
+		t.Errorf("Expected page 3 to hold versions 5..1, got %v", page)
+	}
+
+	// Cached pages are not fetched again
+	if _, err := pager.Page(ctx, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 history calls, got %d", calls)
+	}
+
+	if _, err := pager.Page(ctx, 0); err == nil {
+		t.Error("Expected error for page 0")
+	}
+}
+
+func TestPager_FindVersion(t *testing.T) {
+	calls := 0
+	pager := newTestPager(t, newPagedMockStack(25, &calls), 10)
+	ctx := context.Background()
+
+	tests := []struct {
+		version      int
+		expectedPage int
+		expectError  bool
+	}{
+		{25, 1, false},
+		{16, 1, false},
+		{15, 2, false},
+		{1, 3, false},
+		{99, 0, true},
+	}
+
+	for _, tt := range tests {
+		update, page, err := pager.FindVersion(ctx, 25, tt.version)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("Version %d: expected error, got nil", tt.version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Version %d: unexpected error: %v", tt.version, err)
+			continue
+		}
+		if update.Version != tt.version || page != tt.expectedPage {
+			t.Errorf("Version %d: got version %d on page %d, want page %d", tt.version, update.Version, page, tt.expectedPage)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Environment key the Pulumi CLI records when the program had uncommitted changes
+const envGitDirty = "git.dirty"
+
+// ProgramRevision identifies the git revision of a Pulumi program
+type ProgramRevision struct {
+	Commit string
+	// Dirty is set when the program had uncommitted changes
+	Dirty bool
+}
+
+// String returns the short commit, suffixed with "-dirty" like git describe
+func (r ProgramRevision) String() string {
+	commit := r.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if r.Dirty {
+		return commit + "-dirty"
+	}
+	return commit
+}
+
+// ProgramRevision returns the program revision the Pulumi CLI recorded for
+// the update, if it was run from a git repository
+func (u UpdateInfo) ProgramRevision() (ProgramRevision, bool) {
+	commit := u.Environment[envGitHead]
+	if commit == "" {
+		return ProgramRevision{}, false
+	}
+	return ProgramRevision{Commit: commit, Dirty: u.Environment[envGitDirty] == "true"}, true
+}
+
+// LocalProgramRevision returns the git revision of the program in dir. It
+// fails when git is not installed or dir is not in a git repository.
+func LocalProgramRevision(ctx context.Context, dir string) (ProgramRevision, error) {
+	head, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ProgramRevision{}, fmt.Errorf("failed to read the git revision of %s: %w", dir, err)
+	}
+	status, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain", "--", ".").Output()
+	if err != nil {
+		return ProgramRevision{}, fmt.Errorf("failed to read the git status of %s: %w", dir, err)
+	}
+	return ProgramRevision{
+		Commit: strings.TrimSpace(string(head)),
+		Dirty:  len(strings.TrimSpace(string(status))) > 0,
+	}, nil
+}
+
+// ProgramWarnings explains how the local program differs from the programs
+// that produced the current and target versions. A rollback restores state
+// only: the preview and up run the local program against the old state.
+func ProgramWarnings(local ProgramRevision, current, target UpdateInfo) []string {
+	var warnings []string
+	if local.Dirty {
+		warnings = append(warnings, "the local program has uncommitted changes")
+	}
+
+	if rev, ok := current.ProgramRevision(); ok && rev.Commit != local.Commit {
+		warnings = append(warnings, fmt.Sprintf("the local program (%s) differs from the program that deployed the current version %d (%s)",
+			local, current.Version, rev))
+	}
+	if rev, ok := target.ProgramRevision(); ok && rev.Commit != local.Commit {
+		warnings = append(warnings, fmt.Sprintf("version %d was deployed from %s, not the local program (%s); check out that revision to roll back the code too",
+			target.Version, rev, local))
+	}
+	return warnings
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProgramWarnings(t *testing.T) {
+	deployed := func(version int, commit string) UpdateInfo {
+		return UpdateInfo{Version: version, Environment: map[string]string{"git.head": commit}}
+	}
+
+	tests := []struct {
+		name     string
+		local    ProgramRevision
+		current  UpdateInfo
+		target   UpdateInfo
+		expected []string
+	}{
+		{
+			name:    "same revision everywhere",
+			local:   ProgramRevision{Commit: "aaa"},
+			current: deployed(5, "aaa"),
+			target:  deployed(3, "aaa"),
+		},
+		{
+			name:    "no recorded revisions",
+			local:   ProgramRevision{Commit: "aaa"},
+			current: UpdateInfo{Version: 5},
+			target:  UpdateInfo{Version: 3},
+		},
+		{
+			name:     "uncommitted changes",
+			local:    ProgramRevision{Commit: "aaa", Dirty: true},
+			current:  deployed(5, "aaa"),
+			target:   deployed(3, "aaa"),
+			expected: []string{"uncommitted changes"},
+		},
+		{
+			name:     "target deployed from older code",
+			local:    ProgramRevision{Commit: "bbb"},
+			current:  deployed(5, "bbb"),
+			target:   deployed(3, "aaa"),
+			expected: []string{"version 3 was deployed from aaa"},
+		},
+		{
+			name:     "local code ahead of the stack",
+			local:    ProgramRevision{Commit: "ccc"},
+			current:  deployed(5, "bbb"),
+			target:   deployed(3, "ccc"),
+			expected: []string{"differs from the program that deployed the current version 5 (bbb)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := ProgramWarnings(tt.local, tt.current, tt.target)
+			if len(warnings) != len(tt.expected) {
+				t.Fatalf("Expected %d warning(s), got %v", len(tt.expected), warnings)
+			}
+			for i, expected := range tt.expected {
+				if !strings.Contains(warnings[i], expected) {
+					t.Errorf("Expected warning %d to contain %q, got %q", i, expected, warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProgramRevisionString(t *testing.T) {
+	rev := ProgramRevision{Commit: "0123456789abcdef", Dirty: true}
+	if got := rev.String(); got != "0123456789ab-dirty" {
+		t.Errorf("Expected 0123456789ab-dirty, got %s", got)
+	}
+}
+
+func TestLocalProgramRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "Pulumi.yaml"), []byte("name: test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+
+	rev, err := LocalProgramRevision(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rev.Commit) < 40 || rev.Dirty {
+		t.Errorf("Expected a clean commit, got %+v", rev)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Pulumi.yaml"), []byte("name: changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rev, err = LocalProgramRevision(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !rev.Dirty {
+		t.Error("Expected uncommitted changes to be detected")
+	}
+
+	if _, err := LocalProgramRevision(context.Background(), t.TempDir()); err == nil {
+		t.Error("Expected error outside a git repository")
+	}
+}
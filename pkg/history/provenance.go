@@ -0,0 +1,199 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Provenance records where a rollback update came from
+type Provenance struct {
+	SourceVersion int    `json:"sourceVersion"`         // Version whose state was restored
+	ToolVersion   string `json:"toolVersion,omitempty"` // pulumi-rollback version that performed the rollback
+	Operator      string `json:"operator,omitempty"`    // Who ran the rollback
+}
+
+// rollbackMessagePattern matches the messages written by RollbackMessage.
+// The provenance suffix is required, so a user's own "Rollback to version
+// N" message is not taken for a rollback.
+var rollbackMessagePattern = regexp.MustCompile(`^Rollback to version (\d+) \[pulumi-rollback (.*)\]$`)
+
+// RollbackMessage returns the update message for a rollback, embedding the
+// provenance so it can be recovered from the stack history later
+func RollbackMessage(p Provenance) string {
+	fields := []string{provenanceField("source-version", strconv.Itoa(p.SourceVersion))}
+	if p.ToolVersion != "" {
+		fields = append(fields, provenanceField("tool-version", p.ToolVersion))
+	}
+	if p.Operator != "" {
+		fields = append(fields, provenanceField("operator", p.Operator))
+	}
+	return fmt.Sprintf("Rollback to version %d [pulumi-rollback %s]", p.SourceVersion, strings.Join(fields, " "))
+}
+
+// provenanceField formats a key=value field of the provenance suffix. Values
+// that are empty or contain spaces, quotes, backslashes or brackets are
+// quoted as Go strings.
+func provenanceField(key, value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\"\\[]") || !strconv.CanBackquote(value) {
+		return key + "=" + strconv.Quote(value)
+	}
+	return key + "=" + value
+}
+
+// ParseRollbackProvenance extracts the provenance from an update message
+// written by RollbackMessage
+func ParseRollbackProvenance(message string) (*Provenance, bool) {
+	match := rollbackMessagePattern.FindStringSubmatch(message)
+	if match == nil {
+		return nil, false
+	}
+	source, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil, false
+	}
+	fields, ok := parseProvenanceFields(match[2])
+	if !ok || fields["source-version"] != match[1] {
+		return nil, false
+	}
+	return &Provenance{SourceVersion: source, ToolVersion: fields["tool-version"], Operator: fields["operator"]}, true
+}
+
+// parseProvenanceFields splits the space-separated key=value fields of a
+// provenance suffix, unquoting quoted values
+func parseProvenanceFields(s string) (map[string]string, bool) {
+	fields := make(map[string]string)
+	for s = strings.TrimLeft(s, " "); s != ""; s = strings.TrimLeft(s, " ") {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok || key == "" || strings.Contains(key, " ") {
+			return nil, false
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, false
+			}
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return nil, false
+			}
+			rest = rest[len(quoted):]
+			if rest != "" && rest[0] != ' ' {
+				return nil, false
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, " ")
+		}
+		fields[key] = value
+		s = rest
+	}
+	return fields, true
+}
+
+// Stack tag keys under which a rollback records its provenance. Stack tags
+// describe the stack, so they hold the provenance of its latest rollback.
+const (
+	TagRollbackUpdate         = "pulumi-rollback:update"
+	TagRollbackSource         = "pulumi-rollback:source-version"
+	TagRollbackToolVersion    = "pulumi-rollback:tool-version"
+	TagRollbackOperator       = "pulumi-rollback:operator"
+	unknownProvenanceTagValue = "unknown"
+)
+
+// Tags returns the stack tags recording that the update with the given
+// version was this rollback. Unknown fields are tagged "unknown" so no
+// value of an earlier rollback is left behind.
+func (p Provenance) Tags(version int) map[string]string {
+	tags := map[string]string{
+		TagRollbackUpdate:      strconv.Itoa(version),
+		TagRollbackSource:      strconv.Itoa(p.SourceVersion),
+		TagRollbackToolVersion: p.ToolVersion,
+		TagRollbackOperator:    p.Operator,
+	}
+	for key, value := range tags {
+		if value == "" {
+			tags[key] = unknownProvenanceTagValue
+		}
+	}
+	return tags
+}
+
+// Provenance returns the provenance of a rollback update, from its message
+// or else from the provenance log it was annotated with
+func (u UpdateInfo) Provenance() (*Provenance, bool) {
+	if p, ok := ParseRollbackProvenance(u.Message); ok {
+		return p, true
+	}
+	return u.Rollback, u.Rollback != nil
+}
+
+// IsRollback reports whether the update was created by a rollback
+func (u UpdateInfo) IsRollback() bool {
+	_, ok := u.Provenance()
+	return ok
+}
+
+// StateVersion returns the version whose state the stack held after the
+// update: the restored version for a rollback, otherwise its own version
+func (u UpdateInfo) StateVersion() int {
+	if p, ok := u.Provenance(); ok {
+		return p.SourceVersion
+	}
+	return u.Version
+}
+
+// Direction is where setting a stack to a version moves it, relative to
+// the version whose state it currently holds
+type Direction string
+
+const (
+	DirectionBackward Direction = "backward"
+	DirectionForward  Direction = "forward"
+	DirectionReapply  Direction = "re-apply"
+)
+
+// updateKind is the kind of the history entries that deploy a program.
+// Refreshes, previews, renames and imports leave the version whose state
+// the stack holds unchanged.
+const updateKind = "update"
+
+// LatestStateUpdate returns the newest entry of updates, newest first, that
+// set the stack's state: the newest update, skipping refreshes and other
+// non-update entries. Entries without a kind count as updates. It returns
+// false when there is none.
+func LatestStateUpdate(updates []UpdateInfo) (UpdateInfo, bool) {
+	for _, u := range updates {
+		if u.Kind == updateKind || u.Kind == "" {
+			return u, true
+		}
+	}
+	return UpdateInfo{}, false
+}
+
+// DirectionTo returns the direction of setting a stack with updates, newest
+// first, to the state of the target version. It is relative to the state
+// set by the latest update, as returned by LatestStateUpdate, so a stack
+// rolled back to an older version moves forward when set to a version
+// after that one. Without any update it is relative to the newest entry.
+func DirectionTo(updates []UpdateInfo, target int) Direction {
+	if len(updates) == 0 {
+		return ""
+	}
+	latest, ok := LatestStateUpdate(updates)
+	if !ok {
+		latest = updates[0]
+	}
+	current := latest.StateVersion()
+	switch {
+	case target < current:
+		return DirectionBackward
+	case target > current:
+		return DirectionForward
+	default:
+		return DirectionReapply
+	}
+}
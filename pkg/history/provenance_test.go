@@ -0,0 +1,192 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRollbackMessageRoundTrip(t *testing.T) {
+	tests := []Provenance{
+		{SourceVersion: 38, ToolVersion: "1.2.0", Operator: "Jane Doe"},
+		{SourceVersion: 38, Operator: "ci_bot"},
+		{SourceVersion: 38, Operator: "Jane_Doe and ci_bot"},
+		{SourceVersion: 38, Operator: `a "quoted" name [admin]`},
+		{SourceVersion: 38, Operator: `DOMAIN\jane`},
+		{SourceVersion: 38},
+	}
+
+	for _, original := range tests {
+		message := RollbackMessage(original)
+		parsed, ok := ParseRollbackProvenance(message)
+		if !ok {
+			t.Errorf("Expected %q to parse as a rollback", message)
+			continue
+		}
+		if *parsed != original {
+			t.Errorf("Expected %+v from %q, got %+v", original, message, *parsed)
+		}
+	}
+}
+
+func TestParseRollbackProvenance(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected *Provenance
+	}{
+		{
+			name:    "user message without provenance",
+			message: "Rollback to version 5",
+		},
+		{
+			name:    "user message with more text",
+			message: "Rollback to version 5 after the outage [pulumi-rollback source-version=4]",
+		},
+		{
+			name:    "source version mismatch",
+			message: "Rollback to version 5 [pulumi-rollback source-version=4]",
+		},
+		{
+			name:    "text after the provenance",
+			message: "Rollback to version 5 [pulumi-rollback source-version=5] and more",
+		},
+		{
+			name:     "quoted operator",
+			message:  `Rollback to version 5 [pulumi-rollback source-version=5 operator="Jane Doe"]`,
+			expected: &Provenance{SourceVersion: 5, Operator: "Jane Doe"},
+		},
+		{
+			name:     "partial provenance",
+			message:  "Rollback to version 7 [pulumi-rollback source-version=7 operator=ci]",
+			expected: &Provenance{SourceVersion: 7, Operator: "ci"},
+		},
+		{
+			name:    "regular update",
+			message: "Deploy feature X",
+		},
+		{
+			name:    "empty message",
+			message: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, ok := ParseRollbackProvenance(tt.message)
+			if tt.expected == nil {
+				if ok {
+					t.Errorf("Expected no provenance, got %+v", parsed)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("Expected provenance, got none")
+			}
+			if *parsed != *tt.expected {
+				t.Errorf("Expected %+v, got %+v", *tt.expected, *parsed)
+			}
+
+			if !(UpdateInfo{Message: tt.message}).IsRollback() {
+				t.Error("Expected IsRollback to be true")
+			}
+		})
+	}
+}
+
+func TestDirectionTo(t *testing.T) {
+	deploy := UpdateInfo{Version: 10, Kind: "update", Message: "deploy v2.3"}
+	rolledBack := UpdateInfo{Version: 10, Kind: "update", Message: RollbackMessage(Provenance{SourceVersion: 5})}
+	refresh := UpdateInfo{Version: 11, Kind: "refresh"}
+	preview := UpdateInfo{Version: 12, Kind: "preview"}
+
+	tests := []struct {
+		name     string
+		updates  []UpdateInfo
+		target   int
+		expected Direction
+	}{
+		{"older version", []UpdateInfo{deploy}, 7, DirectionBackward},
+		{"latest version", []UpdateInfo{deploy}, 10, DirectionReapply},
+		{"before the restored version", []UpdateInfo{rolledBack}, 3, DirectionBackward},
+		{"the restored version", []UpdateInfo{rolledBack}, 5, DirectionReapply},
+		{"after the restored version", []UpdateInfo{rolledBack}, 9, DirectionForward},
+		{"refresh after an update", []UpdateInfo{refresh, deploy}, 10, DirectionReapply},
+		{"refreshes after a rollback", []UpdateInfo{preview, refresh, rolledBack}, 9, DirectionForward},
+		{"refresh version", []UpdateInfo{refresh, rolledBack}, 11, DirectionForward},
+		{"no kind counts as an update", []UpdateInfo{{Version: 8}}, 8, DirectionReapply},
+		{"only refreshes", []UpdateInfo{refresh}, 9, DirectionBackward},
+		{"no history", nil, 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DirectionTo(tt.updates, tt.target); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestProvenanceTags(t *testing.T) {
+	tags := Provenance{SourceVersion: 38, Operator: "Jane Doe"}.Tags(43)
+	expected := map[string]string{
+		TagRollbackUpdate:      "43",
+		TagRollbackSource:      "38",
+		TagRollbackToolVersion: "unknown",
+		TagRollbackOperator:    "Jane Doe",
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+}
+
+func TestProvenanceLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ProvenanceLogName)
+
+	log, err := LoadProvenanceLog(path)
+	if err != nil {
+		t.Fatalf("Unexpected error for a missing log: %v", err)
+	}
+	log.Record("prod", 43, Provenance{SourceVersion: 38, Operator: "Jane Doe"})
+	if err := log.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	log, err = LoadProvenanceLog(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p, ok := log.Lookup("prod", 43)
+	if !ok || p.SourceVersion != 38 || p.Operator != "Jane Doe" {
+		t.Errorf("Expected the recorded provenance, got %+v", p)
+	}
+	if _, ok := log.Lookup("prod", 42); ok {
+		t.Error("Expected no provenance for another version")
+	}
+
+	// The message takes precedence; the log fills in for messages without one
+	updates := []UpdateInfo{
+		{Version: 44, Message: RollbackMessage(Provenance{SourceVersion: 40})},
+		{Version: 43, Message: "truncated"},
+		{Version: 42, Message: "deploy"},
+	}
+	log.Annotate("prod", updates)
+	if p, ok := updates[0].Provenance(); !ok || p.SourceVersion != 40 {
+		t.Errorf("Expected the message's provenance, got %+v", p)
+	}
+	if p, ok := updates[1].Provenance(); !ok || p.SourceVersion != 38 {
+		t.Errorf("Expected the logged provenance, got %+v", p)
+	}
+	if updates[2].IsRollback() {
+		t.Error("Expected version 42 not to be a rollback")
+	}
+	var none *ProvenanceLog
+	none.Annotate("prod", updates[2:])
+	if updates[2].IsRollback() {
+		t.Error("Expected no provenance without a log")
+	}
+}
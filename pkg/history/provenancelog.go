@@ -0,0 +1,99 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ProvenanceLogName is the file name of the provenance log within a project
+const ProvenanceLogName = ".pulumi-rollback-provenance.json"
+
+// ProvenanceLog is a sidecar record of the updates created by rollbacks,
+// kept next to the project. It holds the provenance even when an update's
+// message does not, e.g. because the backend truncated it.
+type ProvenanceLog struct {
+	// Stacks maps stack names to the provenance of each rollback update,
+	// keyed by the update's version
+	Stacks map[string]map[string]Provenance `json:"stacks"`
+}
+
+// LoadProvenanceLog reads a provenance log. A missing file yields an empty
+// log.
+func LoadProvenanceLog(path string) (*ProvenanceLog, error) {
+	log := &ProvenanceLog{Stacks: make(map[string]map[string]Provenance)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return log, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, log); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if log.Stacks == nil {
+		log.Stacks = make(map[string]map[string]Provenance)
+	}
+	return log, nil
+}
+
+// Save writes the log to path, replacing it atomically
+func (l *ProvenanceLog) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Record stores the provenance of the rollback that created update version
+// of a stack
+func (l *ProvenanceLog) Record(stack string, version int, p Provenance) {
+	if l.Stacks[stack] == nil {
+		l.Stacks[stack] = make(map[string]Provenance)
+	}
+	l.Stacks[stack][strconv.Itoa(version)] = p
+}
+
+// Lookup returns the provenance recorded for update version of a stack
+func (l *ProvenanceLog) Lookup(stack string, version int) (*Provenance, bool) {
+	if l == nil {
+		return nil, false
+	}
+	p, ok := l.Stacks[stack][strconv.Itoa(version)]
+	if !ok {
+		return nil, false
+	}
+	return &p, true
+}
+
+// Annotate attaches the recorded provenance to the updates of a stack.
+// The log may be nil.
+func (l *ProvenanceLog) Annotate(stack string, updates []UpdateInfo) {
+	for i := range updates {
+		if p, ok := l.Lookup(stack, updates[i].Version); ok {
+			updates[i].Rollback = p
+		}
+	}
+}
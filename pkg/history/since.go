@@ -0,0 +1,119 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// sincePageSize is the page size used by GetHistorySince. Polls usually
+// find only a few new updates, so pages are small.
+const sincePageSize = 10
+
+// HistoryDelta is the result of GetHistorySince
+type HistoryDelta struct {
+	// Updates are the updates newer than the requested version, newest
+	// first. After a reset they are the stack's whole history.
+	Updates []UpdateInfo
+	// Reset reports that the stack's latest version is older than the
+	// requested version: the stack was recreated and its versions restarted.
+	// Previously fetched history should be discarded.
+	Reset bool
+}
+
+// GetHistorySince retrieves only the updates newer than sinceVersion,
+// fetching the history a page at a time and stopping at the first page that
+// reaches sinceVersion. A sinceVersion of zero fetches the whole history.
+//
+// A stack that was recreated and has since reached sinceVersion again
+// cannot be told apart from the original stack.
+func GetHistorySince(ctx context.Context, projectPath, stackName string, sinceVersion int, selector StackSelector) (*HistoryDelta, error) {
+	stack, err := selector.SelectStack(ctx, stackName, projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select stack %s: %w", stackName, err)
+	}
+
+	if sinceVersion <= 0 {
+		return fetchAll(ctx, stack, stackName, false)
+	}
+
+	var newer []UpdateInfo
+	lastVersion := 0
+	for page := 1; ; page++ {
+		Logger.Debugf("fetching history for stack %s since version %d (page size %d, page %d)", stackName, sinceVersion, sincePageSize, page)
+		history, err := stack.History(ctx, sincePageSize, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stack history: %w", err)
+		}
+		updates := ConvertUpdates(history)
+
+		if page == 1 && latestVersion(updates) < sinceVersion {
+			Logger.Debugf("stack %s has no version %d; its history was reset", stackName, sinceVersion)
+			return fetchAll(ctx, stack, stackName, true)
+		}
+		if len(updates) == 0 {
+			break
+		}
+		// A backend that ignores paging returns the same page again
+		if lastVersion > 0 && updates[0].Version >= lastVersion {
+			break
+		}
+		lastVersion = updates[len(updates)-1].Version
+
+		reached := false
+		for _, u := range updates {
+			if u.Version > sinceVersion {
+				newer = append(newer, u)
+			} else {
+				reached = true
+			}
+		}
+		if reached || len(updates) < sincePageSize {
+			break
+		}
+	}
+	return &HistoryDelta{Updates: newer}, nil
+}
+
+// fetchAll retrieves the whole history of stack
+func fetchAll(ctx context.Context, stack Stack, stackName string, reset bool) (*HistoryDelta, error) {
+	Logger.Debugf("fetching full history for stack %s", stackName)
+	history, err := stack.History(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack history: %w", err)
+	}
+	return &HistoryDelta{Updates: ConvertUpdates(history), Reset: reset}, nil
+}
+
+// latestVersion returns the highest version in updates, or 0
+func latestVersion(updates []UpdateInfo) int {
+	latest := 0
+	for _, u := range updates {
+		latest = max(latest, u.Version)
+	}
+	return latest
+}
+
+// MergeHistory merges newly fetched updates into previously fetched
+// history. Updates in newer replace known updates with the same version.
+// The result is ordered newest first.
+func MergeHistory(known, newer []UpdateInfo) []UpdateInfo {
+	merged := make([]UpdateInfo, 0, len(known)+len(newer))
+	seen := make(map[int]bool, len(newer))
+	for _, u := range newer {
+		if !seen[u.Version] {
+			seen[u.Version] = true
+			merged = append(merged, u)
+		}
+	}
+	for _, u := range known {
+		if !seen[u.Version] {
+			merged = append(merged, u)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Version > merged[j].Version })
+	return merged
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func TestGetHistorySince(t *testing.T) {
+	tests := []struct {
+		name          string
+		latest        int
+		since         int
+		expected      []int
+		expectedReset bool
+		expectedCalls int
+	}{
+		{name: "no new updates", latest: 30, since: 30, expectedCalls: 1},
+		{name: "few new updates", latest: 30, since: 27, expected: []int{30, 29, 28}, expectedCalls: 1},
+		{name: "across pages", latest: 30, since: 15, expected: []int{30, 29, 28, 27, 26, 25, 24, 23, 22, 21, 20, 19, 18, 17, 16}, expectedCalls: 2},
+		{name: "full history", latest: 3, since: 0, expected: []int{3, 2, 1}, expectedCalls: 1},
+		{name: "reset", latest: 2, since: 30, expected: []int{2, 1}, expectedReset: true, expectedCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			mockStack := &MockStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					calls++
+					if pageSize == 0 {
+						pageSize, page = tt.latest, 1
+					}
+					var updates []auto.UpdateSummary
+					for v := tt.latest - (page-1)*pageSize; v > 0 && v > tt.latest-page*pageSize; v-- {
+						updates = append(updates, auto.UpdateSummary{Version: v})
+					}
+					return updates, nil
+				},
+			}
+			mockSelector := &MockStackSelector{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (Stack, error) {
+					return mockStack, nil
+				},
+			}
+
+			delta, err := GetHistorySince(context.Background(), ".", "test", tt.since, mockSelector)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if delta.Reset != tt.expectedReset {
+				t.Errorf("Expected reset %v, got %v", tt.expectedReset, delta.Reset)
+			}
+			if len(delta.Updates) != len(tt.expected) {
+				t.Fatalf("Expected %d update(s), got %d", len(tt.expected), len(delta.Updates))
+			}
+			for i, v := range tt.expected {
+				if delta.Updates[i].Version != v {
+					t.Errorf("Update %d: expected version %d, got %d", i, v, delta.Updates[i].Version)
+				}
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("Expected %d History call(s), got %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestMergeHistory(t *testing.T) {
+	known := []UpdateInfo{{Version: 3, Result: "in-progress"}, {Version: 2}, {Version: 1}}
+	newer := []UpdateInfo{{Version: 4}, {Version: 3, Result: "failed"}}
+
+	merged := MergeHistory(known, newer)
+	if len(merged) != 4 {
+		t.Fatalf("Expected 4 updates, got %d", len(merged))
+	}
+	for i, v := range []int{4, 3, 2, 1} {
+		if merged[i].Version != v {
+			t.Errorf("Update %d: expected version %d, got %d", i, v, merged[i].Version)
+		}
+	}
+	if merged[1].Result != "failed" {
+		t.Errorf("Expected the newer copy of version 3, got %q", merged[1].Result)
+	}
+}
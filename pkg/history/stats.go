@@ -0,0 +1,160 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KindCount is the number of updates of one kind
+type KindCount struct {
+	Kind  string
+	Count int
+}
+
+// HistoryStats summarizes the cadence and reliability of a stack's deployments
+type HistoryStats struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	// SuccessRate is the fraction of finished updates that succeeded
+	SuccessRate float64
+
+	// First and Last are the start times of the oldest and newest updates
+	First time.Time
+	Last  time.Time
+	// PerDay and PerWeek are the average number of updates over the period
+	// from First to Last, which counts as at least one day
+	PerDay  float64
+	PerWeek float64
+
+	// AverageDuration is averaged over updates with both start and end times
+	AverageDuration time.Duration
+
+	// LongestGap is the longest time between the starts of consecutive
+	// updates, beginning at LongestGapStart
+	LongestGap      time.Duration
+	LongestGapStart time.Time
+
+	// FailingKinds lists the kinds of failed updates, most failures first
+	FailingKinds []KindCount
+}
+
+// ComputeHistoryStats computes aggregate statistics for a history in any order
+func ComputeHistoryStats(history []UpdateInfo) HistoryStats {
+	stats := HistoryStats{Total: len(history)}
+
+	var starts []time.Time
+	var totalDuration time.Duration
+	timed := 0
+	failures := make(map[string]int)
+
+	for _, u := range history {
+		switch u.Result {
+		case "succeeded":
+			stats.Succeeded++
+		case "failed":
+			stats.Failed++
+			failures[u.Kind]++
+		}
+
+		if u.StartTime.IsZero() {
+			continue
+		}
+		starts = append(starts, u.StartTime)
+		if !u.EndTime.IsZero() && !u.EndTime.Before(u.StartTime) {
+			totalDuration += u.EndTime.Sub(u.StartTime)
+			timed++
+		}
+	}
+
+	if finished := stats.Succeeded + stats.Failed; finished > 0 {
+		stats.SuccessRate = float64(stats.Succeeded) / float64(finished)
+	}
+	if timed > 0 {
+		stats.AverageDuration = totalDuration / time.Duration(timed)
+	}
+
+	if len(starts) > 0 {
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+		stats.First = starts[0]
+		stats.Last = starts[len(starts)-1]
+
+		days := stats.Last.Sub(stats.First).Hours() / 24
+		if days < 1 {
+			days = 1
+		}
+		stats.PerDay = float64(len(starts)) / days
+		stats.PerWeek = stats.PerDay * 7
+
+		for i := 1; i < len(starts); i++ {
+			if gap := starts[i].Sub(starts[i-1]); gap > stats.LongestGap {
+				stats.LongestGap = gap
+				stats.LongestGapStart = starts[i-1]
+			}
+		}
+	}
+
+	for kind, count := range failures {
+		stats.FailingKinds = append(stats.FailingKinds, KindCount{Kind: kind, Count: count})
+	}
+	sort.Slice(stats.FailingKinds, func(i, j int) bool {
+		a, b := stats.FailingKinds[i], stats.FailingKinds[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Kind < b.Kind
+	})
+
+	return stats
+}
+
+// ResultCounts maps update results such as "succeeded" and "failed" to the
+// number of updates with that result
+type ResultCounts map[string]int
+
+// resultOrder is the order in which ResultCounts.String lists known results
+var resultOrder = []string{"succeeded", "failed", "in-progress"}
+
+// SummarizeHistory counts updates by result
+func SummarizeHistory(history []UpdateInfo) ResultCounts {
+	counts := make(ResultCounts)
+	for _, u := range history {
+		counts[u.Result]++
+	}
+	return counts
+}
+
+// Total returns the number of updates counted
+func (c ResultCounts) Total() int {
+	total := 0
+	for _, n := range c {
+		total += n
+	}
+	return total
+}
+
+// String formats the counts for scripts, e.g. "succeeded=40 failed=2".
+// Succeeded and failed are always listed; other results follow when present.
+func (c ResultCounts) String() string {
+	var others []string
+	for result := range c {
+		if result != "succeeded" && result != "failed" && result != "in-progress" {
+			others = append(others, result)
+		}
+	}
+	sort.Strings(others)
+
+	var parts []string
+	for _, result := range slices.Concat(resultOrder, others) {
+		if n, ok := c[result]; ok || result == "succeeded" || result == "failed" {
+			parts = append(parts, fmt.Sprintf("%s=%d", result, n))
+		}
+	}
+	return strings.Join(parts, " ")
+}
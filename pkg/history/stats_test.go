@@ -0,0 +1,153 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeHistoryStats_Empty(t *testing.T) {
+	stats := ComputeHistoryStats(nil)
+
+	if stats.Total != 0 || stats.SuccessRate != 0 || stats.PerDay != 0 {
+		t.Errorf("Expected zero stats, got %+v", stats)
+	}
+	if !stats.First.IsZero() || stats.LongestGap != 0 {
+		t.Errorf("Expected no time range, got %+v", stats)
+	}
+	if len(stats.FailingKinds) != 0 {
+		t.Errorf("Expected no failing kinds, got %v", stats.FailingKinds)
+	}
+}
+
+func TestComputeHistoryStats(t *testing.T) {
+	base := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	update := func(version int, kind, result string, start time.Duration, duration time.Duration) UpdateInfo {
+		u := UpdateInfo{Version: version, Kind: kind, Result: result, StartTime: base.Add(start)}
+		if duration > 0 {
+			u.EndTime = u.StartTime.Add(duration)
+		}
+		return u
+	}
+
+	// Newest first, as returned by the backend
+	history := []UpdateInfo{
+		update(6, "update", "in-progress", 10*day, 0),
+		update(5, "update", "failed", 9*day, 2*time.Minute),
+		update(4, "refresh", "failed", 8*day, time.Minute),
+		update(3, "update", "failed", 2*day, 3*time.Minute),
+		update(2, "update", "succeeded", 1*day, 4*time.Minute),
+		update(1, "update", "succeeded", 0, 6*time.Minute),
+		{Version: 0, Kind: "update", Result: "succeeded"},
+	}
+
+	stats := ComputeHistoryStats(history)
+
+	if stats.Total != 7 {
+		t.Errorf("Expected Total 7, got %d", stats.Total)
+	}
+	if stats.Succeeded != 3 || stats.Failed != 3 {
+		t.Errorf("Expected 3 succeeded and 3 failed, got %d and %d", stats.Succeeded, stats.Failed)
+	}
+	if stats.SuccessRate != 0.5 {
+		t.Errorf("Expected SuccessRate 0.5, got %v", stats.SuccessRate)
+	}
+	if !stats.First.Equal(base) || !stats.Last.Equal(base.Add(10*day)) {
+		t.Errorf("Unexpected range %v - %v", stats.First, stats.Last)
+	}
+	// 6 timed updates over 10 days
+	if math.Abs(stats.PerDay-0.6) > 1e-9 {
+		t.Errorf("Expected PerDay 0.6, got %v", stats.PerDay)
+	}
+	if math.Abs(stats.PerWeek-4.2) > 1e-9 {
+		t.Errorf("Expected PerWeek 4.2, got %v", stats.PerWeek)
+	}
+	if stats.AverageDuration != 3*time.Minute+12*time.Second {
+		t.Errorf("Expected AverageDuration 3m12s, got %v", stats.AverageDuration)
+	}
+	if stats.LongestGap != 6*day || !stats.LongestGapStart.Equal(base.Add(2*day)) {
+		t.Errorf("Expected 6 day gap from day 2, got %v from %v", stats.LongestGap, stats.LongestGapStart)
+	}
+
+	expectedKinds := []KindCount{{"update", 2}, {"refresh", 1}}
+	if len(stats.FailingKinds) != len(expectedKinds) {
+		t.Fatalf("Expected %d failing kinds, got %v", len(expectedKinds), stats.FailingKinds)
+	}
+	for i, k := range expectedKinds {
+		if stats.FailingKinds[i] != k {
+			t.Errorf("Expected failing kind %v, got %v", k, stats.FailingKinds[i])
+		}
+	}
+}
+
+func TestComputeHistoryStats_SameDay(t *testing.T) {
+	start := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+	history := []UpdateInfo{
+		{Version: 2, Result: "succeeded", StartTime: start.Add(time.Hour)},
+		{Version: 1, Result: "succeeded", StartTime: start},
+	}
+
+	stats := ComputeHistoryStats(history)
+
+	// A period shorter than a day counts as one day
+	if stats.PerDay != 2 {
+		t.Errorf("Expected PerDay 2, got %v", stats.PerDay)
+	}
+	if stats.SuccessRate != 1 {
+		t.Errorf("Expected SuccessRate 1, got %v", stats.SuccessRate)
+	}
+	if stats.AverageDuration != 0 {
+		t.Errorf("Expected no AverageDuration without end times, got %v", stats.AverageDuration)
+	}
+}
+
+func TestComputeHistoryStats_IgnoresNegativeDuration(t *testing.T) {
+	start := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+	history := []UpdateInfo{
+		{Version: 2, StartTime: start, EndTime: start.Add(-time.Minute)},
+		{Version: 1, StartTime: start, EndTime: start.Add(2 * time.Minute)},
+	}
+
+	if got := ComputeHistoryStats(history).AverageDuration; got != 2*time.Minute {
+		t.Errorf("Expected AverageDuration 2m, got %v", got)
+	}
+}
+
+func TestSummarizeHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		updates  []UpdateInfo
+		expected string
+		total    int
+	}{
+		{name: "empty", expected: "succeeded=0 failed=0"},
+		{
+			name:     "succeeded and failed",
+			updates:  []UpdateInfo{{Result: "failed"}, {Result: "succeeded"}, {Result: "succeeded"}},
+			expected: "succeeded=2 failed=1",
+			total:    3,
+		},
+		{
+			name:     "other results",
+			updates:  []UpdateInfo{{Result: "cancelled"}, {Result: "in-progress"}, {Result: "succeeded"}},
+			expected: "succeeded=1 failed=0 in-progress=1 cancelled=1",
+			total:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := SummarizeHistory(tt.updates)
+			if got := counts.String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+			if counts.Total() != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, counts.Total())
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+// StackStatus summarizes whether a stack can be rolled back right now
+type StackStatus struct {
+	// Latest is the most recent update
+	Latest UpdateInfo
+	// InProgress is true when the most recent update has not finished,
+	// in which case a rollback is unsafe
+	InProgress bool
+	// AvailableVersions is the number of versions before the latest one
+	AvailableVersions int
+	// LastSucceededVersion is the newest version before the latest one whose
+	// update succeeded, or 0 if there is none
+	LastSucceededVersion int
+}
+
+// GetStackStatus summarizes a history slice, newest first
+func GetStackStatus(history []UpdateInfo, stackName string) (StackStatus, error) {
+	if _, err := GetLatestVersionFromHistory(history, stackName); err != nil {
+		return StackStatus{}, err
+	}
+
+	status := StackStatus{
+		Latest:            history[0],
+		InProgress:        history[0].Result == "in-progress",
+		AvailableVersions: len(history) - 1,
+	}
+	for _, update := range history[1:] {
+		if update.Result == "succeeded" {
+			status.LastSucceededVersion = update.Version
+			break
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package history
+
+import (
+	"testing"
+)
+
+func TestGetStackStatus(t *testing.T) {
+	tests := []struct {
+		name                  string
+		history               []UpdateInfo
+		expectedLatest        int
+		expectedInProgress    bool
+		expectedAvailable     int
+		expectedLastSucceeded int
+	}{
+		{
+			name: "ready",
+			history: []UpdateInfo{
+				{Version: 3, Result: "succeeded"},
+				{Version: 2, Result: "failed"},
+				{Version: 1, Result: "succeeded"},
+			},
+			expectedLatest:        3,
+			expectedAvailable:     2,
+			expectedLastSucceeded: 1,
+		},
+		{
+			name: "update in progress",
+			history: []UpdateInfo{
+				{Version: 2, Result: "in-progress"},
+				{Version: 1, Result: "succeeded"},
+			},
+			expectedLatest:        2,
+			expectedInProgress:    true,
+			expectedAvailable:     1,
+			expectedLastSucceeded: 1,
+		},
+		{
+			name: "single version",
+			history: []UpdateInfo{
+				{Version: 1, Result: "succeeded"},
+			},
+			expectedLatest: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := GetStackStatus(tt.history, "test")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if status.Latest.Version != tt.expectedLatest {
+				t.Errorf("Expected latest version %d, got %d", tt.expectedLatest, status.Latest.Version)
+			}
+			if status.InProgress != tt.expectedInProgress {
+				t.Errorf("Expected in progress %v, got %v", tt.expectedInProgress, status.InProgress)
+			}
+			if status.AvailableVersions != tt.expectedAvailable {
+				t.Errorf("Expected %d available versions, got %d", tt.expectedAvailable, status.AvailableVersions)
+			}
+			if status.LastSucceededVersion != tt.expectedLastSucceeded {
+				t.Errorf("Expected last succeeded version %d, got %d", tt.expectedLastSucceeded, status.LastSucceededVersion)
+			}
+		})
+	}
+}
+
+func TestGetStackStatus_EmptyHistory(t *testing.T) {
+	if _, err := GetStackStatus(nil, "test"); err == nil {
+		t.Error("Expected error for empty history")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+// Package lockfile reads and writes rollback.lock, which records a known
+// good version to roll back to for each stack.
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultName is the file name of the lockfile within a project
+const DefaultName = "rollback.lock"
+
+// Pin records a known good version for a stack
+type Pin struct {
+	Version  int       `json:"version"`
+	PinnedAt time.Time `json:"pinnedAt"`
+	PinnedBy string    `json:"pinnedBy,omitempty"`
+	Note     string    `json:"note,omitempty"`
+}
+
+// Lockfile maps stack names to their pinned versions
+type Lockfile struct {
+	Stacks map[string]Pin `json:"stacks"`
+}
+
+// Load reads a lockfile. A missing file yields an empty lockfile.
+func Load(path string) (*Lockfile, error) {
+	lf := &Lockfile{Stacks: make(map[string]Pin)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lf, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, lf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if lf.Stacks == nil {
+		lf.Stacks = make(map[string]Pin)
+	}
+	for stack, pin := range lf.Stacks {
+		if pin.Version < 1 {
+			return nil, fmt.Errorf("invalid version %d pinned for stack %s in %s", pin.Version, stack, path)
+		}
+	}
+	return lf, nil
+}
+
+// Save writes the lockfile to path. Keys are sorted so the file diffs cleanly.
+func (lf *Lockfile) Save(path string) error {
+	data, err := json.MarshalIndent(lf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Get returns the pin for a stack
+func (lf *Lockfile) Get(stack string) (Pin, bool) {
+	pin, ok := lf.Stacks[stack]
+	return pin, ok
+}
+
+// Set pins a stack to a version
+func (lf *Lockfile) Set(stack string, pin Pin) {
+	lf.Stacks[stack] = pin
+}
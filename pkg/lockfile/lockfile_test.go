@@ -0,0 +1,76 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadMissing(t *testing.T) {
+	lf, err := Load(filepath.Join(t.TempDir(), DefaultName))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(lf.Stacks) != 0 {
+		t.Errorf("Expected empty lockfile, got %d stacks", len(lf.Stacks))
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultName)
+	pinnedAt := time.Date(2026, 4, 2, 9, 30, 0, 0, time.UTC)
+
+	lf, _ := Load(path)
+	lf.Set("prod", Pin{Version: 38, PinnedAt: pinnedAt, PinnedBy: "alice"})
+	lf.Set("dev", Pin{Version: 12, PinnedAt: pinnedAt})
+	if err := lf.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if strings.Index(string(raw), `"dev"`) > strings.Index(string(raw), `"prod"`) {
+		t.Error("Expected stacks to be written in sorted order")
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pin, ok := loaded.Get("prod")
+	if !ok {
+		t.Fatal("Expected prod to be pinned")
+	}
+	if pin.Version != 38 || pin.PinnedBy != "alice" || !pin.PinnedAt.Equal(pinnedAt) {
+		t.Errorf("Unexpected pin: %+v", pin)
+	}
+	if _, ok := loaded.Get("staging"); ok {
+		t.Error("Expected staging not to be pinned")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"malformed", `{"stacks":`},
+		{"invalid version", `{"stacks":{"prod":{"version":0}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), DefaultName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
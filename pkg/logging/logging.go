@@ -0,0 +1,112 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+// Package logging provides the leveled logger shared by the history and
+// rollback packages.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Level is a logging verbosity level
+type Level int
+
+const (
+	// LevelError logs only errors
+	LevelError Level = iota
+	// LevelWarn also logs recoverable issues
+	LevelWarn
+	// LevelInfo also logs phase boundaries
+	LevelInfo
+	// LevelDebug also logs requests, sizes and per-resource decisions
+	LevelDebug
+)
+
+// String returns the name of the level
+func (l Level) String() string {
+	switch l {
+	case LevelError:
+		return "error"
+	case LevelWarn:
+		return "warn"
+	case LevelInfo:
+		return "info"
+	case LevelDebug:
+		return "debug"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// ParseLevel parses a level name
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "error":
+		return LevelError, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "info":
+		return LevelInfo, nil
+	case "debug":
+		return LevelDebug, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (expected error, warn, info or debug)", name)
+	}
+}
+
+// Logger is a leveled logger
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// WriterLogger writes log messages at or below its level to a writer
+type WriterLogger struct {
+	w     io.Writer
+	level Level
+}
+
+// New returns a logger writing messages at or below level to w
+func New(w io.Writer, level Level) *WriterLogger {
+	return &WriterLogger{w: w, level: level}
+}
+
+// Level returns the logger's level
+func (l *WriterLogger) Level() Level {
+	return l.level
+}
+
+// Debugf logs a debug message
+func (l *WriterLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, "Debug: ", format, args...)
+}
+
+// Infof logs an informational message
+func (l *WriterLogger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, "", format, args...)
+}
+
+// Warnf logs a warning
+func (l *WriterLogger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, "Warning: ", format, args...)
+}
+
+// Errorf logs an error
+func (l *WriterLogger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, "Error: ", format, args...)
+}
+
+func (l *WriterLogger) logf(level Level, prefix, format string, args ...interface{}) {
+	if level > l.level {
+		return
+	}
+	fmt.Fprintf(l.w, prefix+format+"\n", args...)
+}
+
+// Discard is a logger that drops every message
+var Discard Logger = New(io.Discard, LevelError)
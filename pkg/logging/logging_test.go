@@ -0,0 +1,86 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    Level
+		expectError bool
+	}{
+		{"error", LevelError, false},
+		{"warn", LevelWarn, false},
+		{"WARNING", LevelWarn, false},
+		{"info", LevelInfo, false},
+		{"debug", LevelDebug, false},
+		{"trace", 0, true},
+	}
+
+	for _, tt := range tests {
+		level, err := ParseLevel(tt.input)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("ParseLevel(%q): expected error, got nil", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseLevel(%q): unexpected error: %v", tt.input, err)
+		}
+		if level != tt.expected {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.input, level, tt.expected)
+		}
+	}
+}
+
+func TestLevelString(t *testing.T) {
+	for _, level := range []Level{LevelError, LevelWarn, LevelInfo, LevelDebug} {
+		parsed, err := ParseLevel(level.String())
+		if err != nil || parsed != level {
+			t.Errorf("Level %d does not round-trip through String(): %q", level, level.String())
+		}
+	}
+}
+
+func TestWriterLogger_Filtering(t *testing.T) {
+	tests := []struct {
+		level    Level
+		expected []string
+		excluded []string
+	}{
+		{LevelError, []string{"Error: e"}, []string{"Warning: w", "i", "Debug: d"}},
+		{LevelWarn, []string{"Error: e", "Warning: w"}, []string{"Debug: d"}},
+		{LevelInfo, []string{"Error: e", "Warning: w", "i\n"}, []string{"Debug: d"}},
+		{LevelDebug, []string{"Error: e", "Warning: w", "i\n", "Debug: d"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&buf, tt.level)
+			logger.Errorf("e")
+			logger.Warnf("w")
+			logger.Infof("i")
+			logger.Debugf("d")
+
+			out := buf.String()
+			for _, s := range tt.expected {
+				if !strings.Contains(out, s) {
+					t.Errorf("Expected output to contain %q, got %q", s, out)
+				}
+			}
+			for _, s := range tt.excluded {
+				if strings.Contains(out, s) {
+					t.Errorf("Expected output not to contain %q, got %q", s, out)
+				}
+			}
+		})
+	}
+}
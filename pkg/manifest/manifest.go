@@ -0,0 +1,86 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+// Package manifest reads the manifest of a coordinated multi-stack rollback.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Entry is one stack to roll back
+type Entry struct {
+	Stack   string `json:"stack" yaml:"stack"`
+	Version int    `json:"version" yaml:"version"`
+	// Cwd is the stack's project directory, relative to the manifest.
+	// Defaults to the manifest's directory.
+	Cwd string `json:"cwd,omitempty" yaml:"cwd,omitempty"`
+}
+
+// Manifest lists the stacks of a coordinated rollback, in the order they
+// are rolled back
+type Manifest struct {
+	Stacks []Entry `json:"stacks" yaml:"stacks"`
+}
+
+// Load reads and validates a manifest. Files ending in .yaml or .yml are
+// read as YAML, anything else as JSON. Relative Cwd values are resolved
+// against the manifest's directory.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, m)
+	default:
+		err = json.Unmarshal(data, m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for i, e := range m.Stacks {
+		if !filepath.IsAbs(e.Cwd) {
+			m.Stacks[i].Cwd = filepath.Join(dir, e.Cwd)
+		}
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// validate checks that every entry names a stack and version, and that no
+// stack appears twice
+func (m *Manifest) validate() error {
+	if len(m.Stacks) == 0 {
+		return fmt.Errorf("no stacks listed")
+	}
+	seen := make(map[string]bool, len(m.Stacks))
+	for i, e := range m.Stacks {
+		if e.Stack == "" {
+			return fmt.Errorf("entry %d has no stack", i+1)
+		}
+		if e.Version < 1 {
+			return fmt.Errorf("invalid version %d for stack %s", e.Version, e.Stack)
+		}
+		key := e.Cwd + "\x00" + e.Stack
+		if seen[key] {
+			return fmt.Errorf("stack %s is listed more than once", e.Stack)
+		}
+		seen[key] = true
+	}
+	return nil
+}
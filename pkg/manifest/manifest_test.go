@@ -0,0 +1,112 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rollback.json")
+	data := `{"stacks":[
+		{"stack":"network","version":4,"cwd":"network"},
+		{"stack":"app","version":12,"cwd":"/abs/app"},
+		{"stack":"dns","version":2}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(m.Stacks) != 3 {
+		t.Fatalf("Expected 3 stacks, got %d", len(m.Stacks))
+	}
+
+	expected := []string{filepath.Join(dir, "network"), "/abs/app", dir}
+	for i, cwd := range expected {
+		if m.Stacks[i].Cwd != cwd {
+			t.Errorf("Entry %d: expected cwd %s, got %s", i, cwd, m.Stacks[i].Cwd)
+		}
+	}
+	if m.Stacks[1].Version != 12 {
+		t.Errorf("Expected version 12, got %d", m.Stacks[1].Version)
+	}
+}
+
+func TestLoad_YAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rollbacks.yaml")
+	data := `stacks:
+  - stack: network
+    version: 4
+    cwd: network
+  - stack: app
+    version: 12
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Entry{
+		{Stack: "network", Version: 4, Cwd: filepath.Join(dir, "network")},
+		{Stack: "app", Version: 12, Cwd: dir},
+	}
+	if len(m.Stacks) != len(expected) {
+		t.Fatalf("Expected %d stacks, got %d", len(expected), len(m.Stacks))
+	}
+	for i, e := range expected {
+		if m.Stacks[i] != e {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, e, m.Stacks[i])
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.yml")
+	if err := os.WriteFile(bad, []byte("stacks: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(bad); err == nil {
+		t.Error("Expected error for malformed YAML")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "malformed", data: `{"stacks":`},
+		{name: "empty", data: `{"stacks":[]}`},
+		{name: "missing stack", data: `{"stacks":[{"version":1}]}`},
+		{name: "missing version", data: `{"stacks":[{"stack":"app"}]}`},
+		{name: "duplicate", data: `{"stacks":[{"stack":"app","version":1},{"stack":"app","version":2}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rollback.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for a missing manifest")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/concurrent"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// CheckpointStatus reports whether the state of one version can be recovered
+type CheckpointStatus struct {
+	Version   int
+	Available bool
+	// Size is the size of the checkpoint in bytes, when available
+	Size int
+	// Err explains why the checkpoint is missing
+	Err error
+}
+
+// ErrCheckpointVersionMismatch is returned when a checkpoint was not written
+// by the update of the version it was fetched for, e.g. because a provider
+// returned the current state instead
+var ErrCheckpointVersionMismatch = errors.New("checkpoint is not the state of the requested version")
+
+// checkpointClockSkew is how far the time a checkpoint records may lie
+// outside its update, for clock differences between the CLI and the backend
+const checkpointClockSkew = 5 * time.Minute
+
+// AuditCheckpoints tries to fetch the checkpoint of every version in the
+// stack's history, with at most limit fetches in flight, and reports which
+// versions can be rolled back to. A checkpoint only counts as available
+// when it was written during its version's update. Nothing is modified. Statuses are returned
+// in history order, newest first.
+func AuditCheckpoints(ctx context.Context, opts RollbackOptions, limit int) ([]CheckpointStatus, error) {
+	opts = withDefaults(opts)
+	if err := concurrent.ValidateLimit(limit); err != nil {
+		return nil, err
+	}
+
+	stack, err := selectStack(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select stack: %w", err)
+	}
+
+	updates, err := stack.History(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+
+	statuses := make([]CheckpointStatus, len(updates))
+	errs := concurrent.ForEach(ctx, limit, len(updates), func(ctx context.Context, i int) error {
+		version := updates[i].Version
+		opts.Logger.Debugf("fetching checkpoint for version %d", version)
+		checkpoint, err := fetchCheckpoint(ctx, stack, version, opts.CheckpointProvider)
+		if err != nil {
+			return err
+		}
+		if err := checkCheckpointVersion(checkpoint, updates[i]); err != nil {
+			return err
+		}
+		statuses[i] = CheckpointStatus{Version: version, Available: true, Size: len(checkpoint.Deployment)}
+		return nil
+	})
+	for i, err := range errs {
+		if err != nil {
+			statuses[i] = CheckpointStatus{Version: updates[i].Version, Err: err}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// checkCheckpointVersion checks that a checkpoint was written during the
+// update of its version, by the time recorded in its manifest. A
+// checkpoint or update without times cannot be checked and passes.
+func checkCheckpointVersion(checkpoint apitype.UntypedDeployment, update auto.UpdateSummary) error {
+	state, err := parseDeployment(checkpoint)
+	if err != nil {
+		return err
+	}
+	written := state.Manifest.Time
+	if written.IsZero() {
+		return nil
+	}
+
+	if start, ok := history.ParseTimestamp(update.StartTime); ok && written.Before(start.Add(-checkpointClockSkew)) {
+		return fmt.Errorf("%w: version %d started at %s but its checkpoint was written at %s",
+			ErrCheckpointVersionMismatch, update.Version, start.Format(time.RFC3339), written.Format(time.RFC3339))
+	}
+	if update.EndTime != nil {
+		if end, ok := history.ParseTimestamp(*update.EndTime); ok && written.After(end.Add(checkpointClockSkew)) {
+			return fmt.Errorf("%w: version %d ended at %s but its checkpoint was written at %s",
+				ErrCheckpointVersionMismatch, update.Version, end.Format(time.RFC3339), written.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestAuditCheckpoints(t *testing.T) {
+	var exports atomic.Int32
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 3}, {Version: 2}, {Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			// Every other fetch fails
+			if exports.Add(1)%2 == 0 {
+				return apitype.UntypedDeployment{}, errors.New("checkpoint pruned")
+			}
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{CheckpointProvider: exportCheckpoints, StackName: "test", Operator: mockOperator, Output: &output}
+
+	// A limit of 1 keeps the fetch order deterministic
+	statuses, err := AuditCheckpoints(context.Background(), opts, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("Expected 3 statuses, got %d", len(statuses))
+	}
+
+	expected := []struct {
+		version   int
+		available bool
+	}{{3, true}, {2, false}, {1, true}}
+	for i, e := range expected {
+		s := statuses[i]
+		if s.Version != e.version || s.Available != e.available {
+			t.Errorf("Status %d: expected version %d available=%v, got %+v", i, e.version, e.available, s)
+		}
+		if !s.Available && s.Err == nil {
+			t.Errorf("Status %d: expected an error for a missing checkpoint", i)
+		}
+	}
+	if statuses[0].Size != 2 {
+		t.Errorf("Expected size 2, got %d", statuses[0].Size)
+	}
+}
+
+func TestAuditCheckpoints_InvalidLimit(t *testing.T) {
+	if _, err := AuditCheckpoints(context.Background(), RollbackOptions{Operator: &MockStackOperator{}}, 0); err == nil {
+		t.Error("Expected error for invalid limit")
+	}
+}
+
+func TestAuditCheckpoints_WrongVersion(t *testing.T) {
+	end := func(s string) *string { return &s }
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{
+				{Version: 2, StartTime: "2026-10-02T10:00:00Z", EndTime: end("2026-10-02T10:05:00Z")},
+				{Version: 1, StartTime: "2026-10-01T10:00:00Z", EndTime: end("2026-10-01T10:05:00Z")},
+			}, nil
+		},
+	}
+	// The provider serves the current state, written by version 2, for every version
+	provider := &MockCheckpointProvider{
+		CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+			return deployment(`{"manifest": {"time": "2026-10-02T10:04:30Z"}, "resources": []}`), nil
+		},
+	}
+	opts := RollbackOptions{
+		StackName:          "test",
+		Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+		CheckpointProvider: provider,
+		Output:             &bytes.Buffer{},
+	}
+
+	statuses, err := AuditCheckpoints(context.Background(), opts, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !statuses[0].Available {
+		t.Errorf("Expected version 2 to be available, got %+v", statuses[0])
+	}
+	if statuses[1].Available || !errors.Is(statuses[1].Err, ErrCheckpointVersionMismatch) {
+		t.Errorf("Expected version 1 to be unavailable with ErrCheckpointVersionMismatch, got %+v", statuses[1])
+	}
+}
+
+func TestCheckCheckpointVersion(t *testing.T) {
+	end := "2026-10-01T10:05:00Z"
+	update := auto.UpdateSummary{Version: 1, StartTime: "2026-10-01T10:00:00Z", EndTime: &end}
+
+	tests := []struct {
+		name      string
+		written   string
+		update    auto.UpdateSummary
+		expectErr bool
+	}{
+		{name: "during the update", written: "2026-10-01T10:04:00Z", update: update},
+		{name: "within the clock skew", written: "2026-10-01T10:08:00Z", update: update},
+		{name: "after the update", written: "2026-10-02T09:00:00Z", update: update, expectErr: true},
+		{name: "before the update", written: "2026-09-30T10:00:00Z", update: update, expectErr: true},
+		{name: "no manifest time", written: "0001-01-01T00:00:00Z", update: update},
+		{name: "no update times", written: "2026-10-02T09:00:00Z", update: auto.UpdateSummary{Version: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkpoint := deployment(`{"manifest": {"time": "` + tt.written + `"}}`)
+			err := checkCheckpointVersion(checkpoint, tt.update)
+			if tt.expectErr != (err != nil) {
+				t.Errorf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrCheckpointVersionMismatch) {
+				t.Errorf("Expected ErrCheckpointVersionMismatch, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// backupTool marks metadata files written by this tool, so pruning never
+// touches files it did not create
+const backupTool = "pulumi-rollback"
+
+// DefaultBackupDir is where ExecuteRollback saves the state before a
+// rollback when no backup path or directory is given, relative to the
+// project directory
+const DefaultBackupDir = ".pulumi-rollback-backups"
+
+// ProjectBackupDir returns the backup directory of a project: dir when
+// set, otherwise DefaultBackupDir in projectPath
+func ProjectBackupDir(projectPath, dir string) string {
+	if dir != "" {
+		return dir
+	}
+	return filepath.Join(projectPath, DefaultBackupDir)
+}
+
+// writeBackup saves the current state before a rollback changes it, to
+// opts.BackupPath or else to a timestamped file in the backup directory,
+// and returns the file written
+func writeBackup(opts RollbackOptions, deployment apitype.UntypedDeployment, now time.Time) (string, error) {
+	if opts.BackupPath == "" {
+		return WriteBackup(ProjectBackupDir(opts.ProjectPath, opts.BackupDir), opts.StackName, opts.TargetVersion, deployment, now, opts.Compress)
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.BackupPath), 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	path, err := WriteCheckpointFile(opts.BackupPath, deployment, opts.Compress)
+	if err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return path, nil
+}
+
+// backupMetadataSuffix is the suffix of the metadata file written next to
+// each backup checkpoint
+const backupMetadataSuffix = ".meta.json"
+
+// BackupMetadata describes a backup of a stack's state taken before a rollback
+type BackupMetadata struct {
+	Tool          string    `json:"tool"`
+	Stack         string    `json:"stack"`
+	TargetVersion int       `json:"targetVersion"`
+	Created       time.Time `json:"created"`
+	Checkpoint    string    `json:"checkpoint"`
+}
+
+// Backup is a backup found on disk
+type Backup struct {
+	BackupMetadata
+	// CheckpointPath and MetadataPath are the files making up the backup
+	CheckpointPath string
+	MetadataPath   string
+}
+
+// WriteBackup saves the state of a stack to dir before it is rolled back to
+// targetVersion, as plain JSON unless compress is set, and returns the path
+// of the checkpoint file
+func WriteBackup(dir, stackName string, targetVersion int, deployment apitype.UntypedDeployment, now time.Time, compress bool) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	base := fmt.Sprintf("%s-%s", sanitizeFileName(stackName), now.UTC().Format("20060102T150405.000000000Z"))
+	path, err := WriteCheckpointFile(filepath.Join(dir, base+".checkpoint.json"), deployment, compress)
+	if err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	meta := BackupMetadata{
+		Tool:          backupTool,
+		Stack:         stackName,
+		TargetVersion: targetVersion,
+		Created:       now.UTC(),
+		Checkpoint:    filepath.Base(path),
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, base+backupMetadataSuffix), data, 0600); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write backup metadata: %w", err)
+	}
+	return path, nil
+}
+
+// ListBackups returns the backups in dir created by this tool, newest first.
+// A missing directory has no backups.
+func ListBackups(dir string) ([]Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), backupMetadataSuffix) {
+			continue
+		}
+		metaPath := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(metaPath)
+		if err != nil {
+			return nil, err
+		}
+
+		var meta BackupMetadata
+		if err := json.Unmarshal(data, &meta); err != nil || meta.Tool != backupTool {
+			continue
+		}
+		// The checkpoint must live next to its metadata
+		if meta.Checkpoint == "" || filepath.Base(meta.Checkpoint) != meta.Checkpoint {
+			continue
+		}
+
+		backups = append(backups, Backup{
+			BackupMetadata: meta,
+			CheckpointPath: filepath.Join(dir, meta.Checkpoint),
+			MetadataPath:   metaPath,
+		})
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Created.After(backups[j].Created)
+	})
+	return backups, nil
+}
+
+// SelectPrunable returns the backups that may be removed: those beyond the
+// keep most recent backups of their stack that are also older than olderThan.
+// backups must be sorted newest first, as returned by ListBackups.
+func SelectPrunable(backups []Backup, keep int, olderThan time.Duration, now time.Time) []Backup {
+	seen := make(map[string]int)
+	var prunable []Backup
+	for _, b := range backups {
+		seen[b.Stack]++
+		if seen[b.Stack] <= keep {
+			continue
+		}
+		if now.Sub(b.Created) <= olderThan {
+			continue
+		}
+		prunable = append(prunable, b)
+	}
+	return prunable
+}
+
+// RemoveBackup deletes a backup's checkpoint and metadata files. The
+// metadata is removed last so a partial failure is retried on the next prune.
+func RemoveBackup(b Backup) error {
+	if err := os.Remove(b.CheckpointPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(b.MetadataPath)
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names, such
+// as the slashes in fully qualified stack names
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
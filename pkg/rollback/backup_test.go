@@ -0,0 +1,265 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// TestMain runs the tests in a temporary directory, which is where
+// rollbacks without a ProjectPath write their default backups
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "pulumi-rollback-test-")
+	if err == nil {
+		err = os.Chdir(dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestWriteAndListBackups(t *testing.T) {
+	dir := t.TempDir()
+	deployment := apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	for i, stack := range []string{"org/proj/dev", "prod", "prod"} {
+		if _, err := WriteBackup(dir, stack, i+1, deployment, now.Add(time.Duration(i)*time.Hour), false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	// Files not written by this tool are ignored
+	if err := os.WriteFile(filepath.Join(dir, "other.meta.json"), []byte(`{"tool":"other"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := ListBackups(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("Expected 3 backups, got %d", len(backups))
+	}
+	if backups[0].TargetVersion != 3 || backups[2].Stack != "org/proj/dev" {
+		t.Errorf("Expected backups newest first, got %+v", backups)
+	}
+
+	restored, err := ReadCheckpointFile(backups[0].CheckpointPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restored.Version != 3 {
+		t.Errorf("Expected version 3, got %d", restored.Version)
+	}
+
+	if err := RemoveBackup(backups[0]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	backups, _ = ListBackups(dir)
+	if len(backups) != 2 {
+		t.Errorf("Expected 2 backups after removal, got %d", len(backups))
+	}
+}
+
+func TestListBackupsMissingDir(t *testing.T) {
+	backups, err := ListBackups(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("Expected no backups, got %d", len(backups))
+	}
+}
+
+func TestSelectPrunable(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	backup := func(stack string, age time.Duration) Backup {
+		return Backup{BackupMetadata: BackupMetadata{Stack: stack, Created: now.Add(-age)}}
+	}
+
+	// Newest first, as returned by ListBackups
+	backups := []Backup{
+		backup("dev", 1*day),
+		backup("prod", 2*day),
+		backup("dev", 40*day),
+		backup("prod", 45*day),
+		backup("dev", 50*day),
+	}
+
+	tests := []struct {
+		name      string
+		keep      int
+		olderThan time.Duration
+		expected  int
+	}{
+		{"keep one per stack", 1, 30 * day, 3},
+		{"keep two per stack", 2, 30 * day, 1},
+		{"age threshold protects newer", 0, 30 * day, 3},
+		{"nothing old enough", 0, 60 * day, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prunable := SelectPrunable(backups, tt.keep, tt.olderThan, now)
+			if len(prunable) != tt.expected {
+				t.Errorf("Expected %d prunable backups, got %d", tt.expected, len(prunable))
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_WritesBackup(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			return auto.UpResult{}, errors.New("up failed")
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	dir := t.TempDir()
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		BackupDir:          dir,
+	}
+
+	// The backup is written before the stack is changed, even if the rollback fails
+	_, err := ExecuteRollback(context.Background(), opts)
+	var rbErr *RollbackError
+	if !errors.As(err, &rbErr) {
+		t.Fatalf("Expected a RollbackError, got %v", err)
+	}
+	if rbErr.Phase != PhaseUp {
+		t.Errorf("Expected phase %q, got %q", PhaseUp, rbErr.Phase)
+	}
+
+	backups, err := ListBackups(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %d", len(backups))
+	}
+	if backups[0].Stack != "test" || backups[0].TargetVersion != 1 {
+		t.Errorf("Unexpected backup metadata: %+v", backups[0].BackupMetadata)
+	}
+	if rbErr.BackupFile != backups[0].CheckpointPath {
+		t.Errorf("Expected backup path %q in error, got %q", backups[0].CheckpointPath, rbErr.BackupFile)
+	}
+}
+
+func TestExecuteRollback_DefaultBackup(t *testing.T) {
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return &MockRollbackStack{}, nil
+		},
+	}
+	project := t.TempDir()
+	opts := RollbackOptions{
+		StackName:          "prod",
+		ProjectPath:        project,
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &bytes.Buffer{},
+	}
+
+	result, err := ExecuteRollback(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	backups, err := ListBackups(filepath.Join(project, DefaultBackupDir))
+	if err != nil || len(backups) != 1 || result.BackupFile != backups[0].CheckpointPath {
+		t.Errorf("Expected the backup %s in the project directory, got %v (%v)", result.BackupFile, backups, err)
+	}
+
+	opts.BackupPath = filepath.Join(t.TempDir(), "state", "before.json")
+	result, err = ExecuteRollback(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.BackupFile != opts.BackupPath {
+		t.Errorf("Expected the backup at %s, got %s", opts.BackupPath, result.BackupFile)
+	}
+	if _, err := ReadCheckpointFile(opts.BackupPath); err != nil {
+		t.Errorf("Expected a readable backup: %v", err)
+	}
+
+	opts.BackupPath = ""
+	opts.NoBackup = true
+	result, err = ExecuteRollback(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.BackupFile != "" {
+		t.Errorf("Expected no backup, got %s", result.BackupFile)
+	}
+}
+
+func TestWriteBackup_Compress(t *testing.T) {
+	deployment := apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		compress bool
+		suffix   string
+		gzipped  bool
+	}{
+		{name: "plain by default", suffix: ".checkpoint.json"},
+		{name: "compressed", compress: true, suffix: ".checkpoint.json.gz", gzipped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := WriteBackup(t.TempDir(), "prod", 1, deployment, now, tt.compress)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.HasSuffix(path, tt.suffix) {
+				t.Errorf("Expected a %s file, got %s", tt.suffix, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gzipped := len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b; gzipped != tt.gzipped {
+				t.Errorf("Expected gzipped %v, got %v", tt.gzipped, gzipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/concurrent"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/logging"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// RollbackSpec is one stack of a multi-stack rollback
+type RollbackSpec struct {
+	StackName     string
+	ProjectPath   string
+	TargetVersion int
+}
+
+// ErrBatchPreviewFailed is returned by BatchRollback when a stack cannot be
+// rolled back, before any stack is changed
+var ErrBatchPreviewFailed = errors.New("batch preview failed")
+
+// ErrBatchAborted is returned by BatchRollback when the rollback of a stack
+// failed and the stacks already rolled back were restored
+var ErrBatchAborted = errors.New("batch rollback aborted")
+
+// BatchPreview is the preview of one stack of a multi-stack rollback
+type BatchPreview struct {
+	Target RollbackSpec
+	Result *RollbackResult
+	// Err is set when the stack cannot be rolled back
+	Err error
+	// Log holds the preview's progress output
+	Log string
+}
+
+// BatchSummary aggregates the previews of a multi-stack rollback
+type BatchSummary struct {
+	// Changes totals the resource changes of every previewed stack
+	Changes map[string]int
+	// Deletes is the total number of resources that would be deleted
+	Deletes int
+	// Failed lists the stacks that cannot be rolled back, in input order
+	Failed []string
+}
+
+// PreviewBatch previews the rollback of every target without executing any,
+// with at most limit previews in flight. opts supplies the shared options;
+// its stack, project and version are replaced by each target's. Previews
+// are returned in input order and a failing stack does not stop the others.
+func PreviewBatch(ctx context.Context, targets []RollbackSpec, opts RollbackOptions, limit int) ([]BatchPreview, error) {
+	opts = withDefaults(opts)
+	if err := concurrent.ValidateLimit(limit); err != nil {
+		return nil, err
+	}
+
+	previews := make([]BatchPreview, len(targets))
+	errs := concurrent.ForEach(ctx, limit, len(targets), func(ctx context.Context, i int) error {
+		target := targets[i]
+
+		// Concurrent previews would interleave their output, so each gets its own
+		var log bytes.Buffer
+		stackOpts := opts
+		stackOpts.StackName = target.StackName
+		stackOpts.ProjectPath = target.ProjectPath
+		stackOpts.TargetVersion = target.TargetVersion
+		stackOpts.DryRun = true
+		stackOpts.Output = &log
+		stackOpts.Logger = logging.New(&log, logging.LevelInfo)
+		if opts.Verbose {
+			stackOpts.Logger = logging.New(&log, logging.LevelDebug)
+		}
+
+		result, err := PreviewRollback(ctx, stackOpts)
+		previews[i] = BatchPreview{Target: target, Result: result, Log: log.String()}
+		return err
+	})
+	for i, err := range errs {
+		previews[i].Target = targets[i]
+		previews[i].Err = err
+	}
+	return previews, nil
+}
+
+// SummarizeBatch aggregates batch previews
+func SummarizeBatch(previews []BatchPreview) BatchSummary {
+	summary := BatchSummary{Changes: make(map[string]int)}
+	for _, p := range previews {
+		if p.Err != nil {
+			summary.Failed = append(summary.Failed, p.Target.StackName)
+			continue
+		}
+		for op, count := range p.Result.ResourceChanges {
+			summary.Changes[op] += count
+		}
+	}
+	summary.Deletes = summary.Changes[string(apitype.OpDelete)]
+	return summary
+}
+
+// BatchOptions configures BatchRollback
+type BatchOptions struct {
+	// Rollback supplies the options shared by every stack, as for
+	// PreviewBatch. Backups are required: the batch is undone from the
+	// backup of each stack, so NoBackup and a shared BackupPath are refused.
+	Rollback RollbackOptions
+	// Limit is the maximum number of previews in flight
+	Limit int
+	// Confirm, when set, is called once every stack previewed successfully.
+	// Returning false cancels the batch before any stack is changed.
+	Confirm func(previews []BatchPreview, summary BatchSummary) (bool, error)
+}
+
+// BatchExecution is the rollback of one stack by BatchRollback
+type BatchExecution struct {
+	Target RollbackSpec
+	// PreviousVersion is the stack's latest version before its rollback
+	PreviousVersion int
+	Result          *RollbackResult
+	Err             error
+	// Restored is set when the stack was put back to the state of
+	// PreviousVersion after the batch failed. RestoreErr is set when that
+	// failed too.
+	Restored   bool
+	RestoreErr error
+}
+
+// BatchResult is the outcome of BatchRollback
+type BatchResult struct {
+	Previews []BatchPreview
+	Summary  BatchSummary
+	// Executions lists the stacks rolled back, in order, up to and
+	// including the one that failed
+	Executions []BatchExecution
+	// Cancelled is set when Confirm declined the batch
+	Cancelled bool
+}
+
+// BatchRollback rolls back every spec as one transaction. All stacks are
+// previewed first, and nothing is changed unless every preview succeeds.
+// The stacks are then rolled back one at a time in order. If one fails,
+// it and every stack already rolled back are restored from their backups,
+// in reverse order, and the error wraps ErrBatchAborted. A restore rolls
+// the stack forward to its previous version using the backup as the
+// checkpoint, so its infrastructure is put back too.
+func BatchRollback(ctx context.Context, specs []RollbackSpec, opts BatchOptions) (*BatchResult, error) {
+	if opts.Rollback.NoBackup || opts.Rollback.BackupPath != "" {
+		return nil, errors.New("a batch rollback needs a backup of each stack to undo applied stacks from; set BackupDir instead of NoBackup or BackupPath")
+	}
+
+	previews, err := PreviewBatch(ctx, specs, opts.Rollback, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+	result := &BatchResult{Previews: previews, Summary: SummarizeBatch(previews)}
+	if len(result.Summary.Failed) > 0 {
+		return result, fmt.Errorf("%w: %d of %d stack(s) cannot be rolled back",
+			ErrBatchPreviewFailed, len(result.Summary.Failed), len(specs))
+	}
+	if opts.Confirm != nil {
+		confirmed, err := opts.Confirm(previews, result.Summary)
+		if err != nil {
+			return result, err
+		}
+		if !confirmed {
+			result.Cancelled = true
+			return result, nil
+		}
+	}
+
+	shared := withDefaults(opts.Rollback)
+	for i, spec := range specs {
+		stackOpts := batchStackOptions(shared, spec)
+		execution := BatchExecution{Target: spec}
+		execution.PreviousVersion, err = latestVersion(ctx, stackOpts)
+		if err == nil {
+			shared.Logger.Infof("[%d/%d] Rolling back stack '%s' to version %d...", i+1, len(specs), spec.StackName, spec.TargetVersion)
+			execution.Result, err = ExecuteRollback(ctx, stackOpts)
+		}
+		execution.Err = err
+		result.Executions = append(result.Executions, execution)
+		if err != nil {
+			undoBatch(ctx, result.Executions, shared)
+			return result, fmt.Errorf("%w: rollback of stack %s failed: %w", ErrBatchAborted, spec.StackName, err)
+		}
+	}
+	return result, nil
+}
+
+// batchStackOptions returns the options of one target of a batch
+func batchStackOptions(opts RollbackOptions, target RollbackSpec) RollbackOptions {
+	opts.StackName = target.StackName
+	opts.ProjectPath = target.ProjectPath
+	opts.TargetVersion = target.TargetVersion
+	return opts
+}
+
+// latestVersion returns the latest version of a stack, 0 without history
+func latestVersion(ctx context.Context, opts RollbackOptions) (int, error) {
+	stack, err := selectStack(ctx, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select stack: %w", err)
+	}
+	updates, err := stack.History(ctx, 1, 1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get history: %w", err)
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	return updates[0].Version, nil
+}
+
+// batchRestoreTimeout bounds the restore of one stack of a failed batch,
+// which runs a refresh and up of its own
+const batchRestoreTimeout = 30 * time.Minute
+
+// undoBatch restores the stacks of a failed batch, newest first. A stack
+// whose rollback failed before the import was not changed and is skipped.
+// The batch most often fails because ctx was cancelled or timed out, so the
+// restores do not inherit its cancellation.
+func undoBatch(ctx context.Context, executions []BatchExecution, opts RollbackOptions) {
+	for i := len(executions) - 1; i >= 0; i-- {
+		execution := &executions[i]
+		backupPath, changed := batchBackup(execution)
+		if !changed {
+			continue
+		}
+		if backupPath == "" {
+			execution.RestoreErr = errors.New("no backup of the state before the rollback")
+		} else {
+			opts.Logger.Infof("Restoring stack '%s' to version %d from %s...", execution.Target.StackName, execution.PreviousVersion, backupPath)
+			restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), batchRestoreTimeout)
+			execution.RestoreErr = restoreFromBackup(restoreCtx, opts, execution, backupPath)
+			cancel()
+		}
+		if execution.RestoreErr != nil {
+			opts.Logger.Errorf("failed to restore stack %s: %v", execution.Target.StackName, execution.RestoreErr)
+			continue
+		}
+		execution.Restored = true
+	}
+}
+
+// batchBackup returns the backup of a stack rolled back by a batch, and
+// whether its rollback got far enough to change the stack
+func batchBackup(execution *BatchExecution) (string, bool) {
+	if execution.Err == nil {
+		return execution.Result.BackupFile, true
+	}
+	var rbErr *RollbackError
+	if !errors.As(execution.Err, &rbErr) || rbErr.Phase < PhaseImport || errors.Is(rbErr, ErrStackBusy) {
+		return "", false
+	}
+	return rbErr.BackupFile, true
+}
+
+// restoreFromBackup rolls a stack to its previous version, using the backup
+// taken before its rollback as the checkpoint
+func restoreFromBackup(ctx context.Context, opts RollbackOptions, execution *BatchExecution, backupPath string) error {
+	backup, err := ReadCheckpointFile(backupPath)
+	if err != nil {
+		return err
+	}
+
+	restoreOpts := batchStackOptions(opts, execution.Target)
+	restoreOpts.TargetVersion = execution.PreviousVersion
+	restoreOpts.CheckpointProvider = staticCheckpoint{deployment: backup}
+	restoreOpts.TransformCheckpoint = nil
+	// The batch changed the infrastructure on purpose, and the backup holds
+	// whatever the stack had, including resources the batch orphaned
+	restoreOpts.MaxRefreshDrift = 0
+	restoreOpts.AllowEmpty = true
+	restoreOpts.OrphanNewResources = false
+	// The stack is put back to the state already saved in the backup
+	restoreOpts.NoBackup = true
+	_, err = ExecuteRollback(ctx, restoreOpts)
+	return err
+}
+
+// staticCheckpoint is a CheckpointProvider that returns the same
+// deployment for every version
+type staticCheckpoint struct {
+	deployment apitype.UntypedDeployment
+}
+
+func (c staticCheckpoint) CheckpointAt(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+	return c.deployment, nil
+}
@@ -0,0 +1,254 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestPreviewBatch(t *testing.T) {
+	previewed := map[string]bool{}
+	newStack := func(changes map[apitype.OpType]int) *MockRollbackStack {
+		return &MockRollbackStack{
+			HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+				return []auto.UpdateSummary{{Version: 12}, {Version: 4}}, nil
+			},
+			ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+				return deployment(`{}`), nil
+			},
+			PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+				return auto.PreviewResult{ChangeSummary: changes}, nil
+			},
+		}
+	}
+	stacks := map[string]*MockRollbackStack{
+		"network": newStack(map[apitype.OpType]int{apitype.OpUpdate: 1, apitype.OpDelete: 2}),
+		"app":     newStack(map[apitype.OpType]int{apitype.OpCreate: 3, apitype.OpDelete: 1}),
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			stack, ok := stacks[stackName]
+			if !ok {
+				return nil, errors.New("stack not found")
+			}
+			previewed[stackName] = true
+			return stack, nil
+		},
+	}
+
+	targets := []RollbackSpec{
+		{StackName: "network", TargetVersion: 4},
+		{StackName: "missing", TargetVersion: 2},
+		{StackName: "app", TargetVersion: 12},
+	}
+	// A limit of 1 keeps the previews sequential for the unsynchronized map
+	previews, err := PreviewBatch(context.Background(), targets, RollbackOptions{CheckpointProvider: exportCheckpoints, Operator: mockOperator}, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(previews) != 3 {
+		t.Fatalf("Expected 3 previews, got %d", len(previews))
+	}
+	if previews[1].Err == nil || previews[1].Target.StackName != "missing" {
+		t.Errorf("Expected the missing stack to fail, got %+v", previews[1])
+	}
+	if previews[2].Err != nil || previews[2].Target.TargetVersion != 12 {
+		t.Errorf("Expected app to be previewed at version 12, got %+v", previews[2])
+	}
+	if !previewed["network"] || !previewed["app"] {
+		t.Error("Expected a failing stack not to stop the others")
+	}
+
+	summary := SummarizeBatch(previews)
+	if summary.Deletes != 3 {
+		t.Errorf("Expected 3 deletes, got %d", summary.Deletes)
+	}
+	if summary.Changes["create"] != 3 || summary.Changes["update"] != 1 {
+		t.Errorf("Unexpected totals: %v", summary.Changes)
+	}
+	if len(summary.Failed) != 1 || summary.Failed[0] != "missing" {
+		t.Errorf("Expected [missing] to fail, got %v", summary.Failed)
+	}
+}
+
+// batchStacks returns an operator over mock stacks at version 12 whose up
+// returns the error of upErr, when set, and records the stacks applied
+func batchStacks(names []string, upErr func(ctx context.Context, name string) error, applied *[]string) *MockStackOperator {
+	stacks := map[string]*MockRollbackStack{}
+	for _, name := range names {
+		name := name
+		stacks[name] = &MockRollbackStack{
+			HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+				return []auto.UpdateSummary{{Version: 12}, {Version: 4}}, nil
+			},
+			UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+				*applied = append(*applied, name)
+				if upErr != nil {
+					return auto.UpResult{}, upErr(ctx, name)
+				}
+				return auto.UpResult{}, nil
+			},
+		}
+	}
+	return &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			stack, ok := stacks[stackName]
+			if !ok {
+				return nil, errors.New("stack not found")
+			}
+			return stack, nil
+		},
+	}
+}
+
+func TestBatchRollback(t *testing.T) {
+	targets := []RollbackSpec{
+		{StackName: "network", TargetVersion: 4},
+		{StackName: "app", TargetVersion: 4},
+		{StackName: "web", TargetVersion: 4},
+	}
+
+	tests := []struct {
+		name     string
+		failUp   map[string]bool
+		applied  []string
+		err      error
+		restored []bool
+	}{
+		{name: "all succeed", applied: []string{"network", "app", "web"}, restored: []bool{false, false, false}},
+		{
+			name:   "failure restores the applied stacks newest first",
+			failUp: map[string]bool{"app": true},
+			// network and app are applied, then app and network restored
+			applied:  []string{"network", "app", "app", "network"},
+			err:      ErrBatchAborted,
+			restored: []bool{true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var applied []string
+			failUp := tt.failUp
+			operator := batchStacks([]string{"network", "app", "web"}, func(ctx context.Context, name string) error {
+				if failUp[name] {
+					failUp[name] = false
+					return errors.New("up failed")
+				}
+				return nil
+			}, &applied)
+			opts := BatchOptions{Rollback: RollbackOptions{CheckpointProvider: exportCheckpoints, Operator: operator, BackupDir: t.TempDir(), Output: &bytes.Buffer{}}, Limit: 1}
+
+			result, err := BatchRollback(context.Background(), targets, opts)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(applied, tt.applied) {
+				t.Errorf("Expected ups of %v, got %v", tt.applied, applied)
+			}
+			if len(result.Executions) != len(tt.restored) {
+				t.Fatalf("Expected %d execution(s), got %d", len(tt.restored), len(result.Executions))
+			}
+			for i, e := range result.Executions {
+				if e.Restored != tt.restored[i] || e.RestoreErr != nil {
+					t.Errorf("Expected %s restored %v, got %v (%v)", e.Target.StackName, tt.restored[i], e.Restored, e.RestoreErr)
+				}
+				if e.PreviousVersion != 12 {
+					t.Errorf("Expected previous version 12 for %s, got %d", e.Target.StackName, e.PreviousVersion)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchRollback_NothingChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []RollbackSpec
+		confirm bool
+		err     error
+	}{
+		{name: "preview fails", targets: []RollbackSpec{{StackName: "network", TargetVersion: 4}, {StackName: "missing", TargetVersion: 4}}, confirm: true, err: ErrBatchPreviewFailed},
+		{name: "not confirmed", targets: []RollbackSpec{{StackName: "network", TargetVersion: 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var applied []string
+			operator := batchStacks([]string{"network"}, nil, &applied)
+			result, err := BatchRollback(context.Background(), tt.targets, BatchOptions{
+				Rollback: RollbackOptions{CheckpointProvider: exportCheckpoints, Operator: operator, BackupDir: t.TempDir(), Output: &bytes.Buffer{}},
+				Limit:    1,
+				Confirm: func(previews []BatchPreview, summary BatchSummary) (bool, error) {
+					return tt.confirm, nil
+				},
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if len(applied) != 0 || len(result.Executions) != 0 {
+				t.Errorf("Expected no stack to be changed, got ups of %v", applied)
+			}
+			if result.Cancelled != !tt.confirm {
+				t.Errorf("Expected Cancelled %v, got %v", !tt.confirm, result.Cancelled)
+			}
+		})
+	}
+}
+
+func TestBatchRollback_Interrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Ctrl-C arrives while app is being applied
+	interrupted := false
+	var applied []string
+	operator := batchStacks([]string{"network", "app", "web"}, func(upCtx context.Context, name string) error {
+		if name == "app" && !interrupted {
+			interrupted = true
+			cancel()
+			return upCtx.Err()
+		}
+		return upCtx.Err()
+	}, &applied)
+
+	specs := []RollbackSpec{
+		{StackName: "network", TargetVersion: 4},
+		{StackName: "app", TargetVersion: 4},
+		{StackName: "web", TargetVersion: 4},
+	}
+	result, err := BatchRollback(ctx, specs, BatchOptions{
+		Rollback: RollbackOptions{CheckpointProvider: exportCheckpoints, Operator: operator, BackupDir: t.TempDir(), Output: &bytes.Buffer{}},
+		Limit:    1,
+	})
+	if !errors.Is(err, ErrBatchAborted) {
+		t.Fatalf("Expected ErrBatchAborted, got %v", err)
+	}
+	if expected := []string{"network", "app", "app", "network"}; !reflect.DeepEqual(applied, expected) {
+		t.Errorf("Expected ups of %v, got %v", expected, applied)
+	}
+	for _, e := range result.Executions {
+		if !e.Restored || e.RestoreErr != nil {
+			t.Errorf("Expected %s to be restored despite the cancellation, got %v (%v)", e.Target.StackName, e.Restored, e.RestoreErr)
+		}
+	}
+}
+
+func TestBatchRollback_RequiresBackups(t *testing.T) {
+	for _, opts := range []RollbackOptions{{NoBackup: true}, {BackupPath: "state.json"}} {
+		if _, err := BatchRollback(context.Background(), nil, BatchOptions{Rollback: opts}); err == nil {
+			t.Errorf("Expected an error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// ErrStackBusy is returned when another update of the stack is running or
+// the stack is locked. Importing a state underneath it corrupts the stack.
+var ErrStackBusy = errors.New("stack has an update in progress")
+
+// IsStackBusy reports whether the most recent update in a history, newest
+// first, has not finished
+func IsStackBusy(history []auto.UpdateSummary) bool {
+	return len(history) > 0 && history[0].Result == "in-progress"
+}
+
+// checkStackBusy refuses to go on while another update of the stack is in
+// progress. With opts.IgnoreBusy it only warns.
+func checkStackBusy(ctx context.Context, stack RollbackStack, opts RollbackOptions) error {
+	history, err := stack.History(ctx, 1, 1)
+	if err != nil {
+		return fmt.Errorf("failed to check for an update in progress: %w", err)
+	}
+	if !IsStackBusy(history) {
+		return nil
+	}
+
+	latest := history[0]
+	if opts.IgnoreBusy {
+		opts.Logger.Warnf("update %d (%s, started %s) is still in progress; continuing because of IgnoreBusy",
+			latest.Version, latest.Kind, latest.StartTime)
+		return nil
+	}
+	return fmt.Errorf("%w: update %d (%s, started %s) has not finished",
+		ErrStackBusy, latest.Version, latest.Kind, latest.StartTime)
+}
+
+// stackLockedError wraps an import failure caused by another update holding
+// the stack's lock in ErrStackBusy
+func stackLockedError(err error) error {
+	if auto.IsConcurrentUpdateError(err) {
+		return fmt.Errorf("%w: the stack is locked by another update: %v", ErrStackBusy, err)
+	}
+	return err
+}
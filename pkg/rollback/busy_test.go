@@ -0,0 +1,103 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestIsStackBusy(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  []auto.UpdateSummary
+		expected bool
+	}{
+		{name: "empty history", history: nil, expected: false},
+		{name: "latest succeeded", history: []auto.UpdateSummary{{Version: 2, Result: "succeeded"}, {Version: 1, Result: "in-progress"}}, expected: false},
+		{name: "latest failed", history: []auto.UpdateSummary{{Version: 2, Result: "failed"}}, expected: false},
+		{name: "latest in progress", history: []auto.UpdateSummary{{Version: 2, Result: "in-progress"}, {Version: 1, Result: "succeeded"}}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStackBusy(tt.history); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStackBusyGuard(t *testing.T) {
+	tests := []struct {
+		name       string
+		preview    bool
+		force      bool
+		ignoreBusy bool
+		expectErr  bool
+	}{
+		{name: "rollback refuses", expectErr: true},
+		{name: "rollback with force refuses", force: true, expectErr: true},
+		{name: "rollback ignoring busy proceeds", ignoreBusy: true},
+		{name: "preview refuses", preview: true, expectErr: true},
+		{name: "preview with force refuses", preview: true, force: true, expectErr: true},
+		{name: "preview ignoring busy proceeds", preview: true, ignoreBusy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := 0
+			mockStack := &MockRollbackStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					return []auto.UpdateSummary{{Version: 4, Kind: "update", Result: "in-progress"}, {Version: 3, Result: "succeeded"}}, nil
+				},
+				ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+					imports++
+					return nil
+				},
+			}
+			opts := RollbackOptions{
+				CheckpointProvider: exportCheckpoints,
+				StackName:          "dev",
+				TargetVersion:      3,
+				Force:              tt.force,
+				IgnoreBusy:         tt.ignoreBusy,
+				Output:             &bytes.Buffer{},
+				Operator: &MockStackOperator{
+					SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+						return mockStack, nil
+					},
+				},
+			}
+
+			var err error
+			if tt.preview {
+				_, err = PreviewRollback(context.Background(), opts)
+			} else {
+				_, err = ExecuteRollback(context.Background(), opts)
+			}
+
+			if tt.expectErr {
+				if !errors.Is(err, ErrStackBusy) {
+					t.Fatalf("Expected ErrStackBusy, got %v", err)
+				}
+				if imports != 0 {
+					t.Errorf("Expected no import while the stack is busy, got %d", imports)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if imports == 0 {
+				t.Error("Expected the target state to be imported")
+			}
+		})
+	}
+}
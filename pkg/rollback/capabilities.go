@@ -0,0 +1,63 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+// CapabilitiesSchemaVersion is incremented when fields are removed from
+// CapabilitySet or change meaning. Adding fields does not change it.
+const CapabilitiesSchemaVersion = 1
+
+// CapabilitySet describes what this build of the tool supports, so scripts
+// and embedders can adapt to the installed version
+type CapabilitySet struct {
+	SchemaVersion int `json:"schemaVersion"`
+	// Backends lists the state backends checkpoints can be fetched from
+	Backends []string `json:"backends"`
+	// OutputFormats lists the values accepted by --output
+	OutputFormats []string `json:"outputFormats"`
+	// PreviewModes lists the values accepted by preview --mode
+	PreviewModes []string `json:"previewModes"`
+	// Locking reports whether the tool locks a stack against concurrent
+	// rollbacks. The backend's own update lock always applies.
+	Locking bool `json:"locking"`
+	// Features lists optional features by name
+	Features []string `json:"features"`
+}
+
+// Capabilities returns the capabilities of this build
+func Capabilities() CapabilitySet {
+	return CapabilitySet{
+		SchemaVersion: CapabilitiesSchemaVersion,
+		Backends:      []string{"pulumi-cloud", "s3", "azblob", "gcs", "file"},
+		OutputFormats: []string{"text", "json"},
+		PreviewModes:  []string{string(PreviewModeStateOnly), string(PreviewModeLive)},
+		Locking:       false,
+		Features: []string{
+			"audit-checkpoints",
+			"backups",
+			"config-rollback",
+			"dump-states",
+			"expectations",
+			"import-verification",
+			"orphan-new-resources",
+			"pinned-versions",
+			"provenance",
+			"reencrypt-secrets",
+			"remote-stacks",
+			"result-file",
+			"type-targets",
+			"verify-preview",
+			"watch",
+		},
+	}
+}
+
+// HasFeature reports whether the named feature is supported
+func (c CapabilitySet) HasFeature(name string) bool {
+	for _, f := range c.Features {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import "testing"
+
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities()
+	if caps.SchemaVersion != CapabilitiesSchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", CapabilitiesSchemaVersion, caps.SchemaVersion)
+	}
+	for _, mode := range caps.PreviewModes {
+		if _, err := ParsePreviewMode(mode); err != nil {
+			t.Errorf("Preview mode %q is not accepted: %v", mode, err)
+		}
+	}
+	if !caps.HasFeature("backups") {
+		t.Error("Expected backups to be supported")
+	}
+	if caps.HasFeature("time-travel") {
+		t.Error("Expected unknown feature to be unsupported")
+	}
+}
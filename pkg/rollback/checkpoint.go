@@ -0,0 +1,275 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// ErrPendingOperations is returned when a checkpoint contains operations left
+// behind by an interrupted update
+var ErrPendingOperations = errors.New("checkpoint has pending operations")
+
+// ErrEmptyCheckpoint is returned when the target checkpoint has no
+// resources and AllowEmpty is not set
+var ErrEmptyCheckpoint = errors.New("target checkpoint has no resources")
+
+// ErrStackMismatch is returned when the target checkpoint's resources belong
+// to a different stack than the one being rolled back
+var ErrStackMismatch = errors.New("checkpoint belongs to a different stack")
+
+// CheckpointHash returns the hex encoded SHA-256 of a deployment's bytes,
+// which identifies the checkpoint a rollback used in audit records
+func CheckpointHash(deployment apitype.UntypedDeployment) string {
+	sum := sha256.Sum256(deployment.Deployment)
+	return hex.EncodeToString(sum[:])
+}
+
+// PendingOp describes an operation that was in flight when a checkpoint was written
+type PendingOp struct {
+	Type string
+	URN  string
+}
+
+// String returns a human readable description of the pending operation
+func (p PendingOp) String() string {
+	return fmt.Sprintf("%s %s", p.Type, p.URN)
+}
+
+// parseDeployment decodes the resource-level structure of a deployment.
+// A rollback parses each checkpoint once and passes the result to its checks.
+func parseDeployment(deployment apitype.UntypedDeployment) (*apitype.DeploymentV3, error) {
+	var state apitype.DeploymentV3
+	if err := json.Unmarshal(deployment.Deployment, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment: %w", err)
+	}
+	return &state, nil
+}
+
+// HasPendingOperations returns the pending operations recorded in a deployment
+func HasPendingOperations(deployment apitype.UntypedDeployment) ([]PendingOp, error) {
+	state, err := parseDeployment(deployment)
+	if err != nil {
+		return nil, err
+	}
+	return pendingOperations(state), nil
+}
+
+// pendingOperations returns the pending operations recorded in a parsed deployment
+func pendingOperations(state *apitype.DeploymentV3) []PendingOp {
+	var ops []PendingOp
+	for _, op := range state.PendingOperations {
+		ops = append(ops, PendingOp{
+			Type: string(op.Type),
+			URN:  string(op.Resource.URN),
+		})
+	}
+	return ops
+}
+
+// checkPendingOperations reports pending operations in a checkpoint. When
+// refuse is set it returns ErrPendingOperations, otherwise it only warns.
+func checkPendingOperations(state *apitype.DeploymentV3, label string, refuse bool, opts RollbackOptions) error {
+	ops := pendingOperations(state)
+	if len(ops) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(ops))
+	for i, op := range ops {
+		lines[i] = "  " + op.String()
+	}
+	opts.Logger.Warnf("the %s state has %d pending operation(s) from an interrupted update:\n%s",
+		label, len(ops), strings.Join(lines, "\n"))
+
+	if refuse {
+		return fmt.Errorf("%w: %s state has %d pending operation(s)", ErrPendingOperations, label, len(ops))
+	}
+	return nil
+}
+
+// CheckpointStacks returns the stack names, sorted, found in the URNs of a
+// deployment's resources
+func CheckpointStacks(deployment apitype.UntypedDeployment) ([]string, error) {
+	state, err := parseDeployment(deployment)
+	if err != nil {
+		return nil, err
+	}
+	return checkpointStacks(state), nil
+}
+
+// checkpointStacks returns the stack names, sorted, found in the URNs of a
+// parsed deployment's resources
+func checkpointStacks(state *apitype.DeploymentV3) []string {
+	seen := make(map[string]bool)
+	var stacks []string
+	for _, r := range state.Resources {
+		if !r.URN.IsValid() {
+			continue
+		}
+		name := string(r.URN.Stack())
+		if !seen[name] {
+			seen[name] = true
+			stacks = append(stacks, name)
+		}
+	}
+	sort.Strings(stacks)
+	return stacks
+}
+
+// checkStackName refuses a checkpoint whose URNs name a stack other than
+// opts.StackName. Importing it would mix another stack's resources into
+// this one's state. A fully qualified org/project/stack name is compared by
+// its stack part, which is all a URN records. Checkpoints taken before a
+// rename are accepted once renameCheckpointStack rewrote them.
+func checkStackName(state *apitype.DeploymentV3, opts RollbackOptions) error {
+	want := stackPart(opts.StackName)
+	if want == "" {
+		return nil
+	}
+
+	for _, name := range checkpointStacks(state) {
+		if name != want {
+			return fmt.Errorf("%w: the checkpoint for version %d has resources of stack %q, not %q (use --renamed-from %s if the stack was renamed)",
+				ErrStackMismatch, opts.TargetVersion, name, want, name)
+		}
+	}
+	return nil
+}
+
+// stackPart returns the stack part of a possibly fully qualified
+// org/project/stack name
+func stackPart(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// renameCheckpointStack rewrites the URNs of a checkpoint taken before the
+// stack was renamed from opts.RenamedFrom to name the current stack, as
+// pulumi stack rename rewrites the current state. Other checkpoints are
+// returned unchanged.
+func renameCheckpointStack(checkpoint apitype.UntypedDeployment, opts RollbackOptions) (apitype.UntypedDeployment, error) {
+	from, to := stackPart(opts.RenamedFrom), stackPart(opts.StackName)
+	if from == "" || from == to {
+		return checkpoint, nil
+	}
+	if to == "" {
+		return checkpoint, fmt.Errorf("renamed-from %q needs the stack name to rename to", opts.RenamedFrom)
+	}
+
+	old := []byte("urn:pulumi:" + from + "::")
+	if !bytes.Contains(checkpoint.Deployment, old) {
+		return checkpoint, nil
+	}
+	opts.Logger.Infof("Renaming the resources of stack %q in the checkpoint for version %d to %q", from, opts.TargetVersion, to)
+	renamed := bytes.ReplaceAll(checkpoint.Deployment, old, []byte("urn:pulumi:"+to+"::"))
+	return apitype.UntypedDeployment{Version: checkpoint.Version, Deployment: renamed}, nil
+}
+
+// CountResources returns the number of resources in a deployment, not
+// counting the root stack resource, provider resources or resources pending
+// deletion, which do not represent infrastructure
+func CountResources(deployment apitype.UntypedDeployment) (int, error) {
+	state, err := parseDeployment(deployment)
+	if err != nil {
+		return 0, err
+	}
+	return countResources(state), nil
+}
+
+// countResources counts the resources of a parsed deployment as
+// CountResources does
+func countResources(state *apitype.DeploymentV3) int {
+	count := 0
+	for _, res := range state.Resources {
+		typ := string(res.Type)
+		if res.Delete || typ == "pulumi:pulumi:Stack" || strings.HasPrefix(typ, "pulumi:providers:") {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// checkEmptyCheckpoint refuses a target checkpoint without resources, which
+// would delete all current infrastructure, unless refuse is false or
+// opts.AllowEmpty is set
+func checkEmptyCheckpoint(target, current *apitype.DeploymentV3, refuse bool, opts RollbackOptions) error {
+	if countResources(target) > 0 {
+		return nil
+	}
+	currentCount := countResources(current)
+	if currentCount == 0 {
+		return nil
+	}
+
+	if refuse && !opts.AllowEmpty {
+		return fmt.Errorf("%w: rolling back to version %d would delete all %d current resource(s)",
+			ErrEmptyCheckpoint, opts.TargetVersion, currentCount)
+	}
+	opts.Logger.Warnf("Version %d has no resources: this rollback will DELETE ALL %d current resource(s)", opts.TargetVersion, currentCount)
+	return nil
+}
+
+// URNsByType returns the URNs of resources in a deployment whose type token
+// matches one of the given types
+func URNsByType(deployment apitype.UntypedDeployment, types []string) ([]string, error) {
+	state, err := parseDeployment(deployment)
+	if err != nil {
+		return nil, err
+	}
+	return urnsByType(state, types), nil
+}
+
+// urnsByType returns the URNs of a parsed deployment's resources whose type
+// token matches one of the given types
+func urnsByType(state *apitype.DeploymentV3, types []string) []string {
+	wanted := make(map[string]bool, len(types))
+	for _, typ := range types {
+		wanted[typ] = true
+	}
+
+	var urns []string
+	for _, res := range state.Resources {
+		if wanted[string(res.Type)] {
+			urns = append(urns, string(res.URN))
+		}
+	}
+	return urns
+}
+
+// resolveTargets returns the URNs up is limited to: the resources of
+// opts.Types in the checkpoint followed by opts.Targets. It returns nil
+// when the whole stack is rolled back.
+func resolveTargets(checkpoint *apitype.DeploymentV3, opts RollbackOptions) ([]string, error) {
+	var urns []string
+	if len(opts.Types) > 0 {
+		urns = urnsByType(checkpoint, opts.Types)
+		if len(urns) == 0 {
+			return nil, fmt.Errorf("no resources of type %s found in version %d",
+				strings.Join(opts.Types, ", "), opts.TargetVersion)
+		}
+		opts.Logger.Infof("Targeting %d resource(s) by type", len(urns))
+	}
+	if len(opts.Targets) > 0 {
+		urns = append(urns, opts.Targets...)
+		opts.Logger.Infof("Targeting %d selected resource(s)", len(opts.Targets))
+	}
+
+	for _, urn := range urns {
+		opts.Logger.Debugf("targeting %s", urn)
+	}
+	return urns, nil
+}
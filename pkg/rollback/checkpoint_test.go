@@ -0,0 +1,566 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+const pendingDeployment = `{
+	"resources": [],
+	"pending_operations": [
+		{"resource": {"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b", "type": "aws:s3/bucket:Bucket"}, "type": "creating"}
+	]
+}`
+
+func TestHasPendingOperations(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployment  string
+		expected    []PendingOp
+		expectError bool
+	}{
+		{
+			name:       "no pending operations",
+			deployment: `{"resources": []}`,
+		},
+		{
+			name:       "pending create",
+			deployment: pendingDeployment,
+			expected: []PendingOp{
+				{Type: "creating", URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b"},
+			},
+		},
+		{
+			name:        "invalid json",
+			deployment:  `{invalid}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := HasPendingOperations(apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(tt.deployment)})
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(ops) != len(tt.expected) {
+				t.Fatalf("Expected %d pending operations, got %d", len(tt.expected), len(ops))
+			}
+			for i, exp := range tt.expected {
+				if ops[i] != exp {
+					t.Errorf("Operation %d: expected %v, got %v", i, exp, ops[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_PendingOperations(t *testing.T) {
+	upCalled := false
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(pendingDeployment)}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upCalled = true
+			return auto.UpResult{}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+	}
+
+	_, err := ExecuteRollback(context.Background(), opts)
+	if !errors.Is(err, ErrPendingOperations) {
+		t.Fatalf("Expected ErrPendingOperations, got %v", err)
+	}
+	var rbErr *RollbackError
+	if !errors.As(err, &rbErr) || rbErr.Phase != PhaseValidate {
+		t.Errorf("Expected a RollbackError in phase %s, got %v", PhaseValidate, err)
+	}
+	if upCalled {
+		t.Error("Expected Up not to be called with pending operations")
+	}
+	if !bytes.Contains(output.Bytes(), []byte("creating urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b")) {
+		t.Error("Expected pending operation to be listed in output")
+	}
+
+	opts.Force = true
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error with Force: %v", err)
+	}
+}
+
+func TestPreviewRollback_PendingOperationsWarns(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(pendingDeployment)}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+	}
+
+	if _, err := PreviewRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Contains(output.Bytes(), []byte("pending operation")) {
+		t.Error("Expected pending operations warning in output")
+	}
+}
+
+func TestCountResources(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployment  string
+		expected    int
+		expectError bool
+	}{
+		{
+			name:       "no resources",
+			deployment: `{}`,
+		},
+		{
+			name: "only stack and provider",
+			deployment: `{"resources": [
+				{"urn": "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev", "type": "pulumi:pulumi:Stack"},
+				{"urn": "urn:pulumi:dev::proj::pulumi:providers:aws::default", "type": "pulumi:providers:aws"}
+			]}`,
+		},
+		{
+			name: "skips pending deletes",
+			deployment: `{"resources": [
+				{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a", "type": "aws:s3/bucket:Bucket"},
+				{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a", "type": "aws:s3/bucket:Bucket", "delete": true}
+			]}`,
+			expected: 1,
+		},
+		{
+			name:        "invalid json",
+			deployment:  `{invalid}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := CountResources(deployment(tt.deployment))
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if count != tt.expected {
+				t.Errorf("Expected %d resources, got %d", tt.expected, count)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_EmptyCheckpoint(t *testing.T) {
+	current := `{"resources": [{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a", "type": "aws:s3/bucket:Bucket"}]}`
+	imported := false
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			if imported {
+				return deployment(`{"resources": []}`), nil
+			}
+			return deployment(current), nil
+		},
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			imported = true
+			return nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		Force:              true,
+		TransformCheckpoint: func(d apitype.UntypedDeployment) (apitype.UntypedDeployment, error) {
+			return deployment(`{"resources": []}`), nil
+		},
+	}
+
+	_, err := ExecuteRollback(context.Background(), opts)
+	if !errors.Is(err, ErrEmptyCheckpoint) {
+		t.Fatalf("Expected ErrEmptyCheckpoint even with Force, got %v", err)
+	}
+	if imported {
+		t.Error("Expected nothing to be imported for an empty checkpoint")
+	}
+
+	opts.AllowEmpty = true
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error with AllowEmpty: %v", err)
+	}
+	if !bytes.Contains(output.Bytes(), []byte("DELETE ALL 1 current resource(s)")) {
+		t.Error("Expected a warning that all resources will be deleted")
+	}
+}
+
+const typedDeployment = `{
+	"resources": [
+		{"urn": "urn:pulumi:dev::proj::aws:lambda/function:Function::a", "type": "aws:lambda/function:Function"},
+		{"urn": "urn:pulumi:dev::proj::aws:lambda/function:Function::b", "type": "aws:lambda/function:Function"},
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::c", "type": "aws:s3/bucket:Bucket"}
+	]
+}`
+
+func TestURNsByType(t *testing.T) {
+	deployment := apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(typedDeployment)}
+
+	tests := []struct {
+		name     string
+		types    []string
+		expected int
+	}{
+		{"single type", []string{"aws:lambda/function:Function"}, 2},
+		{"multiple types", []string{"aws:lambda/function:Function", "aws:s3/bucket:Bucket"}, 3},
+		{"no match", []string{"aws:ec2/instance:Instance"}, 0},
+		{"no types", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urns, err := URNsByType(deployment, tt.types)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(urns) != tt.expected {
+				t.Errorf("Expected %d URNs, got %d: %v", tt.expected, len(urns), urns)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_Types(t *testing.T) {
+	var upTargets []string
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(typedDeployment)}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upOpts := &optup.Options{}
+			for _, o := range opts {
+				o.ApplyOption(upOpts)
+			}
+			upTargets = upOpts.Target
+			return auto.UpResult{}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "dev",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		Types:              []string{"aws:s3/bucket:Bucket"},
+	}
+
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(upTargets) != 1 || upTargets[0] != "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::c" {
+		t.Errorf("Expected up to target the bucket, got %v", upTargets)
+	}
+
+	opts.Targets = []string{"urn:pulumi:dev::proj::aws:lambda/function:Function::a"}
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(upTargets) != 2 || upTargets[1] != opts.Targets[0] {
+		t.Errorf("Expected up to target the bucket and the selected function, got %v", upTargets)
+	}
+
+	opts.Types = nil
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(upTargets) != 1 || upTargets[0] != opts.Targets[0] {
+		t.Errorf("Expected up to target only the selected function, got %v", upTargets)
+	}
+
+	opts.Targets = nil
+	opts.Types = []string{"aws:ec2/instance:Instance"}
+	if _, err := ExecuteRollback(context.Background(), opts); err == nil {
+		t.Error("Expected error when no resources match the type")
+	}
+}
+
+func TestPreviewRollback_Targets(t *testing.T) {
+	var previewTargets []string
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(typedDeployment)}, nil
+		},
+		PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+			previewOpts := &optpreview.Options{}
+			for _, o := range opts {
+				o.ApplyOption(previewOpts)
+			}
+			previewTargets = previewOpts.Target
+			return auto.PreviewResult{}, nil
+		},
+	}
+
+	opts := RollbackOptions{
+		StackName:          "dev",
+		TargetVersion:      1,
+		Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+		CheckpointProvider: exportCheckpoints,
+		Output:             &bytes.Buffer{},
+		Targets:            []string{"urn:pulumi:dev::proj::aws:lambda/function:Function::a"},
+	}
+
+	if _, err := PreviewRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(previewTargets) != 1 || previewTargets[0] != opts.Targets[0] {
+		t.Errorf("Expected the preview to target the function, got %v", previewTargets)
+	}
+
+	opts.Targets = []string{}
+	if _, err := PreviewRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if previewTargets != nil {
+		t.Errorf("Expected an empty target list to preview everything, got %v", previewTargets)
+	}
+}
+
+func TestCheckStackName(t *testing.T) {
+	tests := []struct {
+		name        string
+		stackName   string
+		resources   string
+		expectedErr bool
+	}{
+		{name: "same stack", stackName: "dev", resources: `[{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a"}]`},
+		{name: "qualified stack name", stackName: "acme/proj/dev", resources: `[{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a"}]`},
+		{name: "no resources", stackName: "dev", resources: `[]`},
+		{name: "no stack name", stackName: "", resources: `[{"urn": "urn:pulumi:prod::proj::aws:s3/bucket:Bucket::a"}]`},
+		{name: "other stack", stackName: "dev", resources: `[{"urn": "urn:pulumi:prod::proj::aws:s3/bucket:Bucket::a"}]`, expectedErr: true},
+		{
+			name:        "mixed stacks",
+			stackName:   "dev",
+			resources:   `[{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a"}, {"urn": "urn:pulumi:staging::proj::aws:s3/bucket:Bucket::b"}]`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := parseDeployment(deployment(`{"resources": ` + tt.resources + `}`))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			err = checkStackName(state, RollbackOptions{StackName: tt.stackName, TargetVersion: 4})
+			if tt.expectedErr && !errors.Is(err, ErrStackMismatch) {
+				t.Errorf("Expected ErrStackMismatch, got %v", err)
+			}
+			if !tt.expectedErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_RefusesOtherStacksCheckpoint(t *testing.T) {
+	imported := false
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return deployment(`{"resources": [{"urn": "urn:pulumi:prod::proj::aws:s3/bucket:Bucket::a"}]}`), nil
+		},
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			imported = true
+			return nil
+		},
+	}
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	_, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:          "dev",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+	})
+	if !errors.Is(err, ErrStackMismatch) {
+		t.Fatalf("Expected ErrStackMismatch, got %v", err)
+	}
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) || rollbackErr.Phase != PhaseFetchCheckpoint {
+		t.Errorf("Expected a RollbackError in phase fetch-checkpoint, got %v", err)
+	}
+	if imported {
+		t.Error("Expected the checkpoint not to be imported")
+	}
+}
+
+func TestRenameCheckpointStack(t *testing.T) {
+	const old = `{"resources": [{"urn": "urn:pulumi:prod::proj::aws:s3/bucket:Bucket::a", "provider": "urn:pulumi:prod::proj::pulumi:providers:aws::default::p1"}]}`
+	const renamed = `{"resources": [{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a", "provider": "urn:pulumi:dev::proj::pulumi:providers:aws::default::p1"}]}`
+
+	tests := []struct {
+		name        string
+		stackName   string
+		renamedFrom string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "renamed", stackName: "dev", renamedFrom: "prod", expected: renamed},
+		{name: "qualified names", stackName: "acme/proj/dev", renamedFrom: "acme/proj/prod", expected: renamed},
+		{name: "not renamed", stackName: "dev", expected: old},
+		{name: "other old name", stackName: "dev", renamedFrom: "staging", expected: old},
+		{name: "no stack name", renamedFrom: "prod", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := withDefaults(RollbackOptions{StackName: tt.stackName, RenamedFrom: tt.renamedFrom, Output: &bytes.Buffer{}})
+			got, err := renameCheckpointStack(deployment(old), opts)
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(got.Deployment) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got.Deployment)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_RenamedFrom(t *testing.T) {
+	state := deployment(`{"resources": [{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a", "id": "new"}]}`)
+	mockStack := archivedStack(&state)
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+	provider := &MockCheckpointProvider{
+		CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+			return deployment(`{"resources": [{"urn": "urn:pulumi:prod::proj::aws:s3/bucket:Bucket::a", "id": "old"}]}`), nil
+		},
+	}
+
+	opts := RollbackOptions{
+		StackName:          "dev",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: provider,
+		Output:             &bytes.Buffer{},
+	}
+	if _, err := ExecuteRollback(context.Background(), opts); !errors.Is(err, ErrStackMismatch) {
+		t.Fatalf("Expected ErrStackMismatch without RenamedFrom, got %v", err)
+	}
+
+	opts.RenamedFrom = "prod"
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `{"resources": [{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a", "id": "old"}]}`
+	if string(state.Deployment) != want {
+		t.Errorf("Expected the renamed checkpoint to be imported, got %s", state.Deployment)
+	}
+}
+
+func TestCheckpointHash(t *testing.T) {
+	// SHA-256 of "{}"
+	const expected = "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if got := CheckpointHash(deployment(`{}`)); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	result, err := ExecuteRollback(context.Background(), RollbackOptions{
+		CheckpointProvider: exportCheckpoints,
+		StackName:          "test",
+		TargetVersion:      1,
+		Output:             &bytes.Buffer{},
+		Operator: &MockStackOperator{
+			SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+				return &MockRollbackStack{}, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.CheckpointHash != expected {
+		t.Errorf("Expected the result to record checkpoint hash %s, got %q", expected, result.CheckpointHash)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// CompressedExtension is appended to compressed checkpoint files
+const CompressedExtension = ".gz"
+
+// WriteCheckpoint writes a deployment in the format produced by
+// `pulumi stack export`, optionally gzip-compressed
+func WriteCheckpoint(w io.Writer, deployment apitype.UntypedDeployment, compress bool) error {
+	if compress {
+		gz := gzip.NewWriter(w)
+		if err := json.NewEncoder(gz).Encode(deployment); err != nil {
+			gz.Close()
+			return fmt.Errorf("failed to write checkpoint: %w", err)
+		}
+		return gz.Close()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(deployment); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ReadCheckpoint reads a deployment written by WriteCheckpoint or
+// `pulumi stack export`, transparently decompressing gzip input
+func ReadCheckpoint(r io.Reader) (apitype.UntypedDeployment, error) {
+	br := bufio.NewReader(r)
+	if header, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(header, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return apitype.UntypedDeployment{}, fmt.Errorf("failed to decompress checkpoint: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var deployment apitype.UntypedDeployment
+	if err := json.NewDecoder(r).Decode(&deployment); err != nil {
+		return apitype.UntypedDeployment{}, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	return deployment, nil
+}
+
+// WriteCheckpointFile writes a deployment to path. When compress is set the
+// CompressedExtension is appended unless already present. It returns the
+// path actually written.
+func WriteCheckpointFile(path string, deployment apitype.UntypedDeployment, compress bool) (string, error) {
+	if compress && !strings.HasSuffix(path, CompressedExtension) {
+		path += CompressedExtension
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	if err := WriteCheckpoint(f, deployment, compress); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ReadCheckpointFile reads a deployment from a plain or gzip-compressed file
+func ReadCheckpointFile(path string) (apitype.UntypedDeployment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return apitype.UntypedDeployment{}, err
+	}
+	defer f.Close()
+
+	return ReadCheckpoint(f)
+}
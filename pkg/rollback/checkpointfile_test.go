@@ -0,0 +1,77 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestCheckpointFileRoundTrip(t *testing.T) {
+	original := apitype.UntypedDeployment{
+		Version:    3,
+		Deployment: json.RawMessage(`{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b"}]}`),
+	}
+
+	tests := []struct {
+		name         string
+		file         string
+		compress     bool
+		expectedFile string
+	}{
+		{"plain", "state.json", false, "state.json"},
+		{"compressed adds extension", "state.json", true, "state.json.gz"},
+		{"compressed keeps extension", "state.json.gz", true, "state.json.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path, err := WriteCheckpointFile(filepath.Join(dir, tt.file), original, tt.compress)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if filepath.Base(path) != tt.expectedFile {
+				t.Errorf("Expected file %q, got %q", tt.expectedFile, filepath.Base(path))
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.compress != bytes.HasPrefix(raw, gzipMagic) {
+				t.Errorf("Expected compressed=%v on disk", tt.compress)
+			}
+
+			restored, err := ReadCheckpointFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if restored.Version != original.Version {
+				t.Errorf("Expected version %d, got %d", original.Version, restored.Version)
+			}
+			if !strings.Contains(string(restored.Deployment), "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b") {
+				t.Errorf("Deployment content was not preserved: %s", restored.Deployment)
+			}
+		})
+	}
+}
+
+func TestReadCheckpoint_Invalid(t *testing.T) {
+	if _, err := ReadCheckpoint(strings.NewReader(`{invalid`)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+	if _, err := ReadCheckpoint(bytes.NewReader(append(gzipMagic, 0, 0))); err == nil {
+		t.Error("Expected error for corrupt gzip data")
+	}
+	if _, err := ReadCheckpointFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// ErrNoAccessToken is returned when a checkpoint must be fetched from
+// Pulumi Cloud but no access token is available
+var ErrNoAccessToken = errors.New("no Pulumi access token: set PULUMI_ACCESS_TOKEN or run 'pulumi login'")
+
+// CloudClient fetches historical checkpoints from the Pulumi Cloud REST API
+type CloudClient struct {
+	// BaseURL is the API URL, e.g. https://api.pulumi.com
+	BaseURL string
+	Token   string
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// NewCloudClient returns a client for the backend at backendURL, using
+// PULUMI_ACCESS_TOKEN or the token stored by 'pulumi login'
+func NewCloudClient(backendURL string) (*CloudClient, error) {
+	token := os.Getenv("PULUMI_ACCESS_TOKEN")
+	if token == "" {
+		token = storedAccessToken(backendURL)
+	}
+	if token == "" {
+		return nil, ErrNoAccessToken
+	}
+	return &CloudClient{BaseURL: backendURL, Token: token}, nil
+}
+
+// storedAccessToken reads the token for a backend from the credentials
+// file written by 'pulumi login', or returns an empty string
+func storedAccessToken(backendURL string) string {
+	return readCredentials(pulumiHomeDir("")).AccessTokens[strings.TrimSuffix(backendURL, "/")]
+}
+
+// credentials is the part of ~/.pulumi/credentials.json the tool reads
+type credentials struct {
+	// Current is the URL of the backend 'pulumi login' last logged in to
+	Current      string            `json:"current"`
+	AccessTokens map[string]string `json:"accessTokens"`
+}
+
+// readCredentials reads the credentials file in a Pulumi home directory.
+// A missing or unreadable file yields empty credentials.
+func readCredentials(home string) credentials {
+	var creds credentials
+	if home == "" {
+		return creds
+	}
+	data, err := os.ReadFile(filepath.Join(home, "credentials.json"))
+	if err != nil {
+		return creds
+	}
+	json.Unmarshal(data, &creds)
+	return creds
+}
+
+// pulumiHomeDir returns home, or else PULUMI_HOME, or else ~/.pulumi
+func pulumiHomeDir(home string) string {
+	if home != "" {
+		return home
+	}
+	if home := os.Getenv("PULUMI_HOME"); home != "" {
+		return home
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(userHome, ".pulumi")
+}
+
+// defaultCloudURL is the API of Pulumi Cloud
+const defaultCloudURL = "https://api.pulumi.com"
+
+// configuredBackendURL returns the URL of the backend a workspace uses, in
+// the order the Pulumi CLI picks it: PULUMI_BACKEND_URL, the project's
+// backend.url, then the current login in credentials.json. It returns an
+// empty string when none is set.
+func configuredBackendURL(env map[string]string, projectURL, pulumiHome string) string {
+	if url := env["PULUMI_BACKEND_URL"]; url != "" {
+		return url
+	}
+	if url := os.Getenv("PULUMI_BACKEND_URL"); url != "" {
+		return url
+	}
+	if projectURL != "" {
+		return projectURL
+	}
+	return readCredentials(pulumiHomeDir(pulumiHome)).Current
+}
+
+// cloudAPIURL maps the URL reported by whoami to a backend URL. For Pulumi
+// Cloud that is the console, e.g. https://app.pulumi.com/alice, which is
+// mapped to its API; other URLs are returned unchanged.
+func cloudAPIURL(consoleURL string) string {
+	if consoleURL == "" {
+		return defaultCloudURL
+	}
+	u, err := url.Parse(consoleURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return consoleURL
+	}
+	if host, ok := strings.CutPrefix(u.Host, "app."); ok {
+		return u.Scheme + "://api." + host
+	}
+	return consoleURL
+}
+
+// ExportDeployment returns the checkpoint of a stack at a version. The
+// stack is fully qualified: org/project/stack.
+func (c *CloudClient) ExportDeployment(ctx context.Context, stack string, version int) (apitype.UntypedDeployment, error) {
+	parts := strings.Split(stack, "/")
+	if len(parts) != 3 {
+		return apitype.UntypedDeployment{}, fmt.Errorf("stack name %q is not fully qualified (org/project/stack)", stack)
+	}
+	endpoint := fmt.Sprintf("%s/api/stacks/%s/%s/%s/export/%d", strings.TrimSuffix(c.BaseURL, "/"),
+		url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.PathEscape(parts[2]), version)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return apitype.UntypedDeployment{}, err
+	}
+	req.Header.Set("Authorization", "token "+c.Token)
+	req.Header.Set("Accept", "application/vnd.pulumi+8")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return apitype.UntypedDeployment{}, fmt.Errorf("failed to fetch checkpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return apitype.UntypedDeployment{}, fmt.Errorf("failed to fetch checkpoint of version %d: %s: %s",
+			version, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var deployment apitype.UntypedDeployment
+	if err := json.NewDecoder(resp.Body).Decode(&deployment); err != nil {
+		return apitype.UntypedDeployment{}, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return deployment, nil
+}
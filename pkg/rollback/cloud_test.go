@@ -0,0 +1,194 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// newCloudServer serves version 4 of acme/app/prod and rejects anything else
+func newCloudServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token pul-test" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/stacks/acme/app/prod/export/4" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"version":3,"deployment":{"resources":[{"urn":"urn:pulumi:prod::app::aws:s3/bucket:Bucket::b","id":"v4"}]}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCloudClient_ExportDeployment(t *testing.T) {
+	server := newCloudServer(t)
+	client := &CloudClient{BaseURL: server.URL, Token: "pul-test"}
+
+	deployment, err := client.ExportDeployment(context.Background(), "acme/app/prod", 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deployment.Version != 3 || !strings.Contains(string(deployment.Deployment), `"v4"`) {
+		t.Errorf("Unexpected deployment: %s", deployment.Deployment)
+	}
+
+	if _, err := client.ExportDeployment(context.Background(), "acme/app/prod", 5); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	if _, err := client.ExportDeployment(context.Background(), "prod", 4); err == nil {
+		t.Error("Expected error for a stack name that is not fully qualified")
+	}
+
+	client.Token = "wrong"
+	if _, err := client.ExportDeployment(context.Background(), "acme/app/prod", 4); err == nil {
+		t.Error("Expected error for a rejected token")
+	}
+}
+
+func TestNewCloudClient_Token(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("PULUMI_HOME", home)
+	t.Setenv("PULUMI_ACCESS_TOKEN", "")
+
+	if _, err := NewCloudClient("https://api.pulumi.com"); !errors.Is(err, ErrNoAccessToken) {
+		t.Errorf("Expected ErrNoAccessToken, got %v", err)
+	}
+
+	creds := `{"current":"https://api.pulumi.com","accessTokens":{"https://api.pulumi.com":"pul-stored"}}`
+	if err := os.WriteFile(filepath.Join(home, "credentials.json"), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewCloudClient("https://api.pulumi.com/")
+	if err != nil || client.Token != "pul-stored" {
+		t.Errorf("Expected the stored token, got %+v (%v)", client, err)
+	}
+
+	t.Setenv("PULUMI_ACCESS_TOKEN", "pul-env")
+	client, err = NewCloudClient("https://api.pulumi.com")
+	if err != nil || client.Token != "pul-env" {
+		t.Errorf("Expected PULUMI_ACCESS_TOKEN to take precedence, got %+v (%v)", client, err)
+	}
+}
+
+func TestGetCheckpointForVersion_PulumiCloud(t *testing.T) {
+	server := newCloudServer(t)
+	t.Setenv("PULUMI_ACCESS_TOKEN", "pul-test")
+
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 7}, {Version: 4}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			t.Error("Expected the checkpoint not to be exported from the current state")
+			return deployment(`{}`), nil
+		},
+		BackendFunc: func(ctx context.Context) (BackendInfo, error) {
+			return BackendInfo{URL: server.URL, Stack: "acme/app/prod"}, nil
+		},
+	}
+
+	checkpoint, err := GetCheckpointForVersion(context.Background(), mockStack, 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(checkpoint.Deployment), `"v4"`) {
+		t.Errorf("Expected the checkpoint of version 4, got %s", checkpoint.Deployment)
+	}
+}
+
+func TestDefaultCheckpointProvider_EnvVarsToken(t *testing.T) {
+	server := newCloudServer(t)
+	t.Setenv("PULUMI_HOME", t.TempDir())
+	t.Setenv("PULUMI_ACCESS_TOKEN", "")
+
+	stack := &MockRollbackStack{
+		BackendFunc: func(ctx context.Context) (BackendInfo, error) {
+			return BackendInfo{URL: server.URL, Stack: "acme/app/prod"}, nil
+		},
+	}
+	provider := &DefaultCheckpointProvider{EnvVars: map[string]string{"PULUMI_ACCESS_TOKEN": "pul-test"}}
+	checkpoint, err := provider.CheckpointAt(context.Background(), stack, 4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(checkpoint.Deployment), `"v4"`) {
+		t.Errorf("Expected the checkpoint of version 4, got %s", checkpoint.Deployment)
+	}
+}
+
+func TestConfiguredBackendURL(t *testing.T) {
+	home := t.TempDir()
+	creds := `{"current":"https://api.pulumi.com","accessTokens":{"https://api.pulumi.com":"pul-stored"}}`
+	if err := os.WriteFile(filepath.Join(home, "credentials.json"), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PULUMI_BACKEND_URL", "")
+
+	tests := []struct {
+		name       string
+		env        map[string]string
+		projectURL string
+		home       string
+		expected   string
+	}{
+		{name: "workspace env", env: map[string]string{"PULUMI_BACKEND_URL": "s3://state"}, projectURL: "gs://project", home: home, expected: "s3://state"},
+		{name: "project backend", projectURL: "gs://project", home: home, expected: "gs://project"},
+		{name: "current login", home: home, expected: "https://api.pulumi.com"},
+		{name: "nothing configured", home: t.TempDir(), expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := configuredBackendURL(tt.env, tt.projectURL, tt.home); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCloudAPIURL(t *testing.T) {
+	tests := []struct {
+		whoami   string
+		expected string
+	}{
+		{"https://app.pulumi.com/alice", "https://api.pulumi.com"},
+		{"https://app.pulumi.example.com/alice", "https://api.pulumi.example.com"},
+		{"https://api.pulumi.com", "https://api.pulumi.com"},
+		{"s3://state", "s3://state"},
+		{"", "https://api.pulumi.com"},
+	}
+
+	for _, tt := range tests {
+		if got := cloudAPIURL(tt.whoami); got != tt.expected {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.whoami, got)
+		}
+	}
+
+	// The mapped URL is the key 'pulumi login' stores the token under
+	home := t.TempDir()
+	t.Setenv("PULUMI_HOME", home)
+	t.Setenv("PULUMI_ACCESS_TOKEN", "")
+	creds := `{"accessTokens":{"https://api.pulumi.com":"pul-stored"}}`
+	if err := os.WriteFile(filepath.Join(home, "credentials.json"), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewCloudClient(cloudAPIURL("https://app.pulumi.com/alice"))
+	if err != nil || client.Token != "pul-stored" || client.BaseURL != "https://api.pulumi.com" {
+		t.Errorf("Expected the API URL and its stored token, got %+v (%v)", client, err)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+)
+
+// ErrConfigNotRecorded is returned by RollbackConfig when the history does
+// not hold the configuration of the target version, or only holds its
+// secrets masked
+var ErrConfigNotRecorded = errors.New("configuration not recorded")
+
+// Config change actions
+const (
+	ConfigSet    = "set"
+	ConfigRemove = "remove"
+)
+
+// ConfigChange is one config key changed by a config rollback. Values of
+// secret keys are masked.
+type ConfigChange struct {
+	Key    string `json:"key"`
+	Action string `json:"action"`
+	Secret bool   `json:"secret,omitempty"`
+	// Old is the current value, empty for a key added back
+	Old string `json:"old,omitempty"`
+	// New is the restored value, empty for a removed key
+	New string `json:"new,omitempty"`
+}
+
+// ConfigRollbackResult is the result of RollbackConfig
+type ConfigRollbackResult struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Changes []ConfigChange `json:"changes"`
+	// ResourceChanges holds the changes made by up, if it ran
+	ResourceChanges map[string]int `json:"resourceChanges,omitempty"`
+	Stdout          string         `json:"-"`
+	Stderr          string         `json:"-"`
+}
+
+// RollbackConfig restores the stack's configuration to what it was at
+// opts.TargetVersion, as recorded in the update history, without touching
+// the resource state. With up set, up then reconciles the resources to the
+// restored config. With opts.DryRun only the changes are computed.
+func RollbackConfig(ctx context.Context, opts RollbackOptions, up bool) (*ConfigRollbackResult, error) {
+	opts = withDefaults(opts)
+
+	stack, err := selectStack(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select stack: %w", err)
+	}
+
+	opts.Logger.Infof("Fetching configuration of version %d...", opts.TargetVersion)
+	target, err := configForVersion(ctx, stack, opts.TargetVersion)
+	if err != nil {
+		return nil, err
+	}
+	current, err := stack.GetAllConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current config: %w", err)
+	}
+
+	redactor := configRedactor(current, target)
+	changes := DiffConfig(current, target)
+	result := &ConfigRollbackResult{Success: true, Changes: changes}
+	if opts.DryRun {
+		result.Message = fmt.Sprintf("Preview of config rollback to version %d completed", opts.TargetVersion)
+		return result, nil
+	}
+
+	if err := applyConfigChanges(ctx, stack, target, changes, opts.Logger.Infof); err != nil {
+		return nil, redactor.Error(err)
+	}
+	result.Message = fmt.Sprintf("Restored the configuration of version %d", opts.TargetVersion)
+
+	if !up {
+		return result, nil
+	}
+	opts.Logger.Infof("Applying the restored configuration...")
+	upResult, err := stack.Up(ctx, optup.Message(fmt.Sprintf("Rollback config to version %d", opts.TargetVersion)))
+	if err != nil {
+		// The config has been restored either way
+		return nil, redactor.Error(fmt.Errorf("config restored, but up failed: %w", err))
+	}
+	result.Message = fmt.Sprintf("Restored and applied the configuration of version %d", opts.TargetVersion)
+	result.ResourceChanges = copyChanges(upResult.Summary.ResourceChanges)
+	result.Stdout = redactor.String(upResult.StdOut)
+	result.Stderr = redactor.String(upResult.StdErr)
+	return result, nil
+}
+
+// applyConfigChanges makes changes, as returned by DiffConfig(current,
+// target), to the stack's config, taking the values to set from target
+func applyConfigChanges(ctx context.Context, stack RollbackStack, target auto.ConfigMap, changes []ConfigChange, logf func(string, ...interface{})) error {
+	set := make(auto.ConfigMap)
+	var remove []string
+	for _, change := range changes {
+		if change.Action == ConfigRemove {
+			remove = append(remove, change.Key)
+		} else {
+			set[change.Key] = target[change.Key]
+		}
+	}
+	if len(set) > 0 {
+		logf("Setting %d config value(s)...", len(set))
+		if err := stack.SetAllConfig(ctx, set); err != nil {
+			return fmt.Errorf("failed to set config: %w", err)
+		}
+	}
+	if len(remove) > 0 {
+		logf("Removing %d config value(s)...", len(remove))
+		if err := stack.RemoveAllConfig(ctx, remove); err != nil {
+			return fmt.Errorf("failed to remove config: %w", err)
+		}
+	}
+	return nil
+}
+
+// restoreConfig undoes any change to the stack's config since snapshot was
+// taken
+func restoreConfig(ctx context.Context, stack RollbackStack, snapshot auto.ConfigMap, opts RollbackOptions) error {
+	current, err := stack.GetAllConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get config: %w", err)
+	}
+	changes := DiffConfig(current, snapshot)
+	if len(changes) == 0 {
+		return nil
+	}
+	opts.Logger.Warnf("the import changed %d config key(s); restoring them", len(changes))
+	return applyConfigChanges(ctx, stack, snapshot, changes, opts.Logger.Debugf)
+}
+
+// configForVersion returns the configuration recorded with an update
+func configForVersion(ctx context.Context, stack RollbackStack, version int) (auto.ConfigMap, error) {
+	updates, err := stack.History(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	for _, update := range updates {
+		if update.Version != version {
+			continue
+		}
+		if update.Config == nil {
+			return nil, fmt.Errorf("%w for version %d", ErrConfigNotRecorded, version)
+		}
+		for key, value := range update.Config {
+			if value.Secret && value.Value == format.Mask {
+				return nil, fmt.Errorf("%w: secret %s of version %d is masked in the history", ErrConfigNotRecorded, key, version)
+			}
+		}
+		return update.Config, nil
+	}
+	return nil, versionNotFound(updates, version)
+}
+
+// configRedactor returns a redactor for the secret values of both configs
+func configRedactor(configs ...auto.ConfigMap) *format.Redactor {
+	redactor := &format.Redactor{}
+	for _, config := range configs {
+		for _, value := range config {
+			if value.Secret {
+				redactor.AddPlaintext(value.Value)
+			}
+		}
+	}
+	return redactor
+}
+
+// DiffConfig returns the changes that turn current into target, sorted by
+// key. Values of keys that are secret in either config are masked.
+func DiffConfig(current, target auto.ConfigMap) []ConfigChange {
+	var changes []ConfigChange
+	for key, value := range target {
+		old, ok := current[key]
+		if ok && old == value {
+			continue
+		}
+		change := ConfigChange{Key: key, Action: ConfigSet, Secret: value.Secret || old.Secret, New: value.Value}
+		if ok {
+			change.Old = old.Value
+		}
+		changes = append(changes, change)
+	}
+	for key, old := range current {
+		if _, ok := target[key]; !ok {
+			changes = append(changes, ConfigChange{Key: key, Action: ConfigRemove, Secret: old.Secret, Old: old.Value})
+		}
+	}
+
+	for i := range changes {
+		if changes[i].Secret {
+			changes[i].Old = maskConfig(changes[i].Old)
+			changes[i].New = maskConfig(changes[i].New)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// maskConfig masks a non-empty secret config value
+func maskConfig(value string) string {
+	if value == "" {
+		return ""
+	}
+	return format.Mask
+}
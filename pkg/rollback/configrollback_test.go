@@ -0,0 +1,267 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestDiffConfig(t *testing.T) {
+	current := auto.ConfigMap{
+		"app:replicas": {Value: "5"},
+		"app:flag":     {Value: "on"},
+		"app:token":    {Value: "new-token", Secret: true},
+		"app:region":   {Value: "us-east-1"},
+	}
+	target := auto.ConfigMap{
+		"app:replicas": {Value: "3"},
+		"app:token":    {Value: "old-token", Secret: true},
+		"app:region":   {Value: "us-east-1"},
+		"app:legacy":   {Value: "yes"},
+	}
+
+	changes := DiffConfig(current, target)
+	expected := []ConfigChange{
+		{Key: "app:flag", Action: ConfigRemove, Old: "on"},
+		{Key: "app:legacy", Action: ConfigSet, New: "yes"},
+		{Key: "app:replicas", Action: ConfigSet, Old: "5", New: "3"},
+		{Key: "app:token", Action: ConfigSet, Secret: true, Old: format.Mask, New: format.Mask},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], changes[i])
+		}
+	}
+}
+
+func TestRollbackConfig(t *testing.T) {
+	var set auto.ConfigMap
+	var removed []string
+	var upMessage string
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{
+				{Version: 7, Config: auto.ConfigMap{"app:replicas": {Value: "5"}}},
+				{Version: 4, Config: auto.ConfigMap{"app:token": {Value: "old-token", Secret: true}}},
+			}, nil
+		},
+		GetAllConfigFunc: func(ctx context.Context) (auto.ConfigMap, error) {
+			return auto.ConfigMap{"app:replicas": {Value: "5"}}, nil
+		},
+		SetAllConfigFunc: func(ctx context.Context, config auto.ConfigMap) error {
+			set = config
+			return nil
+		},
+		RemoveAllConfigFunc: func(ctx context.Context, keys []string) error {
+			removed = keys
+			return nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upOpts := &optup.Options{}
+			for _, o := range opts {
+				o.ApplyOption(upOpts)
+			}
+			upMessage = upOpts.Message
+			return auto.UpResult{StdOut: "using old-token"}, nil
+		},
+	}
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{StackName: "test", TargetVersion: 4, Operator: mockOperator, Output: &output}
+
+	opts.DryRun = true
+	result, err := RollbackConfig(context.Background(), opts, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Changes) != 2 || set != nil || upMessage != "" {
+		t.Errorf("Expected a dry run to only compute 2 changes, got %+v", result.Changes)
+	}
+
+	opts.DryRun = false
+	result, err = RollbackConfig(context.Background(), opts, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if set["app:token"] != (auto.ConfigValue{Value: "old-token", Secret: true}) {
+		t.Errorf("Expected the secret to be restored, got %v", set)
+	}
+	if len(removed) != 1 || removed[0] != "app:replicas" {
+		t.Errorf("Expected app:replicas to be removed, got %v", removed)
+	}
+	if upMessage != "Rollback config to version 4" {
+		t.Errorf("Unexpected up message %q", upMessage)
+	}
+	if strings.Contains(result.Stdout, "old-token") {
+		t.Errorf("Expected the secret to be masked in the output, got %q", result.Stdout)
+	}
+}
+
+func TestRollbackConfig_NotRecorded(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []auto.UpdateSummary
+	}{
+		{"no config", []auto.UpdateSummary{{Version: 4}}},
+		{"masked secret", []auto.UpdateSummary{{Version: 4, Config: auto.ConfigMap{"app:token": {Value: format.Mask, Secret: true}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStack := &MockRollbackStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					return tt.updates, nil
+				},
+				SetAllConfigFunc: func(ctx context.Context, config auto.ConfigMap) error {
+					t.Error("Expected no config to be set")
+					return nil
+				},
+			}
+			mockOperator := &MockStackOperator{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+					return mockStack, nil
+				},
+			}
+
+			var output bytes.Buffer
+			_, err := RollbackConfig(context.Background(), RollbackOptions{TargetVersion: 4, Operator: mockOperator, Output: &output}, false)
+			if !errors.Is(err, ErrConfigNotRecorded) {
+				t.Errorf("Expected ErrConfigNotRecorded, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPreviewRollback_RestoresConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		importErr       error
+		previewErr      error
+		expectedImports int
+	}{
+		{name: "preview succeeds", expectedImports: 2},
+		{name: "preview fails", previewErr: errors.New("preview failed"), expectedImports: 2},
+		{name: "import fails", importErr: errors.New("import failed"), expectedImports: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := auto.ConfigMap{
+				"app:replicas": {Value: "3"},
+				"app:token":    {Value: "secret-token", Secret: true},
+			}
+			config := auto.ConfigMap{}
+			for k, v := range original {
+				config[k] = v
+			}
+
+			configReads, imports := 0, 0
+			mockStack := &MockRollbackStack{
+				ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+					imports++
+					if imports == 1 {
+						// The import of the target state changes the config
+						config["app:replicas"] = auto.ConfigValue{Value: "1"}
+						config["app:added"] = auto.ConfigValue{Value: "yes"}
+						delete(config, "app:token")
+						return tt.importErr
+					}
+					return nil
+				},
+				PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+					return auto.PreviewResult{}, tt.previewErr
+				},
+				GetAllConfigFunc: func(ctx context.Context) (auto.ConfigMap, error) {
+					configReads++
+					snapshot := auto.ConfigMap{}
+					for k, v := range config {
+						snapshot[k] = v
+					}
+					return snapshot, nil
+				},
+				SetAllConfigFunc: func(ctx context.Context, values auto.ConfigMap) error {
+					for k, v := range values {
+						config[k] = v
+					}
+					return nil
+				},
+				RemoveAllConfigFunc: func(ctx context.Context, keys []string) error {
+					for _, k := range keys {
+						delete(config, k)
+					}
+					return nil
+				},
+			}
+
+			opts := RollbackOptions{
+				StackName:          "test",
+				TargetVersion:      1,
+				Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+				CheckpointProvider: exportCheckpoints,
+				Output:             &bytes.Buffer{},
+			}
+
+			_, err := PreviewRollback(context.Background(), opts)
+			if (tt.importErr != nil || tt.previewErr != nil) != (err != nil) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if imports != tt.expectedImports {
+				t.Errorf("Expected %d imports, got %d", tt.expectedImports, imports)
+			}
+			if configReads != 2 {
+				t.Errorf("Expected the config to be read once before and once after the import, got %d reads", configReads)
+			}
+			if !reflect.DeepEqual(config, original) {
+				t.Errorf("Expected config %v to be restored, got %v", original, config)
+			}
+		})
+	}
+}
+
+func TestPreviewRollback_ConfigSnapshotError(t *testing.T) {
+	imported := false
+	mockStack := &MockRollbackStack{
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			imported = true
+			return nil
+		},
+		GetAllConfigFunc: func(ctx context.Context) (auto.ConfigMap, error) {
+			return nil, errors.New("config unavailable")
+		},
+	}
+
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+		CheckpointProvider: exportCheckpoints,
+		Output:             &bytes.Buffer{},
+	}
+
+	if _, err := PreviewRollback(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "snapshot config") {
+		t.Fatalf("Expected a config snapshot error, got %v", err)
+	}
+	if imported {
+		t.Error("Expected nothing to be imported when the config cannot be snapshotted")
+	}
+}
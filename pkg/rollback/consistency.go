@@ -0,0 +1,68 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// ErrPreviewInconsistent is returned by CheckPreviewConsistency when the
+// change counts shown to the user do not match what the preview reported
+var ErrPreviewInconsistent = errors.New("preview change counts are inconsistent")
+
+// replacementSubOps are the extra steps of a replacement or read. The
+// engine reports them as steps but counts the replacement once.
+var replacementSubOps = map[string]bool{
+	string(apitype.OpCreateReplacement):    true,
+	string(apitype.OpDeleteReplaced):       true,
+	string(apitype.OpReadReplacement):      true,
+	string(apitype.OpDiscardReplaced):      true,
+	string(apitype.OpRemovePendingReplace): true,
+	string(apitype.OpImportReplacement):    true,
+}
+
+// CheckPreviewConsistency cross-checks a preview's change summary, the
+// changes derived from it and the steps enumerated from its engine events.
+// Every op in the summary must be kept in changes, and the count of each op
+// must match the number of steps performing it. Unchanged resources and the
+// sub-steps of replacements are not compared.
+func CheckPreviewConsistency(summary map[apitype.OpType]int, changes map[string]int, steps []ResourceStep) error {
+	var problems []string
+	for op, n := range summary {
+		if got, ok := changes[string(op)]; !ok || got != n {
+			problems = append(problems, fmt.Sprintf("%s: pulumi reported %d, shown as %d", op, n, got))
+		}
+	}
+
+	stepCounts := make(map[string]int)
+	for _, s := range steps {
+		stepCounts[s.Op]++
+	}
+	ops := make(map[string]bool)
+	for op := range changes {
+		ops[op] = true
+	}
+	for op := range stepCounts {
+		ops[op] = true
+	}
+	for op := range ops {
+		if op == string(apitype.OpSame) || replacementSubOps[op] {
+			continue
+		}
+		if changes[op] != stepCounts[op] {
+			problems = append(problems, fmt.Sprintf("%s: summary has %d, steps have %d", op, changes[op], stepCounts[op]))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("%w: %s", ErrPreviewInconsistent, strings.Join(problems, "; "))
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestCheckPreviewConsistency(t *testing.T) {
+	steps := []ResourceStep{
+		{URN: "urn:a", Op: "delete"},
+		{URN: "urn:b", Op: "replace"},
+		{URN: "urn:b", Op: "create-replacement"},
+		{URN: "urn:b", Op: "delete-replaced"},
+	}
+
+	tests := []struct {
+		name      string
+		summary   map[apitype.OpType]int
+		changes   map[string]int
+		steps     []ResourceStep
+		expectErr bool
+	}{
+		{
+			name:    "consistent",
+			summary: map[apitype.OpType]int{apitype.OpDelete: 1, apitype.OpReplace: 1, apitype.OpSame: 4},
+			changes: map[string]int{"delete": 1, "replace": 1, "same": 4},
+			steps:   steps,
+		},
+		{
+			name:      "dropped key",
+			summary:   map[apitype.OpType]int{apitype.OpDelete: 1, apitype.OpReplace: 1},
+			changes:   map[string]int{"delete": 1},
+			steps:     steps,
+			expectErr: true,
+		},
+		{
+			name:      "count differs from steps",
+			summary:   map[apitype.OpType]int{apitype.OpDelete: 2, apitype.OpReplace: 1},
+			changes:   map[string]int{"delete": 2, "replace": 1},
+			steps:     steps,
+			expectErr: true,
+		},
+		{
+			name:      "step missing from summary",
+			summary:   map[apitype.OpType]int{apitype.OpReplace: 1},
+			changes:   map[string]int{"replace": 1},
+			steps:     steps,
+			expectErr: true,
+		},
+		{
+			name:    "no changes",
+			summary: map[apitype.OpType]int{apitype.OpSame: 3},
+			changes: map[string]int{"same": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPreviewConsistency(tt.summary, tt.changes, tt.steps)
+			if tt.expectErr && !errors.Is(err, ErrPreviewInconsistent) {
+				t.Errorf("Expected ErrPreviewInconsistent, got %v", err)
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPreviewRollback_VerifyPreview(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return deployment(`{}`), nil
+		},
+		// The summary reports an update the events never announce
+		PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+			previewOpts := &optpreview.Options{}
+			for _, o := range opts {
+				o.ApplyOption(previewOpts)
+			}
+			for _, ch := range previewOpts.EventStreams {
+				ch <- stepEvent(apitype.OpDelete, "urn:b", "aws:s3/bucket:Bucket")
+			}
+			return auto.PreviewResult{ChangeSummary: map[apitype.OpType]int{apitype.OpDelete: 1, apitype.OpUpdate: 1}}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+	}
+	if _, err := PreviewRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Expected no check without VerifyPreview, got %v", err)
+	}
+
+	opts.VerifyPreview = true
+	if _, err := PreviewRollback(context.Background(), opts); !errors.Is(err, ErrPreviewInconsistent) {
+		t.Errorf("Expected ErrPreviewInconsistent, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// DiffKind is how a resource differs between two checkpoints
+type DiffKind string
+
+const (
+	DiffAdded     DiffKind = "added"
+	DiffRemoved   DiffKind = "removed"
+	DiffChanged   DiffKind = "changed"
+	DiffUnchanged DiffKind = "unchanged"
+	// DiffRenamed is a resource whose URN changed, linked by an alias
+	DiffRenamed DiffKind = "renamed"
+)
+
+// ResourceDiff is how one resource differs between two checkpoints
+type ResourceDiff struct {
+	URN  string   `json:"urn"`
+	Type string   `json:"type"`
+	Kind DiffKind `json:"kind"`
+	// From is the URN a renamed resource had in the from checkpoint
+	From string `json:"from,omitempty"`
+	// Properties lists the inputs and outputs whose values differ, and
+	// "id" when the resource ID does, for changed and renamed resources
+	Properties []string `json:"properties,omitempty"`
+}
+
+// DiffCheckpoints compares the resources of two checkpoints by URN.
+// Resources only in from are removed, resources only in to are added,
+// unless an alias links the two URNs, in which case the resource is
+// renamed (see FindRenames). Values are compared as stored, so a secret
+// that was re-encrypted counts as a change. Diffs are sorted by URN.
+func DiffCheckpoints(from, to apitype.UntypedDeployment) ([]ResourceDiff, error) {
+	fromState, err := parseDeployment(from)
+	if err != nil {
+		return nil, err
+	}
+	toState, err := parseDeployment(to)
+	if err != nil {
+		return nil, err
+	}
+
+	fromByURN := resourcesByURN(fromState.Resources)
+	toByURN := resourcesByURN(toState.Resources)
+
+	var diffs []ResourceDiff
+	renamedFrom := make(map[string]bool)
+	renamedTo := make(map[string]bool)
+	for _, r := range matchRenames(liveResources(fromState.Resources), liveResources(toState.Resources)) {
+		renamedFrom[r.From] = true
+		renamedTo[r.To] = true
+		res := toByURN[r.To]
+		diffs = append(diffs, ResourceDiff{
+			URN:        r.To,
+			Type:       string(res.Type),
+			Kind:       DiffRenamed,
+			From:       r.From,
+			Properties: changedProperties(fromByURN[r.From], res),
+		})
+	}
+
+	for urn, old := range fromByURN {
+		if _, ok := toByURN[urn]; !ok && !renamedFrom[urn] {
+			diffs = append(diffs, ResourceDiff{URN: urn, Type: string(old.Type), Kind: DiffRemoved})
+		}
+	}
+	for urn, res := range toByURN {
+		if renamedTo[urn] {
+			continue
+		}
+		old, ok := fromByURN[urn]
+		if !ok {
+			diffs = append(diffs, ResourceDiff{URN: urn, Type: string(res.Type), Kind: DiffAdded})
+			continue
+		}
+		diff := ResourceDiff{URN: urn, Type: string(res.Type), Kind: DiffUnchanged}
+		if props := changedProperties(old, res); len(props) > 0 {
+			diff.Kind = DiffChanged
+			diff.Properties = props
+		}
+		diffs = append(diffs, diff)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].URN < diffs[j].URN
+	})
+	return diffs, nil
+}
+
+// DiffVersions compares the checkpoints of two versions of a stack
+func DiffVersions(ctx context.Context, opts RollbackOptions, from, to int) ([]ResourceDiff, error) {
+	opts = withDefaults(opts)
+
+	stack, err := selectStack(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select stack: %w", err)
+	}
+
+	fromCheckpoint, _, err := getCheckpointForVersion(ctx, stack, from, opts.CheckpointProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint for version %d: %w", from, err)
+	}
+	toCheckpoint, _, err := getCheckpointForVersion(ctx, stack, to, opts.CheckpointProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint for version %d: %w", to, err)
+	}
+	return DiffCheckpoints(fromCheckpoint, toCheckpoint)
+}
+
+// resourcesByURN indexes resources by URN, leaving out resources that are
+// pending deletion after a replacement
+func resourcesByURN(resources []apitype.ResourceV3) map[string]apitype.ResourceV3 {
+	byURN := make(map[string]apitype.ResourceV3, len(resources))
+	for _, res := range resources {
+		if !res.Delete {
+			byURN[string(res.URN)] = res
+		}
+	}
+	return byURN
+}
+
+// liveResources leaves out resources that are pending deletion after a
+// replacement
+func liveResources(resources []apitype.ResourceV3) []apitype.ResourceV3 {
+	live := make([]apitype.ResourceV3, 0, len(resources))
+	for _, res := range resources {
+		if !res.Delete {
+			live = append(live, res)
+		}
+	}
+	return live
+}
+
+// changedProperties returns the sorted names of the properties that differ
+func changedProperties(from, to apitype.ResourceV3) []string {
+	changed := make(map[string]bool)
+	if from.ID != to.ID {
+		changed["id"] = true
+	}
+	for _, props := range [][2]map[string]interface{}{{from.Inputs, to.Inputs}, {from.Outputs, to.Outputs}} {
+		for key, value := range props[0] {
+			if other, ok := props[1][key]; !ok || !reflect.DeepEqual(value, other) {
+				changed[key] = true
+			}
+		}
+		for key := range props[1] {
+			if _, ok := props[0][key]; !ok {
+				changed[key] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rename pairs a resource URN in one checkpoint with the URN it has in
+// another, as recorded by Pulumi aliases
+type Rename struct {
+	From string
+	To   string
+}
+
+// FindRenames pairs resources that exist under different URNs in the two
+// deployments and are linked by an alias in either direction. Resources whose
+// URN exists in both deployments are never treated as renames.
+func FindRenames(from, to apitype.UntypedDeployment) ([]Rename, error) {
+	fromState, err := parseDeployment(from)
+	if err != nil {
+		return nil, err
+	}
+	toState, err := parseDeployment(to)
+	if err != nil {
+		return nil, err
+	}
+	return matchRenames(fromState.Resources, toState.Resources), nil
+}
+
+func matchRenames(from, to []apitype.ResourceV3) []Rename {
+	fromByURN := make(map[string]apitype.ResourceV3, len(from))
+	for _, res := range from {
+		fromByURN[string(res.URN)] = res
+	}
+	toByURN := make(map[string]apitype.ResourceV3, len(to))
+	for _, res := range to {
+		toByURN[string(res.URN)] = res
+	}
+
+	// Only resources missing from the other side can be renames
+	removed := make(map[string]bool)
+	for urn := range fromByURN {
+		if _, ok := toByURN[urn]; !ok {
+			removed[urn] = true
+		}
+	}
+	added := make(map[string]bool)
+	for urn := range toByURN {
+		if _, ok := fromByURN[urn]; !ok {
+			added[urn] = true
+		}
+	}
+
+	var renames []Rename
+	pair := func(fromURN, toURN string) {
+		if removed[fromURN] && added[toURN] {
+			renames = append(renames, Rename{From: fromURN, To: toURN})
+			delete(removed, fromURN)
+			delete(added, toURN)
+		}
+	}
+
+	// Forward rename: the new resource lists its old URN as an alias
+	for _, res := range to {
+		for _, alias := range res.Aliases {
+			pair(string(alias), string(res.URN))
+		}
+	}
+	// Reverse rename (e.g. rolling back across a refactor): the old resource
+	// lists the new URN as an alias
+	for _, res := range from {
+		for _, alias := range res.Aliases {
+			pair(string(res.URN), string(alias))
+		}
+	}
+
+	sort.Slice(renames, func(i, j int) bool {
+		return renames[i].From < renames[j].From
+	})
+	return renames
+}
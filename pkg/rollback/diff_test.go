@@ -0,0 +1,202 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func deployment(s string) apitype.UntypedDeployment {
+	return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(s)}
+}
+
+func TestFindRenames(t *testing.T) {
+	oldURN := "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::old"
+	newURN := "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::new"
+	keptURN := "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::kept"
+
+	withoutAlias := `{"resources": [
+		{"urn": "` + oldURN + `", "type": "aws:s3/bucket:Bucket"},
+		{"urn": "` + keptURN + `", "type": "aws:s3/bucket:Bucket"}
+	]}`
+	withAlias := `{"resources": [
+		{"urn": "` + newURN + `", "type": "aws:s3/bucket:Bucket", "aliases": ["` + oldURN + `"]},
+		{"urn": "` + keptURN + `", "type": "aws:s3/bucket:Bucket", "aliases": ["` + oldURN + `"]}
+	]}`
+	unrelated := `{"resources": [
+		{"urn": "` + newURN + `", "type": "aws:s3/bucket:Bucket"}
+	]}`
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected []Rename
+	}{
+		{
+			name:     "forward rename via alias",
+			from:     withoutAlias,
+			to:       withAlias,
+			expected: []Rename{{From: oldURN, To: newURN}},
+		},
+		{
+			name:     "reverse rename when rolling back",
+			from:     withAlias,
+			to:       withoutAlias,
+			expected: []Rename{{From: newURN, To: oldURN}},
+		},
+		{
+			name: "no alias is not a rename",
+			from: withoutAlias,
+			to:   unrelated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renames, err := FindRenames(deployment(tt.from), deployment(tt.to))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(renames) != len(tt.expected) {
+				t.Fatalf("Expected %d renames, got %v", len(tt.expected), renames)
+			}
+			for i, exp := range tt.expected {
+				if renames[i] != exp {
+					t.Errorf("Rename %d: expected %v, got %v", i, exp, renames[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFindRenames_InvalidDeployment(t *testing.T) {
+	if _, err := FindRenames(deployment(`{invalid}`), deployment(`{}`)); err == nil {
+		t.Error("Expected error for invalid deployment")
+	}
+}
+
+func TestDiffCheckpoints(t *testing.T) {
+	from := deployment(`{"resources": [
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::kept", "type": "aws:s3/bucket:Bucket", "id": "kept", "inputs": {"acl": "private"}},
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::changed", "type": "aws:s3/bucket:Bucket", "id": "c1",
+			"inputs": {"acl": "private", "tags": {"env": "dev"}}, "outputs": {"arn": "arn:1"}},
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::removed", "type": "aws:s3/bucket:Bucket", "id": "r"},
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::Removed", "type": "aws:s3/bucket:Bucket", "id": "R"}
+	]}`)
+	to := deployment(`{"resources": [
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::kept", "type": "aws:s3/bucket:Bucket", "id": "kept", "inputs": {"acl": "private"}},
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::changed", "type": "aws:s3/bucket:Bucket", "id": "c2",
+			"inputs": {"tags": {"env": "prod"}, "versioning": true}, "outputs": {"arn": "arn:1"}},
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::added", "type": "aws:s3/bucket:Bucket", "id": "a"},
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::removed", "type": "aws:s3/bucket:Bucket", "id": "r", "delete": true}
+	]}`)
+
+	diffs, err := DiffCheckpoints(from, to)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []ResourceDiff{
+		{URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::Removed", Type: "aws:s3/bucket:Bucket", Kind: DiffRemoved},
+		{URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::added", Type: "aws:s3/bucket:Bucket", Kind: DiffAdded},
+		{URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::changed", Type: "aws:s3/bucket:Bucket", Kind: DiffChanged,
+			Properties: []string{"acl", "id", "tags", "versioning"}},
+		{URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::kept", Type: "aws:s3/bucket:Bucket", Kind: DiffUnchanged},
+		{URN: "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::removed", Type: "aws:s3/bucket:Bucket", Kind: DiffRemoved},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, diffs)
+	}
+
+	if _, err := DiffCheckpoints(deployment(`not json`), to); err == nil {
+		t.Error("Expected error for an invalid checkpoint")
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 2}, {Version: 1}}, nil
+		},
+	}
+	provider := &MockCheckpointProvider{
+		CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+			if version == 1 {
+				return deployment(`{"resources": []}`), nil
+			}
+			return deployment(`{"resources": [{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b", "type": "aws:s3/bucket:Bucket"}]}`), nil
+		},
+	}
+	opts := RollbackOptions{
+		StackName:          "dev",
+		Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+		CheckpointProvider: provider,
+	}
+
+	diffs, err := DiffVersions(context.Background(), opts, 1, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].Kind != DiffAdded {
+		t.Errorf("Expected one added resource, got %+v", diffs)
+	}
+
+	if _, err := DiffVersions(context.Background(), opts, 1, 3); err == nil {
+		t.Error("Expected error for a version missing from history")
+	}
+}
+
+func TestDiffVersions_Rename(t *testing.T) {
+	oldURN := "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::old"
+	newURN := "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::new"
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 2}, {Version: 1}}, nil
+		},
+	}
+	provider := &MockCheckpointProvider{
+		CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+			if version == 1 {
+				return deployment(`{"resources": [{"urn": "` + oldURN + `", "type": "aws:s3/bucket:Bucket", "id": "b", "inputs": {"acl": "private"}}]}`), nil
+			}
+			return deployment(`{"resources": [
+				{"urn": "` + newURN + `", "type": "aws:s3/bucket:Bucket", "id": "b", "inputs": {"acl": "public-read"}, "aliases": ["` + oldURN + `"]},
+				{"urn": "` + oldURN + `", "type": "aws:s3/bucket:Bucket", "id": "b", "delete": true}
+			]}`), nil
+		},
+	}
+	opts := RollbackOptions{
+		StackName:          "dev",
+		Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+		CheckpointProvider: provider,
+	}
+
+	diffs, err := DiffVersions(context.Background(), opts, 1, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []ResourceDiff{
+		{URN: newURN, Type: "aws:s3/bucket:Bucket", Kind: DiffRenamed, From: oldURN, Properties: []string{"acl"}},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, diffs)
+	}
+
+	// Rolling back across the rename pairs the resources the other way
+	diffs, err = DiffVersions(context.Background(), opts, 2, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].Kind != DiffRenamed || diffs[0].From != newURN || diffs[0].URN != oldURN {
+		t.Errorf("Expected %s renamed back to %s, got %+v", newURN, oldURN, diffs)
+	}
+}
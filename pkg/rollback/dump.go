@@ -0,0 +1,77 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// StateDump is the pair of checkpoints written by DumpStates
+type StateDump struct {
+	// CurrentPath holds the state of the stack before the rollback
+	CurrentPath string `json:"currentPath"`
+	// TargetPath holds the checkpoint the stack was rolled back to
+	TargetPath string `json:"targetPath"`
+}
+
+// DumpStates validates the current state and the target checkpoint of a
+// rollback and writes both to dir, uncompressed so they can be compared
+// directly unless compress is set. The file names carry the stack, the time
+// and both versions, e.g. prod-20261017T101500Z-current-v12.checkpoint.json,
+// with CompressedExtension appended when compressed.
+func DumpStates(dir, stackName string, currentVersion int, current apitype.UntypedDeployment, targetVersion int, target apitype.UntypedDeployment, now time.Time, compress bool) (*StateDump, error) {
+	if err := ValidateDeployment(current); err != nil {
+		return nil, fmt.Errorf("current state of version %d is invalid: %w", currentVersion, err)
+	}
+	if err := ValidateDeployment(target); err != nil {
+		return nil, fmt.Errorf("target checkpoint of version %d is invalid: %w", targetVersion, err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create state dump directory: %w", err)
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%s-%s", sanitizeFileName(stackName), now.UTC().Format("20060102T150405Z")))
+	currentPath, err := WriteCheckpointFile(fmt.Sprintf("%s-current-v%d.checkpoint.json", base, currentVersion), current, compress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write current state: %w", err)
+	}
+	targetPath, err := WriteCheckpointFile(fmt.Sprintf("%s-target-v%d.checkpoint.json", base, targetVersion), target, compress)
+	if err != nil {
+		os.Remove(currentPath)
+		return nil, fmt.Errorf("failed to write target checkpoint: %w", err)
+	}
+	return &StateDump{CurrentPath: currentPath, TargetPath: targetPath}, nil
+}
+
+// currentVersion returns the newest version in the stack's history, or 0
+// for a stack without updates
+func currentVersion(updates []auto.UpdateSummary) int {
+	latest := 0
+	for _, u := range updates {
+		latest = max(latest, u.Version)
+	}
+	return latest
+}
+
+// dumpStates writes the rollback's checkpoints to opts.DumpStatesDir, if
+// set. updates is the stack's history, already fetched by the rollback.
+func dumpStates(updates []auto.UpdateSummary, current, target apitype.UntypedDeployment, opts RollbackOptions) (*StateDump, error) {
+	if opts.DumpStatesDir == "" {
+		return nil, nil
+	}
+	version := currentVersion(updates)
+	dump, err := DumpStates(opts.DumpStatesDir, opts.StackName, version, current, opts.TargetVersion, target, time.Now(), opts.Compress)
+	if err != nil {
+		return nil, err
+	}
+	opts.Logger.Infof("Wrote current state (version %d) to %s", version, dump.CurrentPath)
+	opts.Logger.Infof("Wrote target checkpoint (version %d) to %s", opts.TargetVersion, dump.TargetPath)
+	return dump, nil
+}
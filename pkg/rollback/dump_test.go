@@ -0,0 +1,127 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestDumpStates(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dumps")
+	now := time.Date(2026, 10, 17, 10, 15, 0, 0, time.UTC)
+	current := deployment(`{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b","id":"new"}]}`)
+	target := deployment(`{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b","id":"old"}]}`)
+
+	dump, err := DumpStates(dir, "org/proj/prod", 12, current, 4, target, now, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filepath.Base(dump.CurrentPath) != "org_proj_prod-20261017T101500Z-current-v12.checkpoint.json" {
+		t.Errorf("Unexpected current path %s", dump.CurrentPath)
+	}
+	if filepath.Base(dump.TargetPath) != "org_proj_prod-20261017T101500Z-target-v4.checkpoint.json" {
+		t.Errorf("Unexpected target path %s", dump.TargetPath)
+	}
+
+	for path, expected := range map[string]string{dump.CurrentPath: `"new"`, dump.TargetPath: `"old"`} {
+		written, err := ReadCheckpointFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error reading %s: %v", path, err)
+		}
+		if !strings.Contains(string(written.Deployment), expected) {
+			t.Errorf("Expected %s to hold %s, got %s", path, expected, written.Deployment)
+		}
+	}
+
+	if _, err := DumpStates(dir, "prod", 12, current, 4, deployment(`{"resources":`), now, false); err == nil {
+		t.Error("Expected error for an invalid target checkpoint")
+	}
+}
+
+func TestDumpStates_Compress(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 17, 10, 15, 0, 0, time.UTC)
+	current := deployment(`{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b","id":"new"}]}`)
+	target := deployment(`{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b","id":"old"}]}`)
+
+	dump, err := DumpStates(dir, "prod", 12, current, 4, target, now, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for path, expected := range map[string]string{dump.CurrentPath: `"new"`, dump.TargetPath: `"old"`} {
+		if !strings.HasSuffix(path, ".checkpoint.json"+CompressedExtension) {
+			t.Errorf("Expected %s to end in .checkpoint.json.gz", path)
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !bytes.HasPrefix(raw, gzipMagic) {
+			t.Errorf("Expected %s to be gzip-compressed", path)
+		}
+		written, err := ReadCheckpointFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error reading %s: %v", path, err)
+		}
+		if !strings.Contains(string(written.Deployment), expected) {
+			t.Errorf("Expected %s to hold %s, got %s", path, expected, written.Deployment)
+		}
+	}
+}
+
+func TestExecuteRollback_DumpStates(t *testing.T) {
+	dir := t.TempDir()
+	historyCalls := 0
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			historyCalls++
+			return []auto.UpdateSummary{{Version: 7}, {Version: 3}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return deployment(`{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b"}]}`), nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	result, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:          "dev",
+		TargetVersion:      3,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		DumpStatesDir:      dir,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.DumpedStates == nil {
+		t.Fatal("Expected the dumped states in the result")
+	}
+	if !strings.HasSuffix(result.DumpedStates.CurrentPath, "-current-v7.checkpoint.json") ||
+		!strings.HasSuffix(result.DumpedStates.TargetPath, "-target-v3.checkpoint.json") {
+		t.Errorf("Expected both versions in the file names, got %+v", result.DumpedStates)
+	}
+	if !strings.Contains(output.String(), result.DumpedStates.TargetPath) {
+		t.Error("Expected the dump paths to be logged")
+	}
+	// The version lookup and the busy check; the dump reuses the former
+	if historyCalls != 2 {
+		t.Errorf("Expected 2 history calls, got %d", historyCalls)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"fmt"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// ErrVersionNotFound is returned, as a *history.VersionNotFoundError, when
+// the target version is not in the stack's history
+var ErrVersionNotFound = history.ErrVersionNotFound
+
+// versionNotFound returns the error for a version missing from updates
+func versionNotFound(updates []auto.UpdateSummary, version int) error {
+	available := make([]int, 0, len(updates))
+	for _, update := range updates {
+		available = append(available, update.Version)
+	}
+	return &history.VersionNotFoundError{Version: version, Available: available}
+}
+
+// Phase identifies a step of a rollback. Errors, logs and results name
+// phases by their String value, which is stable.
+type Phase int
+
+// Phases of a rollback, in the order ExecuteRollback runs them. Validate
+// checks both states before anything is changed. A dry run previews
+// instead of running up. Restore is the re-import of the current state
+// after a preview.
+const (
+	PhaseSelectStack Phase = iota + 1
+	PhaseFetchCheckpoint
+	PhaseExportCurrent
+	PhaseValidate
+	PhaseImport
+	PhaseVerify
+	PhaseRefresh
+	PhaseUp
+	PhasePreview
+	PhaseRestore
+)
+
+var phaseNames = map[Phase]string{
+	PhaseSelectStack:     "select-stack",
+	PhaseFetchCheckpoint: "fetch-checkpoint",
+	PhaseExportCurrent:   "export-current",
+	PhaseValidate:        "validate",
+	PhaseImport:          "import",
+	PhaseVerify:          "verify",
+	PhaseRefresh:         "refresh",
+	PhaseUp:              "up",
+	PhasePreview:         "preview",
+	PhaseRestore:         "restore",
+}
+
+// Phases returns every phase in order
+func Phases() []Phase {
+	return []Phase{
+		PhaseSelectStack, PhaseFetchCheckpoint, PhaseExportCurrent, PhaseValidate,
+		PhaseImport, PhaseVerify, PhaseRefresh, PhaseUp, PhasePreview, PhaseRestore,
+	}
+}
+
+func (p Phase) String() string {
+	if name, ok := phaseNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("phase(%d)", int(p))
+}
+
+// MarshalText encodes the phase by name
+func (p Phase) MarshalText() ([]byte, error) {
+	if _, ok := phaseNames[p]; !ok {
+		return nil, fmt.Errorf("unknown phase %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a phase name
+func (p *Phase) UnmarshalText(text []byte) error {
+	parsed, err := ParsePhase(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// ParsePhase returns the phase with the given name
+func ParsePhase(name string) (Phase, error) {
+	for phase, n := range phaseNames {
+		if n == name {
+			return phase, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown phase %q", name)
+}
+
+// RollbackError is returned by ExecuteRollback and records how far the
+// rollback got before it failed
+type RollbackError struct {
+	// Phase is the phase that failed
+	Phase Phase
+	Err   error
+	// BackupFile is the backup written before the failure, if any
+	BackupFile string
+	// ResourceChanges holds changes already applied to the stack state,
+	// such as those made by the refresh
+	ResourceChanges map[string]int
+	// Phases records the phases run up to and including the failed one
+	Phases []PhaseTiming
+	// Recovered is set when the rollback was interrupted after the import,
+	// or the imported state failed verification, and the state from before
+	// the rollback was imported again
+	Recovered bool
+}
+
+func (e *RollbackError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
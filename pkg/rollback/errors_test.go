@@ -0,0 +1,76 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func TestPhase(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, phase := range Phases() {
+		name := phase.String()
+		if seen[name] {
+			t.Errorf("Duplicate phase name %s", name)
+		}
+		seen[name] = true
+
+		parsed, err := ParsePhase(name)
+		if err != nil || parsed != phase {
+			t.Errorf("ParsePhase(%q): expected %d, got %d (%v)", name, phase, parsed, err)
+		}
+	}
+	if len(seen) != len(phaseNames) {
+		t.Errorf("Expected Phases to list all %d phases, got %d", len(phaseNames), len(seen))
+	}
+
+	if _, err := ParsePhase("teleport"); err == nil {
+		t.Error("Expected error for unknown phase")
+	}
+	if got := Phase(0).String(); got != "phase(0)" {
+		t.Errorf("Expected phase(0), got %s", got)
+	}
+}
+
+func TestPhase_JSON(t *testing.T) {
+	data, err := json.Marshal(map[string]Phase{"phase": PhaseRefresh})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != `{"phase":"refresh"}` {
+		t.Errorf("Expected the phase encoded by name, got %s", data)
+	}
+
+	var decoded struct{ Phase Phase }
+	if err := json.Unmarshal([]byte(`{"Phase":"verify"}`), &decoded); err != nil || decoded.Phase != PhaseVerify {
+		t.Errorf("Expected verify, got %v (%v)", decoded.Phase, err)
+	}
+	if _, err := json.Marshal(Phase(0)); err == nil {
+		t.Error("Expected error encoding an unknown phase")
+	}
+}
+
+func TestGetCheckpointForVersion_VersionNotFound(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 3}, {Version: 2}}, nil
+		},
+	}
+
+	_, err := GetCheckpointForVersion(context.Background(), mockStack, 99)
+	if !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("Expected ErrVersionNotFound, got %v", err)
+	}
+	var notFound *history.VersionNotFoundError
+	if !errors.As(err, &notFound) || notFound.Version != 99 || !reflect.DeepEqual(notFound.Available, []int{3, 2}) {
+		t.Errorf("Expected the requested and available versions, got %+v", notFound)
+	}
+}
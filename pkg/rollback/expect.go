@@ -0,0 +1,96 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrExpectationFailed is returned when resource changes violate an expectation
+var ErrExpectationFailed = errors.New("resource changes violate expectation")
+
+// Expectation constrains the number of changes of one operation type,
+// e.g. "delete<=0"
+type Expectation struct {
+	Op         string
+	Comparator string
+	Count      int
+}
+
+var expectationPattern = regexp.MustCompile(`^([a-z][a-z-]*)\s*(<=|>=|==|!=|<|>|=)\s*(\d+)$`)
+
+// expectationOps are the operation types an expectation can constrain
+var expectationOps = []string{"create", "update", "delete", "replace", "same"}
+
+// String returns the expectation in the form it is parsed from
+func (e Expectation) String() string {
+	return fmt.Sprintf("%s%s%d", e.Op, e.Comparator, e.Count)
+}
+
+// Check reports whether the given count satisfies the expectation
+func (e Expectation) Check(count int) bool {
+	switch e.Comparator {
+	case "<=":
+		return count <= e.Count
+	case ">=":
+		return count >= e.Count
+	case "<":
+		return count < e.Count
+	case ">":
+		return count > e.Count
+	case "=", "==":
+		return count == e.Count
+	case "!=":
+		return count != e.Count
+	default:
+		return false
+	}
+}
+
+// ParseExpectations parses a comma-separated list of expectations such as
+// "delete<=0,create<=5". The operation must be create, update, delete,
+// replace or same.
+func ParseExpectations(s string) ([]Expectation, error) {
+	var expectations []Expectation
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		match := expectationPattern.FindStringSubmatch(part)
+		if match == nil {
+			return nil, fmt.Errorf("invalid expectation %q (expected <op><comparator><count>, e.g. delete<=0)", part)
+		}
+		if !slices.Contains(expectationOps, match[1]) {
+			return nil, fmt.Errorf("unknown operation %q in expectation %q (expected one of %s)",
+				match[1], part, strings.Join(expectationOps, ", "))
+		}
+		count, err := strconv.Atoi(match[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid count in expectation %q: %w", part, err)
+		}
+		expectations = append(expectations, Expectation{Op: match[1], Comparator: match[2], Count: count})
+	}
+	return expectations, nil
+}
+
+// CheckExpectations checks resource changes against expectations and
+// returns an error naming every violated expectation
+func CheckExpectations(expectations []Expectation, changes map[string]int) error {
+	var violated []string
+	for _, e := range expectations {
+		if count := changes[e.Op]; !e.Check(count) {
+			violated = append(violated, fmt.Sprintf("%s (got %d)", e, count))
+		}
+	}
+	if len(violated) > 0 {
+		return fmt.Errorf("%w: %s", ErrExpectationFailed, strings.Join(violated, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseExpectations(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []Expectation
+		expectError bool
+	}{
+		{
+			name:     "multiple",
+			input:    "delete<=0,create<=5",
+			expected: []Expectation{{"delete", "<=", 0}, {"create", "<=", 5}},
+		},
+		{
+			name:     "spaces",
+			input:    " replace > 1 , update=2, same != 0",
+			expected: []Expectation{{"replace", ">", 1}, {"update", "=", 2}, {"same", "!=", 0}},
+		},
+		{name: "empty", input: ""},
+		{name: "missing count", input: "delete<=", expectError: true},
+		{name: "unknown comparator", input: "delete=<1", expectError: true},
+		{name: "negative count", input: "delete>=-1", expectError: true},
+		{name: "unknown op", input: "delete<=0,destroy<=0", expectError: true},
+		{name: "op outside the change counts", input: "create-replacement>1", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpectations(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d expectations, got %d", len(tt.expected), len(got))
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckExpectations(t *testing.T) {
+	expectations, err := ParseExpectations("delete<=0,create<=5,same>=1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := CheckExpectations(expectations, map[string]int{"create": 5, "same": 3}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err = CheckExpectations(expectations, map[string]int{"create": 6, "delete": 1})
+	if !errors.Is(err, ErrExpectationFailed) {
+		t.Fatalf("Expected ErrExpectationFailed, got %v", err)
+	}
+	for _, name := range []string{"delete<=0 (got 1)", "create<=5 (got 6)", "same>=1 (got 0)"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected error to name %q, got %q", name, err)
+		}
+	}
+}
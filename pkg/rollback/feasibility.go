@@ -0,0 +1,142 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+)
+
+// FeasibilityCacheSchemaVersion is the schema version of FeasibilityCache.
+// Caches with another version are treated as missing.
+const FeasibilityCacheSchemaVersion = 1
+
+// FeasibilityCacheTTL is how long a cached history summary is trusted
+const FeasibilityCacheTTL = time.Hour
+
+// Feasibility is the answer of CachedRollbackFeasibility
+type Feasibility string
+
+const (
+	// FeasibilityYes means a rollback is likely possible
+	FeasibilityYes Feasibility = "yes"
+	// FeasibilityNo means a rollback is not possible
+	FeasibilityNo Feasibility = "no"
+	// FeasibilityUnknown means the cache cannot tell and a live check is needed
+	FeasibilityUnknown Feasibility = "unknown"
+)
+
+// FeasibilityCache is what a UI stores to decide offline whether a stack
+// can be rolled back. It holds no credentials or state.
+type FeasibilityCache struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Stack         string `json:"stack"`
+	// Backend is the kind of state backend, as listed in
+	// CapabilitySet.Backends, or empty if it is not known
+	Backend string `json:"backend,omitempty"`
+	// History summarizes the stack history, if it was fetched
+	History *HistorySummary `json:"history,omitempty"`
+}
+
+// HistorySummary is the part of a stack history that decides whether a
+// rollback is possible
+type HistorySummary struct {
+	LatestVersion int `json:"latestVersion"`
+	// RollbackTargets counts the succeeded versions before the latest
+	RollbackTargets int       `json:"rollbackTargets"`
+	FetchedAt       time.Time `json:"fetchedAt"`
+}
+
+// FeasibilityResult is a feasibility and the reason for it
+type FeasibilityResult struct {
+	Feasibility Feasibility `json:"feasibility"`
+	Reason      string      `json:"reason"`
+}
+
+// NewFeasibilityCache builds the cache entry of a stack from its backend
+// URL and history, e.g. after a live check
+func NewFeasibilityCache(stack, backendURL string, updates []history.UpdateInfo, now time.Time) *FeasibilityCache {
+	summary := &HistorySummary{FetchedAt: now.UTC()}
+	for _, u := range updates {
+		summary.LatestVersion = max(summary.LatestVersion, u.Version)
+	}
+	for _, u := range updates {
+		if u.Version < summary.LatestVersion && u.Result == "succeeded" {
+			summary.RollbackTargets++
+		}
+	}
+	return &FeasibilityCache{
+		SchemaVersion: FeasibilityCacheSchemaVersion,
+		Stack:         stack,
+		Backend:       BackendKind(backendURL),
+		History:       summary,
+	}
+}
+
+// BackendKind returns the kind of a state backend URL, or an empty string
+// if it is not recognized
+func BackendKind(url string) string {
+	switch {
+	case strings.HasPrefix(url, "s3://"):
+		return "s3"
+	case strings.HasPrefix(url, "azblob://"):
+		return "azblob"
+	case strings.HasPrefix(url, "gs://"):
+		return "gcs"
+	case strings.HasPrefix(url, "file://"):
+		return "file"
+	case strings.HasPrefix(url, "https://"), strings.HasPrefix(url, "http://"):
+		return "pulumi-cloud"
+	default:
+		return ""
+	}
+}
+
+// DecodeFeasibilityCache parses a cache written by EncodeFeasibilityCache
+func DecodeFeasibilityCache(data []byte) (*FeasibilityCache, error) {
+	var cache FeasibilityCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse feasibility cache: %w", err)
+	}
+	if cache.SchemaVersion != FeasibilityCacheSchemaVersion {
+		return nil, fmt.Errorf("unsupported feasibility cache schema version %d", cache.SchemaVersion)
+	}
+	return &cache, nil
+}
+
+// EncodeFeasibilityCache serializes a cache
+func EncodeFeasibilityCache(cache *FeasibilityCache) ([]byte, error) {
+	return json.Marshal(cache)
+}
+
+// CachedRollbackFeasibility decides from a cache alone whether a rollback
+// is likely possible, without any live calls. It answers unknown whenever
+// the cache is missing, incomplete or older than FeasibilityCacheTTL, in
+// which case the caller should run a live check.
+func CachedRollbackFeasibility(cache *FeasibilityCache, now time.Time) FeasibilityResult {
+	if cache == nil || cache.SchemaVersion != FeasibilityCacheSchemaVersion {
+		return FeasibilityResult{FeasibilityUnknown, "no cached check"}
+	}
+	if cache.Backend != "" && !slices.Contains(Capabilities().Backends, cache.Backend) {
+		return FeasibilityResult{FeasibilityNo, fmt.Sprintf("backend %s is not supported", cache.Backend)}
+	}
+	if cache.History == nil {
+		return FeasibilityResult{FeasibilityUnknown, "history not cached"}
+	}
+	if age := now.Sub(cache.History.FetchedAt); age > FeasibilityCacheTTL {
+		return FeasibilityResult{FeasibilityUnknown, fmt.Sprintf("cached history is %s old", age.Round(time.Minute))}
+	}
+	if cache.History.RollbackTargets == 0 {
+		return FeasibilityResult{FeasibilityNo, "no earlier successful version to roll back to"}
+	}
+	if cache.Backend == "" {
+		return FeasibilityResult{FeasibilityUnknown, "backend not known"}
+	}
+	return FeasibilityResult{FeasibilityYes, fmt.Sprintf("%d earlier successful version(s)", cache.History.RollbackTargets)}
+}
@@ -0,0 +1,91 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+)
+
+func TestCachedRollbackFeasibility(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	updates := []history.UpdateInfo{
+		{Version: 3, Result: "succeeded"},
+		{Version: 2, Result: "failed"},
+		{Version: 1, Result: "succeeded"},
+	}
+	fresh := NewFeasibilityCache("prod", "s3://state-bucket", updates, now.Add(-time.Minute))
+
+	tests := []struct {
+		name     string
+		cache    *FeasibilityCache
+		expected Feasibility
+	}{
+		{"no cache", nil, FeasibilityUnknown},
+		{"rollback possible", fresh, FeasibilityYes},
+		{"old schema", &FeasibilityCache{SchemaVersion: 0, Backend: "s3", History: fresh.History}, FeasibilityUnknown},
+		{"unsupported backend", &FeasibilityCache{SchemaVersion: 1, Backend: "etcd"}, FeasibilityNo},
+		{"history not cached", &FeasibilityCache{SchemaVersion: 1, Backend: "s3"}, FeasibilityUnknown},
+		{"stale history", NewFeasibilityCache("prod", "s3://b", updates, now.Add(-2*time.Hour)), FeasibilityUnknown},
+		{"nothing to roll back to", NewFeasibilityCache("prod", "s3://b", updates[:2], now), FeasibilityNo},
+		{"unknown backend", NewFeasibilityCache("prod", "", updates, now), FeasibilityUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CachedRollbackFeasibility(tt.cache, now)
+			if result.Feasibility != tt.expected {
+				t.Errorf("Expected %s, got %s (%s)", tt.expected, result.Feasibility, result.Reason)
+			}
+			if result.Reason == "" {
+				t.Error("Expected a reason")
+			}
+		})
+	}
+}
+
+func TestFeasibilityCacheRoundTrip(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	cache := NewFeasibilityCache("prod", "https://api.pulumi.com", []history.UpdateInfo{{Version: 2, Result: "succeeded"}, {Version: 1, Result: "succeeded"}}, now)
+
+	data, err := EncodeFeasibilityCache(cache)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded, err := DecodeFeasibilityCache(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded.Backend != "pulumi-cloud" || decoded.History.LatestVersion != 2 || decoded.History.RollbackTargets != 1 {
+		t.Errorf("Unexpected cache after round trip: %+v %+v", decoded, decoded.History)
+	}
+	if !decoded.History.FetchedAt.Equal(now) {
+		t.Errorf("Expected fetch time %s, got %s", now, decoded.History.FetchedAt)
+	}
+
+	if _, err := DecodeFeasibilityCache([]byte(`{"schemaVersion":99}`)); err == nil {
+		t.Error("Expected error for an unsupported schema version")
+	}
+	if _, err := DecodeFeasibilityCache([]byte(`not json`)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestBackendKind(t *testing.T) {
+	tests := map[string]string{
+		"s3://bucket/prefix":     "s3",
+		"azblob://container":     "azblob",
+		"gs://bucket":            "gcs",
+		"file://~":               "file",
+		"https://api.pulumi.com": "pulumi-cloud",
+		"etcd://cluster":         "",
+	}
+	for url, expected := range tests {
+		if got := BackendKind(url); got != expected {
+			t.Errorf("BackendKind(%q): expected %q, got %q", url, expected, got)
+		}
+	}
+}
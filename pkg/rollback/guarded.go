@@ -0,0 +1,78 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrAlreadyAtVersion is returned by GuardedExecute when the target is
+	// the current version and AllowNoop is not set
+	ErrAlreadyAtVersion = errors.New("target version is the current version")
+	// ErrRollbackDeclined is returned by GuardedExecute when the
+	// confirmation callback declines the rollback
+	ErrRollbackDeclined = errors.New("rollback declined")
+)
+
+// PreviewResult is passed to the confirmation callback of GuardedExecute
+type PreviewResult struct {
+	StackName      string
+	CurrentVersion int
+	TargetVersion  int
+	// Preview holds the changes the rollback is expected to make
+	Preview *RollbackResult
+}
+
+// ConfirmFunc decides whether a previewed rollback should go ahead
+type ConfirmFunc func(PreviewResult) (bool, error)
+
+// GuardedExecute validates the target version, previews the rollback and
+// executes it only if confirm approves the previewed changes
+func GuardedExecute(ctx context.Context, opts RollbackOptions, confirm ConfirmFunc) (*RollbackResult, error) {
+	opts = withDefaults(opts)
+
+	stack, err := selectStack(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select stack: %w", err)
+	}
+
+	history, err := stack.History(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	if !VersionExistsInHistory(history, opts.TargetVersion) {
+		return nil, versionNotFound(history, opts.TargetVersion)
+	}
+
+	current := 0
+	for _, update := range history {
+		current = max(current, update.Version)
+	}
+	if opts.TargetVersion == current && !opts.AllowNoop {
+		return nil, fmt.Errorf("%w: version %d", ErrAlreadyAtVersion, current)
+	}
+
+	preview, err := PreviewRollback(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("preview failed: %w", err)
+	}
+
+	ok, err := confirm(PreviewResult{
+		StackName:      opts.StackName,
+		CurrentVersion: current,
+		TargetVersion:  opts.TargetVersion,
+		Preview:        preview,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("confirmation failed: %w", err)
+	}
+	if !ok {
+		return nil, ErrRollbackDeclined
+	}
+
+	return ExecuteRollback(ctx, opts)
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestGuardedExecute(t *testing.T) {
+	confirmErr := errors.New("approval service unavailable")
+
+	tests := []struct {
+		name          string
+		targetVersion int
+		allowNoop     bool
+		approve       bool
+		confirmErr    error
+		expectedErr   error
+		expectConfirm bool
+		expectUp      bool
+	}{
+		{name: "approved", targetVersion: 1, approve: true, expectConfirm: true, expectUp: true},
+		{name: "declined", targetVersion: 1, expectedErr: ErrRollbackDeclined, expectConfirm: true},
+		{name: "confirm error", targetVersion: 1, confirmErr: confirmErr, expectedErr: confirmErr, expectConfirm: true},
+		{name: "already at version", targetVersion: 2, expectedErr: ErrAlreadyAtVersion},
+		{name: "noop allowed", targetVersion: 2, allowNoop: true, approve: true, expectConfirm: true, expectUp: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upCalled := false
+			mockStack := &MockRollbackStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					return []auto.UpdateSummary{{Version: 2}, {Version: 1}}, nil
+				},
+				ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+					return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
+				},
+				PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+					return auto.PreviewResult{
+						ChangeSummary: map[apitype.OpType]int{apitype.OpUpdate: 2},
+					}, nil
+				},
+				UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+					upCalled = true
+					return auto.UpResult{}, nil
+				},
+			}
+
+			mockOperator := &MockStackOperator{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+					return mockStack, nil
+				},
+			}
+
+			var output bytes.Buffer
+			opts := RollbackOptions{
+				StackName:          "test",
+				TargetVersion:      tt.targetVersion,
+				AllowNoop:          tt.allowNoop,
+				Operator:           mockOperator,
+				CheckpointProvider: exportCheckpoints,
+				Output:             &output,
+			}
+
+			confirmCalled := false
+			_, err := GuardedExecute(context.Background(), opts, func(p PreviewResult) (bool, error) {
+				confirmCalled = true
+				if p.CurrentVersion != 2 || p.TargetVersion != tt.targetVersion {
+					t.Errorf("Unexpected versions in preview result: %+v", p)
+				}
+				if p.Preview.ResourceChanges["update"] != 2 {
+					t.Errorf("Expected 2 updates in preview, got %d", p.Preview.ResourceChanges["update"])
+				}
+				return tt.approve, tt.confirmErr
+			})
+
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Expected error %v, got %v", tt.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if confirmCalled != tt.expectConfirm {
+				t.Errorf("Expected confirm called=%v, got %v", tt.expectConfirm, confirmCalled)
+			}
+			if upCalled != tt.expectUp {
+				t.Errorf("Expected up called=%v, got %v", tt.expectUp, upCalled)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob" // azblob:// backends
+	_ "gocloud.dev/blob/fileblob"  // file:// backends
+	_ "gocloud.dev/blob/gcsblob"   // gs:// backends
+	_ "gocloud.dev/blob/s3blob"    // s3:// backends
+)
+
+// ErrCheckpointNotInStore is returned when a self-managed backend has no
+// history object for a version
+var ErrCheckpointNotInStore = errors.New("checkpoint not found in backend history")
+
+// ErrCheckpointIntegrity is returned when a checkpoint read from a backend
+// does not match the size or hash the backend recorded for it, e.g.
+// because it was truncated
+var ErrCheckpointIntegrity = errors.New("checkpoint failed its integrity check")
+
+const (
+	historySuffix    = ".history.json"
+	checkpointSuffix = ".checkpoint.json"
+)
+
+// CheckpointStore reads the objects a self-managed backend (file, S3, GCS
+// or Azure Blob) keeps under its .pulumi directory
+type CheckpointStore interface {
+	// List returns the keys of the objects directly under a prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Read returns the content of an object
+	Read(ctx context.Context, key string) ([]byte, error)
+}
+
+// BucketCheckpointStore is a CheckpointStore over a blob bucket. The
+// bucket driver is chosen by the backend URL scheme.
+type BucketCheckpointStore struct {
+	Bucket *blob.Bucket
+	// SkipHashCheck turns off the comparison with the MD5 hash the bucket
+	// reports for an object. The size is still checked.
+	SkipHashCheck bool
+}
+
+// OpenCheckpointStore opens the bucket of a self-managed backend URL, with
+// the same credentials the Pulumi CLI uses for it. A path after the bucket
+// name, as in s3://bucket/dir, is treated as a prefix.
+func OpenCheckpointStore(ctx context.Context, backendURL string) (*BucketCheckpointStore, error) {
+	if strings.HasPrefix(backendURL, "file://") {
+		var err error
+		if backendURL, err = fileBucketURL(backendURL); err != nil {
+			return nil, err
+		}
+	}
+	u, err := url.Parse(backendURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend URL %q: %w", backendURL, err)
+	}
+
+	prefix := ""
+	if u.Scheme != "file" {
+		prefix = strings.Trim(u.Path, "/")
+		u.Path = ""
+	}
+	bucket, err := blob.OpenBucket(ctx, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backend %s: %w", u.Redacted(), err)
+	}
+	if prefix != "" {
+		bucket = blob.PrefixedBucket(bucket, prefix+"/")
+	}
+	return &BucketCheckpointStore{Bucket: bucket}, nil
+}
+
+// fileBucketURL resolves the path of a file:// backend URL the way the
+// Pulumi CLI does: environment variables are expanded, a leading ~ is the
+// home directory and a relative path is relative to the working directory.
+// Query parameters are kept.
+func fileBucketURL(backendURL string) (string, error) {
+	p, query, _ := strings.Cut(strings.TrimPrefix(backendURL, "file://"), "?")
+	p = os.ExpandEnv(p)
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve ~ in backend URL %q: %w", backendURL, err)
+		}
+		p = filepath.Join(home, p[1:])
+	}
+	p, err := filepath.Abs(filepath.FromSlash(p))
+	if err != nil {
+		return "", fmt.Errorf("invalid backend URL %q: %w", backendURL, err)
+	}
+
+	u := "file://" + filepath.ToSlash(p)
+	if !strings.HasPrefix(u, "file:///") {
+		// A Windows drive letter
+		u = "file:///" + strings.TrimPrefix(u, "file://")
+	}
+	if query != "" {
+		u += "?" + query
+	}
+	return u, nil
+}
+
+// List returns the keys of the objects directly under a prefix
+func (s *BucketCheckpointStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	iter := s.Bucket.List(&blob.ListOptions{Prefix: prefix, Delimiter: "/"})
+	for {
+		obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		if !obj.IsDir {
+			keys = append(keys, obj.Key)
+		}
+	}
+}
+
+// Read returns the content of an object, checked against the size and,
+// when the bucket reports a content hash, the MD5 hash of the object
+func (s *BucketCheckpointStore) Read(ctx context.Context, key string) ([]byte, error) {
+	attrs, err := s.Bucket.Attributes(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	data, err := s.Bucket.ReadAll(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	var md5sum []byte
+	if !s.SkipHashCheck {
+		md5sum = contentMD5(attrs)
+	}
+	if err := verifyObject(key, data, attrs.Size, md5sum); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// contentMD5 returns the MD5 hash of an object's content as the bucket
+// reports it, or nil when it reports none. S3 derives it from the ETag,
+// which is not a hash of the content for objects encrypted with SSE-KMS or
+// SSE-C, or uploaded in parts.
+func contentMD5(attrs *blob.Attributes) []byte {
+	var head s3.HeadObjectOutput
+	if attrs.As(&head) {
+		switch {
+		case head.ServerSideEncryption == s3types.ServerSideEncryptionAwsKms,
+			head.ServerSideEncryption == s3types.ServerSideEncryptionAwsKmsDsse,
+			head.SSECustomerAlgorithm != nil,
+			head.ETag != nil && strings.Contains(*head.ETag, "-"):
+			return nil
+		}
+	}
+	return attrs.MD5
+}
+
+// verifyObject checks the content of an object against its recorded size
+// and MD5 hash. A nil hash is not checked.
+func verifyObject(key string, data []byte, size int64, md5sum []byte) error {
+	if int64(len(data)) != size {
+		return fmt.Errorf("%w: read %d bytes of %s, expected %d", ErrCheckpointIntegrity, len(data), key, size)
+	}
+	if len(md5sum) == 0 {
+		return nil
+	}
+	if sum := md5.Sum(data); !bytes.Equal(sum[:], md5sum) {
+		return fmt.Errorf("%w: MD5 of %s is %x, the backend recorded %x", ErrCheckpointIntegrity, key, sum, md5sum)
+	}
+	return nil
+}
+
+// Close closes the bucket
+func (s *BucketCheckpointStore) Close() error {
+	return s.Bucket.Close()
+}
+
+// historyEntry is one update recorded in a backend's history directory
+type historyEntry struct {
+	key       string
+	timestamp int64
+}
+
+// ReadHistoryCheckpoint returns the checkpoint a self-managed backend
+// recorded for a version of a fully qualified stack. Backends write a
+// <stack>-<timestamp>.history.json and .checkpoint.json pair per update,
+// under .pulumi/history/<project>/<stack>, or .pulumi/history/<stack> for
+// stacks created before projects were scoped.
+func ReadHistoryCheckpoint(ctx context.Context, store CheckpointStore, stack string, version int) (apitype.UntypedDeployment, error) {
+	parts := strings.Split(stack, "/")
+	if len(parts) != 3 {
+		return apitype.UntypedDeployment{}, fmt.Errorf("stack name %q is not fully qualified (org/project/stack)", stack)
+	}
+	project, name := parts[1], parts[2]
+
+	for _, dir := range []string{
+		path.Join(".pulumi", "history", project, name) + "/",
+		path.Join(".pulumi", "history", name) + "/",
+	} {
+		entries, err := listHistory(ctx, store, dir, name)
+		if err != nil {
+			return apitype.UntypedDeployment{}, err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		key, err := findHistoryEntry(ctx, store, entries, version)
+		if err != nil {
+			return apitype.UntypedDeployment{}, err
+		}
+		return readStoredCheckpoint(ctx, store, strings.Replace(key, historySuffix, checkpointSuffix, 1), version)
+	}
+	return apitype.UntypedDeployment{}, fmt.Errorf("%w: no history for stack %s", ErrCheckpointNotInStore, stack)
+}
+
+// listHistory returns the history entries in dir, oldest first
+func listHistory(ctx context.Context, store CheckpointStore, dir, name string) ([]historyEntry, error) {
+	keys, err := store.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []historyEntry
+	for _, key := range keys {
+		base := strings.TrimSuffix(path.Base(key), CompressedExtension)
+		if !strings.HasPrefix(base, name+"-") || !strings.HasSuffix(base, historySuffix) {
+			continue
+		}
+		ts, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(base, name+"-"), historySuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, historyEntry{key: key, timestamp: ts})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].timestamp < entries[j].timestamp })
+	return entries, nil
+}
+
+// findHistoryEntry returns the history key of a version. Versions count
+// updates from 1, oldest first, which is also what the history files
+// record when they carry a version. The entry at that position is checked
+// first; the others are only read when history was pruned.
+func findHistoryEntry(ctx context.Context, store CheckpointStore, entries []historyEntry, version int) (string, error) {
+	if i := version - 1; i >= 0 && i < len(entries) {
+		recorded, err := readHistoryVersion(ctx, store, entries[i].key)
+		if err != nil {
+			return "", err
+		}
+		if recorded == 0 || recorded == version {
+			return entries[i].key, nil
+		}
+	}
+
+	for _, entry := range entries {
+		recorded, err := readHistoryVersion(ctx, store, entry.key)
+		if err != nil {
+			return "", err
+		}
+		if recorded == version {
+			return entry.key, nil
+		}
+	}
+	return "", fmt.Errorf("%w: version %d", ErrCheckpointNotInStore, version)
+}
+
+// readHistoryVersion returns the version recorded in a history file, or
+// zero if it has none
+func readHistoryVersion(ctx context.Context, store CheckpointStore, key string) (int, error) {
+	data, err := readStoreObject(ctx, store, key)
+	if err != nil {
+		return 0, err
+	}
+	var update struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &update); err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return update.Version, nil
+}
+
+// readStoredCheckpoint reads a versioned checkpoint file and returns its
+// deployment in the format of `pulumi stack export`
+func readStoredCheckpoint(ctx context.Context, store CheckpointStore, key string, version int) (apitype.UntypedDeployment, error) {
+	data, err := readStoreObject(ctx, store, key)
+	if err != nil {
+		return apitype.UntypedDeployment{}, err
+	}
+
+	var versioned apitype.VersionedCheckpoint
+	if err := json.Unmarshal(data, &versioned); err != nil {
+		return apitype.UntypedDeployment{}, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	var checkpoint struct {
+		Latest json.RawMessage `json:"latest"`
+	}
+	if err := json.Unmarshal(versioned.Checkpoint, &checkpoint); err != nil {
+		return apitype.UntypedDeployment{}, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	if len(checkpoint.Latest) == 0 || string(checkpoint.Latest) == "null" {
+		return apitype.UntypedDeployment{}, fmt.Errorf("checkpoint of version %d has no deployment", version)
+	}
+	return apitype.UntypedDeployment{Version: versioned.Version, Deployment: checkpoint.Latest}, nil
+}
+
+// readStoreObject reads an object, decompressing it when the backend
+// stored it gzipped
+func readStoreObject(ctx context.Context, store CheckpointStore, key string) ([]byte, error) {
+	data, err := store.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", key, err)
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
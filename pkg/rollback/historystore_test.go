@@ -0,0 +1,374 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"gocloud.dev/blob"
+)
+
+// mapCheckpointStore is an in-memory CheckpointStore
+type mapCheckpointStore map[string][]byte
+
+func (m mapCheckpointStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for key := range m {
+		if strings.HasPrefix(key, prefix) && !strings.Contains(strings.TrimPrefix(key, prefix), "/") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m mapCheckpointStore) Read(ctx context.Context, key string) ([]byte, error) {
+	data, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", key)
+	}
+	return data, nil
+}
+
+// addUpdate records an update the way a self-managed backend does
+func (m mapCheckpointStore) addUpdate(dir, stack string, timestamp int64, version int, resourceID string) {
+	base := fmt.Sprintf("%s/%s-%d", dir, stack, timestamp)
+	m[base+historySuffix] = []byte(fmt.Sprintf(`{"kind":"update","result":"succeeded","version":%d}`, version))
+	m[base+checkpointSuffix] = []byte(fmt.Sprintf(
+		`{"version":3,"checkpoint":{"stack":"%s","latest":{"resources":[{"urn":"urn:pulumi:prod::app::aws:s3/bucket:Bucket::b","id":"%s"}]}}}`,
+		stack, resourceID))
+}
+
+func TestReadHistoryCheckpoint(t *testing.T) {
+	store := mapCheckpointStore{}
+	dir := ".pulumi/history/app/prod"
+	store.addUpdate(dir, "prod", 1700000000000000000, 1, "v1")
+	store.addUpdate(dir, "prod", 1700000100000000000, 2, "v2")
+	store.addUpdate(dir, "prod", 1700000200000000000, 3, "v3")
+	// another stack's history in a sibling directory is ignored
+	store.addUpdate(".pulumi/history/app/dev", "dev", 1700000300000000000, 1, "dev")
+
+	deployment, err := ReadHistoryCheckpoint(context.Background(), store, "organization/app/prod", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deployment.Version != 3 {
+		t.Errorf("Expected deployment version 3, got %d", deployment.Version)
+	}
+	if !strings.Contains(string(deployment.Deployment), `"v2"`) {
+		t.Errorf("Expected the checkpoint of version 2, got %s", deployment.Deployment)
+	}
+
+	if _, err := ReadHistoryCheckpoint(context.Background(), store, "organization/app/prod", 4); !errors.Is(err, ErrCheckpointNotInStore) {
+		t.Errorf("Expected ErrCheckpointNotInStore, got %v", err)
+	}
+	if _, err := ReadHistoryCheckpoint(context.Background(), store, "organization/app/staging", 1); !errors.Is(err, ErrCheckpointNotInStore) {
+		t.Errorf("Expected ErrCheckpointNotInStore for a stack without history, got %v", err)
+	}
+	if _, err := ReadHistoryCheckpoint(context.Background(), store, "prod", 1); err == nil {
+		t.Error("Expected error for a stack name that is not fully qualified")
+	}
+}
+
+func TestReadHistoryCheckpoint_Versions(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []int
+		want     string
+	}{
+		{"unversioned history is counted oldest first", []int{0, 0, 0}, "u2"},
+		{"recorded versions are matched", []int{1, 2, 3}, "u2"},
+		{"pruned history is matched by recorded version", []int{2, 3, 4}, "u1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := mapCheckpointStore{}
+			for i, v := range tt.versions {
+				store.addUpdate(".pulumi/history/prod", "prod", int64(1700000000+i), v, fmt.Sprintf("u%d", i+1))
+			}
+
+			deployment, err := ReadHistoryCheckpoint(context.Background(), store, "organization/app/prod", 2)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(string(deployment.Deployment), `"`+tt.want+`"`) {
+				t.Errorf("Expected checkpoint %s, got %s", tt.want, deployment.Deployment)
+			}
+		})
+	}
+}
+
+func TestReadHistoryCheckpoint_Gzip(t *testing.T) {
+	plain := mapCheckpointStore{}
+	plain.addUpdate(".pulumi/history/app/prod", "prod", 1700000000, 1, "gz")
+
+	store := mapCheckpointStore{}
+	for key, data := range plain {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(data)
+		gz.Close()
+		store[key+CompressedExtension] = buf.Bytes()
+	}
+
+	deployment, err := ReadHistoryCheckpoint(context.Background(), store, "organization/app/prod", 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(deployment.Deployment), `"gz"`) {
+		t.Errorf("Expected the decompressed checkpoint, got %s", deployment.Deployment)
+	}
+}
+
+func TestReadHistoryCheckpoint_DestroyedStack(t *testing.T) {
+	store := mapCheckpointStore{
+		".pulumi/history/prod/prod-1.history.json":    []byte(`{"kind":"destroy"}`),
+		".pulumi/history/prod/prod-1.checkpoint.json": []byte(`{"version":3,"checkpoint":{"stack":"prod","latest":null}}`),
+	}
+
+	_, err := ReadHistoryCheckpoint(context.Background(), store, "organization/app/prod", 1)
+	if err == nil || !strings.Contains(err.Error(), "no deployment") {
+		t.Errorf("Expected a missing deployment error, got %v", err)
+	}
+}
+
+func TestGetCheckpointForVersion_FileBackend(t *testing.T) {
+	root := t.TempDir()
+	store := mapCheckpointStore{}
+	store.addUpdate(".pulumi/history/app/prod", "prod", 1700000000, 1, "v1")
+	store.addUpdate(".pulumi/history/app/prod", "prod", 1700000100, 2, "v2")
+	for key, data := range store {
+		path := filepath.Join(root, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 2}, {Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			t.Error("Expected the checkpoint not to be exported from the current state")
+			return deployment(`{}`), nil
+		},
+		BackendFunc: func(ctx context.Context) (BackendInfo, error) {
+			return BackendInfo{URL: "file://" + filepath.ToSlash(root), Stack: "organization/app/prod"}, nil
+		},
+	}
+
+	checkpoint, err := GetCheckpointForVersion(context.Background(), mockStack, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(checkpoint.Deployment), `"v1"`) {
+		t.Errorf("Expected the checkpoint of version 1, got %s", checkpoint.Deployment)
+	}
+}
+
+func TestPreviewRollback_CheckpointStore(t *testing.T) {
+	store := mapCheckpointStore{}
+	store.addUpdate(".pulumi/history/app/prod", "prod", 1700000000, 1, "v1")
+
+	var imported []string
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 2}, {Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return deployment(`{"resources":[{"urn":"urn:pulumi:prod::app::aws:s3/bucket:Bucket::b","id":"v2"}]}`), nil
+		},
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			imported = append(imported, string(state.Deployment))
+			return nil
+		},
+		BackendFunc: func(ctx context.Context) (BackendInfo, error) {
+			return BackendInfo{URL: "s3://state-bucket", Stack: "organization/app/prod"}, nil
+		},
+	}
+
+	_, err := PreviewRollback(context.Background(), RollbackOptions{
+		StackName:       "prod",
+		TargetVersion:   1,
+		Operator:        &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+		CheckpointStore: store,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(imported) == 0 || !strings.Contains(imported[0], `"v1"`) {
+		t.Errorf("Expected the stored checkpoint to be imported, got %v", imported)
+	}
+}
+
+func TestVerifyObject(t *testing.T) {
+	data := []byte(`{"version":3}`)
+	sum := md5.Sum(data)
+
+	tests := []struct {
+		name      string
+		size      int64
+		md5sum    []byte
+		expectErr bool
+	}{
+		{name: "size and hash match", size: int64(len(data)), md5sum: sum[:]},
+		{name: "no hash recorded", size: int64(len(data))},
+		{name: "truncated", size: int64(len(data)) + 10, md5sum: sum[:], expectErr: true},
+		{name: "hash mismatch", size: int64(len(data)), md5sum: make([]byte, md5.Size), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyObject("key", data, tt.size, tt.md5sum)
+			if tt.expectErr && !errors.Is(err, ErrCheckpointIntegrity) {
+				t.Errorf("Expected ErrCheckpointIntegrity, got %v", err)
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestBucketCheckpointStore_DetectsCorruption(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+	store, err := OpenCheckpointStore(ctx, "file://"+filepath.ToSlash(root))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	// Writing through the bucket records the object's MD5
+	key := ".pulumi/history/app/prod/prod-1700000000.checkpoint.json"
+	if err := store.Bucket.WriteAll(ctx, key, []byte(`{"version":3,"checkpoint":{}}`), nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := store.Read(ctx, key); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Same size, different content
+	if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(key)), []byte(`{"version":4,"checkpoint":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Read(ctx, key); !errors.Is(err, ErrCheckpointIntegrity) {
+		t.Errorf("Expected ErrCheckpointIntegrity for a corrupted object, got %v", err)
+	}
+}
+
+func TestBucketCheckpointStore_SkipHashCheck(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+	store, err := OpenCheckpointStore(ctx, "file://"+filepath.ToSlash(root))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	key := ".pulumi/history/app/prod/prod-1700000000.checkpoint.json"
+	if err := store.Bucket.WriteAll(ctx, key, []byte(`{"version":3,"checkpoint":{}}`), nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The recorded hash no longer matches, as with an ETag that is not an MD5
+	if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(key)), []byte(`{"version":4,"checkpoint":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store.SkipHashCheck = true
+	data, err := store.Read(ctx, key)
+	if err != nil {
+		t.Fatalf("Expected the hash not to be checked, got %v", err)
+	}
+	if string(data) != `{"version":4,"checkpoint":{}}` {
+		t.Errorf("Unexpected content: %s", data)
+	}
+}
+
+func TestContentMD5(t *testing.T) {
+	sum := md5.Sum([]byte("data"))
+	if got := contentMD5(&blob.Attributes{MD5: sum[:]}); !bytes.Equal(got, sum[:]) {
+		t.Errorf("Expected the reported hash, got %x", got)
+	}
+	if got := contentMD5(&blob.Attributes{}); got != nil {
+		t.Errorf("Expected no hash, got %x", got)
+	}
+}
+
+func TestFileBucketURL(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("STATE_DIR", "/var/pulumi")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"file://~", "file://" + filepath.ToSlash(home)},
+		{"file://~/state", "file://" + filepath.ToSlash(filepath.Join(home, "state"))},
+		{"file://$STATE_DIR/app", "file:///var/pulumi/app"},
+		{"file:///var/pulumi?no_tmp_dir=true", "file:///var/pulumi?no_tmp_dir=true"},
+		{"file://state", "file://" + filepath.ToSlash(filepath.Join(wd, "state"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := fileBucketURL(tt.url)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOpenCheckpointStore_Home(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	ctx := context.Background()
+
+	key := ".pulumi/history/app/prod/prod-1700000000.checkpoint.json"
+	if err := os.MkdirAll(filepath.Join(home, filepath.Dir(filepath.FromSlash(key))), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, filepath.FromSlash(key)), []byte(`{"version":3,"checkpoint":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenCheckpointStore(ctx, "file://~")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	data, err := store.Read(ctx, key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != `{"version":3,"checkpoint":{}}` {
+		t.Errorf("Expected the checkpoint under the home directory, got %s", data)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// ErrImportMismatch is returned when the stack state after an import does
+// not match the imported checkpoint
+var ErrImportMismatch = errors.New("imported state does not match the checkpoint")
+
+// ImportSafe imports a deployment, then exports the stack and verifies that
+// it holds the same resources. On a mismatch the import is retried once.
+func ImportSafe(ctx context.Context, stack RollbackStack, deployment apitype.UntypedDeployment) error {
+	want, err := stateFingerprint(deployment)
+	if err != nil {
+		return err
+	}
+
+	var mismatch error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := stack.Import(ctx, deployment); err != nil {
+			return err
+		}
+
+		imported, err := stack.Export(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to verify import: %w", err)
+		}
+		got, err := stateFingerprint(imported)
+		if err != nil {
+			return fmt.Errorf("failed to verify import: %w", err)
+		}
+
+		mismatch = compareFingerprints(want, got)
+		if mismatch == nil {
+			return nil
+		}
+	}
+	return mismatch
+}
+
+// stateFingerprint lists the identity of every resource in a deployment.
+// Secrets and timestamps are re-written on import, so only the structure is
+// compared.
+func stateFingerprint(deployment apitype.UntypedDeployment) ([]string, error) {
+	state, err := parseDeployment(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := make([]string, 0, len(state.Resources))
+	for _, r := range state.Resources {
+		fingerprint = append(fingerprint, fmt.Sprintf("%s|%s|%s|%t", r.URN, r.Type, r.ID, r.Delete))
+	}
+	sort.Strings(fingerprint)
+	return fingerprint, nil
+}
+
+// compareFingerprints describes the first difference between two fingerprints
+func compareFingerprints(want, got []string) error {
+	if len(want) != len(got) {
+		return fmt.Errorf("%w: expected %d resource(s), stack has %d", ErrImportMismatch, len(want), len(got))
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			return fmt.Errorf("%w: expected %s, found %s", ErrImportMismatch, want[i], got[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestImportSafe(t *testing.T) {
+	target := deployment(`{"resources":[
+		{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a","type":"aws:s3/bucket:Bucket","id":"a-123"},
+		{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b","type":"aws:s3/bucket:Bucket","id":"b-456"}
+	]}`)
+	// Same resources in a different order, with rewritten secrets and timestamps
+	reordered := deployment(`{"manifest":{"time":"2026-01-01T00:00:00Z"},"resources":[
+		{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b","type":"aws:s3/bucket:Bucket","id":"b-456","outputs":{"x":1}},
+		{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a","type":"aws:s3/bucket:Bucket","id":"a-123"}
+	]}`)
+	partial := deployment(`{"resources":[
+		{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a","type":"aws:s3/bucket:Bucket","id":"a-123"}
+	]}`)
+
+	tests := []struct {
+		name            string
+		exports         []apitype.UntypedDeployment
+		expectedImports int
+		expectedErr     error
+	}{
+		{name: "matches", exports: []apitype.UntypedDeployment{reordered}, expectedImports: 1},
+		{name: "matches after retry", exports: []apitype.UntypedDeployment{partial, target}, expectedImports: 2},
+		{name: "never matches", exports: []apitype.UntypedDeployment{partial, partial}, expectedImports: 2, expectedErr: ErrImportMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports := 0
+			mockStack := &MockRollbackStack{
+				ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+					imports++
+					return nil
+				},
+				ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+					return tt.exports[imports-1], nil
+				},
+			}
+
+			err := ImportSafe(context.Background(), mockStack, target)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Expected %v, got %v", tt.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if imports != tt.expectedImports {
+				t.Errorf("Expected %d import(s), got %d", tt.expectedImports, imports)
+			}
+		})
+	}
+}
+
+func TestImportSafe_ImportError(t *testing.T) {
+	importErr := errors.New("import failed")
+	mockStack := &MockRollbackStack{
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			return importErr
+		},
+	}
+
+	if err := ImportSafe(context.Background(), mockStack, deployment(`{}`)); !errors.Is(err, importErr) {
+		t.Errorf("Expected import error, got %v", err)
+	}
+}
+
+func TestExecuteRollback_VerifyPhase(t *testing.T) {
+	imported := false
+	mockStack := &MockRollbackStack{
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			imported = true
+			return nil
+		},
+		// The import silently drops every resource
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			if imported {
+				return deployment(`{"resources":[]}`), nil
+			}
+			return deployment(`{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a","id":"a-123"}]}`), nil
+		},
+	}
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	_, err := ExecuteRollback(context.Background(), RollbackOptions{CheckpointProvider: exportCheckpoints, TargetVersion: 1, Operator: mockOperator, Output: io.Discard})
+	var rbErr *RollbackError
+	if !errors.As(err, &rbErr) || rbErr.Phase != PhaseVerify {
+		t.Errorf("Expected a RollbackError in phase %s, got %v", PhaseVerify, err)
+	}
+}
@@ -5,12 +5,19 @@ package rollback
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
 )
 
 // StackOperator is an interface for stack operations needed for rollback
@@ -26,23 +33,138 @@ type RollbackStack interface {
 	Preview(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error)
 	Refresh(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error)
 	Up(ctx context.Context, opts ...optup.Option) (auto.UpResult, error)
+	ListPlugins(ctx context.Context) ([]workspace.PluginInfo, error)
+	GetAllConfig(ctx context.Context) (auto.ConfigMap, error)
+	SetAllConfig(ctx context.Context, config auto.ConfigMap) error
+	RemoveAllConfig(ctx context.Context, keys []string) error
+	Backend(ctx context.Context) (BackendInfo, error)
+	SetTags(ctx context.Context, tags map[string]string) error
+}
+
+// BackendInfo identifies where a stack's state is stored
+type BackendInfo struct {
+	// URL is the backend URL, e.g. https://api.pulumi.com or s3://bucket
+	URL string
+	// Stack is the fully qualified stack name, org/project/stack
+	Stack string
 }
 
 // DefaultStackOperator uses the real Pulumi SDK
-type DefaultStackOperator struct{}
+type DefaultStackOperator struct {
+	// PulumiCommand overrides the Pulumi CLI used by the workspace.
+	// When nil, the pulumi binary found on PATH is used.
+	PulumiCommand auto.PulumiCommand
+	// Project, when set, selects the stack by its fully qualified name
+	// without a local project directory. Unless Repo is set, preview and up
+	// are then unavailable; see history.SelectRemoteStack.
+	Project string
+	// Repo, when set, is a clone of the stack's program from a git
+	// repository, read instead of projectPath, so preview and up work
+	// without a local checkout. It takes precedence over Project. The
+	// repository is cloned once for every stack selected from it; the
+	// caller removes the clone with its Close.
+	Repo *history.GitClone
+	// Passphrase unlocks stacks that use the passphrase secrets provider.
+	// When empty, PULUMI_CONFIG_PASSPHRASE or PULUMI_CONFIG_PASSPHRASE_FILE
+	// is passed on from the environment.
+	Passphrase string
+	// EnvVars are set for the Pulumi CLI in addition to the process
+	// environment, e.g. PULUMI_BACKEND_URL or cloud credentials, so a stack
+	// can be selected without changing the environment of the process
+	EnvVars map[string]string
+	// WorkspaceOptions are applied to the stack's workspace after the
+	// options above, e.g. auto.SecretsProvider or auto.PulumiHome
+	WorkspaceOptions []auto.LocalWorkspaceOption
+	// NoUpRefresh stops up from refreshing even when the project's
+	// Pulumi.yaml sets options.refresh: always, e.g. for a ForceImport that
+	// must not reconcile anything
+	NoUpRefresh bool
+}
 
 // SelectStack selects a stack using the Pulumi SDK
 func (d *DefaultStackOperator) SelectStack(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
-	stack, err := auto.SelectStackLocalSource(ctx, stackName, projectPath)
+	command := d.PulumiCommand
+	if d.NoUpRefresh {
+		if command == nil {
+			var err error
+			command, err = auto.NewPulumiCommand(nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		command = noUpRefreshCommand{command}
+	}
+
+	var wsOpts []auto.LocalWorkspaceOption
+	if command != nil {
+		wsOpts = append(wsOpts, auto.Pulumi(command))
+	}
+	if env := workspaceEnvVars(d.Passphrase, d.EnvVars); len(env) > 0 {
+		wsOpts = append(wsOpts, auto.EnvVars(env))
+	}
+	wsOpts = append(wsOpts, d.WorkspaceOptions...)
+
+	var stack auto.Stack
+	var err error
+	switch {
+	case d.Repo != nil:
+		var dir string
+		dir, err = d.Repo.ProjectDir(ctx, wsOpts...)
+		if err == nil {
+			stack, err = auto.SelectStackLocalSource(ctx, stackName, dir, wsOpts...)
+		}
+	case d.Project != "":
+		stack, err = history.SelectRemoteStack(ctx, stackName, d.Project, wsOpts...)
+	default:
+		stack, err = auto.SelectStackLocalSource(ctx, stackName, projectPath, wsOpts...)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &RealRollbackStack{stack: stack}, nil
 }
 
+// passphraseEnvVars returns the environment the Pulumi CLI needs to decrypt
+// a passphrase-encrypted stack: passphrase if set, otherwise the passphrase
+// variables of the current environment
+func passphraseEnvVars(passphrase string) map[string]string {
+	if passphrase != "" {
+		return map[string]string{"PULUMI_CONFIG_PASSPHRASE": passphrase}
+	}
+	if v, ok := os.LookupEnv("PULUMI_CONFIG_PASSPHRASE"); ok {
+		return map[string]string{"PULUMI_CONFIG_PASSPHRASE": v}
+	}
+	if v := os.Getenv("PULUMI_CONFIG_PASSPHRASE_FILE"); v != "" {
+		return map[string]string{"PULUMI_CONFIG_PASSPHRASE_FILE": v}
+	}
+	return nil
+}
+
+// workspaceEnvVars returns the environment of a stack's workspace: env plus
+// the passphrase variables. An explicit passphrase overrides env; the
+// process environment's passphrase is only used when env has none.
+func workspaceEnvVars(passphrase string, env map[string]string) map[string]string {
+	merged := make(map[string]string, len(env)+1)
+	for k, v := range env {
+		merged[k] = v
+	}
+	_, hasPassphrase := env["PULUMI_CONFIG_PASSPHRASE"]
+	_, hasPassphraseFile := env["PULUMI_CONFIG_PASSPHRASE_FILE"]
+	if passphrase != "" || (!hasPassphrase && !hasPassphraseFile) {
+		for k, v := range passphraseEnvVars(passphrase) {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
 // RealRollbackStack wraps a real Pulumi stack
 type RealRollbackStack struct {
 	stack auto.Stack
+
+	// backend caches Backend, which runs the Pulumi CLI
+	backendMu sync.Mutex
+	backend   *BackendInfo
 }
 
 // Export exports the stack state
@@ -75,5 +197,89 @@ func (r *RealRollbackStack) Up(ctx context.Context, opts ...optup.Option) (auto.
 	return r.stack.Up(ctx, opts...)
 }
 
+// ListPlugins lists the plugins installed in the stack's workspace
+func (r *RealRollbackStack) ListPlugins(ctx context.Context) ([]workspace.PluginInfo, error) {
+	return r.stack.Workspace().ListPlugins(ctx)
+}
+
+// GetAllConfig returns the stack's config
+func (r *RealRollbackStack) GetAllConfig(ctx context.Context) (auto.ConfigMap, error) {
+	return r.stack.GetAllConfig(ctx)
+}
+
+// SetAllConfig sets several config values
+func (r *RealRollbackStack) SetAllConfig(ctx context.Context, config auto.ConfigMap) error {
+	return r.stack.SetAllConfig(ctx, config)
+}
+
+// RemoveAllConfig removes several config values
+func (r *RealRollbackStack) RemoveAllConfig(ctx context.Context, keys []string) error {
+	return r.stack.RemoveAllConfig(ctx, keys)
+}
+
+// SetTags sets stack tags
+func (r *RealRollbackStack) SetTags(ctx context.Context, tags map[string]string) error {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := r.stack.Workspace().SetTag(ctx, r.stack.Name(), key, tags[key]); err != nil {
+			return fmt.Errorf("failed to set stack tag %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Backend returns the backend the stack is stored in
+func (r *RealRollbackStack) Backend(ctx context.Context) (BackendInfo, error) {
+	r.backendMu.Lock()
+	defer r.backendMu.Unlock()
+	if r.backend != nil {
+		return *r.backend, nil
+	}
+
+	ws := r.stack.Workspace()
+	var projectURL string
+	if project, err := ws.ProjectSettings(ctx); err == nil && project != nil && project.Backend != nil {
+		projectURL = project.Backend.URL
+	}
+	url := configuredBackendURL(ws.GetEnvVars(), projectURL, ws.PulumiHome())
+	if url == "" {
+		// Nothing names the backend, so ask the CLI where it is logged in
+		who, err := ws.WhoAmIDetails(ctx)
+		if err != nil {
+			return BackendInfo{}, fmt.Errorf("failed to get backend: %w", err)
+		}
+		url = cloudAPIURL(who.URL)
+	}
+
+	name := r.stack.Name()
+	if strings.Count(name, "/") != 2 {
+		var err error
+		name, err = fullyQualifiedStackName(ctx, ws, name)
+		if err != nil {
+			return BackendInfo{}, err
+		}
+	}
+	r.backend = &BackendInfo{URL: url, Stack: name}
+	return *r.backend, nil
+}
+
+// fullyQualifiedStackName asks the Pulumi CLI for a stack's org/project/stack name
+func fullyQualifiedStackName(ctx context.Context, ws auto.Workspace, stack string) (string, error) {
+	var env []string
+	for k, v := range ws.GetEnvVars() {
+		env = append(env, k+"="+v)
+	}
+	stdout, stderr, _, err := ws.PulumiCommand().Run(ctx, ws.WorkDir(), nil, nil, nil, env,
+		"stack", "--show-name", "--fully-qualify-stack-names", "--stack", stack, "--non-interactive")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve stack name %s: %w: %s", stack, err, strings.TrimSpace(stderr))
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
 // DefaultOperator is the default stack operator using real Pulumi SDK
 var DefaultOperator StackOperator = &DefaultStackOperator{}
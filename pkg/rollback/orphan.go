@@ -0,0 +1,94 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// OrphanedResource is a resource that a rollback leaves in place but no
+// longer manages
+type OrphanedResource struct {
+	URN  string `json:"urn"`
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+}
+
+// String describes the resource for display
+func (o OrphanedResource) String() string {
+	if o.ID == "" {
+		return fmt.Sprintf("%s (%s)", o.URN, o.Type)
+	}
+	return fmt.Sprintf("%s (%s, id %s)", o.URN, o.Type, o.ID)
+}
+
+// FindNewResources returns the resources in current that are absent from
+// target, in the order they appear in current. Resources pending deletion
+// are ignored.
+func FindNewResources(current, target apitype.UntypedDeployment) ([]OrphanedResource, error) {
+	currentState, err := parseDeployment(current)
+	if err != nil {
+		return nil, err
+	}
+	targetState, err := parseDeployment(target)
+	if err != nil {
+		return nil, err
+	}
+	return findNewResources(currentState, targetState), nil
+}
+
+// findNewResources returns the resources of the parsed current state that
+// are absent from the parsed target, as FindNewResources does
+func findNewResources(currentState, targetState *apitype.DeploymentV3) []OrphanedResource {
+	inTarget := make(map[string]bool, len(targetState.Resources))
+	for _, r := range targetState.Resources {
+		inTarget[string(r.URN)] = true
+	}
+
+	var added []OrphanedResource
+	for _, r := range currentState.Resources {
+		if r.Delete || inTarget[string(r.URN)] {
+			continue
+		}
+		added = append(added, OrphanedResource{URN: string(r.URN), Type: string(r.Type), ID: string(r.ID)})
+	}
+	return added
+}
+
+// resolveOrphans lists the resources that opts.OrphanNewResources will
+// orphan and returns the targets that keep up from recreating them. Import
+// already drops these resources from state, so up must only touch the
+// resources in the target checkpoint. Type targets are already restricted
+// to the checkpoint and are returned unchanged.
+func resolveOrphans(current, target *apitype.DeploymentV3, targets []string, opts RollbackOptions) ([]OrphanedResource, []string, error) {
+	if !opts.OrphanNewResources {
+		return nil, targets, nil
+	}
+
+	orphans := findNewResources(current, target)
+	if len(orphans) == 0 {
+		opts.Logger.Infof("No resources need to be orphaned")
+		return nil, targets, nil
+	}
+
+	opts.Logger.Warnf("%d resource(s) added after version %d will be orphaned (removed from state, not destroyed):", len(orphans), opts.TargetVersion)
+	for _, o := range orphans {
+		opts.Logger.Warnf("  %s", o)
+	}
+
+	if len(targets) > 0 {
+		return orphans, targets, nil
+	}
+	for _, r := range target.Resources {
+		if !r.Delete {
+			targets = append(targets, string(r.URN))
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil, fmt.Errorf("version %d has no resources, so every resource would be orphaned", opts.TargetVersion)
+	}
+	return orphans, targets, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+const (
+	orphanTarget = `{"resources":[
+		{"urn":"urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev","type":"pulumi:pulumi:Stack"},
+		{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a","type":"aws:s3/bucket:Bucket","id":"a-123"}
+	]}`
+	orphanCurrent = `{"resources":[
+		{"urn":"urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev","type":"pulumi:pulumi:Stack"},
+		{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a","type":"aws:s3/bucket:Bucket","id":"a-123"},
+		{"urn":"urn:pulumi:dev::proj::aws:rds/instance:Instance::db","type":"aws:rds/instance:Instance","id":"db-1"},
+		{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::old","type":"aws:s3/bucket:Bucket","id":"old-1","delete":true}
+	]}`
+)
+
+func TestFindNewResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		target   string
+		expected []string
+	}{
+		{name: "new resource", current: orphanCurrent, target: orphanTarget, expected: []string{"urn:pulumi:dev::proj::aws:rds/instance:Instance::db"}},
+		{name: "no new resources", current: orphanTarget, target: orphanCurrent},
+		{name: "empty target", current: orphanTarget, target: `{}`, expected: []string{
+			"urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev",
+			"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orphans, err := FindNewResources(deployment(tt.current), deployment(tt.target))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(orphans) != len(tt.expected) {
+				t.Fatalf("Expected %d orphan(s), got %v", len(tt.expected), orphans)
+			}
+			for i, urn := range tt.expected {
+				if orphans[i].URN != urn {
+					t.Errorf("Expected %s, got %s", urn, orphans[i].URN)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_OrphanNewResources(t *testing.T) {
+	exports := 0
+	var upTargets []string
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			exports++
+			// The second export is the current state; the rest are the target
+			if exports == 2 {
+				return deployment(orphanCurrent), nil
+			}
+			return deployment(orphanTarget), nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upOpts := &optup.Options{}
+			for _, o := range opts {
+				o.ApplyOption(upOpts)
+			}
+			upTargets = upOpts.Target
+			return auto.UpResult{}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	result, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:          "dev",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		OrphanNewResources: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result.Orphaned) != 1 || result.Orphaned[0].ID != "db-1" {
+		t.Errorf("Expected the database to be orphaned, got %v", result.Orphaned)
+	}
+	if len(upTargets) != 2 {
+		t.Errorf("Expected up to target the 2 resources of the target version, got %v", upTargets)
+	}
+	if !bytes.Contains(output.Bytes(), []byte("db-1")) {
+		t.Errorf("Expected the orphaned resource to be listed, got %q", output.String())
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+// ErrMissingPlugins is returned when a checkpoint references provider plugins
+// that are not installed locally
+var ErrMissingPlugins = errors.New("required provider plugins are not installed")
+
+const providerTypePrefix = "pulumi:providers:"
+
+// PluginRef identifies a provider plugin referenced by a checkpoint
+type PluginRef struct {
+	Name    string
+	Version string // Empty when the checkpoint does not pin a version
+}
+
+// String returns the plugin in name@version form
+func (p PluginRef) String() string {
+	if p.Version == "" {
+		return p.Name
+	}
+	return p.Name + "@" + p.Version
+}
+
+// InstallHint returns the command that installs the plugin
+func (p PluginRef) InstallHint() string {
+	hint := "pulumi plugin install resource " + p.Name
+	if p.Version != "" {
+		hint += " " + strings.TrimPrefix(p.Version, "v")
+	}
+	return hint
+}
+
+// ProviderPlugins returns the provider plugins referenced by a deployment
+func ProviderPlugins(deployment apitype.UntypedDeployment) ([]PluginRef, error) {
+	state, err := parseDeployment(deployment)
+	if err != nil {
+		return nil, err
+	}
+	return providerPlugins(state), nil
+}
+
+// providerPlugins returns the provider plugins referenced by a parsed deployment
+func providerPlugins(state *apitype.DeploymentV3) []PluginRef {
+	seen := make(map[PluginRef]bool)
+	var refs []PluginRef
+	for _, res := range state.Resources {
+		typ := string(res.Type)
+		if !strings.HasPrefix(typ, providerTypePrefix) {
+			continue
+		}
+
+		ref := PluginRef{Name: strings.TrimPrefix(typ, providerTypePrefix)}
+		if version, ok := res.Inputs["version"].(string); ok {
+			ref.Version = version
+		}
+
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].String() < refs[j].String()
+	})
+
+	return refs
+}
+
+// MissingPlugins returns the required plugins that are not installed
+func MissingPlugins(required []PluginRef, installed []workspace.PluginInfo) []PluginRef {
+	var missing []PluginRef
+	for _, ref := range required {
+		if !pluginInstalled(ref, installed) {
+			missing = append(missing, ref)
+		}
+	}
+	return missing
+}
+
+func pluginInstalled(ref PluginRef, installed []workspace.PluginInfo) bool {
+	for _, info := range installed {
+		if info.Kind != apitype.ResourcePlugin || info.Name != ref.Name {
+			continue
+		}
+		if ref.Version == "" {
+			return true
+		}
+		if info.Version != nil && info.Version.String() == strings.TrimPrefix(ref.Version, "v") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPlugins verifies the provider plugins required by the checkpoint are
+// installed. Missing plugins are reported as warnings unless opts.CheckPlugins
+// is set, in which case they fail the operation (unless opts.Force is set).
+func checkPlugins(ctx context.Context, stack RollbackStack, checkpoint *apitype.DeploymentV3, opts RollbackOptions) error {
+	strict := opts.CheckPlugins && !opts.Force
+
+	required := providerPlugins(checkpoint)
+	if len(required) == 0 {
+		return nil
+	}
+	for _, ref := range required {
+		opts.Logger.Debugf("target checkpoint requires plugin %s", ref)
+	}
+
+	installed, err := stack.ListPlugins(ctx)
+	if err != nil {
+		if strict {
+			return fmt.Errorf("failed to list installed plugins: %w", err)
+		}
+		opts.Logger.Warnf("could not check installed plugins: %v", err)
+		return nil
+	}
+
+	missing := MissingPlugins(required, installed)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(missing))
+	for i, ref := range missing {
+		lines[i] = fmt.Sprintf("  %s (install with: %s)", ref, ref.InstallHint())
+	}
+	opts.Logger.Warnf("the target checkpoint references %d plugin(s) that are not installed:\n%s",
+		len(missing), strings.Join(lines, "\n"))
+
+	if strict {
+		names := make([]string, len(missing))
+		for i, ref := range missing {
+			names[i] = ref.String()
+		}
+		return fmt.Errorf("%w: %s", ErrMissingPlugins, strings.Join(names, ", "))
+	}
+	return nil
+}
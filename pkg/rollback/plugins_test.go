@@ -0,0 +1,127 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+)
+
+const providerDeployment = `{
+	"resources": [
+		{"urn": "urn:pulumi:dev::proj::pulumi:providers:aws::default", "type": "pulumi:providers:aws", "inputs": {"version": "6.0.0"}},
+		{"urn": "urn:pulumi:dev::proj::pulumi:providers:aws::east", "type": "pulumi:providers:aws", "inputs": {"version": "6.0.0"}},
+		{"urn": "urn:pulumi:dev::proj::pulumi:providers:random::default", "type": "pulumi:providers:random"},
+		{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b", "type": "aws:s3/bucket:Bucket"}
+	]
+}`
+
+func TestProviderPlugins(t *testing.T) {
+	refs, err := ProviderPlugins(apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(providerDeployment)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []PluginRef{
+		{Name: "aws", Version: "6.0.0"},
+		{Name: "random"},
+	}
+	if len(refs) != len(expected) {
+		t.Fatalf("Expected %d plugins, got %d: %v", len(expected), len(refs), refs)
+	}
+	for i, exp := range expected {
+		if refs[i] != exp {
+			t.Errorf("Plugin %d: expected %v, got %v", i, exp, refs[i])
+		}
+	}
+}
+
+func TestProviderPlugins_InvalidDeployment(t *testing.T) {
+	_, err := ProviderPlugins(apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{invalid}`)})
+	if err == nil {
+		t.Error("Expected error for invalid deployment")
+	}
+}
+
+func TestMissingPlugins(t *testing.T) {
+	v6 := semver.MustParse("6.0.0")
+	v5 := semver.MustParse("5.0.0")
+	installed := []workspace.PluginInfo{
+		{Name: "aws", Kind: apitype.ResourcePlugin, Version: &v5},
+		{Name: "random", Kind: apitype.ResourcePlugin, Version: &v6},
+		{Name: "nodejs", Kind: apitype.LanguagePlugin, Version: &v6},
+	}
+
+	tests := []struct {
+		name     string
+		required []PluginRef
+		missing  int
+	}{
+		{"unversioned installed", []PluginRef{{Name: "random"}}, 0},
+		{"matching version", []PluginRef{{Name: "random", Version: "v6.0.0"}}, 0},
+		{"wrong version", []PluginRef{{Name: "aws", Version: "6.0.0"}}, 1},
+		{"not installed", []PluginRef{{Name: "gcp"}}, 1},
+		{"language plugin does not count", []PluginRef{{Name: "nodejs"}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if missing := MissingPlugins(tt.required, installed); len(missing) != tt.missing {
+				t.Errorf("Expected %d missing plugins, got %v", tt.missing, missing)
+			}
+		})
+	}
+}
+
+func TestPluginRefInstallHint(t *testing.T) {
+	ref := PluginRef{Name: "aws", Version: "v6.0.0"}
+	if hint := ref.InstallHint(); hint != "pulumi plugin install resource aws 6.0.0" {
+		t.Errorf("Unexpected install hint: %q", hint)
+	}
+}
+
+func TestCheckPlugins(t *testing.T) {
+	checkpoint, err := parseDeployment(apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(providerDeployment)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mockStack := &MockRollbackStack{}
+
+	tests := []struct {
+		name        string
+		opts        RollbackOptions
+		expectError bool
+	}{
+		{"warn only by default", RollbackOptions{}, false},
+		{"strict fails", RollbackOptions{CheckPlugins: true}, true},
+		{"strict with force", RollbackOptions{CheckPlugins: true, Force: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			tt.opts.Output = &output
+
+			err := checkPlugins(context.Background(), mockStack, checkpoint, withDefaults(tt.opts))
+			if tt.expectError {
+				if !errors.Is(err, ErrMissingPlugins) {
+					t.Errorf("Expected ErrMissingPlugins, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if !bytes.Contains(output.Bytes(), []byte("pulumi plugin install resource aws 6.0.0")) {
+				t.Error("Expected install hint in output")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
+)
+
+// lineWriter passes whole lines to w, so that a redacting writer sees every
+// secret in one piece. Call Flush to write a trailing partial line.
+type lineWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// newProgressWriter returns the writer for Pulumi's progress output of a
+// rollback, or nil if opts.ProgressWriter is not set
+func newProgressWriter(opts RollbackOptions, redactor *format.Redactor) *lineWriter {
+	if opts.ProgressWriter == nil {
+		return nil
+	}
+	return &lineWriter{w: redactor.Writer(opts.ProgressWriter)}
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := l.w.Write(l.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any buffered partial line. It is a no-op on a nil writer.
+func (l *lineWriter) Flush() error {
+	if l == nil || len(l.buf) == 0 {
+		return nil
+	}
+	_, err := l.w.Write(l.buf)
+	l.buf = nil
+	return err
+}
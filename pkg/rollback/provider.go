@@ -0,0 +1,72 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// ErrUnsupportedBackend is returned for a backend whose past checkpoints
+// cannot be read
+var ErrUnsupportedBackend = errors.New("backend does not keep past checkpoints")
+
+// CheckpointProvider resolves the checkpoint of a stack at a version of its
+// history. The version is known to exist; the result is validated by the
+// caller.
+type CheckpointProvider interface {
+	CheckpointAt(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error)
+}
+
+// DefaultCheckpointProvider reads checkpoints from wherever the stack's
+// backend keeps them. Pulumi Cloud serves every version of a stack through
+// its REST API, and self-managed backends keep every version in their
+// history directory. Other backends return ErrUnsupportedBackend: their
+// current state is not a checkpoint of a past version.
+type DefaultCheckpointProvider struct {
+	// Store, when set, is read instead of the backend's bucket
+	Store CheckpointStore
+	// EnvVars, when they set PULUMI_ACCESS_TOKEN, supply the token for
+	// Pulumi Cloud instead of the process environment
+	EnvVars map[string]string
+	// SkipHashCheck does not compare checkpoints read from a bucket with
+	// the hash the bucket reports; see BucketCheckpointStore
+	SkipHashCheck bool
+}
+
+// CheckpointAt returns the checkpoint of a stack at a version
+func (p *DefaultCheckpointProvider) CheckpointAt(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+	backend, err := stack.Backend(ctx)
+	if err != nil {
+		return apitype.UntypedDeployment{}, err
+	}
+
+	switch kind := BackendKind(backend.URL); {
+	case p.Store != nil:
+		return ReadHistoryCheckpoint(ctx, p.Store, backend.Stack, version)
+	case kind == "pulumi-cloud":
+		client := &CloudClient{BaseURL: backend.URL, Token: p.EnvVars["PULUMI_ACCESS_TOKEN"]}
+		if client.Token == "" {
+			client, err = NewCloudClient(backend.URL)
+			if err != nil {
+				return apitype.UntypedDeployment{}, err
+			}
+		}
+		return client.ExportDeployment(ctx, backend.Stack, version)
+	case kind == "s3", kind == "gcs", kind == "azblob", kind == "file":
+		bucket, err := OpenCheckpointStore(ctx, backend.URL)
+		if err != nil {
+			return apitype.UntypedDeployment{}, err
+		}
+		defer bucket.Close()
+		bucket.SkipHashCheck = p.SkipHashCheck
+		return ReadHistoryCheckpoint(ctx, bucket, backend.Stack, version)
+	default:
+		return apitype.UntypedDeployment{}, fmt.Errorf("%w %q: only Pulumi Cloud and s3, gs, azblob and file backends keep the checkpoints of past versions",
+			ErrUnsupportedBackend, backend.URL)
+	}
+}
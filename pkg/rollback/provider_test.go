@@ -0,0 +1,143 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// MockCheckpointProvider implements CheckpointProvider for testing
+type MockCheckpointProvider struct {
+	CheckpointAtFunc func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error)
+}
+
+func (m *MockCheckpointProvider) CheckpointAt(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+	if m.CheckpointAtFunc == nil {
+		return stack.Export(ctx)
+	}
+	return m.CheckpointAtFunc(ctx, stack, version)
+}
+
+// exportCheckpoints serves a mock stack's current state as the checkpoint
+// of every version, standing in for a backend's history store
+var exportCheckpoints = &MockCheckpointProvider{}
+
+// archivedStack is a stack whose state follows imports and whose backend
+// has no history store
+func archivedStack(state *apitype.UntypedDeployment) *MockRollbackStack {
+	return &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 2}, {Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return *state, nil
+		},
+		ImportFunc: func(ctx context.Context, imported apitype.UntypedDeployment) error {
+			*state = imported
+			return nil
+		},
+	}
+}
+
+func TestCheckpointProvider_Execute(t *testing.T) {
+	state := deployment(`{"resources":[{"urn":"urn:pulumi:prod::app::aws:s3/bucket:Bucket::b","id":"current"}]}`)
+	mockStack := archivedStack(&state)
+
+	var requested []int
+	provider := &MockCheckpointProvider{
+		CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+			requested = append(requested, version)
+			return deployment(`{"resources":[{"urn":"urn:pulumi:prod::app::aws:s3/bucket:Bucket::b","id":"archived"}]}`), nil
+		},
+	}
+	opts := RollbackOptions{
+		StackName:          "prod",
+		TargetVersion:      1,
+		Output:             &bytes.Buffer{},
+		Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+		CheckpointProvider: provider,
+	}
+
+	if _, err := PreviewRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected preview error: %v", err)
+	}
+	if !strings.Contains(string(state.Deployment), `"current"`) {
+		t.Errorf("Expected the preview to restore the current state, got %s", state.Deployment)
+	}
+
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected execute error: %v", err)
+	}
+	if !strings.Contains(string(state.Deployment), `"archived"`) {
+		t.Errorf("Expected the provider's checkpoint to be applied, got %s", state.Deployment)
+	}
+	if len(requested) != 2 || requested[0] != 1 || requested[1] != 1 {
+		t.Errorf("Expected version 1 to be requested twice, got %v", requested)
+	}
+}
+
+func TestCheckpointProvider_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    int
+		checkpoint apitype.UntypedDeployment
+		err        error
+		wantErr    string
+	}{
+		{"provider error", 1, apitype.UntypedDeployment{}, errors.New("archive unavailable"), "archive unavailable"},
+		{"invalid checkpoint", 1, deployment(`not json`), nil, "failed to parse deployment"},
+		{"version not in history", 5, deployment(`{}`), nil, "version 5 not found in stack history"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := deployment(`{}`)
+			mockStack := archivedStack(&state)
+			provider := &MockCheckpointProvider{
+				CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+					return tt.checkpoint, tt.err
+				},
+			}
+
+			_, err := ExecuteRollback(context.Background(), RollbackOptions{
+				StackName:          "prod",
+				TargetVersion:      tt.version,
+				Output:             &bytes.Buffer{},
+				Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+				CheckpointProvider: provider,
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDefaultCheckpointProvider_UnsupportedBackend(t *testing.T) {
+	exported := false
+	mockStack := &MockRollbackStack{
+		BackendFunc: func(ctx context.Context) (BackendInfo, error) {
+			return BackendInfo{URL: "postgres://state", Stack: "organization/app/prod"}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			exported = true
+			return deployment(`{"resources":[]}`), nil
+		},
+	}
+
+	_, err := (&DefaultCheckpointProvider{}).CheckpointAt(context.Background(), mockStack, 1)
+	if !errors.Is(err, ErrUnsupportedBackend) {
+		t.Errorf("Expected ErrUnsupportedBackend, got %v", err)
+	}
+	if exported {
+		t.Error("Expected the current state not to be used as a past checkpoint")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// PreviewReport is the input of WriteMarkdownReport
+type PreviewReport struct {
+	StackName      string
+	CurrentVersion int
+	Target         history.UpdateInfo
+	Preview        *RollbackResult
+	// EstimatedDuration is how long the rollback is expected to take. Zero
+	// means unknown.
+	EstimatedDuration time.Duration
+	Generated         time.Time
+}
+
+// WriteMarkdownReport renders a previewed rollback as a markdown document
+// suitable for attaching to a change ticket
+func WriteMarkdownReport(w io.Writer, r PreviewReport) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# Rollback plan: %s to version %d\n\n", r.StackName, r.Target.Version)
+	fmt.Fprintf(b, "Generated %s.\n\n", r.Generated.UTC().Format(time.RFC3339))
+
+	fmt.Fprintln(b, "## Target version")
+	fmt.Fprintln(b)
+	fmt.Fprintln(b, "| Field | Value |")
+	fmt.Fprintln(b, "|-------|-------|")
+	fmt.Fprintf(b, "| Stack | %s |\n", markdownCell(r.StackName))
+	fmt.Fprintf(b, "| Current version | %d |\n", r.CurrentVersion)
+	fmt.Fprintf(b, "| Target version | %d |\n", r.Target.Version)
+	fmt.Fprintf(b, "| Kind | %s |\n", markdownCell(r.Target.Kind))
+	fmt.Fprintf(b, "| Result | %s |\n", markdownCell(r.Target.Result))
+	if !r.Target.StartTime.IsZero() {
+		fmt.Fprintf(b, "| Deployed | %s |\n", r.Target.StartTime.UTC().Format(time.RFC3339))
+	}
+	if r.Target.Message != "" {
+		fmt.Fprintf(b, "| Message | %s |\n", markdownCell(r.Target.Message))
+	}
+	if r.EstimatedDuration > 0 {
+		fmt.Fprintf(b, "| Estimated duration | %s |\n", r.EstimatedDuration.Round(time.Second))
+	} else {
+		fmt.Fprintln(b, "| Estimated duration | unknown |")
+	}
+	fmt.Fprintln(b)
+
+	fmt.Fprintln(b, "## Resource changes")
+	fmt.Fprintln(b)
+	changes := sortedKeys(r.Preview.ResourceChanges)
+	if !r.Preview.HasChanges() {
+		fmt.Fprintln(b, "No resources would change.")
+	} else {
+		fmt.Fprintln(b, "| Operation | Count |")
+		fmt.Fprintln(b, "|-----------|-------|")
+		for _, op := range changes {
+			fmt.Fprintf(b, "| %s | %d |\n", op, r.Preview.ResourceChanges[op])
+		}
+	}
+	fmt.Fprintln(b)
+
+	if len(r.Preview.Steps) > 0 {
+		fmt.Fprintln(b, "## Changes by type")
+		fmt.Fprintln(b)
+		fmt.Fprintln(b, "| Type | Changes |")
+		fmt.Fprintln(b, "|------|---------|")
+		byType := ChangesByType(r.Preview.Steps)
+		for _, typ := range sortedKeys(byType) {
+			var parts []string
+			for _, op := range sortedKeys(byType[typ]) {
+				parts = append(parts, fmt.Sprintf("%s %d", op, byType[typ][op]))
+			}
+			fmt.Fprintf(b, "| `%s` | %s |\n", typ, strings.Join(parts, ", "))
+		}
+		fmt.Fprintln(b)
+	}
+
+	deletes := StepsWithOp(r.Preview.Steps, string(apitype.OpDelete))
+	if len(deletes) > 0 {
+		fmt.Fprintln(b, "## Deletions")
+		fmt.Fprintln(b)
+		for _, s := range deletes {
+			fmt.Fprintf(b, "- `%s`\n", s.URN)
+		}
+		fmt.Fprintln(b)
+	}
+
+	return b.Flush()
+}
+
+// markdownCell escapes a value for use in a markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
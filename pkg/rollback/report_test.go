@@ -0,0 +1,257 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// stepEvent returns the engine event announcing a step
+func stepEvent(op apitype.OpType, urn, typ string) events.EngineEvent {
+	return events.EngineEvent{EngineEvent: apitype.EngineEvent{
+		ResourcePreEvent: &apitype.ResourcePreEvent{
+			Metadata: apitype.StepEventMetadata{Op: op, URN: urn, Type: typ},
+		},
+	}}
+}
+
+func TestPreviewRollback_Steps(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return deployment(`{}`), nil
+		},
+		PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+			previewOpts := &optpreview.Options{}
+			for _, o := range opts {
+				o.ApplyOption(previewOpts)
+			}
+			for _, ch := range previewOpts.EventStreams {
+				ch <- stepEvent(apitype.OpSame, "urn:a", "aws:s3/bucket:Bucket")
+				ch <- stepEvent(apitype.OpDelete, "urn:b", "aws:s3/bucket:Bucket")
+				ch <- stepEvent(apitype.OpCreate, "urn:c", "aws:lambda/function:Function")
+			}
+			return auto.PreviewResult{}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	result, err := PreviewRollback(context.Background(), RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %v", result.Steps)
+	}
+	if result.Steps[0].Op != "delete" || result.Steps[1].URN != "urn:c" {
+		t.Errorf("Unexpected steps: %v", result.Steps)
+	}
+}
+
+func TestPreviewRollback_Diff(t *testing.T) {
+	update := stepEvent(apitype.OpUpdate, "urn:a", "aws:s3/bucket:Bucket")
+	update.ResourcePreEvent.Metadata.Old = &apitype.StepEventStateMetadata{
+		Inputs:  map[string]interface{}{"tags": map[string]interface{}{"env": "prod"}, "acl": "private"},
+		Outputs: map[string]interface{}{"arn": "arn:old"},
+	}
+	update.ResourcePreEvent.Metadata.New = &apitype.StepEventStateMetadata{
+		Inputs: map[string]interface{}{"tags": map[string]interface{}{"env": "staging"}, "versioning": true},
+	}
+	update.ResourcePreEvent.Metadata.DetailedDiff = map[string]apitype.PropertyDiff{
+		"versioning": {Kind: apitype.DiffAdd, InputDiff: true},
+		"tags.env":   {Kind: apitype.DiffUpdate, InputDiff: true},
+		"acl":        {Kind: apitype.DiffDeleteReplace, InputDiff: true},
+	}
+	legacy := stepEvent(apitype.OpUpdate, "urn:b", "aws:s3/bucket:Bucket")
+	legacy.ResourcePreEvent.Metadata.Diffs = []string{"arn"}
+	legacy.ResourcePreEvent.Metadata.Old = &apitype.StepEventStateMetadata{Outputs: map[string]interface{}{"arn": "arn:old"}}
+	legacy.ResourcePreEvent.Metadata.New = &apitype.StepEventStateMetadata{Inputs: map[string]interface{}{"arn": "arn:new"}}
+
+	var diffRequested bool
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return deployment(`{}`), nil
+		},
+		PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+			previewOpts := &optpreview.Options{}
+			for _, o := range opts {
+				o.ApplyOption(previewOpts)
+			}
+			diffRequested = previewOpts.Diff
+			for _, ch := range previewOpts.EventStreams {
+				ch <- update
+				ch <- legacy
+			}
+			return auto.PreviewResult{}, nil
+		},
+	}
+
+	result, err := PreviewRollback(context.Background(), RollbackOptions{
+		CheckpointProvider: exportCheckpoints,
+		StackName:          "test",
+		TargetVersion:      1,
+		Diff:               true,
+		Operator: &MockStackOperator{
+			SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+				return mockStack, nil
+			},
+		},
+		Output: &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !diffRequested {
+		t.Error("Expected the preview to request diffs")
+	}
+	if len(result.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %v", result.Steps)
+	}
+
+	expected := []PropertyChange{
+		{Path: "acl", Kind: "delete-replace", Old: "private"},
+		{Path: "tags.env", Kind: "update", Old: "prod", New: "staging"},
+		{Path: "versioning", Kind: "add", New: true},
+	}
+	if !reflect.DeepEqual(result.Steps[0].Diffs, expected) {
+		t.Errorf("Expected diffs %v, got %v", expected, result.Steps[0].Diffs)
+	}
+	expected = []PropertyChange{{Path: "arn", Kind: "update", Old: "arn:old", New: "arn:new"}}
+	if !reflect.DeepEqual(result.Steps[1].Diffs, expected) {
+		t.Errorf("Expected diffs %v, got %v", expected, result.Steps[1].Diffs)
+	}
+
+	var out bytes.Buffer
+	if err := WriteStepDiffs(&out, result.Steps); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `update urn:a (aws:s3/bucket:Bucket)
+    - acl: "private" (forces replacement)
+    ~ tags.env: "prod" => "staging"
+    + versioning: true
+update urn:b (aws:s3/bucket:Bucket)
+    ~ arn: "arn:old" => "arn:new"
+`
+	if out.String() != want {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestPreviewRollback_NoDiffByDefault(t *testing.T) {
+	event := stepEvent(apitype.OpUpdate, "urn:a", "aws:s3/bucket:Bucket")
+	event.ResourcePreEvent.Metadata.Diffs = []string{"acl"}
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return deployment(`{}`), nil
+		},
+		PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+			previewOpts := &optpreview.Options{}
+			for _, o := range opts {
+				o.ApplyOption(previewOpts)
+			}
+			for _, ch := range previewOpts.EventStreams {
+				ch <- event
+			}
+			return auto.PreviewResult{}, nil
+		},
+	}
+
+	result, err := PreviewRollback(context.Background(), RollbackOptions{
+		CheckpointProvider: exportCheckpoints,
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator: &MockStackOperator{
+			SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+				return mockStack, nil
+			},
+		},
+		Output: &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Steps) != 1 || result.Steps[0].Diffs != nil {
+		t.Errorf("Expected one step without diffs, got %v", result.Steps)
+	}
+}
+
+func TestWriteMarkdownReport(t *testing.T) {
+	preview := &RollbackResult{
+		ResourceChanges: map[string]int{"delete": 1, "create": 1, "same": 3},
+		Steps: []ResourceStep{
+			{URN: "urn:b", Type: "aws:s3/bucket:Bucket", Op: "delete"},
+			{URN: "urn:c", Type: "aws:lambda/function:Function", Op: "create"},
+		},
+	}
+
+	var out bytes.Buffer
+	err := WriteMarkdownReport(&out, PreviewReport{
+		StackName:         "prod",
+		CurrentVersion:    7,
+		Target:            history.UpdateInfo{Version: 5, Kind: "update", Result: "succeeded", Message: "fix | pipe"},
+		Preview:           preview,
+		EstimatedDuration: 90 * time.Second,
+		Generated:         time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	report := out.String()
+	for _, want := range []string{
+		"# Rollback plan: prod to version 5",
+		"| Current version | 7 |",
+		`| Message | fix \| pipe |`,
+		"| Estimated duration | 1m30s |",
+		"| delete | 1 |",
+		"| `aws:s3/bucket:Bucket` | delete 1 |",
+		"## Deletions\n\n- `urn:b`",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
+
+func TestWriteMarkdownReport_NoChanges(t *testing.T) {
+	var out bytes.Buffer
+	err := WriteMarkdownReport(&out, PreviewReport{
+		StackName: "prod",
+		Target:    history.UpdateInfo{Version: 5},
+		Preview:   &RollbackResult{ResourceChanges: map[string]int{"same": 3}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report := out.String()
+	if !strings.Contains(report, "No resources would change.") || strings.Contains(report, "## Deletions") {
+		t.Errorf("Unexpected report:\n%s", report)
+	}
+	if !strings.Contains(report, "| Estimated duration | unknown |") {
+		t.Errorf("Expected unknown duration, got:\n%s", report)
+	}
+}
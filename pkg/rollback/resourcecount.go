@@ -0,0 +1,61 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/concurrent"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+)
+
+// CountResourcesByVersion sets the ResourceCount of each update from its
+// checkpoint, as counted by CountResources, with at most limit fetches in
+// flight. Updates whose checkpoint cannot be read keep a nil count and are
+// returned as the number of failures. Nothing is modified. This fetches a whole
+// checkpoint per update, so callers should limit updates to the versions
+// they show.
+func CountResourcesByVersion(ctx context.Context, opts RollbackOptions, updates []history.UpdateInfo, limit int) (int, error) {
+	opts = withDefaults(opts)
+	if err := concurrent.ValidateLimit(limit); err != nil {
+		return 0, err
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	stack, err := selectStack(ctx, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select stack: %w", err)
+	}
+
+	errs := concurrent.ForEach(ctx, limit, len(updates), func(ctx context.Context, i int) error {
+		version := updates[i].Version
+		opts.Logger.Debugf("fetching checkpoint for version %d", version)
+		checkpoint, err := fetchCheckpoint(ctx, stack, version, opts.CheckpointProvider)
+		if err != nil {
+			return err
+		}
+		count, err := CountResources(checkpoint)
+		if err != nil {
+			return err
+		}
+		updates[i].ResourceCount = &count
+		return nil
+	})
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			opts.Logger.Debugf("could not count the resources of version %d: %v", updates[i].Version, err)
+			updates[i].ResourceCount = nil
+			failed++
+		}
+	}
+	return failed, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestCountResourcesByVersion(t *testing.T) {
+	checkpoints := map[int]string{
+		3: `{"resources": [
+			{"urn": "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev", "type": "pulumi:pulumi:Stack"},
+			{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a", "type": "aws:s3/bucket:Bucket"},
+			{"urn": "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::b", "type": "aws:s3/bucket:Bucket"}
+		]}`,
+		1: `{"resources": []}`,
+	}
+	provider := &MockCheckpointProvider{
+		CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+			checkpoint, ok := checkpoints[version]
+			if !ok {
+				return apitype.UntypedDeployment{}, errors.New("checkpoint pruned")
+			}
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(checkpoint)}, nil
+		},
+	}
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return &MockRollbackStack{}, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{StackName: "dev", Operator: mockOperator, Output: &output, CheckpointProvider: provider}
+	updates := []history.UpdateInfo{{Version: 3}, {Version: 2}, {Version: 1}}
+
+	failed, err := CountResourcesByVersion(context.Background(), opts, updates, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failed != 1 {
+		t.Errorf("Expected 1 failure, got %d", failed)
+	}
+
+	expected := []int{2, -1, 0}
+	for i, count := range expected {
+		got := updates[i].ResourceCount
+		if count < 0 {
+			if got != nil {
+				t.Errorf("Version %d: expected an unknown count, got %d", updates[i].Version, *got)
+			}
+			continue
+		}
+		if got == nil || *got != count {
+			t.Errorf("Version %d: expected %d resources, got %v", updates[i].Version, count, got)
+		}
+	}
+}
+
+func TestCountResourcesByVersion_Empty(t *testing.T) {
+	selected := false
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			selected = true
+			return &MockRollbackStack{}, nil
+		},
+	}
+
+	failed, err := CountResourcesByVersion(context.Background(), RollbackOptions{Operator: mockOperator, Output: &bytes.Buffer{}}, nil, 4)
+	if err != nil || failed != 0 {
+		t.Fatalf("Expected no failures and no error, got %d, %v", failed, err)
+	}
+	if selected {
+		t.Error("Expected no stack to be selected for an empty history")
+	}
+}
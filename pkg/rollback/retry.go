@@ -0,0 +1,191 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// RetryPolicy controls how History, Export, Import and Up are retried when
+// they fail with a transient backend error
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries, including the first.
+	// Values below 1 mean a single try.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry. It doubles after
+	// each further attempt.
+	BaseDelay time.Duration
+	// IsRetryable reports whether an error is worth retrying. Defaults to
+	// IsTransientError, and for up to errors from before the update started.
+	IsRetryable func(error) bool
+}
+
+// DefaultRetryPolicy is used when RollbackOptions.Retry is nil
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second}
+
+// preUpdateMarkers are substrings of the errors the Pulumi CLI and cloud
+// storage report when a request failed before it changed anything: the
+// backend could not be reached, or another update holds the stack's lock
+var preUpdateMarkers = []string{
+	"connection refused",
+	"connection reset",
+	"no such host",
+	"i/o timeout",
+	"tls handshake timeout",
+	"[409] conflict",
+	"currently locked",
+}
+
+// IsTransientError reports whether err failed before the request changed
+// anything: a connection failure or a stack locked by another update (409).
+// Cancellation, missing versions, throttling and failed updates are not
+// transient.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrVersionNotFound) {
+		return false
+	}
+	if isConnectError(err) || auto.IsConcurrentUpdateError(err) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, marker := range preUpdateMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryableUpError reports whether up failed before the update started:
+// the backend refused it because another update holds the stack (409), or
+// could not be reached. Up's output is never matched against
+// preUpdateMarkers, because a resource that failed with a network error
+// means the update already changed infrastructure.
+func isRetryableUpError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return auto.IsConcurrentUpdateError(err) || isConnectError(err)
+}
+
+// isConnectError reports whether err is a failure to connect, so no
+// request was sent
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retry runs op until it succeeds, fails with an error that is not
+// retryable, or the policy's attempts are used up
+func retry(ctx context.Context, policy RetryPolicy, logf func(string, ...interface{}), name string, op func() error) error {
+	isRetryable := policy.IsRetryable
+	if isRetryable == nil {
+		isRetryable = IsTransientError
+	}
+
+	delay := policy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= policy.MaxAttempts || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+		logf("%s failed (attempt %d of %d), retrying in %s: %v", name, attempt, policy.MaxAttempts, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// selectStack selects the stack of opts, retrying its backend operations
+// according to opts.Retry
+func selectStack(ctx context.Context, opts RollbackOptions) (RollbackStack, error) {
+	stack, err := opts.Operator.SelectStack(ctx, opts.StackName, opts.ProjectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := DefaultRetryPolicy
+	if opts.Retry != nil {
+		policy = *opts.Retry
+	}
+	if policy.MaxAttempts <= 1 {
+		return stack, nil
+	}
+	return &retryingStack{RollbackStack: stack, policy: policy, logf: opts.Logger.Warnf}, nil
+}
+
+// retryingStack retries the backend operations of a RollbackStack
+type retryingStack struct {
+	RollbackStack
+	policy RetryPolicy
+	logf   func(string, ...interface{})
+}
+
+// Export exports the stack state, retrying transient failures
+func (r *retryingStack) Export(ctx context.Context) (apitype.UntypedDeployment, error) {
+	var deployment apitype.UntypedDeployment
+	err := retry(ctx, r.policy, r.logf, "export", func() error {
+		var err error
+		deployment, err = r.RollbackStack.Export(ctx)
+		return err
+	})
+	return deployment, err
+}
+
+// Import imports a stack state, retrying transient failures
+func (r *retryingStack) Import(ctx context.Context, state apitype.UntypedDeployment) error {
+	return retry(ctx, r.policy, r.logf, "import", func() error {
+		return r.RollbackStack.Import(ctx, state)
+	})
+}
+
+// History returns the stack history, retrying transient failures
+func (r *retryingStack) History(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+	var updates []auto.UpdateSummary
+	err := retry(ctx, r.policy, r.logf, "history", func() error {
+		var err error
+		updates, err = r.RollbackStack.History(ctx, pageSize, page)
+		return err
+	})
+	return updates, err
+}
+
+// Up runs an update, retrying failures from before the update started
+func (r *retryingStack) Up(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+	policy := r.policy
+	if policy.IsRetryable == nil {
+		policy.IsRetryable = isRetryableUpError
+	}
+	var result auto.UpResult
+	err := retry(ctx, policy, r.logf, "up", func() error {
+		var err error
+		result, err = r.RollbackStack.Up(ctx, opts...)
+		return err
+	})
+	return result, err
+}
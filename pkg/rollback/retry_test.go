@@ -0,0 +1,252 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "connection reset", err: fmt.Errorf("exporting: %w", syscall.ECONNRESET), expected: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, expected: true},
+		{name: "cli connect message", err: errors.New("dial tcp 10.0.0.1:443: connect: connection refused"), expected: true},
+		{name: "conflict", err: errors.New("error: [409] Conflict: Another update is currently in progress."), expected: true},
+		{name: "locked", err: errors.New("error: the stack is currently locked by 1 lock(s)"), expected: true},
+		{name: "cli throttling message", err: errors.New("error: [429] Too Many Requests"), expected: false},
+		{name: "s3 slow down", err: errors.New("operation error S3: GetObject, SlowDown: Please reduce your request rate"), expected: false},
+		{name: "gateway timeout", err: errors.New("failed: 504 Gateway Timeout"), expected: false},
+		{name: "version not found", err: &history.VersionNotFoundError{Version: 4}, expected: false},
+		{name: "canceled", err: fmt.Errorf("up: %w", context.Canceled), expected: false},
+		{name: "resource failure", err: errors.New("update failed: 1 error occurred"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientError(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	transient := errors.New("connection reset by peer")
+	permanent := errors.New("permission denied")
+
+	tests := []struct {
+		name             string
+		policy           RetryPolicy
+		errs             []error
+		expectedAttempts int
+		expectedErr      error
+	}{
+		{
+			name:             "succeeds after transient failures",
+			policy:           RetryPolicy{MaxAttempts: 3},
+			errs:             []error{transient, transient, nil},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "gives up after max attempts",
+			policy:           RetryPolicy{MaxAttempts: 2},
+			errs:             []error{transient, transient, nil},
+			expectedAttempts: 2,
+			expectedErr:      transient,
+		},
+		{
+			name:             "does not retry permanent errors",
+			policy:           RetryPolicy{MaxAttempts: 3},
+			errs:             []error{permanent, nil},
+			expectedAttempts: 1,
+			expectedErr:      permanent,
+		},
+		{
+			name:             "custom retryable check",
+			policy:           RetryPolicy{MaxAttempts: 3, IsRetryable: func(err error) bool { return err == permanent }},
+			errs:             []error{permanent, nil},
+			expectedAttempts: 2,
+		},
+		{
+			name:             "single attempt",
+			policy:           RetryPolicy{},
+			errs:             []error{transient, nil},
+			expectedAttempts: 1,
+			expectedErr:      transient,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			var logged []string
+			logf := func(format string, args ...interface{}) {
+				logged = append(logged, fmt.Sprintf(format, args...))
+			}
+
+			err := retry(context.Background(), tt.policy, logf, "export", func() error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+			if err != tt.expectedErr {
+				t.Errorf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+			if len(logged) != attempts-1 {
+				t.Errorf("Expected %d retry messages, got %v", attempts-1, logged)
+			}
+		})
+	}
+}
+
+func TestRetry_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	transient := errors.New("i/o timeout")
+	err := retry(ctx, RetryPolicy{MaxAttempts: 3}, func(string, ...interface{}) {}, "history", func() error {
+		attempts++
+		return transient
+	})
+	if err != transient {
+		t.Errorf("Expected %v, got %v", transient, err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestExecuteRollback_RetriesTransientFailures(t *testing.T) {
+	historyCalls, exportCalls, upCalls := 0, 0, 0
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			historyCalls++
+			if historyCalls == 1 {
+				return nil, errors.New("error: [409] Conflict: Another update is currently in progress.")
+			}
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			exportCalls++
+			if exportCalls == 1 {
+				return apitype.UntypedDeployment{}, syscall.ECONNRESET
+			}
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upCalls++
+			if upCalls == 1 {
+				return auto.UpResult{}, &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+			}
+			return auto.UpResult{}, nil
+		},
+	}
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	result, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		Retry:              &RetryPolicy{MaxAttempts: 3},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Error("Expected Success to be true")
+	}
+	if historyCalls < 2 || exportCalls < 2 || upCalls != 2 {
+		t.Errorf("Expected each operation to be retried once, got history=%d export=%d up=%d", historyCalls, exportCalls, upCalls)
+	}
+}
+
+func TestExecuteRollback_VersionNotFoundNotRetried(t *testing.T) {
+	historyCalls := 0
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			historyCalls++
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+	}
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	_, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:     "test",
+		TargetVersion: 7,
+		Operator:      mockOperator,
+		Output:        &output,
+		Retry:         &RetryPolicy{MaxAttempts: 3},
+	})
+	if !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("Expected ErrVersionNotFound, got %v", err)
+	}
+	if historyCalls != 1 {
+		t.Errorf("Expected history to be fetched once, got %d", historyCalls)
+	}
+}
+
+func TestExecuteRollback_UpNotRetriedOnMessage(t *testing.T) {
+	upCalls := 0
+	mockStack := &MockRollbackStack{
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upCalls++
+			// A resource failed mid-update; its message looks transient
+			return auto.UpResult{}, errors.New("creating bucket: read tcp: i/o timeout")
+		},
+	}
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	_, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		Retry:              &RetryPolicy{MaxAttempts: 3},
+	})
+	if err == nil {
+		t.Fatal("Expected the rollback to fail")
+	}
+	if upCalls != 1 {
+		t.Errorf("Expected up not to be retried, got %d calls", upCalls)
+	}
+}
@@ -4,18 +4,64 @@
 package rollback
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"time"
 
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/logging"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
 )
 
+// ErrDriftExceeded is returned when the refresh reveals more drift than allowed
+var ErrDriftExceeded = errors.New("live infrastructure drift exceeds threshold")
+
+// ErrUnsupportedDeploymentVersion is returned for a deployment whose schema
+// version the Pulumi engine cannot import
+var ErrUnsupportedDeploymentVersion = errors.New("unsupported deployment schema version")
+
+// Deployment schema versions the Pulumi engine can import. Versions 1 and
+// 2 are migrated on import.
+const (
+	MinDeploymentVersion = 1
+	MaxDeploymentVersion = apitype.DeploymentSchemaVersionLatest
+)
+
+// PreviewMode controls what a rollback preview is computed against
+type PreviewMode string
+
+const (
+	// PreviewModeStateOnly previews against the recorded target state only
+	PreviewModeStateOnly PreviewMode = "state-only"
+	// PreviewModeLive refreshes the target state against live infrastructure
+	// within the preview; the refreshed state is never saved
+	PreviewModeLive PreviewMode = "live"
+)
+
+// ParsePreviewMode validates a preview mode name. An empty name selects
+// PreviewModeStateOnly.
+func ParsePreviewMode(name string) (PreviewMode, error) {
+	switch PreviewMode(name) {
+	case "", PreviewModeStateOnly:
+		return PreviewModeStateOnly, nil
+	case PreviewModeLive:
+		return PreviewModeLive, nil
+	default:
+		return "", fmt.Errorf("unknown preview mode %q (expected %q or %q)", name, PreviewModeStateOnly, PreviewModeLive)
+	}
+}
+
 // RollbackOptions contains options for the rollback operation
 type RollbackOptions struct {
 	ProjectPath   string
@@ -24,28 +70,348 @@ type RollbackOptions struct {
 	DryRun        bool
 	Verbose       bool
 	Output        io.Writer
-	Operator      StackOperator // Optional: use for testing
+	Operator      StackOperator  // Optional: use for testing
+	Logger        logging.Logger // Optional: defaults to logging to Output
+	// CheckpointProvider resolves the target checkpoint. Optional: defaults
+	// to DefaultCheckpointProvider, which reads it from the stack's backend.
+	CheckpointProvider CheckpointProvider
+	// CheckpointStore reads historical checkpoints instead of the bucket
+	// of the stack's self-managed backend. Optional: use for testing.
+	// Ignored when CheckpointProvider is set.
+	CheckpointStore CheckpointStore
+	// SkipHashCheck does not compare checkpoints read from a self-managed
+	// backend's bucket with the MD5 hash the bucket reports for them, for
+	// buckets whose hashes are not content hashes. Sizes are still checked.
+	SkipHashCheck bool
+	// Retry controls how transient failures of History, Export, Import and
+	// Up are retried. Optional: defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	// MaxRefreshDrift aborts the rollback when the refresh changes more than
+	// this many resources. Zero disables the check.
+	MaxRefreshDrift int
+	// RefreshParallel is the number of resource operations the refresh runs
+	// at once. Refresh is mostly waiting on cloud APIs, so it often benefits
+	// from more parallelism than up. Zero uses the Pulumi default.
+	RefreshParallel int
+	// UpOptions are passed to the up of ExecuteRollback, for engine
+	// settings it does not expose, e.g. optup.Parallel. They must not set
+	// the message, which records the rollback's provenance, or targets (see
+	// Targets). Event and progress streams are added to the rollback's own.
+	UpOptions []optup.Option
+	// PreviewOptions are passed to the previews of PreviewRollback and of a
+	// dry run, with the same restrictions as UpOptions
+	PreviewOptions []optpreview.Option
+	// PreviewMode selects what PreviewRollback compares against.
+	// Defaults to PreviewModeStateOnly.
+	PreviewMode PreviewMode
+	// VerifyPreview makes PreviewRollback fail when the change counts it
+	// reports do not match the steps of Pulumi's preview
+	VerifyPreview bool
+	// Diff records the property changes of each step of a preview in
+	// ResourceStep.Diffs
+	Diff bool
+	// Types limits the rollback to resources of these type tokens
+	// (e.g. aws:lambda/function:Function) in the target checkpoint. Only
+	// they and their dependencies are imported; see scopeCheckpoint.
+	Types []string
+	// Targets limits the rollback to these resource URNs, e.g. the steps of
+	// a preview selected one by one. They are added to the Types targets.
+	Targets []string
+	// CheckPlugins fails the operation when the target checkpoint references
+	// provider plugins that are not installed, instead of only warning
+	CheckPlugins bool
+	// Force proceeds past safety checks that would otherwise abort the rollback
+	Force bool
+	// RenamedFrom is the stack's name before a pulumi stack rename.
+	// Checkpoints taken under that name are accepted, with their URNs
+	// rewritten to name the current stack, instead of failing with
+	// ErrStackMismatch.
+	RenamedFrom string
+	// IgnoreBusy proceeds while the latest update of the stack is still in
+	// progress, e.g. one whose process died without finishing it. It is
+	// separate from Force because importing underneath a live update
+	// corrupts the stack.
+	IgnoreBusy bool
+	// ToolVersion and Initiator are recorded in the rollback update message
+	// and in stack tags (see history.Provenance.Tags), so the update can
+	// later be identified as a rollback
+	ToolVersion string
+	Initiator   string
+	// ProvenanceLog, when set, is the sidecar file the provenance of the
+	// rollback update is recorded in; see history.ProvenanceLog
+	ProvenanceLog string
+	// BackupPath is the file ExecuteRollback saves the current state to,
+	// as JSON, before changing it. When empty the state is saved to a
+	// timestamped file in BackupDir.
+	BackupPath string
+	// BackupDir is where the default backup file is written. It defaults
+	// to DefaultBackupDir in ProjectPath; see ProjectBackupDir.
+	BackupDir string
+	// NoBackup skips the backup of the current state
+	NoBackup bool
+	// DumpStatesDir, when set, receives both the current state and the
+	// target checkpoint, as they were before the rollback, for forensics
+	DumpStatesDir string
+	// Compress gzip-compresses the checkpoints the rollback exports: the
+	// backup and the dumped states. Both are plain JSON by default.
+	Compress bool
+	// AllowNoop lets GuardedExecute re-apply the current version
+	AllowNoop bool
+	// ReencryptSecrets re-encrypts the target checkpoint's secrets for the
+	// stack's current secrets provider when the two differ
+	ReencryptSecrets bool
+	// Passphrase unlocks a stack that uses the passphrase secrets provider
+	// when Operator is a DefaultStackOperator. Defaults to the
+	// PULUMI_CONFIG_PASSPHRASE or PULUMI_CONFIG_PASSPHRASE_FILE environment
+	// variable.
+	Passphrase string
+	// SourcePassphrase decrypts a target checkpoint that used the passphrase
+	// provider. Defaults to Passphrase, then PULUMI_CONFIG_PASSPHRASE.
+	SourcePassphrase string
+	// EnvVars are passed to the Pulumi CLI when Operator is a
+	// DefaultStackOperator, e.g. PULUMI_BACKEND_URL, PULUMI_ACCESS_TOKEN or
+	// cloud credentials, without changing the process environment. The
+	// default checkpoint provider uses their PULUMI_ACCESS_TOKEN.
+	EnvVars map[string]string
+	// WorkspaceOptions configure the stack's workspace when Operator is a
+	// DefaultStackOperator, e.g. auto.SecretsProvider or auto.PulumiHome
+	WorkspaceOptions []auto.LocalWorkspaceOption
+	// SecretsDecrypter decrypts the target checkpoint's secrets instead of
+	// SourcePassphrase, for other secrets providers
+	SecretsDecrypter config.Decrypter
+	// OrphanNewResources leaves resources that are absent from the target
+	// version in place, unmanaged, instead of letting up recreate them
+	OrphanNewResources bool
+	// ForceImport treats the target checkpoint as ground truth: the refresh
+	// after the import is skipped, so up applies the checkpoint without
+	// reconciling it with live infrastructure. MaxRefreshDrift is ignored.
+	ForceImport bool
+	// SkipRefresh applies the imported checkpoint as-is: ExecuteRollback goes
+	// straight from the import to up, so drift in live infrastructure is not
+	// reconciled first. MaxRefreshDrift is ignored.
+	SkipRefresh bool
+	// StateOnly makes ExecuteRollback import the target checkpoint and stop:
+	// no refresh and no up run, so infrastructure is not touched. Use it when
+	// live infrastructure already matches the target version. It cannot be
+	// combined with DryRun, Types, Targets or OrphanNewResources.
+	StateOnly bool
+	// AllowEmpty lets ExecuteRollback roll back to a checkpoint without
+	// resources, deleting all current infrastructure
+	AllowEmpty bool
+	// ProgressWriter, when set, receives Pulumi's live output of the refresh
+	// and up (or preview, in a dry run) of ExecuteRollback, resource by
+	// resource as `pulumi up` shows it.
+	// Known secrets are masked.
+	ProgressWriter io.Writer
+	// TransformCheckpoint, when set, may modify the target checkpoint after
+	// it is fetched and validated and before anything else uses it, e.g. to
+	// update a provider region that no longer exists. The result is
+	// validated again.
+	TransformCheckpoint func(apitype.UntypedDeployment) (apitype.UntypedDeployment, error)
 }
 
 // RollbackResult contains the result of a rollback operation
 type RollbackResult struct {
-	Success         bool
-	Message         string
-	ResourceChanges map[string]int
-	Stdout          string
-	Stderr          string
+	Success         bool           `json:"success"`
+	Message         string         `json:"message"`
+	ResourceChanges map[string]int `json:"resourceChanges"`
+	Stdout          string         `json:"stdout,omitempty"`
+	Stderr          string         `json:"stderr,omitempty"`
+	// BackupFile is the backup of the state taken before the rollback, if any
+	BackupFile string `json:"backupFile,omitempty"`
+	// Orphaned lists the resources left unmanaged by OrphanNewResources
+	Orphaned []OrphanedResource `json:"orphaned,omitempty"`
+	// Steps lists the resources a preview would change
+	Steps []ResourceStep `json:"steps,omitempty"`
+	// DumpedStates are the checkpoints written for DumpStatesDir
+	DumpedStates *StateDump `json:"dumpedStates,omitempty"`
+	// Phases records the status and duration of each phase of a rollback
+	Phases []PhaseTiming `json:"phases,omitempty"`
+	// Direction is where the rollback moves the stack relative to the
+	// version whose state it held: backward, forward or re-apply
+	Direction history.Direction `json:"direction,omitempty"`
+	// CheckpointHash is the SHA-256 of the target checkpoint as it was
+	// fetched, before any transformation; see CheckpointHash
+	CheckpointHash string `json:"checkpointHash,omitempty"`
 }
 
-// PreviewRollback shows what changes would be made by rolling back
-func PreviewRollback(ctx context.Context, opts RollbackOptions) (*RollbackResult, error) {
+// HasChanges reports whether the result contains any changes other than "same"
+func (r *RollbackResult) HasChanges() bool {
+	for op, count := range r.ResourceChanges {
+		if op != string(apitype.OpSame) && count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withDefaults fills in the optional fields of opts
+func withDefaults(opts RollbackOptions) RollbackOptions {
 	if opts.Output == nil {
 		opts.Output = os.Stdout
 	}
 	if opts.Operator == nil {
 		opts.Operator = DefaultOperator
 	}
+	if d, ok := opts.Operator.(*DefaultStackOperator); ok {
+		opts.Operator = withWorkspace(d, opts)
+	}
+	if opts.CheckpointProvider == nil {
+		opts.CheckpointProvider = &DefaultCheckpointProvider{Store: opts.CheckpointStore, EnvVars: opts.EnvVars, SkipHashCheck: opts.SkipHashCheck}
+	}
+	if opts.Logger == nil {
+		level := logging.LevelInfo
+		if opts.Verbose {
+			level = logging.LevelDebug
+		}
+		opts.Logger = logging.New(opts.Output, level)
+	}
+	return opts
+}
+
+// withWorkspace returns a copy of d with the passphrase, environment and
+// workspace options of opts. Settings of d itself take precedence.
+func withWorkspace(d *DefaultStackOperator, opts RollbackOptions) *DefaultStackOperator {
+	if opts.Passphrase == "" && len(opts.EnvVars) == 0 && len(opts.WorkspaceOptions) == 0 {
+		return d
+	}
+	merged := *d
+	if merged.Passphrase == "" {
+		merged.Passphrase = opts.Passphrase
+	}
+	if len(opts.EnvVars) > 0 {
+		merged.EnvVars = make(map[string]string, len(opts.EnvVars)+len(d.EnvVars))
+		for k, v := range opts.EnvVars {
+			merged.EnvVars[k] = v
+		}
+		for k, v := range d.EnvVars {
+			merged.EnvVars[k] = v
+		}
+	}
+	merged.WorkspaceOptions = append(append([]auto.LocalWorkspaceOption(nil), opts.WorkspaceOptions...), d.WorkspaceOptions...)
+	return &merged
+}
+
+// validateParallel rejects a negative parallelism
+func validateParallel(opts RollbackOptions) error {
+	if opts.RefreshParallel < 0 {
+		return fmt.Errorf("refresh parallelism must not be negative, got %d", opts.RefreshParallel)
+	}
+	return nil
+}
 
-	stack, err := opts.Operator.SelectStack(ctx, opts.StackName, opts.ProjectPath)
+// validatePassthrough rejects UpOptions and PreviewOptions that would
+// replace the rollback's message or targets
+func validatePassthrough(opts RollbackOptions) error {
+	var up optup.Options
+	for _, o := range opts.UpOptions {
+		o.ApplyOption(&up)
+	}
+	var preview optpreview.Options
+	for _, o := range opts.PreviewOptions {
+		o.ApplyOption(&preview)
+	}
+	switch {
+	case up.Message != "" || preview.Message != "":
+		return fmt.Errorf("up and preview options must not set the message: the rollback records its provenance in it")
+	case len(up.Target) > 0 || len(preview.Target) > 0:
+		return fmt.Errorf("up and preview options must not set targets: use RollbackOptions.Targets")
+	}
+	return nil
+}
+
+// upOptions returns the rollback's own up options with opts.UpOptions.
+// The rollback's options are applied last so they cannot be overridden,
+// and the event and progress streams of both are merged.
+func upOptions(opts RollbackOptions, own ...optup.Option) []optup.Option {
+	var user, internal optup.Options
+	for _, o := range opts.UpOptions {
+		o.ApplyOption(&user)
+	}
+	for _, o := range own {
+		o.ApplyOption(&internal)
+	}
+	merged := append(append([]optup.Option(nil), opts.UpOptions...), own...)
+	if len(user.EventStreams) > 0 {
+		merged = append(merged, optup.EventStreams(append(internal.EventStreams, user.EventStreams...)...))
+	}
+	if len(user.ProgressStreams) > 0 {
+		merged = append(merged, optup.ProgressStreams(append(internal.ProgressStreams, user.ProgressStreams...)...))
+	}
+	return merged
+}
+
+// previewOptions returns the rollback's own preview options with
+// opts.PreviewOptions, merged like upOptions
+func previewOptions(opts RollbackOptions, own ...optpreview.Option) []optpreview.Option {
+	var user, internal optpreview.Options
+	for _, o := range opts.PreviewOptions {
+		o.ApplyOption(&user)
+	}
+	for _, o := range own {
+		o.ApplyOption(&internal)
+	}
+	merged := append(append([]optpreview.Option(nil), opts.PreviewOptions...), own...)
+	if len(user.EventStreams) > 0 {
+		merged = append(merged, optpreview.EventStreams(append(internal.EventStreams, user.EventStreams...)...))
+	}
+	if len(user.ProgressStreams) > 0 {
+		merged = append(merged, optpreview.ProgressStreams(append(internal.ProgressStreams, user.ProgressStreams...)...))
+	}
+	return merged
+}
+
+// validateStateOnly rejects options that only affect the refresh or up,
+// which a state-only rollback does not run
+func validateStateOnly(opts RollbackOptions) error {
+	if !opts.StateOnly {
+		return nil
+	}
+	switch {
+	case opts.DryRun:
+		return fmt.Errorf("a state-only rollback cannot be a dry run")
+	case scoped(opts):
+		return fmt.Errorf("a state-only rollback imports the whole checkpoint and cannot be limited to types or targets")
+	case opts.OrphanNewResources:
+		return fmt.Errorf("a state-only rollback already leaves resources added after version %d unmanaged", opts.TargetVersion)
+	case len(opts.UpOptions) > 0:
+		return fmt.Errorf("a state-only rollback does not run up, so it takes no up options")
+	}
+	return nil
+}
+
+// refreshOptions returns the options of the refresh phase
+func refreshOptions(opts RollbackOptions) []optrefresh.Option {
+	var refreshOpts []optrefresh.Option
+	if opts.RefreshParallel > 0 {
+		refreshOpts = append(refreshOpts, optrefresh.Parallel(opts.RefreshParallel))
+	}
+	return refreshOpts
+}
+
+// scoped reports whether a rollback is limited to types or targets, in
+// which case only those resources and their dependencies are imported
+func scoped(opts RollbackOptions) bool {
+	return len(opts.Types) > 0 || len(opts.Targets) > 0
+}
+
+// PreviewRollback shows what changes would be made by rolling back
+func PreviewRollback(ctx context.Context, opts RollbackOptions) (*RollbackResult, error) {
+	opts = withDefaults(opts)
+	if err := validateParallel(opts); err != nil {
+		return nil, err
+	}
+	if err := validatePassthrough(opts); err != nil {
+		return nil, err
+	}
+
+	mode, err := ParsePreviewMode(string(opts.PreviewMode))
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := selectStack(ctx, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select stack: %w", err)
 	}
@@ -57,129 +423,622 @@ func PreviewRollback(ctx context.Context, opts RollbackOptions) (*RollbackResult
 	}
 
 	// Get the checkpoint for the target version
-	targetCheckpoint, err := GetCheckpointForVersion(ctx, stack, opts.TargetVersion)
+	opts.Logger.Infof("Fetching checkpoint for version %d...", opts.TargetVersion)
+	targetCheckpoint, updates, err := getCheckpointForVersion(ctx, stack, opts.TargetVersion, opts.CheckpointProvider)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get checkpoint for version %d: %w", opts.TargetVersion, err)
 	}
+	opts.Logger.Debugf("checkpoint for version %d is %d bytes", opts.TargetVersion, len(targetCheckpoint.Deployment))
+	direction := directionTo(updates, opts)
+	checkpointHash := CheckpointHash(targetCheckpoint)
+	opts.Logger.Debugf("checkpoint for version %d has SHA-256 %s", opts.TargetVersion, checkpointHash)
+
+	targetCheckpoint, err = transformCheckpoint(targetCheckpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+	targetCheckpoint, err = renameCheckpointStack(targetCheckpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+	targetDeployment, err := parseDeployment(targetCheckpoint)
+	if err != nil {
+		return nil, err
+	}
+	currentDeployment, err := parseDeployment(currentState)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStackName(targetDeployment, opts); err != nil {
+		return nil, err
+	}
+
+	if err := checkPlugins(ctx, stack, targetDeployment, opts); err != nil {
+		return nil, err
+	}
+
+	// Pending operations only matter once the rollback is applied, so warn
+	if err := checkPendingOperations(currentDeployment, "current", false, opts); err != nil {
+		return nil, err
+	}
+	if err := checkPendingOperations(targetDeployment, "target", false, opts); err != nil {
+		return nil, err
+	}
+	if err := checkEmptyCheckpoint(targetDeployment, currentDeployment, false, opts); err != nil {
+		return nil, err
+	}
+
+	targets, err := resolveTargets(targetDeployment, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	targetCheckpoint, err = prepareSecrets(ctx, targetCheckpoint, currentState, opts)
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := newRedactor(targetCheckpoint, currentState, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if scoped(opts) {
+		targetCheckpoint, targets, err = scopeCheckpoint(targetCheckpoint, currentDeployment, targets, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkStackBusy(ctx, stack, opts); err != nil {
+		return nil, err
+	}
+
+	// Importing can change the stack's config, so it is restored along with
+	// the state
+	configSnapshot, err := stack.GetAllConfig(ctx)
+	if err != nil {
+		return nil, redactor.Error(fmt.Errorf("failed to snapshot config: %w", err))
+	}
 
 	// Import the target state temporarily
+	opts.Logger.Debugf("importing target state")
 	err = stack.Import(ctx, targetCheckpoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to import target state: %w", err)
+		if restoreErr := restoreConfig(ctx, stack, configSnapshot, opts); restoreErr != nil {
+			opts.Logger.Warnf("%s: failed to restore config: %v", PhaseRestore, redactor.Error(restoreErr))
+		}
+		return nil, redactor.Error(fmt.Errorf("failed to import target state: %w", stackLockedError(err)))
 	}
 
 	// Run preview to see what would change
+	steps := newStepCollector(opts.Diff)
 	previewOpts := []optpreview.Option{
 		optpreview.Message(fmt.Sprintf("Preview rollback to version %d", opts.TargetVersion)),
+		optpreview.EventStreams(steps.events),
 	}
+	if len(targets) > 0 {
+		previewOpts = append(previewOpts, optpreview.Target(targets))
+	}
+	if opts.Diff {
+		previewOpts = append(previewOpts, optpreview.Diff())
+	}
+	// In live mode the preview reconciles the target state with real
+	// infrastructure in memory, so it reflects what the rollback would
+	// actually change without a state-changing refresh
+	if mode == PreviewModeLive {
+		opts.Logger.Infof("Previewing against live infrastructure...")
+		previewOpts = append(previewOpts, optpreview.Refresh())
+	}
+	previewOpts = previewOptions(opts, previewOpts...)
 
 	result, err := stack.Preview(ctx, previewOpts...)
 
 	// Restore the current state regardless of preview result
+	opts.Logger.Debugf("restoring current state")
 	restoreErr := stack.Import(ctx, currentState)
 	if restoreErr != nil {
-		fmt.Fprintf(opts.Output, "Warning: failed to restore current state: %v\n", restoreErr)
+		opts.Logger.Warnf("%s: failed to restore current state: %v", PhaseRestore, restoreErr)
+	}
+	if restoreErr := restoreConfig(ctx, stack, configSnapshot, opts); restoreErr != nil {
+		opts.Logger.Warnf("%s: failed to restore config: %v", PhaseRestore, redactor.Error(restoreErr))
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("preview failed: %w", err)
+		return nil, redactor.Error(fmt.Errorf("preview failed: %w", err))
+	}
+
+	changes := convertOpTypeChangeSummary(result.ChangeSummary)
+	if opts.VerifyPreview {
+		if err := CheckPreviewConsistency(result.ChangeSummary, changes, steps.Steps()); err != nil {
+			return nil, err
+		}
+		opts.Logger.Debugf("preview change counts match its %d step(s)", len(steps.Steps()))
 	}
 
 	return &RollbackResult{
 		Success:         true,
-		Message:         fmt.Sprintf("Preview of rollback to version %d completed", opts.TargetVersion),
-		ResourceChanges: convertOpTypeChangeSummary(result.ChangeSummary),
-		Stdout:          result.StdOut,
-		Stderr:          result.StdErr,
+		Message:         fmt.Sprintf("Preview of rollback to version %d completed (%s)", opts.TargetVersion, mode),
+		Direction:       direction,
+		CheckpointHash:  checkpointHash,
+		ResourceChanges: changes,
+		Stdout:          redactor.String(result.StdOut),
+		Stderr:          redactor.String(result.StdErr),
+		Steps:           redactSteps(steps.Steps(), redactor),
 	}, nil
 }
 
-// ExecuteRollback performs the actual rollback to a previous version
+// restoreTimeout bounds the re-import of the current state once a
+// rollback's context is done
+const restoreTimeout = 5 * time.Minute
+
+// ExecuteRollback performs the actual rollback to a previous version. With
+// opts.DryRun it imports and refreshes the target state like a real
+// rollback, then previews instead of running up, and restores the current
+// state and config. No backup is written in a dry run. With opts.StateOnly
+// it stops after the import. When ctx is cancelled after the import, the
+// current state is imported again and the RollbackError is Recovered, as it
+// is when the imported state fails verification.
 func ExecuteRollback(ctx context.Context, opts RollbackOptions) (*RollbackResult, error) {
-	if opts.Output == nil {
-		opts.Output = os.Stdout
+	opts = withDefaults(opts)
+	if err := validateParallel(opts); err != nil {
+		return nil, err
 	}
-	if opts.Operator == nil {
-		opts.Operator = DefaultOperator
+	if err := validatePassthrough(opts); err != nil {
+		return nil, err
+	}
+	if err := validateStateOnly(opts); err != nil {
+		return nil, err
+	}
+
+	var backupFile string
+	var redactor *format.Redactor
+	phases := newPhaseTracker()
+	fail := func(phase Phase, err error, changes map[string]int) (*RollbackResult, error) {
+		return nil, &RollbackError{
+			Phase:           phase,
+			Err:             redactor.Error(err),
+			BackupFile:      backupFile,
+			ResourceChanges: changes,
+			Phases:          phases.fail(phase),
+		}
 	}
 
-	stack, err := opts.Operator.SelectStack(ctx, opts.StackName, opts.ProjectPath)
+	phases.start(PhaseSelectStack)
+	stack, err := selectStack(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to select stack: %w", err)
+		return fail(PhaseSelectStack, fmt.Errorf("failed to select stack: %w", err), nil)
 	}
 
 	// Get the checkpoint for the target version
-	targetCheckpoint, err := GetCheckpointForVersion(ctx, stack, opts.TargetVersion)
+	phases.start(PhaseFetchCheckpoint)
+	opts.Logger.Infof("Fetching checkpoint for version %d...", opts.TargetVersion)
+	targetCheckpoint, updates, err := getCheckpointForVersion(ctx, stack, opts.TargetVersion, opts.CheckpointProvider)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get checkpoint for version %d: %w", opts.TargetVersion, err)
+		return fail(PhaseFetchCheckpoint, fmt.Errorf("failed to get checkpoint for version %d: %w", opts.TargetVersion, err), nil)
+	}
+	opts.Logger.Debugf("checkpoint for version %d is %d bytes", opts.TargetVersion, len(targetCheckpoint.Deployment))
+	direction := directionTo(updates, opts)
+	checkpointHash := CheckpointHash(targetCheckpoint)
+	opts.Logger.Debugf("checkpoint for version %d has SHA-256 %s", opts.TargetVersion, checkpointHash)
+
+	targetCheckpoint, err = transformCheckpoint(targetCheckpoint, opts)
+	if err != nil {
+		return fail(PhaseFetchCheckpoint, err, nil)
+	}
+	targetCheckpoint, err = renameCheckpointStack(targetCheckpoint, opts)
+	if err != nil {
+		return fail(PhaseFetchCheckpoint, err, nil)
+	}
+	targetDeployment, err := parseDeployment(targetCheckpoint)
+	if err != nil {
+		return fail(PhaseFetchCheckpoint, err, nil)
+	}
+	if err := checkStackName(targetDeployment, opts); err != nil {
+		return fail(PhaseFetchCheckpoint, err, nil)
+	}
+
+	if err := checkPlugins(ctx, stack, targetDeployment, opts); err != nil {
+		return fail(PhaseFetchCheckpoint, err, nil)
+	}
+
+	// Export the current state so it can be checked and restored if needed
+	phases.start(PhaseExportCurrent)
+	currentState, err := stack.Export(ctx)
+	if err != nil {
+		return fail(PhaseExportCurrent, fmt.Errorf("failed to export current state: %w", err), nil)
+	}
+	currentDeployment, err := parseDeployment(currentState)
+	if err != nil {
+		return fail(PhaseExportCurrent, err, nil)
+	}
+
+	if !opts.NoBackup && !opts.DryRun {
+		backupFile, err = writeBackup(opts, currentState, time.Now())
+		if err != nil {
+			return fail(PhaseExportCurrent, err, nil)
+		}
+		opts.Logger.Infof("Backed up current state to %s", backupFile)
+	}
+
+	dump, err := dumpStates(updates, currentState, targetCheckpoint, opts)
+	if err != nil {
+		return fail(PhaseExportCurrent, err, nil)
+	}
+
+	// Both states are checked before anything is changed
+	phases.start(PhaseValidate)
+	if err := checkPendingOperations(currentDeployment, "current", !opts.Force, opts); err != nil {
+		return fail(PhaseValidate, err, nil)
+	}
+	if err := checkPendingOperations(targetDeployment, "target", !opts.Force, opts); err != nil {
+		return fail(PhaseValidate, err, nil)
+	}
+	if err := checkEmptyCheckpoint(targetDeployment, currentDeployment, true, opts); err != nil {
+		return fail(PhaseValidate, err, nil)
+	}
+
+	targets, err := resolveTargets(targetDeployment, opts)
+	if err != nil {
+		return fail(PhaseValidate, err, nil)
+	}
+
+	orphans, targets, err := resolveOrphans(currentDeployment, targetDeployment, targets, opts)
+	if err != nil {
+		return fail(PhaseValidate, err, nil)
+	}
+
+	targetCheckpoint, err = prepareSecrets(ctx, targetCheckpoint, currentState, opts)
+	if err != nil {
+		return fail(PhaseValidate, err, nil)
+	}
+	redactor, err = newRedactor(targetCheckpoint, currentState, &opts)
+	if err != nil {
+		return fail(PhaseValidate, err, nil)
+	}
+	if scoped(opts) {
+		targetCheckpoint, targets, err = scopeCheckpoint(targetCheckpoint, currentDeployment, targets, orphans)
+		if err != nil {
+			return fail(PhaseValidate, err, nil)
+		}
+	}
+
+	skipRefresh := opts.ForceImport || opts.SkipRefresh
+	checkDrift := opts.MaxRefreshDrift > 0 && !opts.Force && !skipRefresh
+
+	phases.start(PhaseImport)
+	// Importing can change the stack's config, which a dry run restores
+	// along with the state
+	var configSnapshot auto.ConfigMap
+	if opts.DryRun {
+		configSnapshot, err = stack.GetAllConfig(ctx)
+		if err != nil {
+			return fail(PhaseImport, fmt.Errorf("failed to snapshot config: %w", err), nil)
+		}
+	}
+	// restore runs even when ctx was cancelled, so an interrupted rollback
+	// can still put the current state back
+	restore := func() error {
+		phases.start(PhaseRestore)
+		restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), restoreTimeout)
+		defer cancel()
+		opts.Logger.Debugf("restoring current state")
+		err := stack.Import(restoreCtx, currentState)
+		if err != nil {
+			opts.Logger.Warnf("failed to restore current state: %v", err)
+		}
+		if opts.DryRun {
+			if err := restoreConfig(restoreCtx, stack, configSnapshot, opts); err != nil {
+				opts.Logger.Warnf("failed to restore config: %v", redactor.Error(err))
+			}
+		}
+		return err
+	}
+	// recoverState imports the state from before the rollback again and
+	// fails the rollback, reporting whether the restore worked
+	recoverState := func(phase Phase, err error, changes map[string]int) (*RollbackResult, error) {
+		if restoreErr := restore(); restoreErr != nil {
+			return fail(phase, fmt.Errorf("%w; restoring the state from before the rollback also failed: %v", err, restoreErr), changes)
+		}
+		return nil, &RollbackError{
+			Phase:           phase,
+			Err:             redactor.Error(fmt.Errorf("%w; the state from before the rollback was restored", err)),
+			BackupFile:      backupFile,
+			ResourceChanges: changes,
+			Phases:          phases.fail(phase),
+			Recovered:       true,
+		}
+	}
+	// abort fails the rollback after the import. When ctx was cancelled,
+	// e.g. by Ctrl-C, the state from before the rollback is imported again
+	// rather than leaving the stack half rolled back.
+	abort := func(phase Phase, err error, changes map[string]int) (*RollbackResult, error) {
+		if ctx.Err() == nil || opts.DryRun {
+			return fail(phase, err, changes)
+		}
+		opts.Logger.Warnf("Rollback interrupted during %s, restoring the state from before the rollback...", phase)
+		return recoverState(phase, err, changes)
 	}
 
 	// Import the target state
-	err = stack.Import(ctx, targetCheckpoint)
+	if err := checkStackBusy(ctx, stack, opts); err != nil {
+		return fail(PhaseImport, err, nil)
+	}
+	opts.Logger.Infof("Importing state from version %d...", opts.TargetVersion)
+	err = stackLockedError(ImportSafe(ctx, stack, targetCheckpoint))
+	if errors.Is(err, ErrImportMismatch) {
+		// The state that failed verification was written to the stack
+		opts.Logger.Warnf("Imported state does not match version %d, restoring the state from before the rollback...", opts.TargetVersion)
+		return recoverState(PhaseVerify, fmt.Errorf("failed to import target state: %w", err), nil)
+	}
+	if err != nil && opts.DryRun {
+		restore()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to import target state: %w", err)
+		return abort(PhaseImport, fmt.Errorf("failed to import target state: %w", err), nil)
+	}
+
+	if opts.StateOnly {
+		phases.skip(PhaseRefresh)
+		phases.skip(PhaseUp)
+		opts.Logger.Infof("Skipping refresh and up: only the recorded state was changed")
+		return &RollbackResult{
+			Success:         true,
+			Message:         fmt.Sprintf("Restored the state of version %d without changing infrastructure", opts.TargetVersion),
+			Direction:       direction,
+			CheckpointHash:  checkpointHash,
+			ResourceChanges: map[string]int{},
+			BackupFile:      backupFile,
+			DumpedStates:    dump,
+			Phases:          phases.finish(),
+		}, nil
 	}
 
+	progress := newProgressWriter(opts, redactor)
+
 	// Run refresh to reconcile with actual infrastructure
-	fmt.Fprintf(opts.Output, "Refreshing stack to reconcile with target state...\n")
-	_, err = stack.Refresh(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("refresh failed: %w", err)
+	var refreshChanges map[string]int
+	if skipRefresh {
+		phases.skip(PhaseRefresh)
+		if opts.ForceImport {
+			opts.Logger.Warnf("Skipping refresh: the checkpoint for version %d is treated as ground truth", opts.TargetVersion)
+		} else {
+			opts.Logger.Infof("Skipping refresh: the state of version %d is applied as-is", opts.TargetVersion)
+		}
+	} else {
+		phases.start(PhaseRefresh)
+		if err := ctx.Err(); err != nil {
+			return abort(PhaseRefresh, fmt.Errorf("rollback interrupted: %w", err), nil)
+		}
+		opts.Logger.Infof("Refreshing stack to reconcile with target state...")
+		refreshOpts := refreshOptions(opts)
+		if progress != nil {
+			refreshOpts = append(refreshOpts, optrefresh.ProgressStreams(progress))
+		}
+		refreshResult, err := stack.Refresh(ctx, refreshOpts...)
+		progress.Flush()
+		if err != nil {
+			if opts.DryRun {
+				restore()
+			}
+			return abort(PhaseRefresh, fmt.Errorf("refresh failed: %w", err), nil)
+		}
+		refreshChanges = copyChanges(refreshResult.Summary.ResourceChanges)
+	}
+
+	if checkDrift {
+		drift := CountRefreshDrift(&refreshChanges)
+		if drift > opts.MaxRefreshDrift {
+			restore()
+			return fail(PhaseRefresh, fmt.Errorf("%w: refresh changed %d resource(s), maximum is %d",
+				ErrDriftExceeded, drift, opts.MaxRefreshDrift), refreshChanges)
+		}
+	}
+
+	// A dry run shows what up would change, then puts everything back
+	if opts.DryRun {
+		phases.start(PhasePreview)
+		opts.Logger.Infof("Previewing rollback changes...")
+		steps := newStepCollector(opts.Diff)
+		previewOpts := []optpreview.Option{
+			optpreview.Message(fmt.Sprintf("Preview rollback to version %d", opts.TargetVersion)),
+			optpreview.EventStreams(steps.events),
+		}
+		if len(targets) > 0 {
+			previewOpts = append(previewOpts, optpreview.Target(targets))
+		}
+		if opts.Diff {
+			previewOpts = append(previewOpts, optpreview.Diff())
+		}
+		if progress != nil {
+			previewOpts = append(previewOpts, optpreview.ProgressStreams(progress))
+		}
+		previewOpts = previewOptions(opts, previewOpts...)
+
+		preview, err := stack.Preview(ctx, previewOpts...)
+		progress.Flush()
+		restore()
+		if err != nil {
+			return fail(PhasePreview, fmt.Errorf("preview failed: %w", err), refreshChanges)
+		}
+
+		return &RollbackResult{
+			Success:         true,
+			Message:         fmt.Sprintf("Dry run of rollback to version %d completed", opts.TargetVersion),
+			Direction:       direction,
+			CheckpointHash:  checkpointHash,
+			ResourceChanges: convertOpTypeChangeSummary(preview.ChangeSummary),
+			Stdout:          redactor.String(preview.StdOut),
+			Stderr:          redactor.String(preview.StdErr),
+			Orphaned:        orphans,
+			Steps:           redactSteps(steps.Steps(), redactor),
+			DumpedStates:    dump,
+			Phases:          phases.finish(),
+		}, nil
 	}
 
 	// Run up to apply the changes
-	fmt.Fprintf(opts.Output, "Applying rollback changes...\n")
+	phases.start(PhaseUp)
+	if err := ctx.Err(); err != nil {
+		return abort(PhaseUp, fmt.Errorf("rollback interrupted: %w", err), refreshChanges)
+	}
+	opts.Logger.Infof("Applying rollback changes...")
+	provenance := history.Provenance{
+		SourceVersion: opts.TargetVersion,
+		ToolVersion:   opts.ToolVersion,
+		Operator:      opts.Initiator,
+	}
 	upOpts := []optup.Option{
-		optup.Message(fmt.Sprintf("Rollback to version %d", opts.TargetVersion)),
+		optup.Message(history.RollbackMessage(provenance)),
+	}
+	if len(targets) > 0 {
+		upOpts = append(upOpts, optup.Target(targets))
+	}
+	if progress != nil {
+		upOpts = append(upOpts, optup.ProgressStreams(progress))
 	}
+	upOpts = upOptions(opts, upOpts...)
 
 	result, err := stack.Up(ctx, upOpts...)
+	progress.Flush()
 	if err != nil {
-		return nil, fmt.Errorf("rollback failed: %w", err)
+		// The state already reflects the refresh when up fails
+		return abort(PhaseUp, fmt.Errorf("rollback failed: %w", err), refreshChanges)
 	}
+	recordProvenance(ctx, stack, result.Summary.Version, provenance, opts)
 
+	return &RollbackResult{
+		Success:         true,
+		Message:         appliedMessage(direction, opts.TargetVersion),
+		Direction:       direction,
+		CheckpointHash:  checkpointHash,
+		ResourceChanges: copyChanges(result.Summary.ResourceChanges),
+		Stdout:          redactor.String(result.StdOut),
+		Stderr:          redactor.String(result.StdErr),
+		BackupFile:      backupFile,
+		Orphaned:        orphans,
+		DumpedStates:    dump,
+		Phases:          phases.finish(),
+	}, nil
+}
+
+// recordProvenance records that update version of the stack was the
+// rollback, in stack tags and in opts.ProvenanceLog. The update already
+// carries its provenance in its message, so failures are only warnings.
+func recordProvenance(ctx context.Context, stack RollbackStack, version int, p history.Provenance, opts RollbackOptions) {
+	if version <= 0 {
+		return
+	}
+	if err := stack.SetTags(ctx, p.Tags(version)); err != nil {
+		opts.Logger.Warnf("failed to tag the stack with the rollback's provenance: %v", err)
+	}
+	if opts.ProvenanceLog == "" {
+		return
+	}
+	log, err := history.LoadProvenanceLog(opts.ProvenanceLog)
+	if err == nil {
+		log.Record(opts.StackName, version, p)
+		err = log.Save(opts.ProvenanceLog)
+	}
+	if err != nil {
+		opts.Logger.Warnf("failed to record the rollback's provenance in %s: %v", opts.ProvenanceLog, err)
+	}
+}
+
+// newRedactor returns the redactor for a rollback's checkpoints and routes
+// the rollback's log output through it. The target checkpoint holds
+// plaintext secrets once re-encrypted, which must never be shown.
+func newRedactor(target, current apitype.UntypedDeployment, opts *RollbackOptions) (*format.Redactor, error) {
+	redactor, err := format.NewRedactor(target, current)
+	if err != nil {
+		return nil, err
+	}
+	opts.Logger = redactor.Logger(opts.Logger)
+	return redactor, nil
+}
+
+// copyChanges copies an update summary's resource changes, which may be nil
+func copyChanges(summary *map[string]int) map[string]int {
 	changes := make(map[string]int)
-	if result.Summary.ResourceChanges != nil {
-		for k, v := range *result.Summary.ResourceChanges {
+	if summary != nil {
+		for k, v := range *summary {
 			changes[k] = v
 		}
 	}
+	return changes
+}
 
-	return &RollbackResult{
-		Success:         true,
-		Message:         fmt.Sprintf("Successfully rolled back to version %d", opts.TargetVersion),
-		ResourceChanges: changes,
-		Stdout:          result.StdOut,
-		Stderr:          result.StdErr,
-	}, nil
+// transformCheckpoint applies opts.TransformCheckpoint and validates the result
+func transformCheckpoint(checkpoint apitype.UntypedDeployment, opts RollbackOptions) (apitype.UntypedDeployment, error) {
+	if opts.TransformCheckpoint == nil {
+		return checkpoint, nil
+	}
+
+	opts.Logger.Debugf("transforming checkpoint for version %d", opts.TargetVersion)
+	transformed, err := opts.TransformCheckpoint(checkpoint)
+	if err != nil {
+		return apitype.UntypedDeployment{}, fmt.Errorf("failed to transform checkpoint for version %d: %w", opts.TargetVersion, err)
+	}
+	if err := ValidateDeployment(transformed); err != nil {
+		return apitype.UntypedDeployment{}, fmt.Errorf("transformed checkpoint for version %d is invalid: %w", opts.TargetVersion, err)
+	}
+	return transformed, nil
 }
 
 // GetCheckpointForVersion retrieves the state checkpoint for a specific version
 func GetCheckpointForVersion(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+	deployment, _, err := getCheckpointForVersion(ctx, stack, version, &DefaultCheckpointProvider{})
+	return deployment, err
+}
+
+// getCheckpointForVersion is GetCheckpointForVersion resolving the
+// checkpoint through provider. It also returns the history it looked the
+// version up in, newest first.
+func getCheckpointForVersion(ctx context.Context, stack RollbackStack, version int, provider CheckpointProvider) (apitype.UntypedDeployment, []auto.UpdateSummary, error) {
 	// Get the stack history to find the checkpoint
-	history, err := stack.History(ctx, 0, 0)
+	updates, err := stack.History(ctx, 0, 0)
 	if err != nil {
-		return apitype.UntypedDeployment{}, fmt.Errorf("failed to get history: %w", err)
+		return apitype.UntypedDeployment{}, nil, fmt.Errorf("failed to get history: %w", err)
 	}
 
 	// Find the version in history
-	if !VersionExistsInHistory(history, version) {
-		return apitype.UntypedDeployment{}, fmt.Errorf("version %d not found in history", version)
+	if !VersionExistsInHistory(updates, version) {
+		return apitype.UntypedDeployment{}, nil, versionNotFound(updates, version)
 	}
 
-	// Export the current deployment to get the structure
-	// Note: Pulumi's API doesn't directly expose historical checkpoints
-	// We need to use the export at that version through backend-specific means
-	// For now, we'll export the current state and note this limitation
+	deployment, err := fetchCheckpoint(ctx, stack, version, provider)
+	return deployment, updates, err
+}
 
-	// The proper way to get historical checkpoints depends on the backend:
-	// - Pulumi Cloud: API call to get deployment at version
-	// - S3/GCS/Azure: Read the checkpoint file directly from storage
-	// - Local: Read from .pulumi directory
+// directionTo returns where rolling a stack with updates, newest first, to
+// the target version moves it. The provenance log, when set, identifies
+// earlier rollbacks whose message lost it.
+func directionTo(updates []auto.UpdateSummary, opts RollbackOptions) history.Direction {
+	infos := history.ConvertUpdates(updates)
+	if opts.ProvenanceLog != "" {
+		if log, err := history.LoadProvenanceLog(opts.ProvenanceLog); err == nil {
+			log.Annotate(opts.StackName, infos)
+		}
+	}
+	return history.DirectionTo(infos, opts.TargetVersion)
+}
+
+// appliedMessage describes a completed rollback in the given direction
+func appliedMessage(direction history.Direction, target int) string {
+	switch direction {
+	case history.DirectionForward:
+		return fmt.Sprintf("Successfully rolled forward to version %d", target)
+	case history.DirectionReapply:
+		return fmt.Sprintf("Successfully re-applied version %d", target)
+	default:
+		return fmt.Sprintf("Successfully rolled back to version %d", target)
+	}
+}
 
-	deployment, err := stack.Export(ctx)
+// fetchCheckpoint retrieves and validates the checkpoint for a version that
+// is known to exist in the history
+func fetchCheckpoint(ctx context.Context, stack RollbackStack, version int, provider CheckpointProvider) (apitype.UntypedDeployment, error) {
+	deployment, err := provider.CheckpointAt(ctx, stack, version)
 	if err != nil {
-		return apitype.UntypedDeployment{}, fmt.Errorf("failed to export deployment: %w", err)
+		return apitype.UntypedDeployment{}, err
 	}
 
 	// Validate the deployment can be parsed
@@ -200,15 +1059,120 @@ func VersionExistsInHistory(history []auto.UpdateSummary, version int) bool {
 	return false
 }
 
-// ValidateDeployment validates that a deployment can be parsed
+// ValidateDeployment validates that a deployment has a supported schema
+// version and can be parsed. The document is streamed token by token so
+// large checkpoints are never fully materialized.
 func ValidateDeployment(deployment apitype.UntypedDeployment) error {
-	var state map[string]interface{}
-	if err := json.Unmarshal(deployment.Deployment, &state); err != nil {
+	switch {
+	case deployment.Version > MaxDeploymentVersion:
+		return fmt.Errorf("%w %d: written by a newer Pulumi engine (this tool supports up to %d)",
+			ErrUnsupportedDeploymentVersion, deployment.Version, MaxDeploymentVersion)
+	case deployment.Version < MinDeploymentVersion:
+		return fmt.Errorf("%w %d: expected %d to %d", ErrUnsupportedDeploymentVersion,
+			deployment.Version, MinDeploymentVersion, MaxDeploymentVersion)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(deployment.Deployment))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		// A JSON null decodes to an empty deployment
+		return expectEOF(dec)
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("deployment must be a JSON object, got %v", tok)
+	}
+
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := keyTok.(string)
+
+		first, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if expected, ok := deploymentFieldDelims[key]; ok && first != nil && first != expected {
+			return fmt.Errorf("deployment field %q has unexpected type", key)
+		}
+		if err := skipValue(dec, first); err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing brace
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	return expectEOF(dec)
+}
+
+// deploymentFieldDelims records the opening delimiter of well-known top-level
+// deployment fields, so structural type errors are caught while streaming
+var deploymentFieldDelims = map[string]json.Delim{
+	"manifest":           '{',
+	"secrets_providers":  '{',
+	"resources":          '[',
+	"pending_operations": '[',
+	"metadata":           '{',
+}
+
+// skipValue consumes the rest of a value whose first token has already been read
+func skipValue(dec *json.Decoder, first json.Token) error {
+	delim, ok := first.(json.Delim)
+	if !ok || (delim != '{' && delim != '[') {
+		return nil
+	}
+
+	depth := 1
+	for depth > 0 {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			switch d {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// expectEOF returns an error if the decoder has trailing data
+func expectEOF(dec *json.Decoder) error {
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			return errors.New("unexpected data after deployment")
+		}
 		return err
 	}
 	return nil
 }
 
+// CountRefreshDrift returns the number of resources a refresh changed
+func CountRefreshDrift(changes *map[string]int) int {
+	if changes == nil {
+		return 0
+	}
+	drift := 0
+	for op, count := range *changes {
+		if op != string(apitype.OpSame) {
+			drift += count
+		}
+	}
+	return drift
+}
+
 func convertOpTypeChangeSummary(summary map[apitype.OpType]int) map[string]int {
 	if summary == nil {
 		return make(map[string]int)
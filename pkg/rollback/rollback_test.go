@@ -8,13 +8,22 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/logging"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
 )
 
 // MockRollbackStack implements RollbackStack for testing
@@ -25,13 +34,21 @@ type MockRollbackStack struct {
 	PreviewFunc func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error)
 	RefreshFunc func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error)
 	UpFunc      func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error)
+
+	ListPluginsFunc func(ctx context.Context) ([]workspace.PluginInfo, error)
+
+	GetAllConfigFunc    func(ctx context.Context) (auto.ConfigMap, error)
+	SetAllConfigFunc    func(ctx context.Context, config auto.ConfigMap) error
+	RemoveAllConfigFunc func(ctx context.Context, keys []string) error
+	BackendFunc         func(ctx context.Context) (BackendInfo, error)
+	SetTagsFunc         func(ctx context.Context, tags map[string]string) error
 }
 
 func (m *MockRollbackStack) Export(ctx context.Context) (apitype.UntypedDeployment, error) {
 	if m.ExportFunc != nil {
 		return m.ExportFunc(ctx)
 	}
-	return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+	return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 }
 
 func (m *MockRollbackStack) Import(ctx context.Context, state apitype.UntypedDeployment) error {
@@ -49,6 +66,17 @@ func (m *MockRollbackStack) History(ctx context.Context, pageSize int, page int)
 }
 
 func (m *MockRollbackStack) Preview(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+	// Like the Automation API, close the event streams once the preview returns
+	previewOpts := &optpreview.Options{}
+	for _, o := range opts {
+		o.ApplyOption(previewOpts)
+	}
+	defer func() {
+		for _, ch := range previewOpts.EventStreams {
+			close(ch)
+		}
+	}()
+
 	if m.PreviewFunc != nil {
 		return m.PreviewFunc(ctx, opts...)
 	}
@@ -69,6 +97,48 @@ func (m *MockRollbackStack) Up(ctx context.Context, opts ...optup.Option) (auto.
 	return auto.UpResult{}, nil
 }
 
+func (m *MockRollbackStack) ListPlugins(ctx context.Context) ([]workspace.PluginInfo, error) {
+	if m.ListPluginsFunc != nil {
+		return m.ListPluginsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockRollbackStack) GetAllConfig(ctx context.Context) (auto.ConfigMap, error) {
+	if m.GetAllConfigFunc != nil {
+		return m.GetAllConfigFunc(ctx)
+	}
+	return auto.ConfigMap{}, nil
+}
+
+func (m *MockRollbackStack) SetAllConfig(ctx context.Context, config auto.ConfigMap) error {
+	if m.SetAllConfigFunc != nil {
+		return m.SetAllConfigFunc(ctx, config)
+	}
+	return nil
+}
+
+func (m *MockRollbackStack) RemoveAllConfig(ctx context.Context, keys []string) error {
+	if m.RemoveAllConfigFunc != nil {
+		return m.RemoveAllConfigFunc(ctx, keys)
+	}
+	return nil
+}
+
+func (m *MockRollbackStack) Backend(ctx context.Context) (BackendInfo, error) {
+	if m.BackendFunc != nil {
+		return m.BackendFunc(ctx)
+	}
+	return BackendInfo{}, nil
+}
+
+func (m *MockRollbackStack) SetTags(ctx context.Context, tags map[string]string) error {
+	if m.SetTagsFunc != nil {
+		return m.SetTagsFunc(ctx, tags)
+	}
+	return nil
+}
+
 // MockStackOperator implements StackOperator for testing
 type MockStackOperator struct {
 	SelectStackFunc func(ctx context.Context, stackName, projectPath string) (RollbackStack, error)
@@ -183,22 +253,73 @@ func TestValidateDeployment(t *testing.T) {
 	}{
 		{
 			name:        "valid empty object",
-			deployment:  apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)},
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)},
 			expectError: false,
 		},
 		{
 			name:        "valid with data",
-			deployment:  apitype.UntypedDeployment{Deployment: json.RawMessage(`{"key": "value"}`)},
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{"key": "value"}`)},
 			expectError: false,
 		},
 		{
 			name:        "invalid json",
-			deployment:  apitype.UntypedDeployment{Deployment: json.RawMessage(`{invalid}`)},
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{invalid}`)},
 			expectError: true,
 		},
 		{
 			name:        "empty deployment",
-			deployment:  apitype.UntypedDeployment{Deployment: json.RawMessage(``)},
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(``)},
+			expectError: true,
+		},
+		{
+			name:        "null deployment",
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`null`)},
+			expectError: false,
+		},
+		{
+			name: "valid nested resources",
+			deployment: apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(
+				`{"manifest": {"version": "3.0.0"}, "resources": [{"urn": "a", "inputs": {"list": [1, {"x": null}]}}]}`)},
+			expectError: false,
+		},
+		{
+			name:        "top-level array",
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`[]`)},
+			expectError: true,
+		},
+		{
+			name:        "truncated document",
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{"resources": [{"urn": "a"}`)},
+			expectError: true,
+		},
+		{
+			name:        "trailing data",
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{} {}`)},
+			expectError: true,
+		},
+		{
+			name:        "resources with wrong type",
+			deployment:  apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{"resources": {"urn": "a"}}`)},
+			expectError: true,
+		},
+		{
+			name:        "schema version 1",
+			deployment:  apitype.UntypedDeployment{Version: 1, Deployment: json.RawMessage(`{}`)},
+			expectError: false,
+		},
+		{
+			name:        "latest schema version",
+			deployment:  apitype.UntypedDeployment{Version: apitype.DeploymentSchemaVersionLatest, Deployment: json.RawMessage(`{}`)},
+			expectError: false,
+		},
+		{
+			name:        "future schema version",
+			deployment:  apitype.UntypedDeployment{Version: apitype.DeploymentSchemaVersionLatest + 1, Deployment: json.RawMessage(`{}`)},
+			expectError: true,
+		},
+		{
+			name:        "missing schema version",
+			deployment:  apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)},
 			expectError: true,
 		},
 	}
@@ -216,13 +337,23 @@ func TestValidateDeployment(t *testing.T) {
 	}
 }
 
+func TestValidateDeployment_UnsupportedVersion(t *testing.T) {
+	err := ValidateDeployment(apitype.UntypedDeployment{Version: 99, Deployment: json.RawMessage(`{}`)})
+	if !errors.Is(err, ErrUnsupportedDeploymentVersion) {
+		t.Fatalf("Expected ErrUnsupportedDeploymentVersion, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "99") || !strings.Contains(err.Error(), "newer Pulumi engine") {
+		t.Errorf("Expected the error to explain the version, got %q", err)
+	}
+}
+
 func TestPreviewRollback_Success(t *testing.T) {
 	mockStack := &MockRollbackStack{
 		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
 			return []auto.UpdateSummary{{Version: 1}, {Version: 2}}, nil
 		},
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 		},
 		PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
 			return auto.PreviewResult{
@@ -242,11 +373,12 @@ func TestPreviewRollback_Success(t *testing.T) {
 
 	var output bytes.Buffer
 	opts := RollbackOptions{
-		ProjectPath:   "/path/to/project",
-		StackName:     "test-stack",
-		TargetVersion: 1,
-		Output:        &output,
-		Operator:      mockOperator,
+		ProjectPath:        "/path/to/project",
+		StackName:          "test-stack",
+		TargetVersion:      1,
+		Output:             &output,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
 	}
 
 	result, err := PreviewRollback(context.Background(), opts)
@@ -307,7 +439,7 @@ func TestPreviewRollback_ExportError(t *testing.T) {
 func TestPreviewRollback_VersionNotFound(t *testing.T) {
 	mockStack := &MockRollbackStack{
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 		},
 		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
@@ -335,7 +467,7 @@ func TestPreviewRollback_VersionNotFound(t *testing.T) {
 func TestPreviewRollback_ImportError(t *testing.T) {
 	mockStack := &MockRollbackStack{
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 		},
 		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
@@ -367,7 +499,7 @@ func TestPreviewRollback_PreviewError(t *testing.T) {
 	importCount := 0
 	mockStack := &MockRollbackStack{
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 		},
 		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
@@ -389,10 +521,11 @@ func TestPreviewRollback_PreviewError(t *testing.T) {
 
 	var output bytes.Buffer
 	opts := RollbackOptions{
-		StackName:     "test",
-		TargetVersion: 1,
-		Operator:      mockOperator,
-		Output:        &output,
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
 	}
 
 	_, err := PreviewRollback(context.Background(), opts)
@@ -410,7 +543,7 @@ func TestPreviewRollback_RestoreError(t *testing.T) {
 	importCount := 0
 	mockStack := &MockRollbackStack{
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 		},
 		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
@@ -435,10 +568,11 @@ func TestPreviewRollback_RestoreError(t *testing.T) {
 
 	var output bytes.Buffer
 	opts := RollbackOptions{
-		StackName:     "test",
-		TargetVersion: 1,
-		Operator:      mockOperator,
-		Output:        &output,
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
 	}
 
 	result, err := PreviewRollback(context.Background(), opts)
@@ -463,7 +597,7 @@ func TestExecuteRollback_Success(t *testing.T) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
 		},
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 		},
 		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
 			return auto.UpResult{
@@ -483,10 +617,11 @@ func TestExecuteRollback_Success(t *testing.T) {
 
 	var output bytes.Buffer
 	opts := RollbackOptions{
-		StackName:     "test",
-		TargetVersion: 1,
-		Operator:      mockOperator,
-		Output:        &output,
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
 	}
 
 	result, err := ExecuteRollback(context.Background(), opts)
@@ -526,7 +661,7 @@ func TestExecuteRollback_RefreshError(t *testing.T) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
 		},
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 		},
 		RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
 			return auto.RefreshResult{}, errors.New("refresh failed")
@@ -553,16 +688,22 @@ func TestExecuteRollback_RefreshError(t *testing.T) {
 	}
 }
 
-func TestExecuteRollback_UpError(t *testing.T) {
+func TestExecuteRollback_RefreshParallel(t *testing.T) {
+	var parallel int
 	mockStack := &MockRollbackStack{
 		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
 		},
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
 		},
-		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
-			return auto.UpResult{}, errors.New("up failed")
+		RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+			refreshOpts := &optrefresh.Options{}
+			for _, o := range opts {
+				o.ApplyOption(refreshOpts)
+			}
+			parallel = refreshOpts.Parallel
+			return auto.RefreshResult{}, nil
 		},
 	}
 
@@ -574,32 +715,44 @@ func TestExecuteRollback_UpError(t *testing.T) {
 
 	var output bytes.Buffer
 	opts := RollbackOptions{
-		StackName:     "test",
-		TargetVersion: 1,
-		Operator:      mockOperator,
-		Output:        &output,
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		RefreshParallel:    32,
 	}
 
-	_, err := ExecuteRollback(context.Background(), opts)
-	if err == nil {
-		t.Error("Expected error for up failure")
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if parallel != 32 {
+		t.Errorf("Expected refresh parallelism 32, got %d", parallel)
+	}
+
+	opts.RefreshParallel = -1
+	if _, err := ExecuteRollback(context.Background(), opts); err == nil {
+		t.Error("Expected error for negative refresh parallelism")
 	}
 }
 
-func TestExecuteRollback_NilResourceChanges(t *testing.T) {
+func TestExecuteRollback_PassthroughOptions(t *testing.T) {
+	var upOpts optup.Options
+	var previewOpts optpreview.Options
 	mockStack := &MockRollbackStack{
-		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
-			return []auto.UpdateSummary{{Version: 1}}, nil
-		},
-		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{}`)}, nil
-		},
 		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
-			return auto.UpResult{
-				Summary: auto.UpdateSummary{
-					ResourceChanges: nil,
-				},
-			}, nil
+			upOpts = optup.Options{}
+			for _, o := range opts {
+				o.ApplyOption(&upOpts)
+			}
+			return auto.UpResult{}, nil
+		},
+		PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+			previewOpts = optpreview.Options{}
+			for _, o := range opts {
+				o.ApplyOption(&previewOpts)
+			}
+			return auto.PreviewResult{}, nil
 		},
 	}
 
@@ -609,105 +762,1427 @@ func TestExecuteRollback_NilResourceChanges(t *testing.T) {
 		},
 	}
 
-	var output bytes.Buffer
 	opts := RollbackOptions{
-		StackName:     "test",
-		TargetVersion: 1,
-		Operator:      mockOperator,
-		Output:        &output,
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &bytes.Buffer{},
+		Targets:            []string{"urn:pulumi:test::proj::aws:s3/bucket:Bucket::b"},
+		UpOptions:          []optup.Option{optup.Parallel(8)},
+		PreviewOptions:     []optpreview.Option{optpreview.Parallel(4)},
 	}
 
-	result, err := ExecuteRollback(context.Background(), opts)
-	if err != nil {
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if upOpts.Parallel != 8 {
+		t.Errorf("Expected up parallelism 8, got %d", upOpts.Parallel)
+	}
+	if p, ok := history.ParseRollbackProvenance(upOpts.Message); !ok || p.SourceVersion != 1 {
+		t.Errorf("Expected the up message to record the rollback to version 1, got %q", upOpts.Message)
+	}
+	if !reflect.DeepEqual(upOpts.Target, opts.Targets) {
+		t.Errorf("Expected up to target %v, got %v", opts.Targets, upOpts.Target)
+	}
+
+	if _, err := PreviewRollback(context.Background(), opts); err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
+	if previewOpts.Parallel != 4 || previewOpts.Message != "Preview rollback to version 1" {
+		t.Errorf("Expected preview parallelism 4 and the rollback's message, got %d and %q", previewOpts.Parallel, previewOpts.Message)
+	}
 
-	if len(result.ResourceChanges) != 0 {
-		t.Errorf("Expected empty ResourceChanges, got %d entries", len(result.ResourceChanges))
+	previewOpts = optpreview.Options{}
+	opts.DryRun = true
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if previewOpts.Parallel != 4 {
+		t.Errorf("Expected dry run preview parallelism 4, got %d", previewOpts.Parallel)
+	}
+
+	// The message and targets belong to the rollback
+	opts.DryRun = false
+	rejected := []RollbackOptions{opts, opts, opts, opts}
+	rejected[0].UpOptions = []optup.Option{optup.Message("custom")}
+	rejected[1].UpOptions = []optup.Option{optup.Target([]string{"urn:other"})}
+	rejected[2].PreviewOptions = []optpreview.Option{optpreview.Message("custom")}
+	rejected[3].PreviewOptions = []optpreview.Option{optpreview.Target([]string{"urn:other"})}
+	for i, o := range rejected {
+		if _, err := ExecuteRollback(context.Background(), o); err == nil {
+			t.Errorf("Options %d: expected an error for a message or targets in the passthrough options", i)
+		}
+		if _, err := PreviewRollback(context.Background(), o); err == nil {
+			t.Errorf("Options %d: expected a preview error for a message or targets in the passthrough options", i)
+		}
+	}
+
+	opts.StateOnly = true
+	if _, err := ExecuteRollback(context.Background(), opts); err == nil {
+		t.Error("Expected error for up options on a state-only rollback")
 	}
 }
 
-func TestGetCheckpointForVersion_HistoryError(t *testing.T) {
-	mockStack := &MockRollbackStack{
-		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
-			return nil, errors.New("history failed")
-		},
+func TestUpOptions_MergesStreams(t *testing.T) {
+	own, user := make(chan events.EngineEvent), make(chan events.EngineEvent)
+	var ownOut, userOut bytes.Buffer
+	opts := RollbackOptions{UpOptions: []optup.Option{optup.EventStreams(user), optup.ProgressStreams(&userOut), optup.Parallel(2)}}
+
+	var applied optup.Options
+	for _, o := range upOptions(opts, optup.Message("rollback"), optup.EventStreams(own), optup.ProgressStreams(&ownOut)) {
+		o.ApplyOption(&applied)
+	}
+	if applied.Message != "rollback" || applied.Parallel != 2 {
+		t.Errorf("Expected the rollback's message and the user's parallelism, got %q and %d", applied.Message, applied.Parallel)
+	}
+	if len(applied.EventStreams) != 2 || applied.EventStreams[0] != own || applied.EventStreams[1] != user {
+		t.Errorf("Expected both event streams, got %v", applied.EventStreams)
+	}
+	if len(applied.ProgressStreams) != 2 {
+		t.Errorf("Expected both progress streams, got %d", len(applied.ProgressStreams))
 	}
 
-	_, err := GetCheckpointForVersion(context.Background(), mockStack, 1)
-	if err == nil {
-		t.Error("Expected error, got nil")
+	var preview optpreview.Options
+	popts := RollbackOptions{PreviewOptions: []optpreview.Option{optpreview.EventStreams(user)}}
+	for _, o := range previewOptions(popts, optpreview.EventStreams(own)) {
+		o.ApplyOption(&preview)
+	}
+	if len(preview.EventStreams) != 2 {
+		t.Errorf("Expected both preview event streams, got %v", preview.EventStreams)
 	}
 }
 
-func TestGetCheckpointForVersion_ExportError(t *testing.T) {
+func TestExecuteRollback_SkipRefresh(t *testing.T) {
+	refreshed, upCalled := false, false
 	mockStack := &MockRollbackStack{
 		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
 		},
 		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{}, errors.New("export failed")
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
+		},
+		RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+			refreshed = true
+			return auto.RefreshResult{}, errors.New("refresh failed")
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upCalled = true
+			return auto.UpResult{}, nil
 		},
 	}
 
-	_, err := GetCheckpointForVersion(context.Background(), mockStack, 1)
-	if err == nil {
-		t.Error("Expected error, got nil")
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		SkipRefresh:        true,
+		MaxRefreshDrift:    1,
+	}
+
+	result, err := ExecuteRollback(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if refreshed {
+		t.Error("Expected refresh to be skipped")
+	}
+	if !upCalled {
+		t.Error("Expected up to run after the import")
+	}
+	for _, phase := range result.Phases {
+		if phase.Phase == PhaseRefresh && phase.Status != PhaseSkipped {
+			t.Errorf("Expected refresh phase to be skipped, got %s", phase.Status)
+		}
 	}
 }
 
-func TestGetCheckpointForVersion_InvalidDeployment(t *testing.T) {
+func TestExecuteRollback_StateOnly(t *testing.T) {
+	refreshed, upCalled, imports := false, false, 0
 	mockStack := &MockRollbackStack{
 		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
 			return []auto.UpdateSummary{{Version: 1}}, nil
 		},
-		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
-			return apitype.UntypedDeployment{Deployment: json.RawMessage(`{invalid}`)}, nil
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			imports++
+			return nil
+		},
+		RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+			refreshed = true
+			return auto.RefreshResult{}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upCalled = true
+			return auto.UpResult{}, nil
 		},
 	}
 
-	_, err := GetCheckpointForVersion(context.Background(), mockStack, 1)
-	if err == nil {
-		t.Error("Expected error for invalid deployment")
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
 	}
-}
 
-func TestRollbackOptions(t *testing.T) {
+	var output bytes.Buffer
 	opts := RollbackOptions{
-		ProjectPath:   "/path/to/project",
-		StackName:     "test-stack",
-		TargetVersion: 5,
-		DryRun:        true,
-		Verbose:       true,
-		Output:        nil,
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		StateOnly:          true,
 	}
 
-	if opts.ProjectPath != "/path/to/project" {
-		t.Errorf("Expected ProjectPath to be '/path/to/project', got %q", opts.ProjectPath)
+	result, err := ExecuteRollback(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
-	if opts.StackName != "test-stack" {
-		t.Errorf("Expected StackName to be 'test-stack', got %q", opts.StackName)
+	if refreshed || upCalled {
+		t.Errorf("Expected neither refresh nor up to run, got refresh=%v up=%v", refreshed, upCalled)
 	}
-	if opts.TargetVersion != 5 {
-		t.Errorf("Expected TargetVersion to be 5, got %d", opts.TargetVersion)
+	if imports != 1 {
+		t.Errorf("Expected 1 import, got %d", imports)
+	}
+	if result.HasChanges() {
+		t.Errorf("Expected no resource changes, got %v", result.ResourceChanges)
+	}
+	skipped := map[Phase]bool{}
+	for _, phase := range result.Phases {
+		if phase.Status == PhaseSkipped {
+			skipped[phase.Phase] = true
+		}
+	}
+	if !skipped[PhaseRefresh] || !skipped[PhaseUp] {
+		t.Errorf("Expected refresh and up to be skipped, got %+v", result.Phases)
+	}
+
+	invalid := []RollbackOptions{
+		{DryRun: true},
+		{Types: []string{"aws:s3/bucket:Bucket"}},
+		{Targets: []string{"urn:pulumi:test::proj::aws:s3/bucket:Bucket::b"}},
+		{OrphanNewResources: true},
+	}
+	for _, extra := range invalid {
+		extra.StackName, extra.TargetVersion, extra.Operator, extra.Output, extra.StateOnly = "test", 1, mockOperator, &output, true
+		if _, err := ExecuteRollback(context.Background(), extra); err == nil {
+			t.Errorf("Expected error for a state-only rollback with %+v", extra)
+		}
 	}
 }
 
-func TestRollbackResult(t *testing.T) {
-	result := RollbackResult{
-		Success: true,
-		Message: "test message",
-		ResourceChanges: map[string]int{
-			"create": 1,
-		},
-		Stdout: "stdout",
-		Stderr: "stderr",
+func TestExecuteRollback_ProgressWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress bool
+		expected string
+	}{
+		{name: "streams refresh and up output", progress: true, expected: "refreshing aws:s3:Bucket logs\nupdating aws:s3:Bucket logs"},
+		{name: "no writer", progress: false, expected: ""},
 	}
 
-	if !result.Success {
-		t.Error("Expected Success to be true")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams := 0
+			mockStack := &MockRollbackStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					return []auto.UpdateSummary{{Version: 1}}, nil
+				},
+				RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+					refreshOpts := &optrefresh.Options{}
+					for _, o := range opts {
+						o.ApplyOption(refreshOpts)
+					}
+					for _, w := range refreshOpts.ProgressStreams {
+						streams++
+						// Split a line across writes, as the CLI may
+						w.Write([]byte("refreshing aws:s3:Bucket "))
+						w.Write([]byte("logs\n"))
+					}
+					return auto.RefreshResult{}, nil
+				},
+				UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+					upOpts := &optup.Options{}
+					for _, o := range opts {
+						o.ApplyOption(upOpts)
+					}
+					for _, w := range upOpts.ProgressStreams {
+						streams++
+						// No trailing newline: flushed when up returns
+						w.Write([]byte("updating aws:s3:Bucket logs"))
+					}
+					return auto.UpResult{}, nil
+				},
+			}
+			mockOperator := &MockStackOperator{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+					return mockStack, nil
+				},
+			}
+
+			var output, progress bytes.Buffer
+			opts := RollbackOptions{
+				StackName:          "test",
+				TargetVersion:      1,
+				Operator:           mockOperator,
+				CheckpointProvider: exportCheckpoints,
+				Output:             &output,
+			}
+			if tt.progress {
+				opts.ProgressWriter = &progress
+			}
+
+			if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.progress && streams != 0 {
+				t.Errorf("Expected no progress streams, got %d", streams)
+			}
+			if got := progress.String(); got != tt.expected {
+				t.Errorf("Expected progress %q, got %q", tt.expected, got)
+			}
+		})
 	}
-	if result.Message != "test message" {
-		t.Errorf("Expected Message to be 'test message', got %q", result.Message)
+}
+
+func TestExecuteRollback_DryRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		refreshErr      error
+		refreshChanges  map[string]int
+		maxRefreshDrift int
+		previewErr      error
+		expectedErr     string
+		expectPreview   bool
+	}{
+		{name: "previews instead of up", expectPreview: true},
+		{name: "preview fails", previewErr: errors.New("program crashed"), expectedErr: "preview failed", expectPreview: true},
+		{name: "refresh fails", refreshErr: errors.New("provider error"), expectedErr: "refresh failed"},
+		{
+			name:            "drift exceeded",
+			refreshChanges:  map[string]int{"update": 3},
+			maxRefreshDrift: 1,
+			expectedErr:     "drift",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := auto.ConfigMap{"app:replicas": {Value: "3"}}
+			imports, upCalls := 0, 0
+			previewed := false
+			mockStack := &MockRollbackStack{
+				ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+					imports++
+					if imports == 1 {
+						// The import of the target state changes the config
+						config["app:replicas"] = auto.ConfigValue{Value: "1"}
+					}
+					return nil
+				},
+				RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+					changes := tt.refreshChanges
+					return auto.RefreshResult{Summary: auto.UpdateSummary{ResourceChanges: &changes}}, tt.refreshErr
+				},
+				PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+					previewed = true
+					return auto.PreviewResult{ChangeSummary: map[apitype.OpType]int{apitype.OpUpdate: 2}}, tt.previewErr
+				},
+				UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+					upCalls++
+					return auto.UpResult{}, nil
+				},
+				GetAllConfigFunc: func(ctx context.Context) (auto.ConfigMap, error) {
+					snapshot := auto.ConfigMap{}
+					for k, v := range config {
+						snapshot[k] = v
+					}
+					return snapshot, nil
+				},
+				SetAllConfigFunc: func(ctx context.Context, values auto.ConfigMap) error {
+					for k, v := range values {
+						config[k] = v
+					}
+					return nil
+				},
+			}
+			mockOperator := &MockStackOperator{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+					return mockStack, nil
+				},
+			}
+
+			backupDir := t.TempDir()
+			var output bytes.Buffer
+			result, err := ExecuteRollback(context.Background(), RollbackOptions{
+				StackName:          "test",
+				TargetVersion:      1,
+				DryRun:             true,
+				Operator:           mockOperator,
+				CheckpointProvider: exportCheckpoints,
+				Output:             &output,
+				BackupDir:          backupDir,
+				MaxRefreshDrift:    tt.maxRefreshDrift,
+			})
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if result.ResourceChanges["update"] != 2 {
+					t.Errorf("Expected the preview's 2 updates, got %v", result.ResourceChanges)
+				}
+				if result.BackupFile != "" {
+					t.Errorf("Expected no backup, got %s", result.BackupFile)
+				}
+			}
+
+			if upCalls != 0 {
+				t.Errorf("Expected up never to run, got %d calls", upCalls)
+			}
+			if previewed != tt.expectPreview {
+				t.Errorf("Expected preview to run: %v, got %v", tt.expectPreview, previewed)
+			}
+			if imports != 2 {
+				t.Errorf("Expected the target state to be imported and the current state restored, got %d imports", imports)
+			}
+			if config["app:replicas"].Value != "3" {
+				t.Errorf("Expected config to be restored, got %v", config)
+			}
+			if entries, _ := os.ReadDir(backupDir); len(entries) != 0 {
+				t.Errorf("Expected no backup to be written, got %d file(s)", len(entries))
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_ForceImportSkipsRefresh(t *testing.T) {
+	refreshed := false
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
+		},
+		RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+			refreshed = true
+			return auto.RefreshResult{}, errors.New("refresh failed")
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		ForceImport:        true,
+		MaxRefreshDrift:    1,
+	}
+
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if refreshed {
+		t.Error("Expected refresh to be skipped")
+	}
+}
+
+func TestExecuteRollback_UpError(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			return auto.UpResult{}, errors.New("up failed")
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:     "test",
+		TargetVersion: 1,
+		Operator:      mockOperator,
+		Output:        &output,
+	}
+
+	_, err := ExecuteRollback(context.Background(), opts)
+	if err == nil {
+		t.Error("Expected error for up failure")
+	}
+}
+
+func TestExecuteRollback_Interrupted(t *testing.T) {
+	tests := []struct {
+		name       string
+		refresh    func(cancel context.CancelFunc) error
+		up         func(cancel context.CancelFunc) error
+		restoreErr error
+		phase      Phase
+		recovered  bool
+		imports    int
+	}{
+		{
+			name:      "during refresh",
+			refresh:   func(cancel context.CancelFunc) error { cancel(); return context.Canceled },
+			phase:     PhaseRefresh,
+			recovered: true,
+			imports:   2,
+		},
+		{
+			name:      "between refresh and up",
+			refresh:   func(cancel context.CancelFunc) error { cancel(); return nil },
+			phase:     PhaseUp,
+			recovered: true,
+			imports:   2,
+		},
+		{
+			name:      "during up",
+			up:        func(cancel context.CancelFunc) error { cancel(); return context.Canceled },
+			phase:     PhaseUp,
+			recovered: true,
+			imports:   2,
+		},
+		{
+			name:       "restore fails",
+			up:         func(cancel context.CancelFunc) error { cancel(); return context.Canceled },
+			restoreErr: errors.New("backend unavailable"),
+			phase:      PhaseUp,
+			imports:    2,
+		},
+		{
+			name:    "up fails without interruption",
+			up:      func(cancel context.CancelFunc) error { return errors.New("up failed") },
+			phase:   PhaseUp,
+			imports: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			imports := 0
+			mockStack := &MockRollbackStack{
+				ImportFunc: func(importCtx context.Context, state apitype.UntypedDeployment) error {
+					imports++
+					if imports > 1 {
+						if importCtx.Err() != nil {
+							t.Error("Expected the restore to run with a live context")
+						}
+						return tt.restoreErr
+					}
+					return nil
+				},
+				RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+					if tt.refresh != nil {
+						return auto.RefreshResult{}, tt.refresh(cancel)
+					}
+					return auto.RefreshResult{}, nil
+				},
+				UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+					if tt.up != nil {
+						return auto.UpResult{}, tt.up(cancel)
+					}
+					t.Error("Expected up not to run after an interruption")
+					return auto.UpResult{}, nil
+				},
+			}
+
+			_, err := ExecuteRollback(ctx, RollbackOptions{
+				CheckpointProvider: exportCheckpoints,
+				StackName:          "test",
+				TargetVersion:      1,
+				Output:             &bytes.Buffer{},
+				Operator: &MockStackOperator{
+					SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+						return mockStack, nil
+					},
+				},
+			})
+
+			var rbErr *RollbackError
+			if !errors.As(err, &rbErr) {
+				t.Fatalf("Expected a RollbackError, got %v", err)
+			}
+			if rbErr.Phase != tt.phase {
+				t.Errorf("Expected phase %q, got %q", tt.phase, rbErr.Phase)
+			}
+			if rbErr.Recovered != tt.recovered {
+				t.Errorf("Expected recovered %v, got %v (%v)", tt.recovered, rbErr.Recovered, err)
+			}
+			if imports != tt.imports {
+				t.Errorf("Expected %d import(s), got %d", tt.imports, imports)
+			}
+			if tt.recovered && !strings.Contains(err.Error(), "the state from before the rollback was restored") {
+				t.Errorf("Expected the error to report the recovery, got %v", err)
+			}
+			if tt.restoreErr != nil && !strings.Contains(err.Error(), tt.restoreErr.Error()) {
+				t.Errorf("Expected the error to report the failed restore, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_ImportMismatchRestores(t *testing.T) {
+	current := deployment(`{"resources": [{"urn": "urn:pulumi:test::proj::aws:s3/bucket:Bucket::b", "type": "aws:s3/bucket:Bucket", "id": "new"}]}`)
+	target := deployment(`{"resources": [{"urn": "urn:pulumi:test::proj::aws:s3/bucket:Bucket::b", "type": "aws:s3/bucket:Bucket", "id": "old"}]}`)
+
+	// The import succeeds, but the backend keeps returning the current state
+	var imported []apitype.UntypedDeployment
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return current, nil
+		},
+		ImportFunc: func(ctx context.Context, d apitype.UntypedDeployment) error {
+			imported = append(imported, d)
+			return nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			t.Error("Expected up not to run after a failed verification")
+			return auto.UpResult{}, nil
+		},
+	}
+
+	_, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:     "test",
+		TargetVersion: 1,
+		Output:        &bytes.Buffer{},
+		Operator: &MockStackOperator{
+			SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+				return mockStack, nil
+			},
+		},
+		CheckpointProvider: &MockCheckpointProvider{
+			CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+				return target, nil
+			},
+		},
+	})
+
+	var rbErr *RollbackError
+	if !errors.As(err, &rbErr) || !errors.Is(err, ErrImportMismatch) {
+		t.Fatalf("Expected a RollbackError for the mismatch, got %v", err)
+	}
+	if rbErr.Phase != PhaseVerify || !rbErr.Recovered {
+		t.Errorf("Expected a recovered failure in %q, got %q (recovered %v)", PhaseVerify, rbErr.Phase, rbErr.Recovered)
+	}
+	// Two import attempts, then the restore
+	if len(imported) != 3 {
+		t.Fatalf("Expected 3 imports, got %d", len(imported))
+	}
+	if string(imported[2].Deployment) != string(current.Deployment) {
+		t.Errorf("Expected the state from before the rollback to be imported again, got %s", imported[2].Deployment)
+	}
+}
+
+func TestExecuteRollback_NilResourceChanges(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{}`)}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			return auto.UpResult{
+				Summary: auto.UpdateSummary{
+					ResourceChanges: nil,
+				},
+			}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+	}
+
+	result, err := ExecuteRollback(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result.ResourceChanges) != 0 {
+		t.Errorf("Expected empty ResourceChanges, got %d entries", len(result.ResourceChanges))
+	}
+}
+
+func TestGetCheckpointForVersion_HistoryError(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return nil, errors.New("history failed")
+		},
+	}
+
+	_, err := GetCheckpointForVersion(context.Background(), mockStack, 1)
+	if err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
+
+func TestGetCheckpointForVersion_ExportError(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+	}
+	errExport := errors.New("export failed")
+	provider := &MockCheckpointProvider{
+		CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{}, errExport
+		},
+	}
+
+	_, _, err := getCheckpointForVersion(context.Background(), mockStack, 1, provider)
+	if !errors.Is(err, errExport) {
+		t.Errorf("Expected the export error, got %v", err)
+	}
+}
+
+func TestGetCheckpointForVersion_InvalidDeployment(t *testing.T) {
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 1}}, nil
+		},
+	}
+	provider := &MockCheckpointProvider{
+		CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+			return apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(`{invalid}`)}, nil
+		},
+	}
+
+	_, _, err := getCheckpointForVersion(context.Background(), mockStack, 1, provider)
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("Expected a JSON syntax error for invalid deployment, got %v", err)
+	}
+}
+
+func TestRollbackOptions(t *testing.T) {
+	opts := RollbackOptions{
+		ProjectPath:   "/path/to/project",
+		StackName:     "test-stack",
+		TargetVersion: 5,
+		DryRun:        true,
+		Verbose:       true,
+		Output:        nil,
+	}
+
+	if opts.ProjectPath != "/path/to/project" {
+		t.Errorf("Expected ProjectPath to be '/path/to/project', got %q", opts.ProjectPath)
+	}
+	if opts.StackName != "test-stack" {
+		t.Errorf("Expected StackName to be 'test-stack', got %q", opts.StackName)
+	}
+	if opts.TargetVersion != 5 {
+		t.Errorf("Expected TargetVersion to be 5, got %d", opts.TargetVersion)
+	}
+}
+
+func TestRollbackResult(t *testing.T) {
+	result := RollbackResult{
+		Success: true,
+		Message: "test message",
+		ResourceChanges: map[string]int{
+			"create": 1,
+		},
+		Stdout: "stdout",
+		Stderr: "stderr",
+	}
+
+	if !result.Success {
+		t.Error("Expected Success to be true")
+	}
+	if result.Message != "test message" {
+		t.Errorf("Expected Message to be 'test message', got %q", result.Message)
+	}
+}
+
+func TestCountRefreshDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		changes  *map[string]int
+		expected int
+	}{
+		{"nil changes", nil, 0},
+		{"only same", &map[string]int{"same": 10}, 0},
+		{"mixed", &map[string]int{"same": 10, "update": 2, "delete": 1}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := CountRefreshDrift(tt.changes); result != tt.expected {
+				t.Errorf("CountRefreshDrift() = %d, want %d", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_DriftExceeded(t *testing.T) {
+	importCount := 0
+	upCalled := false
+	refreshChanges := map[string]int{"same": 5, "update": 4}
+	mockStack := &MockRollbackStack{
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			importCount++
+			return nil
+		},
+		RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+			return auto.RefreshResult{
+				Summary: auto.UpdateSummary{ResourceChanges: &refreshChanges},
+			}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upCalled = true
+			return auto.UpResult{}, nil
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	opts := RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		MaxRefreshDrift:    3,
+	}
+
+	_, err := ExecuteRollback(context.Background(), opts)
+	if !errors.Is(err, ErrDriftExceeded) {
+		t.Fatalf("Expected ErrDriftExceeded, got %v", err)
+	}
+	if upCalled {
+		t.Error("Expected Up not to be called when drift is exceeded")
+	}
+	if importCount != 2 {
+		t.Errorf("Expected import to be called twice (once for target, once for restore), got %d", importCount)
+	}
+
+	var rbErr *RollbackError
+	if !errors.As(err, &rbErr) {
+		t.Fatalf("Expected a RollbackError, got %T", err)
+	}
+	if rbErr.Phase != PhaseRefresh {
+		t.Errorf("Expected phase %q, got %q", PhaseRefresh, rbErr.Phase)
+	}
+	if rbErr.ResourceChanges["update"] != 4 {
+		t.Errorf("Expected partial changes from the refresh, got %v", rbErr.ResourceChanges)
+	}
+
+	// Force skips the guard
+	opts.Force = true
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error with Force: %v", err)
+	}
+	if !upCalled {
+		t.Error("Expected Up to be called when Force is set")
+	}
+}
+
+func TestRollbackResultHasChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		changes  map[string]int
+		expected bool
+	}{
+		{"nil changes", nil, false},
+		{"only same", map[string]int{"same": 4}, false},
+		{"zero counts", map[string]int{"update": 0}, false},
+		{"with changes", map[string]int{"same": 4, "delete": 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &RollbackResult{ResourceChanges: tt.changes}
+			if result.HasChanges() != tt.expected {
+				t.Errorf("HasChanges() = %v, want %v", result.HasChanges(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestParsePreviewMode(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    PreviewMode
+		expectError bool
+	}{
+		{"", PreviewModeStateOnly, false},
+		{"state-only", PreviewModeStateOnly, false},
+		{"live", PreviewModeLive, false},
+		{"bogus", "", true},
+	}
+
+	for _, tt := range tests {
+		mode, err := ParsePreviewMode(tt.input)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("ParsePreviewMode(%q): expected error, got nil", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePreviewMode(%q): unexpected error: %v", tt.input, err)
+		}
+		if mode != tt.expected {
+			t.Errorf("ParsePreviewMode(%q) = %q, want %q", tt.input, mode, tt.expected)
+		}
+	}
+}
+
+func TestPreviewRollback_Modes(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          PreviewMode
+		previewErr    error
+		expectRefresh bool
+		expectError   bool
+	}{
+		{"default is state-only", "", nil, false, false},
+		{"state-only skips refresh", PreviewModeStateOnly, nil, false, false},
+		{"live refreshes within the preview", PreviewModeLive, nil, true, false},
+		{"live preview failure restores state", PreviewModeLive, errors.New("refresh failed"), true, true},
+		{"invalid mode", "bogus", nil, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshCalled := false
+			previewRefresh := false
+			importCount := 0
+			mockStack := &MockRollbackStack{
+				ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+					importCount++
+					return nil
+				},
+				RefreshFunc: func(ctx context.Context, opts ...optrefresh.Option) (auto.RefreshResult, error) {
+					refreshCalled = true
+					return auto.RefreshResult{}, nil
+				},
+				PreviewFunc: func(ctx context.Context, opts ...optpreview.Option) (auto.PreviewResult, error) {
+					previewOpts := &optpreview.Options{}
+					for _, o := range opts {
+						o.ApplyOption(previewOpts)
+					}
+					previewRefresh = previewOpts.Refresh
+					return auto.PreviewResult{}, tt.previewErr
+				},
+			}
+
+			mockOperator := &MockStackOperator{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+					return mockStack, nil
+				},
+			}
+
+			var output bytes.Buffer
+			opts := RollbackOptions{
+				StackName:          "test",
+				TargetVersion:      1,
+				Operator:           mockOperator,
+				CheckpointProvider: exportCheckpoints,
+				Output:             &output,
+				PreviewMode:        tt.mode,
+			}
+
+			result, err := PreviewRollback(context.Background(), opts)
+			if refreshCalled {
+				t.Error("Expected no state-changing refresh during a preview")
+			}
+			if previewRefresh != tt.expectRefresh {
+				t.Errorf("Preview refresh = %v, want %v", previewRefresh, tt.expectRefresh)
+			}
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				if tt.previewErr != nil && importCount != 2 {
+					t.Errorf("Expected current state to be restored, import called %d times", importCount)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expectedMode := tt.mode
+			if expectedMode == "" {
+				expectedMode = PreviewModeStateOnly
+			}
+			if !strings.Contains(result.Message, string(expectedMode)) {
+				t.Errorf("Expected message to mention mode %q, got %q", expectedMode, result.Message)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_TransformCheckpoint(t *testing.T) {
+	original := `{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a","type":"aws:s3/bucket:Bucket","id":"a-123"}]}`
+	transformed := `{"resources":[{"urn":"urn:pulumi:dev::proj::aws:s3/bucket:Bucket::a","type":"aws:s3/bucket:Bucket","id":"a-456"}]}`
+
+	tests := []struct {
+		name        string
+		transform   func(apitype.UntypedDeployment) (apitype.UntypedDeployment, error)
+		expectedErr bool
+	}{
+		{
+			name: "replaces checkpoint",
+			transform: func(d apitype.UntypedDeployment) (apitype.UntypedDeployment, error) {
+				return deployment(transformed), nil
+			},
+		},
+		{
+			name: "transform error",
+			transform: func(d apitype.UntypedDeployment) (apitype.UntypedDeployment, error) {
+				return apitype.UntypedDeployment{}, errors.New("region not found")
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid result",
+			transform: func(d apitype.UntypedDeployment) (apitype.UntypedDeployment, error) {
+				return deployment(`{"resources":`), nil
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var imported []string
+			mockStack := &MockRollbackStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					return []auto.UpdateSummary{{Version: 1}}, nil
+				},
+				ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+					if len(imported) > 0 {
+						return deployment(imported[len(imported)-1]), nil
+					}
+					return deployment(original), nil
+				},
+				ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+					imported = append(imported, string(state.Deployment))
+					return nil
+				},
+			}
+
+			mockOperator := &MockStackOperator{
+				SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+					return mockStack, nil
+				},
+			}
+
+			var output bytes.Buffer
+			_, err := ExecuteRollback(context.Background(), RollbackOptions{
+				StackName:           "dev",
+				TargetVersion:       1,
+				Operator:            mockOperator,
+				CheckpointProvider:  exportCheckpoints,
+				Output:              &output,
+				TransformCheckpoint: tt.transform,
+			})
+			if tt.expectedErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				if len(imported) != 0 {
+					t.Errorf("Expected nothing to be imported, got %v", imported)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(imported) == 0 || imported[0] != transformed {
+				t.Errorf("Expected the transformed checkpoint to be imported, got %v", imported)
+			}
+		})
+	}
+}
+
+func TestPassphraseEnvVars(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		env        map[string]string
+		expected   map[string]string
+	}{
+		{
+			name:     "nothing set",
+			expected: nil,
+		},
+		{
+			name:       "explicit passphrase wins",
+			passphrase: "explicit",
+			env:        map[string]string{"PULUMI_CONFIG_PASSPHRASE": "env", "PULUMI_CONFIG_PASSPHRASE_FILE": "/tmp/pass"},
+			expected:   map[string]string{"PULUMI_CONFIG_PASSPHRASE": "explicit"},
+		},
+		{
+			name:     "passphrase from environment",
+			env:      map[string]string{"PULUMI_CONFIG_PASSPHRASE": "env", "PULUMI_CONFIG_PASSPHRASE_FILE": "/tmp/pass"},
+			expected: map[string]string{"PULUMI_CONFIG_PASSPHRASE": "env"},
+		},
+		{
+			name:     "empty passphrase from environment",
+			env:      map[string]string{"PULUMI_CONFIG_PASSPHRASE": ""},
+			expected: map[string]string{"PULUMI_CONFIG_PASSPHRASE": ""},
+		},
+		{
+			name:     "passphrase file from environment",
+			env:      map[string]string{"PULUMI_CONFIG_PASSPHRASE_FILE": "/tmp/pass"},
+			expected: map[string]string{"PULUMI_CONFIG_PASSPHRASE_FILE": "/tmp/pass"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PULUMI_CONFIG_PASSPHRASE", "")
+			os.Unsetenv("PULUMI_CONFIG_PASSPHRASE")
+			t.Setenv("PULUMI_CONFIG_PASSPHRASE_FILE", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got := passphraseEnvVars(tt.passphrase)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithDefaults_Passphrase(t *testing.T) {
+	repo := history.NewGitClone(auto.GitRepo{URL: "https://github.com/acme/website.git", Branch: "main"})
+	operator := &DefaultStackOperator{Project: "website", Repo: repo}
+	opts := withDefaults(RollbackOptions{Operator: operator, Passphrase: "secret"})
+
+	d, ok := opts.Operator.(*DefaultStackOperator)
+	if !ok {
+		t.Fatalf("Expected a *DefaultStackOperator, got %T", opts.Operator)
+	}
+	if d.Passphrase != "secret" {
+		t.Errorf("Expected passphrase secret, got %q", d.Passphrase)
+	}
+	if d.Project != "website" {
+		t.Errorf("Expected project website, got %q", d.Project)
+	}
+	if d.Repo != repo {
+		t.Errorf("Expected the repository to be kept, got %+v", d.Repo)
+	}
+	if operator.Passphrase != "" {
+		t.Errorf("Expected the caller's operator to be unchanged, got passphrase %q", operator.Passphrase)
+	}
+
+	opts = withDefaults(RollbackOptions{Operator: &DefaultStackOperator{Passphrase: "own"}, Passphrase: "secret"})
+	if got := opts.Operator.(*DefaultStackOperator).Passphrase; got != "own" {
+		t.Errorf("Expected the operator's own passphrase to be kept, got %q", got)
+	}
+}
+
+func TestWorkspaceEnvVars(t *testing.T) {
+	t.Setenv("PULUMI_CONFIG_PASSPHRASE", "ambient")
+	t.Setenv("PULUMI_CONFIG_PASSPHRASE_FILE", "")
+
+	tests := []struct {
+		name       string
+		passphrase string
+		env        map[string]string
+		expected   map[string]string
+	}{
+		{
+			name:     "ambient passphrase",
+			expected: map[string]string{"PULUMI_CONFIG_PASSPHRASE": "ambient"},
+		},
+		{
+			name:     "env added to the ambient passphrase",
+			env:      map[string]string{"PULUMI_BACKEND_URL": "s3://state"},
+			expected: map[string]string{"PULUMI_BACKEND_URL": "s3://state", "PULUMI_CONFIG_PASSPHRASE": "ambient"},
+		},
+		{
+			name:     "env passphrase file replaces the ambient passphrase",
+			env:      map[string]string{"PULUMI_CONFIG_PASSPHRASE_FILE": "/tmp/pass"},
+			expected: map[string]string{"PULUMI_CONFIG_PASSPHRASE_FILE": "/tmp/pass"},
+		},
+		{
+			name:       "explicit passphrase wins over env",
+			passphrase: "explicit",
+			env:        map[string]string{"PULUMI_CONFIG_PASSPHRASE": "env"},
+			expected:   map[string]string{"PULUMI_CONFIG_PASSPHRASE": "explicit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := workspaceEnvVars(tt.passphrase, tt.env)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithDefaults_Workspace(t *testing.T) {
+	own := auto.PulumiHome("/own")
+	operator := &DefaultStackOperator{
+		EnvVars:          map[string]string{"PULUMI_BACKEND_URL": "s3://own"},
+		WorkspaceOptions: []auto.LocalWorkspaceOption{own},
+	}
+	opts := withDefaults(RollbackOptions{
+		Operator:         operator,
+		EnvVars:          map[string]string{"PULUMI_BACKEND_URL": "s3://opts", "AWS_PROFILE": "prod"},
+		WorkspaceOptions: []auto.LocalWorkspaceOption{auto.SecretsProvider("awskms://key")},
+	})
+
+	d := opts.Operator.(*DefaultStackOperator)
+	expected := map[string]string{"PULUMI_BACKEND_URL": "s3://own", "AWS_PROFILE": "prod"}
+	if !reflect.DeepEqual(d.EnvVars, expected) {
+		t.Errorf("Expected env %v, got %v", expected, d.EnvVars)
+	}
+	if len(d.WorkspaceOptions) != 2 {
+		t.Errorf("Expected 2 workspace options, got %d", len(d.WorkspaceOptions))
+	}
+	if len(operator.EnvVars) != 1 || len(operator.WorkspaceOptions) != 1 {
+		t.Errorf("Expected the caller's operator to be unchanged, got %+v", operator)
+	}
+
+	provider, ok := opts.CheckpointProvider.(*DefaultCheckpointProvider)
+	if !ok || provider.EnvVars["AWS_PROFILE"] != "prod" {
+		t.Errorf("Expected the checkpoint provider to get the env, got %+v", opts.CheckpointProvider)
+	}
+
+	if opts := withDefaults(RollbackOptions{Operator: operator}); opts.Operator != operator {
+		t.Error("Expected the operator to be used as-is without workspace settings")
+	}
+}
+
+// recordingLogger records log messages by level
+type recordingLogger struct {
+	messages map[string][]string
+}
+
+func (l *recordingLogger) record(level, format string, args ...interface{}) {
+	if l.messages == nil {
+		l.messages = make(map[string][]string)
+	}
+	l.messages[level] = append(l.messages[level], fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.record("debug", format, args...)
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.record("info", format, args...)
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.record("warn", format, args...)
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.record("error", format, args...)
+}
+
+func TestWithDefaults_Logger(t *testing.T) {
+	tests := []struct {
+		name     string
+		verbose  bool
+		expected logging.Level
+	}{
+		{name: "default", verbose: false, expected: logging.LevelInfo},
+		{name: "verbose", verbose: true, expected: logging.LevelDebug},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := withDefaults(RollbackOptions{Output: &bytes.Buffer{}, Verbose: tt.verbose})
+			logger, ok := opts.Logger.(*logging.WriterLogger)
+			if !ok {
+				t.Fatalf("Expected a *logging.WriterLogger, got %T", opts.Logger)
+			}
+			if logger.Level() != tt.expected {
+				t.Errorf("Expected level %s, got %s", tt.expected, logger.Level())
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_CustomLogger(t *testing.T) {
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return &MockRollbackStack{}, nil
+		},
+	}
+
+	var output bytes.Buffer
+	logger := &recordingLogger{}
+	_, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:          "test",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		Logger:             logger,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected nothing written to Output with a custom logger, got %q", output.String())
+	}
+	if len(logger.messages["info"]) == 0 {
+		t.Error("Expected the rollback phases to be logged at info level")
+	}
+}
+
+func TestExecuteRollback_Direction(t *testing.T) {
+	tests := []struct {
+		name      string
+		latest    auto.UpdateSummary
+		target    int
+		direction history.Direction
+		message   string
+	}{
+		{name: "backward", latest: auto.UpdateSummary{Version: 5}, target: 3, direction: history.DirectionBackward, message: "Successfully rolled back to version 3"},
+		{name: "forward after a rollback", latest: auto.UpdateSummary{Version: 5, Message: history.RollbackMessage(history.Provenance{SourceVersion: 1})}, target: 3, direction: history.DirectionForward, message: "Successfully rolled forward to version 3"},
+		{name: "re-apply", latest: auto.UpdateSummary{Version: 5}, target: 5, direction: history.DirectionReapply, message: "Successfully re-applied version 5"},
+		{name: "re-apply past a refresh", latest: auto.UpdateSummary{Version: 5, Kind: "refresh"}, target: 3, direction: history.DirectionReapply, message: "Successfully re-applied version 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStack := &MockRollbackStack{
+				HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+					return []auto.UpdateSummary{tt.latest, {Version: 3}, {Version: 1}}, nil
+				},
+			}
+			opts := RollbackOptions{
+				CheckpointProvider: exportCheckpoints,
+				StackName:          "test",
+				TargetVersion:      tt.target,
+				Output:             &bytes.Buffer{},
+				Operator: &MockStackOperator{
+					SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+						return mockStack, nil
+					},
+				},
+			}
+
+			result, err := ExecuteRollback(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.Direction != tt.direction {
+				t.Errorf("Expected direction %s, got %s", tt.direction, result.Direction)
+			}
+			if result.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, result.Message)
+			}
+
+			preview, err := PreviewRollback(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if preview.Direction != tt.direction {
+				t.Errorf("Expected preview direction %s, got %s", tt.direction, preview.Direction)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_RecordsProvenance(t *testing.T) {
+	var tags map[string]string
+	mockStack := &MockRollbackStack{
+		HistoryFunc: func(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+			return []auto.UpdateSummary{{Version: 2}, {Version: 1}}, nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			return auto.UpResult{Summary: auto.UpdateSummary{Version: 3}}, nil
+		},
+		SetTagsFunc: func(ctx context.Context, t map[string]string) error {
+			tags = t
+			return nil
+		},
+	}
+	logPath := filepath.Join(t.TempDir(), history.ProvenanceLogName)
+	opts := RollbackOptions{
+		StackName:          "prod",
+		TargetVersion:      1,
+		Operator:           &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) { return mockStack, nil }},
+		CheckpointProvider: exportCheckpoints,
+		Output:             &bytes.Buffer{},
+		ToolVersion:        "1.2.0",
+		Initiator:          "Jane Doe",
+		ProvenanceLog:      logPath,
+	}
+
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := history.Provenance{SourceVersion: 1, ToolVersion: "1.2.0", Operator: "Jane Doe"}
+	if !reflect.DeepEqual(tags, expected.Tags(3)) {
+		t.Errorf("Expected tags %v, got %v", expected.Tags(3), tags)
+	}
+	log, err := history.LoadProvenanceLog(logPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p, ok := log.Lookup("prod", 3); !ok || *p != expected {
+		t.Errorf("Expected %+v recorded for version 3, got %+v", expected, p)
+	}
+
+	// Failing to tag the stack does not fail the rollback
+	mockStack.SetTagsFunc = func(ctx context.Context, t map[string]string) error {
+		return errors.New("tags not supported")
+	}
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Errorf("Expected a tagging failure to be only a warning, got %v", err)
 	}
 }
@@ -0,0 +1,115 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// scopeCheckpoint returns the state imported by a rollback limited to
+// targets: the current state, with the targeted resources and everything
+// they depend on in the target checkpoint taken from the target instead.
+// Resources outside that closure keep their current state, and orphans are
+// dropped as a full import would drop them. It also returns the targets
+// extended with the dependencies, so up applies every resource whose state
+// was replaced.
+func scopeCheckpoint(target apitype.UntypedDeployment, current *apitype.DeploymentV3, targets []string, orphans []OrphanedResource) (apitype.UntypedDeployment, []string, error) {
+	targetState, err := parseDeployment(target)
+	if err != nil {
+		return apitype.UntypedDeployment{}, nil, err
+	}
+
+	closure := dependencyClosure(targetState, targets)
+	scoped := *current
+	scoped.Resources = nil
+	for _, r := range targetState.Resources {
+		if closure[string(r.URN)] {
+			scoped.Resources = append(scoped.Resources, r)
+		}
+	}
+
+	// Targeted resources absent from the target checkpoint are dropped, as a
+	// full import would drop them
+	dropped := make(map[string]bool, len(targets)+len(orphans))
+	for _, urn := range targets {
+		dropped[urn] = true
+	}
+	for _, o := range orphans {
+		dropped[o.URN] = true
+	}
+	for _, r := range current.Resources {
+		if !closure[string(r.URN)] && !dropped[string(r.URN)] {
+			scoped.Resources = append(scoped.Resources, r)
+		}
+	}
+
+	data, err := json.Marshal(&scoped)
+	if err != nil {
+		return apitype.UntypedDeployment{}, nil, fmt.Errorf("failed to encode scoped checkpoint: %w", err)
+	}
+
+	extended := append([]string(nil), targets...)
+	for _, r := range targetState.Resources {
+		urn := string(r.URN)
+		if closure[urn] && !slices.Contains(extended, urn) {
+			extended = append(extended, urn)
+		}
+	}
+	return apitype.UntypedDeployment{Version: target.Version, Deployment: data}, extended, nil
+}
+
+// dependencyClosure returns the URNs of the targets found in state and of
+// every resource they depend on through dependencies, parents, providers and
+// deleted-with links
+func dependencyClosure(state *apitype.DeploymentV3, targets []string) map[string]bool {
+	byURN := make(map[string][]apitype.ResourceV3, len(state.Resources))
+	for _, r := range state.Resources {
+		byURN[string(r.URN)] = append(byURN[string(r.URN)], r)
+	}
+
+	closure := make(map[string]bool)
+	pending := append([]string(nil), targets...)
+	for len(pending) > 0 {
+		urn := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if closure[urn] || len(byURN[urn]) == 0 {
+			continue
+		}
+		closure[urn] = true
+		for _, r := range byURN[urn] {
+			for _, dep := range r.Dependencies {
+				pending = append(pending, string(dep))
+			}
+			for _, deps := range r.PropertyDependencies {
+				for _, dep := range deps {
+					pending = append(pending, string(dep))
+				}
+			}
+			if r.Parent != "" {
+				pending = append(pending, string(r.Parent))
+			}
+			if r.DeletedWith != "" {
+				pending = append(pending, string(r.DeletedWith))
+			}
+			if r.Provider != "" {
+				pending = append(pending, providerURN(r.Provider))
+			}
+		}
+	}
+	return closure
+}
+
+// providerURN returns the URN of a provider reference, which has the form
+// <urn>::<id>
+func providerURN(ref string) string {
+	if i := strings.LastIndex(ref, "::"); i >= 0 {
+		return ref[:i]
+	}
+	return ref
+}
@@ -0,0 +1,199 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+const (
+	scopeStack    = "urn:pulumi:dev::proj::pulumi:pulumi:Stack::proj-dev"
+	scopeProvider = "urn:pulumi:dev::proj::pulumi:providers:aws::default"
+	scopeRole     = "urn:pulumi:dev::proj::aws:iam/role:Role::role"
+	scopeFunction = "urn:pulumi:dev::proj::aws:lambda/function:Function::fn"
+	scopeBucket   = "urn:pulumi:dev::proj::aws:s3/bucket:Bucket::bucket"
+	scopeQueue    = "urn:pulumi:dev::proj::aws:sqs/queue:Queue::queue"
+)
+
+// scopeTarget is version 1: a function that depends on a role
+var scopeTarget = deployment(`{"resources": [
+	{"urn": "` + scopeStack + `", "type": "pulumi:pulumi:Stack", "id": "old"},
+	{"urn": "` + scopeProvider + `", "type": "pulumi:providers:aws", "id": "p1", "parent": "` + scopeStack + `"},
+	{"urn": "` + scopeRole + `", "type": "aws:iam/role:Role", "id": "old", "parent": "` + scopeStack + `", "provider": "` + scopeProvider + `::p1"},
+	{"urn": "` + scopeFunction + `", "type": "aws:lambda/function:Function", "id": "old", "parent": "` + scopeStack + `", "provider": "` + scopeProvider + `::p1", "dependencies": ["` + scopeRole + `"]},
+	{"urn": "` + scopeBucket + `", "type": "aws:s3/bucket:Bucket", "id": "old", "parent": "` + scopeStack + `", "provider": "` + scopeProvider + `::p1"}
+]}`)
+
+// scopeCurrent is the latest version, which changed every resource and
+// added a queue
+var scopeCurrent = deployment(`{"resources": [
+	{"urn": "` + scopeStack + `", "type": "pulumi:pulumi:Stack", "id": "new"},
+	{"urn": "` + scopeProvider + `", "type": "pulumi:providers:aws", "id": "p1", "parent": "` + scopeStack + `"},
+	{"urn": "` + scopeRole + `", "type": "aws:iam/role:Role", "id": "new", "parent": "` + scopeStack + `", "provider": "` + scopeProvider + `::p1"},
+	{"urn": "` + scopeFunction + `", "type": "aws:lambda/function:Function", "id": "new", "parent": "` + scopeStack + `", "provider": "` + scopeProvider + `::p1", "dependencies": ["` + scopeRole + `"]},
+	{"urn": "` + scopeBucket + `", "type": "aws:s3/bucket:Bucket", "id": "new", "parent": "` + scopeStack + `", "provider": "` + scopeProvider + `::p1"},
+	{"urn": "` + scopeQueue + `", "type": "aws:sqs/queue:Queue", "id": "new", "parent": "` + scopeStack + `", "provider": "` + scopeProvider + `::p1"}
+]}`)
+
+// resourceIDs maps the URNs of a deployment's resources to their IDs
+func resourceIDs(t *testing.T, d apitype.UntypedDeployment) map[string]string {
+	t.Helper()
+	state, err := parseDeployment(d)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ids := make(map[string]string, len(state.Resources))
+	for _, r := range state.Resources {
+		ids[string(r.URN)] = string(r.ID)
+	}
+	return ids
+}
+
+func TestScopeCheckpoint(t *testing.T) {
+	current, err := parseDeployment(scopeCurrent)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		targets         []string
+		orphans         []OrphanedResource
+		expectedTargets []string
+		expectedIDs     map[string]string
+	}{
+		{
+			name:            "dependencies are taken from the target",
+			targets:         []string{scopeFunction},
+			expectedTargets: []string{scopeFunction, scopeStack, scopeProvider, scopeRole},
+			expectedIDs: map[string]string{
+				scopeStack: "old", scopeProvider: "p1", scopeRole: "old", scopeFunction: "old",
+				scopeBucket: "new", scopeQueue: "new",
+			},
+		},
+		{
+			name:            "resources without dependencies",
+			targets:         []string{scopeBucket},
+			expectedTargets: []string{scopeBucket, scopeStack, scopeProvider},
+			expectedIDs: map[string]string{
+				scopeStack: "old", scopeProvider: "p1", scopeRole: "new", scopeFunction: "new",
+				scopeBucket: "old", scopeQueue: "new",
+			},
+		},
+		{
+			name:            "targets missing from the target are dropped",
+			targets:         []string{scopeQueue},
+			expectedTargets: []string{scopeQueue},
+			expectedIDs: map[string]string{
+				scopeStack: "new", scopeProvider: "p1", scopeRole: "new", scopeFunction: "new",
+				scopeBucket: "new",
+			},
+		},
+		{
+			name:            "orphans are dropped",
+			targets:         []string{scopeBucket},
+			orphans:         []OrphanedResource{{URN: scopeQueue}},
+			expectedTargets: []string{scopeBucket, scopeStack, scopeProvider},
+			expectedIDs: map[string]string{
+				scopeStack: "old", scopeProvider: "p1", scopeRole: "new", scopeFunction: "new",
+				scopeBucket: "old",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scoped, targets, err := scopeCheckpoint(scopeTarget, current, tt.targets, tt.orphans)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !slices.Equal(targets, tt.expectedTargets) {
+				t.Errorf("Expected targets %v, got %v", tt.expectedTargets, targets)
+			}
+
+			ids := resourceIDs(t, scoped)
+			if len(ids) != len(tt.expectedIDs) {
+				t.Errorf("Expected %d resources, got %d: %v", len(tt.expectedIDs), len(ids), ids)
+			}
+			for urn, id := range tt.expectedIDs {
+				if ids[urn] != id {
+					t.Errorf("Expected %s to have id %q, got %q", urn, id, ids[urn])
+				}
+			}
+
+			// Parents must precede their children
+			state, err := parseDeployment(scoped)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			seen := map[string]bool{}
+			for _, r := range state.Resources {
+				if r.Parent != "" && !seen[string(r.Parent)] {
+					t.Errorf("Expected %s to follow its parent", r.URN)
+				}
+				seen[string(r.URN)] = true
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_TypesImportOnlyScope(t *testing.T) {
+	state := scopeCurrent
+	var imported []apitype.UntypedDeployment
+	var upTargets []string
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			return state, nil
+		},
+		ImportFunc: func(ctx context.Context, d apitype.UntypedDeployment) error {
+			state = d
+			imported = append(imported, d)
+			return nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			upOpts := &optup.Options{}
+			for _, o := range opts {
+				o.ApplyOption(upOpts)
+			}
+			upTargets = upOpts.Target
+			return auto.UpResult{}, nil
+		},
+	}
+
+	opts := RollbackOptions{
+		StackName:     "dev",
+		TargetVersion: 1,
+		Operator: &MockStackOperator{SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		}},
+		CheckpointProvider: &MockCheckpointProvider{
+			CheckpointAtFunc: func(ctx context.Context, stack RollbackStack, version int) (apitype.UntypedDeployment, error) {
+				return scopeTarget, nil
+			},
+		},
+		Output: &bytes.Buffer{},
+		Types:  []string{"aws:s3/bucket:Bucket"},
+	}
+
+	if _, err := ExecuteRollback(context.Background(), opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(imported) != 1 {
+		t.Fatalf("Expected one import, got %d", len(imported))
+	}
+	ids := resourceIDs(t, imported[0])
+	if ids[scopeBucket] != "old" || ids[scopeFunction] != "new" || ids[scopeQueue] != "new" {
+		t.Errorf("Expected only the bucket and its dependencies to be rolled back, got %v", ids)
+	}
+	if !slices.Contains(upTargets, scopeBucket) || slices.Contains(upTargets, scopeFunction) {
+		t.Errorf("Expected up to target the bucket but not the function, got %v", upTargets)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/sig"
+)
+
+// ErrSecretsProviderMismatch is returned when the target checkpoint's
+// secrets are encrypted by a different secrets provider than the stack's
+var ErrSecretsProviderMismatch = errors.New("secrets provider differs from the current stack")
+
+// passphraseProvider is the secrets provider type of passphrase-based stacks
+const passphraseProvider = "passphrase"
+
+// SecretsProvider returns the secrets provider recorded in a deployment, or
+// nil if the deployment has none
+func SecretsProvider(deployment apitype.UntypedDeployment) (*apitype.SecretsProvidersV1, error) {
+	var d struct {
+		SecretsProviders *apitype.SecretsProvidersV1 `json:"secrets_providers"`
+	}
+	if err := json.Unmarshal(deployment.Deployment, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment: %w", err)
+	}
+	return d.SecretsProviders, nil
+}
+
+// sameSecretsProvider reports whether two providers encrypt identically
+func sameSecretsProvider(a, b *apitype.SecretsProvidersV1) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Type != b.Type {
+		return false
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a.State) != nil || json.Compact(&cb, b.State) != nil {
+		return bytes.Equal(a.State, b.State)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// NewPassphraseDecrypter returns a decrypter for secrets written by the
+// passphrase secrets provider with the given provider state
+func NewPassphraseDecrypter(state json.RawMessage, passphrase string) (config.Decrypter, error) {
+	var s struct {
+		Salt string `json:"salt"`
+	}
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse passphrase provider state: %w", err)
+	}
+
+	// The salt state is "v1:<base64 salt>:<encrypted check value>"
+	parts := strings.SplitN(s.Salt, ":", 3)
+	if len(parts) != 3 || parts[0] != "v1" {
+		return nil, fmt.Errorf("unrecognized passphrase provider salt")
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase provider salt: %w", err)
+	}
+
+	crypter := config.NewSymmetricCrypterFromPassphrase(passphrase, salt)
+	if _, err := crypter.DecryptValue(context.Background(), parts[2]); err != nil {
+		return nil, fmt.Errorf("incorrect passphrase for the target checkpoint's secrets")
+	}
+	return crypter, nil
+}
+
+// ReencryptSecrets decrypts every secret in a deployment and stores it as
+// plaintext without a secrets provider. Importing the result encrypts the
+// secrets with the stack's current secrets provider. It returns the number
+// of secrets converted.
+func ReencryptSecrets(ctx context.Context, deployment apitype.UntypedDeployment, decrypter config.Decrypter) (apitype.UntypedDeployment, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(deployment.Deployment))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return apitype.UntypedDeployment{}, 0, fmt.Errorf("failed to parse deployment: %w", err)
+	}
+
+	count := 0
+	var walk func(v interface{}) error
+	walk = func(v interface{}) error {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if v[sig.Key] == sig.Secret {
+				if ciphertext, ok := v["ciphertext"].(string); ok {
+					plaintext, err := decrypter.DecryptValue(ctx, ciphertext)
+					if err != nil {
+						return fmt.Errorf("failed to decrypt secret: %w", err)
+					}
+					delete(v, "ciphertext")
+					v["plaintext"] = plaintext
+					count++
+				}
+				return nil
+			}
+			for _, child := range v {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for _, child := range v {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(doc); err != nil {
+		return apitype.UntypedDeployment{}, 0, err
+	}
+	delete(doc, "secrets_providers")
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return apitype.UntypedDeployment{}, 0, err
+	}
+	return apitype.UntypedDeployment{Version: deployment.Version, Deployment: data}, count, nil
+}
+
+// prepareSecrets checks that the target checkpoint's secrets can be read by
+// the stack's current secrets provider and, when opts.ReencryptSecrets is
+// set, re-encrypts them if they cannot
+func prepareSecrets(ctx context.Context, target, current apitype.UntypedDeployment, opts RollbackOptions) (apitype.UntypedDeployment, error) {
+	targetProvider, err := SecretsProvider(target)
+	if err != nil {
+		return target, err
+	}
+	currentProvider, err := SecretsProvider(current)
+	if err != nil {
+		return target, err
+	}
+	if targetProvider == nil || sameSecretsProvider(targetProvider, currentProvider) {
+		return target, nil
+	}
+
+	currentType := "none"
+	if currentProvider != nil {
+		currentType = currentProvider.Type
+	}
+	if !opts.ReencryptSecrets && opts.Force {
+		opts.Logger.Warnf("target version uses the %s secrets provider, stack uses %s; importing may fail", targetProvider.Type, currentType)
+		return target, nil
+	}
+	if !opts.ReencryptSecrets {
+		return target, fmt.Errorf("%w: target version uses %s, stack uses %s (use --reencrypt-secrets)",
+			ErrSecretsProviderMismatch, targetProvider.Type, currentType)
+	}
+
+	decrypter := opts.SecretsDecrypter
+	if decrypter == nil {
+		if targetProvider.Type != passphraseProvider {
+			return target, fmt.Errorf("%w: cannot decrypt secrets from the %s provider; only %s is supported",
+				ErrSecretsProviderMismatch, targetProvider.Type, passphraseProvider)
+		}
+		passphrase := opts.SourcePassphrase
+		if passphrase == "" {
+			passphrase = opts.Passphrase
+		}
+		if passphrase == "" {
+			passphrase = opts.EnvVars["PULUMI_CONFIG_PASSPHRASE"]
+		}
+		if passphrase == "" {
+			passphrase = os.Getenv("PULUMI_CONFIG_PASSPHRASE")
+		}
+		decrypter, err = NewPassphraseDecrypter(targetProvider.State, passphrase)
+		if err != nil {
+			return target, err
+		}
+	}
+
+	opts.Logger.Infof("Re-encrypting secrets from the %s provider to the stack's %s provider...", targetProvider.Type, currentType)
+	reencrypted, count, err := ReencryptSecrets(ctx, target, decrypter)
+	if err != nil {
+		return target, err
+	}
+	opts.Logger.Debugf("re-encrypted %d secret(s)", count)
+	return reencrypted, nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/config"
+)
+
+// prefixDecrypter "decrypts" ciphertexts of the form enc(<plaintext>)
+type prefixDecrypter struct{}
+
+func (prefixDecrypter) DecryptValue(ctx context.Context, ciphertext string) (string, error) {
+	if !strings.HasPrefix(ciphertext, "enc(") || !strings.HasSuffix(ciphertext, ")") {
+		return "", errors.New("bad ciphertext")
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(ciphertext, "enc("), ")"), nil
+}
+
+func (d prefixDecrypter) BatchDecrypt(ctx context.Context, ciphertexts []string) ([]string, error) {
+	return config.DefaultBatchDecrypt(ctx, d, ciphertexts)
+}
+
+const encryptedDeployment = `{
+	"secrets_providers": {"type": "passphrase", "state": {"salt": "old"}},
+	"resources": [{
+		"urn": "urn:pulumi:dev::proj::aws:rds/instance:Instance::db",
+		"inputs": {
+			"password": {"4dabf18193072939515e22adb298388d": "1b47061264138c4ac30d75fd1eb44270", "ciphertext": "enc(\"hunter2\")"},
+			"port": 5432
+		},
+		"outputs": {
+			"tags": [{"4dabf18193072939515e22adb298388d": "1b47061264138c4ac30d75fd1eb44270", "ciphertext": "enc(\"token\")"}]
+		}
+	}]
+}`
+
+func TestReencryptSecrets(t *testing.T) {
+	result, count, err := ReencryptSecrets(context.Background(), deployment(encryptedDeployment), prefixDecrypter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 secrets, got %d", count)
+	}
+
+	out := string(result.Deployment)
+	if strings.Contains(out, "ciphertext") || strings.Contains(out, "secrets_providers") {
+		t.Errorf("Expected no ciphertext or secrets provider, got %s", out)
+	}
+	if !strings.Contains(out, `"plaintext":"\"hunter2\""`) {
+		t.Errorf("Expected plaintext password, got %s", out)
+	}
+	if !strings.Contains(out, `"port":5432`) {
+		t.Errorf("Expected other values to be preserved, got %s", out)
+	}
+}
+
+func TestReencryptSecrets_DecryptError(t *testing.T) {
+	d := deployment(`{"resources":[{"inputs":{"p":{"4dabf18193072939515e22adb298388d":"1b47061264138c4ac30d75fd1eb44270","ciphertext":"garbage"}}}]}`)
+	if _, _, err := ReencryptSecrets(context.Background(), d, prefixDecrypter{}); err == nil {
+		t.Error("Expected error for undecryptable secret")
+	}
+}
+
+func TestPrepareSecrets(t *testing.T) {
+	passphraseA := `{"secrets_providers":{"type":"passphrase","state":{"salt":"a"}}}`
+	passphraseB := `{"secrets_providers":{"type":"passphrase","state":{"salt":"b"}}}`
+	kms := `{"secrets_providers":{"type":"awskms","state":{"key":"k"}}}`
+
+	tests := []struct {
+		name        string
+		target      string
+		current     string
+		opts        RollbackOptions
+		expectedErr error
+	}{
+		{name: "same provider", target: passphraseA, current: `{"secrets_providers":{"type":"passphrase","state":{ "salt" : "a" }}}`},
+		{name: "no provider in target", target: `{}`, current: kms},
+		{name: "mismatch without opt-in", target: passphraseA, current: kms, expectedErr: ErrSecretsProviderMismatch},
+		{name: "different passphrase salt", target: passphraseA, current: passphraseB, expectedErr: ErrSecretsProviderMismatch},
+		{
+			name:        "unsupported source provider",
+			target:      kms,
+			current:     passphraseA,
+			opts:        RollbackOptions{ReencryptSecrets: true},
+			expectedErr: ErrSecretsProviderMismatch,
+		},
+		{
+			name:    "custom decrypter",
+			target:  encryptedDeployment,
+			current: kms,
+			opts:    RollbackOptions{ReencryptSecrets: true, SecretsDecrypter: prefixDecrypter{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := prepareSecrets(context.Background(), deployment(tt.target), deployment(tt.current), withDefaults(tt.opts))
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Expected %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestExecuteRollback_RedactsReencryptedSecrets(t *testing.T) {
+	var imported *apitype.UntypedDeployment
+	mockStack := &MockRollbackStack{
+		ExportFunc: func(ctx context.Context) (apitype.UntypedDeployment, error) {
+			if imported != nil {
+				return *imported, nil
+			}
+			return deployment(`{"secrets_providers":{"type":"awskms","state":{"key":"k"}},"resources":[{"urn":"urn:pulumi:dev::proj::aws:rds/instance:Instance::db"}]}`), nil
+		},
+		ImportFunc: func(ctx context.Context, state apitype.UntypedDeployment) error {
+			imported = &state
+			return nil
+		},
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			return auto.UpResult{}, errors.New(`invalid password "hunter2" for db`)
+		},
+	}
+
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+
+	var output bytes.Buffer
+	_, err := ExecuteRollback(context.Background(), RollbackOptions{
+		StackName:          "dev",
+		TargetVersion:      1,
+		Operator:           mockOperator,
+		CheckpointProvider: exportCheckpoints,
+		Output:             &output,
+		Verbose:            true,
+		ReencryptSecrets:   true,
+		SecretsDecrypter:   prefixDecrypter{},
+		TransformCheckpoint: func(d apitype.UntypedDeployment) (apitype.UntypedDeployment, error) {
+			return deployment(encryptedDeployment), nil
+		},
+	})
+	if err == nil {
+		t.Fatal("Expected the failing up to fail the rollback")
+	}
+	if strings.Contains(err.Error(), "hunter2") || !strings.Contains(err.Error(), format.Mask) {
+		t.Errorf("Expected the secret to be masked in the error, got %q", err)
+	}
+	var rbErr *RollbackError
+	if !errors.As(err, &rbErr) || rbErr.Phase != PhaseUp {
+		t.Errorf("Expected a RollbackError in phase %s, got %v", PhaseUp, err)
+	}
+}
+
+func TestNewPassphraseDecrypter(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	crypter := config.NewSymmetricCrypterFromPassphrase("correct horse", salt)
+	check, err := crypter.EncryptValue(context.Background(), "pulumi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := crypter.EncryptValue(context.Background(), `"s3cret"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := json.RawMessage(fmt.Sprintf(`{"salt":"v1:%s:%s"}`, base64.StdEncoding.EncodeToString(salt), check))
+
+	if _, err := NewPassphraseDecrypter(state, "wrong"); err == nil {
+		t.Error("Expected error for wrong passphrase")
+	}
+
+	decrypter, err := NewPassphraseDecrypter(state, "correct horse")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	plaintext, err := decrypter.DecryptValue(context.Background(), secret)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if plaintext != `"s3cret"` {
+		t.Errorf("Expected %q, got %q", `"s3cret"`, plaintext)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/format"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// ResourceStep is a change an operation makes to a single resource
+type ResourceStep struct {
+	URN  string `json:"urn"`
+	Type string `json:"type"`
+	Op   string `json:"op"`
+	// Diffs lists the properties the step changes. It is only recorded
+	// when RollbackOptions.Diff is set.
+	Diffs []PropertyChange `json:"diffs,omitempty"`
+}
+
+// PropertyChange is a change to a single property of a resource
+type PropertyChange struct {
+	// Path is the property path, e.g. tags.env or ingress[0].fromPort
+	Path string `json:"path"`
+	// Kind is add, update or delete, suffixed with -replace when the
+	// change forces the resource to be replaced
+	Kind string `json:"kind"`
+	// Old and New are the values before and after the change, when the
+	// engine reported them. Secrets are masked.
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// stepCollector records the resource steps reported by an operation's
+// engine events. Steps that leave a resource unchanged are dropped.
+type stepCollector struct {
+	events chan events.EngineEvent
+	done   chan struct{}
+	steps  []ResourceStep
+}
+
+// newStepCollector starts collecting steps from the collector's events
+// channel. With diffs it also records the property changes of each step.
+func newStepCollector(diffs bool) *stepCollector {
+	c := &stepCollector{events: make(chan events.EngineEvent), done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		for e := range c.events {
+			if e.ResourcePreEvent == nil {
+				continue
+			}
+			m := e.ResourcePreEvent.Metadata
+			if m.Op == apitype.OpSame {
+				continue
+			}
+			step := ResourceStep{URN: m.URN, Type: m.Type, Op: string(m.Op)}
+			if diffs {
+				step.Diffs = propertyChanges(m)
+			}
+			c.steps = append(c.steps, step)
+		}
+	}()
+	return c
+}
+
+// Steps waits for the events channel to close and returns the steps. The
+// Automation API closes event streams when an operation returns.
+func (c *stepCollector) Steps() []ResourceStep {
+	<-c.done
+	return c.steps
+}
+
+// propertyChanges returns the property changes of a step, sorted by path.
+// The detailed diff is used when the provider reported one; otherwise
+// every changed top-level key is an update.
+func propertyChanges(m apitype.StepEventMetadata) []PropertyChange {
+	var oldInputs, oldOutputs, newInputs map[string]interface{}
+	if m.Old != nil {
+		oldInputs, oldOutputs = m.Old.Inputs, m.Old.Outputs
+	}
+	if m.New != nil {
+		newInputs = m.New.Inputs
+	}
+
+	var changes []PropertyChange
+	add := func(path string, kind apitype.DiffKind, inputDiff bool) {
+		old := oldOutputs
+		if inputDiff {
+			old = oldInputs
+		}
+		change := PropertyChange{Path: path, Kind: string(kind)}
+		change.Old, _ = propertyValue(old, path)
+		change.New, _ = propertyValue(newInputs, path)
+		changes = append(changes, change)
+	}
+	if m.DetailedDiff != nil {
+		for path, diff := range m.DetailedDiff {
+			add(path, diff.Kind, diff.InputDiff)
+		}
+	} else {
+		for _, key := range m.Diffs {
+			add(key, apitype.DiffUpdate, false)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// propertyValue returns the value at a property path of a resource's
+// properties
+func propertyValue(props map[string]interface{}, path string) (interface{}, bool) {
+	if props == nil {
+		return nil, false
+	}
+	parsed, err := resource.ParsePropertyPath(path)
+	if err != nil {
+		v, ok := props[path]
+		return v, ok
+	}
+	v, ok := parsed.Get(resource.NewObjectProperty(resource.NewPropertyMapFromMap(props)))
+	if !ok {
+		return nil, false
+	}
+	return v.Mappable(), true
+}
+
+// redactSteps masks known secrets in the property values of steps
+func redactSteps(steps []ResourceStep, redactor *format.Redactor) []ResourceStep {
+	for i := range steps {
+		for j := range steps[i].Diffs {
+			steps[i].Diffs[j].Old = redactor.Value(steps[i].Diffs[j].Old)
+			steps[i].Diffs[j].New = redactor.Value(steps[i].Diffs[j].New)
+		}
+	}
+	return steps
+}
+
+// ChangesByType counts steps per resource type and operation
+func ChangesByType(steps []ResourceStep) map[string]map[string]int {
+	byType := make(map[string]map[string]int)
+	for _, s := range steps {
+		if byType[s.Type] == nil {
+			byType[s.Type] = make(map[string]int)
+		}
+		byType[s.Type][s.Op]++
+	}
+	return byType
+}
+
+// StepsWithOp returns the steps performing op, sorted by URN
+func StepsWithOp(steps []ResourceStep, op string) []ResourceStep {
+	var matched []ResourceStep
+	for _, s := range steps {
+		if s.Op == op {
+			matched = append(matched, s)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].URN < matched[j].URN })
+	return matched
+}
+
+// WriteStepDiffs writes the property changes of steps, one resource at a
+// time, in the style of pulumi preview --diff. Steps without recorded
+// changes are listed by operation only.
+func WriteStepDiffs(w io.Writer, steps []ResourceStep) error {
+	for _, step := range steps {
+		if _, err := fmt.Fprintf(w, "%s %s (%s)\n", step.Op, step.URN, step.Type); err != nil {
+			return err
+		}
+		for _, change := range step.Diffs {
+			var line string
+			switch strings.TrimSuffix(change.Kind, "-replace") {
+			case string(apitype.DiffAdd):
+				line = fmt.Sprintf("    + %s: %s", change.Path, diffValue(change.New))
+			case string(apitype.DiffDelete):
+				line = fmt.Sprintf("    - %s: %s", change.Path, diffValue(change.Old))
+			default:
+				line = fmt.Sprintf("    ~ %s: %s => %s", change.Path, diffValue(change.Old), diffValue(change.New))
+			}
+			if strings.HasSuffix(change.Kind, "-replace") {
+				line += " (forces replacement)"
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffValue formats a property value for WriteStepDiffs
+func diffValue(v interface{}) string {
+	if v == nil {
+		return "<unknown>"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
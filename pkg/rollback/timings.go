@@ -0,0 +1,92 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PhaseStatus is the outcome of a phase
+type PhaseStatus string
+
+const (
+	PhaseDone    PhaseStatus = "done"
+	PhaseSkipped PhaseStatus = "skipped"
+	PhaseFailed  PhaseStatus = "failed"
+)
+
+// PhaseTiming records how a phase of a rollback went and how long it took
+type PhaseTiming struct {
+	Phase    Phase
+	Status   PhaseStatus
+	Duration time.Duration
+}
+
+// MarshalJSON encodes the duration in milliseconds
+func (t PhaseTiming) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Phase      Phase       `json:"phase"`
+		Status     PhaseStatus `json:"status"`
+		DurationMS int64       `json:"durationMs"`
+	}{t.Phase, t.Status, t.Duration.Milliseconds()})
+}
+
+// phaseTracker times the phases of a rollback as it moves through them
+type phaseTracker struct {
+	now     func() time.Time
+	current Phase
+	started time.Time
+	timings []PhaseTiming
+}
+
+func newPhaseTracker() *phaseTracker {
+	return &phaseTracker{now: time.Now}
+}
+
+// start ends the current phase as done and starts the next one
+func (t *phaseTracker) start(phase Phase) {
+	t.end(PhaseDone)
+	t.current = phase
+	t.started = t.now()
+}
+
+// skip ends the current phase as done and records phase as skipped
+func (t *phaseTracker) skip(phase Phase) {
+	t.end(PhaseDone)
+	t.timings = append(t.timings, PhaseTiming{Phase: phase, Status: PhaseSkipped})
+}
+
+// fail ends the rollback in the failing phase. Checks can fail a phase
+// that already ended, e.g. the fetched checkpoint is rejected after the
+// current state was exported, in which case the earlier record is marked.
+func (t *phaseTracker) fail(phase Phase) []PhaseTiming {
+	if t.current == phase {
+		t.end(PhaseFailed)
+		return t.timings
+	}
+	t.end(PhaseDone)
+	for i := range t.timings {
+		if t.timings[i].Phase == phase {
+			t.timings[i].Status = PhaseFailed
+			return t.timings
+		}
+	}
+	t.timings = append(t.timings, PhaseTiming{Phase: phase, Status: PhaseFailed})
+	return t.timings
+}
+
+// finish ends the current phase as done and returns every timing
+func (t *phaseTracker) finish() []PhaseTiming {
+	t.end(PhaseDone)
+	return t.timings
+}
+
+func (t *phaseTracker) end(status PhaseStatus) {
+	if t.current == 0 {
+		return
+	}
+	t.timings = append(t.timings, PhaseTiming{Phase: t.current, Status: status, Duration: t.now().Sub(t.started)})
+	t.current = 0
+}
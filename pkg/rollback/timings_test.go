@@ -0,0 +1,160 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+)
+
+// fakeClock advances by a second on every reading
+func fakeClock() func() time.Time {
+	t := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		t = t.Add(time.Second)
+		return t
+	}
+}
+
+func TestPhaseTracker(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(p *phaseTracker) []PhaseTiming
+		expected []PhaseTiming
+	}{
+		{
+			name: "success with a skipped phase",
+			run: func(p *phaseTracker) []PhaseTiming {
+				p.start(PhaseImport)
+				p.skip(PhaseRefresh)
+				p.start(PhaseUp)
+				return p.finish()
+			},
+			expected: []PhaseTiming{
+				{PhaseImport, PhaseDone, time.Second},
+				{PhaseRefresh, PhaseSkipped, 0},
+				{PhaseUp, PhaseDone, time.Second},
+			},
+		},
+		{
+			name: "failure in the current phase",
+			run: func(p *phaseTracker) []PhaseTiming {
+				p.start(PhaseImport)
+				p.start(PhaseRefresh)
+				return p.fail(PhaseRefresh)
+			},
+			expected: []PhaseTiming{
+				{PhaseImport, PhaseDone, time.Second},
+				{PhaseRefresh, PhaseFailed, time.Second},
+			},
+		},
+		{
+			name: "failure of an earlier phase",
+			run: func(p *phaseTracker) []PhaseTiming {
+				p.start(PhaseFetchCheckpoint)
+				p.start(PhaseExportCurrent)
+				return p.fail(PhaseFetchCheckpoint)
+			},
+			expected: []PhaseTiming{
+				{PhaseFetchCheckpoint, PhaseFailed, time.Second},
+				{PhaseExportCurrent, PhaseDone, time.Second},
+			},
+		},
+		{
+			name: "failure of a phase not started",
+			run: func(p *phaseTracker) []PhaseTiming {
+				p.start(PhaseImport)
+				return p.fail(PhaseVerify)
+			},
+			expected: []PhaseTiming{
+				{PhaseImport, PhaseDone, time.Second},
+				{PhaseVerify, PhaseFailed, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPhaseTracker()
+			p.now = fakeClock()
+			got := tt.run(p)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPhaseTiming_JSON(t *testing.T) {
+	data, err := json.Marshal(PhaseTiming{PhaseUp, PhaseDone, 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != `{"phase":"up","status":"done","durationMs":1500}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+}
+
+func TestExecuteRollback_Phases(t *testing.T) {
+	upErr := errors.New("up failed")
+	mockStack := &MockRollbackStack{
+		UpFunc: func(ctx context.Context, opts ...optup.Option) (auto.UpResult, error) {
+			return auto.UpResult{}, upErr
+		},
+	}
+	mockOperator := &MockStackOperator{
+		SelectStackFunc: func(ctx context.Context, stackName, projectPath string) (RollbackStack, error) {
+			return mockStack, nil
+		},
+	}
+	opts := RollbackOptions{CheckpointProvider: exportCheckpoints, TargetVersion: 1, Operator: mockOperator, Output: io.Discard, ForceImport: true, AllowEmpty: true}
+
+	_, err := ExecuteRollback(context.Background(), opts)
+	var rbErr *RollbackError
+	if !errors.As(err, &rbErr) {
+		t.Fatalf("Expected a RollbackError, got %v", err)
+	}
+	expected := []struct {
+		phase  Phase
+		status PhaseStatus
+	}{
+		{PhaseSelectStack, PhaseDone},
+		{PhaseFetchCheckpoint, PhaseDone},
+		{PhaseExportCurrent, PhaseDone},
+		{PhaseValidate, PhaseDone},
+		{PhaseImport, PhaseDone},
+		{PhaseRefresh, PhaseSkipped},
+		{PhaseUp, PhaseFailed},
+	}
+	if len(rbErr.Phases) != len(expected) {
+		t.Fatalf("Expected %d phases, got %v", len(expected), rbErr.Phases)
+	}
+	for i, e := range expected {
+		if rbErr.Phases[i].Phase != e.phase || rbErr.Phases[i].Status != e.status {
+			t.Errorf("Expected %s %s, got %v", e.phase, e.status, rbErr.Phases[i])
+		}
+	}
+
+	mockStack.UpFunc = nil
+	result, err := ExecuteRollback(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	last := result.Phases[len(result.Phases)-1]
+	if len(result.Phases) != len(expected) || last.Phase != PhaseUp || last.Status != PhaseDone {
+		t.Errorf("Expected every phase to be recorded, got %v", result.Phases)
+	}
+}
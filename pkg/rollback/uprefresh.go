@@ -0,0 +1,29 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"io"
+	"slices"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// noUpRefreshCommand runs pulumi up with --refresh=false, so a project
+// whose Pulumi.yaml sets options.refresh: always does not refresh during
+// up. The Automation API's optup.Refresh can only turn that refresh on.
+type noUpRefreshCommand struct {
+	auto.PulumiCommand
+}
+
+// Run adds --refresh=false to up and runs every other command unchanged
+func (c noUpRefreshCommand) Run(ctx context.Context, workdir string, stdin io.Reader,
+	additionalOutput []io.Writer, additionalErrorOutput []io.Writer, additionalEnv []string, args ...string,
+) (string, string, int, error) {
+	if len(args) > 0 && args[0] == "up" {
+		args = append(slices.Clip(args), "--refresh=false")
+	}
+	return c.PulumiCommand.Run(ctx, workdir, stdin, additionalOutput, additionalErrorOutput, additionalEnv, args...)
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package rollback
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+// recordingCommand is a Pulumi CLI that records the arguments it is run with
+type recordingCommand struct {
+	args [][]string
+}
+
+func (c *recordingCommand) Run(ctx context.Context, workdir string, stdin io.Reader,
+	additionalOutput []io.Writer, additionalErrorOutput []io.Writer, additionalEnv []string, args ...string,
+) (string, string, int, error) {
+	c.args = append(c.args, args)
+	return "", "", 0, nil
+}
+
+func (c *recordingCommand) Version() semver.Version {
+	return semver.MustParse("3.200.0")
+}
+
+func TestNoUpRefreshCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"up", []string{"up", "--yes", "--skip-preview"}, []string{"up", "--yes", "--skip-preview", "--refresh=false"}},
+		{"refresh", []string{"refresh", "--yes"}, []string{"refresh", "--yes"}},
+		{"preview", []string{"preview"}, []string{"preview"}},
+		{"no args", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &recordingCommand{}
+			command := noUpRefreshCommand{recorder}
+			if _, _, _, err := command.Run(context.Background(), "", nil, nil, nil, nil, tt.args...); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !slices.Equal(recorder.args[0], tt.expected) {
+				t.Errorf("Expected args %v, got %v", tt.expected, recorder.args[0])
+			}
+			if command.Version().String() != "3.200.0" {
+				t.Errorf("Expected the wrapped version, got %s", command.Version())
+			}
+		})
+	}
+}
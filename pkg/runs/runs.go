@@ -0,0 +1,97 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+// Package runs records the outcome of rollbacks by run id, so that a
+// retried invocation with the same run id can replay the earlier result
+// instead of rolling back again.
+package runs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultName is the file name of the run log within a project
+const DefaultName = ".pulumi-rollback-runs.json"
+
+// Run is the recorded outcome of one rollback
+type Run struct {
+	Stack         string    `json:"stack"`
+	TargetVersion int       `json:"targetVersion"`
+	Success       bool      `json:"success"`
+	CompletedAt   time.Time `json:"completedAt"`
+	// Result is the result document of the run, replayed as is
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Log maps run ids to their recorded outcome
+type Log struct {
+	Runs map[string]Run `json:"runs"`
+}
+
+// Load reads a run log. A missing file yields an empty log.
+func Load(path string) (*Log, error) {
+	log := &Log{Runs: make(map[string]Run)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return log, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, log); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if log.Runs == nil {
+		log.Runs = make(map[string]Run)
+	}
+	return log, nil
+}
+
+// Save writes the log to path, replacing it atomically so a crash never
+// loses the runs recorded earlier
+func (l *Log) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Replay returns the successful run recorded for runID, which must have
+// rolled back the same stack to the same version. It returns false when
+// the run id is unknown or its run failed, so the rollback may be retried.
+func (l *Log) Replay(runID, stack string, targetVersion int) (*Run, bool, error) {
+	run, ok := l.Runs[runID]
+	if !ok || !run.Success {
+		return nil, false, nil
+	}
+	if run.Stack != stack || run.TargetVersion != targetVersion {
+		return nil, false, fmt.Errorf("run id %s already rolled back stack %s to version %d; use a new run id",
+			runID, run.Stack, run.TargetVersion)
+	}
+	return &run, true, nil
+}
+
+// Record stores the outcome of a run, replacing any earlier attempt
+func (l *Log) Record(runID string, run Run) {
+	l.Runs[runID] = run
+}
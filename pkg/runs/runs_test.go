@@ -0,0 +1,90 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package runs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadMissing(t *testing.T) {
+	log, err := Load(filepath.Join(t.TempDir(), DefaultName))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(log.Runs) != 0 {
+		t.Errorf("Expected an empty log, got %v", log.Runs)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultName)
+	if err := os.WriteFile(path, []byte(`{"runs":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultName)
+	log, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	log.Record("ci-42", Run{Stack: "prod", TargetVersion: 5, Success: true, CompletedAt: time.Now().UTC(), Result: json.RawMessage(`{"success":true}`)})
+	log.Record("ci-43", Run{Stack: "prod", TargetVersion: 5, Success: false})
+	if err := log.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	log, err = Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		runID       string
+		stack       string
+		version     int
+		expected    bool
+		expectError bool
+	}{
+		{name: "succeeded", runID: "ci-42", stack: "prod", version: 5, expected: true},
+		{name: "failed run may be retried", runID: "ci-43", stack: "prod", version: 5},
+		{name: "unknown run", runID: "ci-44", stack: "prod", version: 5},
+		{name: "other stack", runID: "ci-42", stack: "staging", version: 5, expectError: true},
+		{name: "other version", runID: "ci-42", stack: "prod", version: 4, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, ok, err := log.Replay(tt.runID, tt.stack, tt.version)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error for a reused run id")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ok != tt.expected {
+				t.Fatalf("Expected replay %v, got %v", tt.expected, ok)
+			}
+			if !ok {
+				return
+			}
+			var result struct{ Success bool }
+			if err := json.Unmarshal(run.Result, &result); err != nil || !result.Success {
+				t.Errorf("Expected the recorded result, got %s", run.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// State is saved between watcher runs so that a failed version is handled
+// once, even when the watcher restarts
+type State struct {
+	Stack string `json:"stack"`
+	// LastHandledFailure is the newest failed version the watcher acted on
+	LastHandledFailure int       `json:"lastHandledFailure"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// StateFileName returns the default state file name for a stack
+func StateFileName(stack string) string {
+	return ".pulumi-rollback-watch-" + strings.ReplaceAll(stack, "/", "_") + ".json"
+}
+
+// LoadState reads the state of a stack. A missing file yields an empty
+// state. A file recorded for another stack is an error.
+func LoadState(path, stack string) (*State, error) {
+	state := &State{Stack: stack}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if state.Stack != stack {
+		return nil, fmt.Errorf("%s records stack %s, not %s", path, state.Stack, stack)
+	}
+	if state.LastHandledFailure < 0 {
+		return nil, fmt.Errorf("invalid version %d in %s", state.LastHandledFailure, path)
+	}
+	return state, nil
+}
+
+// Save writes the state to path, replacing the file atomically so a crash
+// never leaves a truncated state behind
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
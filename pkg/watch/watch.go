@@ -0,0 +1,280 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+// Package watch polls a stack's history and rolls back failed deployments.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/logging"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+)
+
+// EventKind describes what a watcher did about a failed deployment
+type EventKind string
+
+const (
+	// EventRollbackAvailable reports a failure that was previewed but not
+	// rolled back because auto-rollback is off
+	EventRollbackAvailable EventKind = "rollback-available"
+	// EventRolledBack reports a successful automatic rollback
+	EventRolledBack EventKind = "rolled-back"
+	// EventRollbackFailed reports a failed preview or rollback
+	EventRollbackFailed EventKind = "rollback-failed"
+	// EventSkipped reports a failure the watcher will not act on
+	EventSkipped EventKind = "skipped"
+)
+
+// Event is sent to the notifier whenever the watcher handles a failure
+type Event struct {
+	Kind          EventKind      `json:"kind"`
+	Stack         string         `json:"stack"`
+	FailedVersion int            `json:"failedVersion"`
+	TargetVersion int            `json:"targetVersion,omitempty"`
+	Message       string         `json:"message"`
+	Changes       map[string]int `json:"changes,omitempty"`
+	Time          time.Time      `json:"time"`
+}
+
+// RollbackFunc previews or executes a rollback to the target version
+type RollbackFunc func(ctx context.Context, target int) (*rollback.RollbackResult, error)
+
+// NotifyFunc delivers an event
+type NotifyFunc func(ctx context.Context, event Event) error
+
+// Options configures a Watcher
+type Options struct {
+	ProjectPath string
+	StackName   string
+	Selector    history.StackSelector
+
+	// Interval is the time between history polls
+	Interval time.Duration
+	// Cooldown is the minimum time between two automatic rollbacks
+	Cooldown time.Duration
+	// AutoRollback executes the rollback after a successful preview
+	AutoRollback bool
+	// MaxRollbacks stops automatic rollbacks after this many. Zero means no limit.
+	MaxRollbacks int
+	// StateFile, when set, records the last handled failed version so that
+	// after a restart the watcher only acts on newer failures
+	StateFile string
+
+	Preview  RollbackFunc
+	Rollback RollbackFunc
+	// Notify is optional
+	Notify NotifyFunc
+	// Logger is optional
+	Logger logging.Logger
+	// Now is optional: defaults to time.Now
+	Now func() time.Time
+}
+
+// Watcher detects failed deployments and rolls them back. Each failed
+// version is handled at most once, across restarts when a state file is set.
+type Watcher struct {
+	opts Options
+	// history caches the stack's history between polls, newest first
+	history      []history.UpdateInfo
+	handled      int
+	rollbacks    int
+	lastRollback time.Time
+}
+
+// New returns a watcher for opts
+func New(opts Options) (*Watcher, error) {
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", opts.Interval)
+	}
+	if opts.Cooldown < 0 {
+		return nil, fmt.Errorf("cooldown must not be negative, got %s", opts.Cooldown)
+	}
+	if opts.Preview == nil || (opts.AutoRollback && opts.Rollback == nil) {
+		return nil, errors.New("preview and rollback functions are required")
+	}
+	if opts.Selector == nil {
+		opts.Selector = history.DefaultSelector
+	}
+	if opts.Logger == nil {
+		opts.Logger = logging.Discard
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	w := &Watcher{opts: opts}
+	if opts.StateFile != "" {
+		state, err := LoadState(opts.StateFile, opts.StackName)
+		if err != nil {
+			return nil, err
+		}
+		w.handled = state.LastHandledFailure
+		if w.handled > 0 {
+			opts.Logger.Debugf("failures up to version %d were already handled", w.handled)
+		}
+	}
+	return w, nil
+}
+
+// Run polls until ctx is cancelled, then returns nil. Errors from a single
+// poll are logged and do not stop the watcher.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Check(ctx); err != nil && ctx.Err() == nil {
+			w.opts.Logger.Warnf("poll failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check polls the history once and handles a new failure of the latest
+// deployment. It returns the event sent, or nil if there was nothing to do.
+func (w *Watcher) Check(ctx context.Context) (*Event, error) {
+	updates, err := w.fetchHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	latest := updates[0]
+	if latest.Result != "failed" || latest.Version <= w.handled {
+		return nil, nil
+	}
+
+	now := w.opts.Now()
+	if !w.lastRollback.IsZero() && now.Sub(w.lastRollback) < w.opts.Cooldown {
+		// Handled on a later poll, once the cooldown has passed
+		w.opts.Logger.Debugf("version %d failed; waiting for cooldown", latest.Version)
+		return nil, nil
+	}
+	w.setHandled(latest.Version)
+
+	event := Event{Stack: w.opts.StackName, FailedVersion: latest.Version, Time: now}
+	w.handle(ctx, latest, updates, &event)
+	w.notify(ctx, event)
+	return &event, nil
+}
+
+// handle decides what to do about a failed update and fills in event
+func (w *Watcher) handle(ctx context.Context, failed history.UpdateInfo, updates []history.UpdateInfo, event *Event) {
+	// A failed rollback must not trigger another rollback
+	if failed.IsRollback() {
+		event.Kind = EventSkipped
+		event.Message = fmt.Sprintf("version %d is a failed rollback; manual intervention required", failed.Version)
+		return
+	}
+	if w.opts.AutoRollback && w.opts.MaxRollbacks > 0 && w.rollbacks >= w.opts.MaxRollbacks {
+		event.Kind = EventSkipped
+		event.Message = fmt.Sprintf("version %d failed but the limit of %d automatic rollback(s) was reached", failed.Version, w.opts.MaxRollbacks)
+		return
+	}
+
+	target := lastSucceeded(updates, failed.Version)
+	if target == 0 {
+		event.Kind = EventSkipped
+		event.Message = fmt.Sprintf("version %d failed and no earlier version succeeded", failed.Version)
+		return
+	}
+	event.TargetVersion = target
+
+	preview, err := w.opts.Preview(ctx, target)
+	if err != nil {
+		event.Kind = EventRollbackFailed
+		event.Message = fmt.Sprintf("preview of rollback to version %d failed: %v", target, err)
+		return
+	}
+	event.Changes = preview.ResourceChanges
+
+	if !w.opts.AutoRollback {
+		event.Kind = EventRollbackAvailable
+		event.Message = fmt.Sprintf("version %d failed; rollback to version %d is available", failed.Version, target)
+		return
+	}
+
+	w.rollbacks++
+	w.lastRollback = w.opts.Now()
+	result, err := w.opts.Rollback(ctx, target)
+	if err != nil {
+		event.Kind = EventRollbackFailed
+		event.Message = fmt.Sprintf("rollback to version %d failed: %v", target, err)
+		return
+	}
+	event.Kind = EventRolledBack
+	event.Message = fmt.Sprintf("version %d failed; rolled back to version %d", failed.Version, target)
+	event.Changes = result.ResourceChanges
+}
+
+// fetchHistory returns the stack's history, fetching only the updates that
+// are new or were still running at the previous poll
+func (w *Watcher) fetchHistory(ctx context.Context) ([]history.UpdateInfo, error) {
+	since := 0
+	for _, u := range w.history {
+		if u.Result == "succeeded" || u.Result == "failed" {
+			since = u.Version
+			break
+		}
+	}
+
+	delta, err := history.GetHistorySince(ctx, w.opts.ProjectPath, w.opts.StackName, since, w.opts.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if delta.Reset {
+		w.opts.Logger.Warnf("stack %s was recreated; watching its new history", w.opts.StackName)
+		w.history = history.MergeHistory(nil, delta.Updates)
+		w.setHandled(0)
+	} else {
+		w.history = history.MergeHistory(w.history, delta.Updates)
+	}
+	return w.history, nil
+}
+
+// setHandled records the last handled failed version, saving it to the
+// state file if there is one. A failed save is logged: the failure is still
+// handled once for as long as the watcher runs.
+func (w *Watcher) setHandled(version int) {
+	w.handled = version
+	if w.opts.StateFile == "" {
+		return
+	}
+	state := &State{Stack: w.opts.StackName, LastHandledFailure: version, UpdatedAt: w.opts.Now()}
+	if err := state.Save(w.opts.StateFile); err != nil {
+		w.opts.Logger.Warnf("failed to save watch state: %v", err)
+	}
+}
+
+// notify sends event to the notifier, logging failures
+func (w *Watcher) notify(ctx context.Context, event Event) {
+	w.opts.Logger.Infof("%s", event.Message)
+	if w.opts.Notify == nil {
+		return
+	}
+	if err := w.opts.Notify(ctx, event); err != nil {
+		w.opts.Logger.Warnf("notification failed: %v", err)
+	}
+}
+
+// lastSucceeded returns the newest succeeded version before version, or 0
+func lastSucceeded(updates []history.UpdateInfo, version int) int {
+	target := 0
+	for _, u := range updates {
+		if u.Version < version && u.Result == "succeeded" && u.Version > target {
+			target = u.Version
+		}
+	}
+	return target
+}
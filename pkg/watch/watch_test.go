@@ -0,0 +1,348 @@
+// Copyright 2026 Pegasus Heavy Industries LLC
+// Contact: pegasusheavyindustries@gmail.com
+
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/history"
+	"github.com/PegasusHeavyIndustries/pulumi-rollback/pkg/rollback"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// MockStack returns a fixed history
+type MockStack struct {
+	Updates []auto.UpdateSummary
+}
+
+func (m *MockStack) History(ctx context.Context, pageSize int, page int) ([]auto.UpdateSummary, error) {
+	return m.Updates, nil
+}
+
+// MockStackSelector always selects Stack
+type MockStackSelector struct {
+	Stack *MockStack
+}
+
+func (m *MockStackSelector) SelectStack(ctx context.Context, stackName, projectPath string) (history.Stack, error) {
+	return m.Stack, nil
+}
+
+func newTestWatcher(t *testing.T, stack *MockStack, opts Options) (*Watcher, *[]int) {
+	t.Helper()
+	var rollbacks []int
+	opts.StackName = "test"
+	opts.Interval = time.Minute
+	opts.Selector = &MockStackSelector{Stack: stack}
+	opts.Preview = func(ctx context.Context, target int) (*rollback.RollbackResult, error) {
+		return &rollback.RollbackResult{Success: true}, nil
+	}
+	opts.Rollback = func(ctx context.Context, target int) (*rollback.RollbackResult, error) {
+		rollbacks = append(rollbacks, target)
+		return &rollback.RollbackResult{Success: true}, nil
+	}
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return w, &rollbacks
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name              string
+		updates           []auto.UpdateSummary
+		autoRollback      bool
+		expectedKind      EventKind
+		expectedTarget    int
+		expectedRollbacks int
+	}{
+		{
+			name:    "latest succeeded",
+			updates: []auto.UpdateSummary{{Version: 2, Result: "succeeded"}, {Version: 1, Result: "succeeded"}},
+		},
+		{
+			name:           "failure without auto-rollback",
+			updates:        []auto.UpdateSummary{{Version: 3, Result: "failed"}, {Version: 2, Result: "failed"}, {Version: 1, Result: "succeeded"}},
+			expectedKind:   EventRollbackAvailable,
+			expectedTarget: 1,
+		},
+		{
+			name:              "failure with auto-rollback",
+			updates:           []auto.UpdateSummary{{Version: 2, Result: "failed"}, {Version: 1, Result: "succeeded"}},
+			autoRollback:      true,
+			expectedKind:      EventRolledBack,
+			expectedTarget:    1,
+			expectedRollbacks: 1,
+		},
+		{
+			name:         "no earlier success",
+			updates:      []auto.UpdateSummary{{Version: 1, Result: "failed"}},
+			autoRollback: true,
+			expectedKind: EventSkipped,
+		},
+		{
+			name: "failed rollback",
+			updates: []auto.UpdateSummary{
+				{Version: 2, Result: "failed", Message: history.RollbackMessage(history.Provenance{SourceVersion: 1})},
+				{Version: 1, Result: "succeeded"},
+			},
+			autoRollback: true,
+			expectedKind: EventSkipped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, rollbacks := newTestWatcher(t, &MockStack{Updates: tt.updates}, Options{AutoRollback: tt.autoRollback})
+
+			event, err := w.Check(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.expectedKind == "" {
+				if event != nil {
+					t.Errorf("Expected no event, got %+v", event)
+				}
+				return
+			}
+			if event == nil {
+				t.Fatalf("Expected %s event, got none", tt.expectedKind)
+			}
+			if event.Kind != tt.expectedKind {
+				t.Errorf("Expected kind %s, got %s (%s)", tt.expectedKind, event.Kind, event.Message)
+			}
+			if event.TargetVersion != tt.expectedTarget {
+				t.Errorf("Expected target %d, got %d", tt.expectedTarget, event.TargetVersion)
+			}
+			if len(*rollbacks) != tt.expectedRollbacks {
+				t.Errorf("Expected %d rollback(s), got %d", tt.expectedRollbacks, len(*rollbacks))
+			}
+		})
+	}
+}
+
+func TestCheck_Debounce(t *testing.T) {
+	stack := &MockStack{Updates: []auto.UpdateSummary{{Version: 2, Result: "failed"}, {Version: 1, Result: "succeeded"}}}
+	w, rollbacks := newTestWatcher(t, stack, Options{AutoRollback: true})
+
+	for i := 0; i < 3; i++ {
+		if _, err := w.Check(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(*rollbacks) != 1 {
+		t.Errorf("Expected 1 rollback, got %d", len(*rollbacks))
+	}
+}
+
+func TestCheck_Cooldown(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stack := &MockStack{Updates: []auto.UpdateSummary{{Version: 2, Result: "failed"}, {Version: 1, Result: "succeeded"}}}
+	w, rollbacks := newTestWatcher(t, stack, Options{
+		AutoRollback: true,
+		Cooldown:     10 * time.Minute,
+		Now:          func() time.Time { return now },
+	})
+
+	if _, err := w.Check(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A new failure within the cooldown waits
+	stack.Updates = append([]auto.UpdateSummary{{Version: 4, Result: "failed"}, {Version: 3, Result: "succeeded"}}, stack.Updates...)
+	now = now.Add(5 * time.Minute)
+	if event, _ := w.Check(context.Background()); event != nil {
+		t.Errorf("Expected no event during cooldown, got %+v", event)
+	}
+
+	now = now.Add(10 * time.Minute)
+	event, err := w.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if event == nil || event.Kind != EventRolledBack || event.TargetVersion != 3 {
+		t.Errorf("Expected rollback to version 3 after cooldown, got %+v", event)
+	}
+	if len(*rollbacks) != 2 {
+		t.Errorf("Expected 2 rollbacks, got %d", len(*rollbacks))
+	}
+}
+
+func TestCheck_MaxRollbacks(t *testing.T) {
+	stack := &MockStack{Updates: []auto.UpdateSummary{{Version: 2, Result: "failed"}, {Version: 1, Result: "succeeded"}}}
+	w, rollbacks := newTestWatcher(t, stack, Options{AutoRollback: true, MaxRollbacks: 1})
+
+	if _, err := w.Check(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stack.Updates = append([]auto.UpdateSummary{{Version: 4, Result: "failed"}, {Version: 3, Result: "succeeded"}}, stack.Updates...)
+	event, err := w.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if event == nil || event.Kind != EventSkipped {
+		t.Errorf("Expected skipped event, got %+v", event)
+	}
+	if len(*rollbacks) != 1 {
+		t.Errorf("Expected 1 rollback, got %d", len(*rollbacks))
+	}
+}
+
+func TestCheck_HistoryReset(t *testing.T) {
+	stack := &MockStack{Updates: []auto.UpdateSummary{{Version: 5, Result: "failed"}, {Version: 4, Result: "succeeded"}}}
+	w, rollbacks := newTestWatcher(t, stack, Options{AutoRollback: true})
+
+	if _, err := w.Check(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The stack is recreated and fails at a version below the one handled
+	stack.Updates = []auto.UpdateSummary{{Version: 2, Result: "failed"}, {Version: 1, Result: "succeeded"}}
+	event, err := w.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if event == nil || event.FailedVersion != 2 || event.TargetVersion != 1 {
+		t.Errorf("Expected the new failure to be handled, got %+v", event)
+	}
+	if len(*rollbacks) != 2 {
+		t.Errorf("Expected 2 rollbacks, got %d", len(*rollbacks))
+	}
+}
+
+func TestCheck_OnlyNewFailures(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), StateFileName("org/proj/test"))
+	stack := &MockStack{Updates: []auto.UpdateSummary{{Version: 2, Result: "failed"}, {Version: 1, Result: "succeeded"}}}
+
+	w, rollbacks := newTestWatcher(t, stack, Options{AutoRollback: true, StateFile: stateFile})
+	if event, err := w.Check(context.Background()); err != nil || event == nil || event.Kind != EventRolledBack {
+		t.Fatalf("Expected the first failure to be rolled back, got %+v (%v)", event, err)
+	}
+
+	// A restarted watcher has already handled this failure
+	w, restartedRollbacks := newTestWatcher(t, stack, Options{AutoRollback: true, StateFile: stateFile})
+	event, err := w.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if event != nil {
+		t.Errorf("Expected no event for an already handled failure, got %+v", event)
+	}
+	if len(*restartedRollbacks) != 0 {
+		t.Errorf("Expected no rollback after restart, got %v", *restartedRollbacks)
+	}
+
+	// A newer failure is handled and recorded
+	stack.Updates = append([]auto.UpdateSummary{{Version: 4, Result: "failed"}, {Version: 3, Result: "succeeded"}}, stack.Updates...)
+	event, err = w.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if event == nil || event.FailedVersion != 4 || event.TargetVersion != 3 {
+		t.Errorf("Expected the new failure to be rolled back, got %+v", event)
+	}
+	if len(*rollbacks)+len(*restartedRollbacks) != 2 {
+		t.Errorf("Expected 2 rollbacks in total, got %d", len(*rollbacks)+len(*restartedRollbacks))
+	}
+
+	state, err := LoadState(stateFile, "test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.LastHandledFailure != 4 {
+		t.Errorf("Expected version 4 to be recorded, got %d", state.LastHandledFailure)
+	}
+}
+
+func TestLoadState(t *testing.T) {
+	dir := t.TempDir()
+
+	state, err := LoadState(filepath.Join(dir, "missing.json"), "test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.LastHandledFailure != 0 || state.Stack != "test" {
+		t.Errorf("Expected an empty state, got %+v", state)
+	}
+
+	path := filepath.Join(dir, "state.json")
+	if err := (&State{Stack: "other", LastHandledFailure: 3}).Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := LoadState(path, "test"); err == nil {
+		t.Error("Expected error for a state file of another stack")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"stack":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadState(path, "test"); err == nil {
+		t.Error("Expected error for a malformed state file")
+	}
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	w, _ := newTestWatcher(t, &MockStack{}, Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.Run(ctx); err != nil {
+		t.Errorf("Expected nil on cancel, got %v", err)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode event: %v", err)
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	event := Event{Kind: EventRolledBack, Stack: "test", FailedVersion: 2, TargetVersion: 1}
+	if err := WebhookNotifier(server.URL, nil)(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Kind != EventRolledBack || received.TargetVersion != 1 {
+		t.Errorf("Expected the event to be delivered, got %+v", received)
+	}
+
+	if err := WebhookNotifier(server.URL+"/fail", nil)(context.Background(), event); err == nil {
+		t.Error("Expected error for a failing webhook")
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	preview := func(ctx context.Context, target int) (*rollback.RollbackResult, error) {
+		return nil, errors.New("unused")
+	}
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "no interval", opts: Options{Preview: preview}},
+		{name: "negative cooldown", opts: Options{Interval: time.Second, Cooldown: -1, Preview: preview}},
+		{name: "no rollback", opts: Options{Interval: time.Second, Preview: preview, AutoRollback: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}